@@ -2,18 +2,27 @@ package auth
 
 import (
 	"context"
+	"fmt"
 
 	"github.com/hori-ryota/zaperr"
 	"go.uber.org/zap"
 )
 
+var ErrUserNotFound = fmt.Errorf("user not found")
+
 type User struct {
 	ID string
 }
 
+//go:generate moq -out authmocks/repository.go -pkg authmocks -rm . Repository:RepositoryMock
 type Repository interface {
+	// AddUser adds user or lets previously removed one back in
 	AddUser(ctx context.Context, user *User) error
+	// GetUser returns nil if there is no such user or user was removed
 	GetUser(ctx context.Context, userID string) (*User, error)
+	ListUsers(ctx context.Context) ([]*User, error)
+	// RemoveUser keeps user's data, but user is no longer returned by GetUser and ListUsers
+	RemoveUser(ctx context.Context, userID string) error
 }
 
 func New(adminUsername string, repository Repository, logger *zap.Logger) *Service {
@@ -38,6 +47,34 @@ func (auth *Service) AddUser(ctx context.Context, userID string) error {
 	return nil
 }
 
+func (auth *Service) ListUsers(ctx context.Context) ([]*User, error) {
+	users, err := auth.repository.ListUsers(ctx)
+	if err != nil {
+		return nil, zaperr.Wrap(err, "failed to list users")
+	}
+	return users, nil
+}
+
+// RemoveUser revokes user's access to the bot, user's feeds and episodes are kept,
+// so that adding user back restores everything
+func (auth *Service) RemoveUser(ctx context.Context, userID string) error {
+	zapFields := []zap.Field{zap.String("user_id", userID)}
+
+	user, err := auth.repository.GetUser(ctx, userID)
+	if err != nil {
+		return zaperr.Wrap(err, "failed to get user", zapFields...)
+	}
+	if user == nil {
+		return zaperr.Wrap(ErrUserNotFound, "", zapFields...)
+	}
+
+	if err := auth.repository.RemoveUser(ctx, userID); err != nil {
+		return zaperr.Wrap(err, "failed to remove user", zapFields...)
+	}
+	auth.logger.Info("user removed", zapFields...)
+	return nil
+}
+
 func (auth *Service) IsAuthenticated(ctx context.Context, userID string, username string) (bool, error) {
 	if isAdmin, err := auth.IsAdmin(ctx, username); err != nil {
 		return false, zaperr.Wrap(err, "error while checking if user is admin")
@@ -0,0 +1,82 @@
+package auth_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"go.uber.org/zap"
+	"tg-podcastotron/auth"
+	"tg-podcastotron/auth/authmocks"
+)
+
+func TestService__RemoveUser(t *testing.T) {
+	ctx := context.Background()
+
+	users := map[string]bool{"123": true}
+	repo := &authmocks.RepositoryMock{
+		GetUserFunc: func(ctx context.Context, userID string) (*auth.User, error) {
+			if !users[userID] {
+				return nil, nil
+			}
+			return &auth.User{ID: userID}, nil
+		},
+		RemoveUserFunc: func(ctx context.Context, userID string) error {
+			users[userID] = false
+			return nil
+		},
+	}
+	svc := auth.New("some-admin", repo, zap.NewNop())
+
+	if ok, err := svc.IsAuthenticated(ctx, "123", "some-user"); err != nil || !ok {
+		t.Fatalf("expected user to be authenticated before removal, got %v, %v", ok, err)
+	}
+
+	if err := svc.RemoveUser(ctx, "123"); err != nil {
+		t.Fatal(err)
+	}
+	if calls := repo.RemoveUserCalls(); len(calls) != 1 || calls[0].UserID != "123" {
+		t.Fatalf("expected user 123 to be removed from repository, got %v", calls)
+	}
+	if ok, err := svc.IsAuthenticated(ctx, "123", "some-user"); err != nil || ok {
+		t.Fatalf("expected removed user not to be authenticated, got %v, %v", ok, err)
+	}
+
+	if err := svc.RemoveUser(ctx, "456"); !errors.Is(err, auth.ErrUserNotFound) {
+		t.Fatalf("expected ErrUserNotFound for unknown user, got %v", err)
+	}
+	if calls := repo.RemoveUserCalls(); len(calls) != 1 {
+		t.Fatalf("expected unknown user not to be removed from repository")
+	}
+
+	// admin is not stored as a user, so it can't be locked out
+	if ok, err := svc.IsAuthenticated(ctx, "789", "some-admin"); err != nil || !ok {
+		t.Fatalf("expected admin to be authenticated, got %v, %v", ok, err)
+	}
+}
+
+func TestService__ListUsers(t *testing.T) {
+	ctx := context.Background()
+
+	repo := &authmocks.RepositoryMock{
+		ListUsersFunc: func(ctx context.Context) ([]*auth.User, error) {
+			return []*auth.User{{ID: "123"}, {ID: "456"}}, nil
+		},
+	}
+	svc := auth.New("some-admin", repo, zap.NewNop())
+
+	users, err := svc.ListUsers(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(users) != 2 || users[0].ID != "123" || users[1].ID != "456" {
+		t.Fatalf("expected users 123 and 456, got %v", users)
+	}
+
+	repo.ListUsersFunc = func(ctx context.Context) ([]*auth.User, error) {
+		return nil, errors.New("some error")
+	}
+	if _, err := svc.ListUsers(ctx); err == nil {
+		t.Fatalf("expected repository error to be returned")
+	}
+}
@@ -11,12 +11,19 @@ func NewSqliteRepository(db *sql.DB) Repository {
 	return &sqliteRepository{db: sqlx.NewDb(db, "sqlite3")}
 }
 
+// NewPostgresRepository shares queries with sqlite, postgres schema has the same tables and placeholders are rebound
+func NewPostgresRepository(db *sql.DB) Repository {
+	return &sqliteRepository{db: sqlx.NewDb(db, "postgres")}
+}
+
 type sqliteRepository struct {
 	db *sqlx.DB
 }
 
 func (s *sqliteRepository) AddUser(ctx context.Context, user *User) error {
-	result := s.db.MustExecContext(ctx, "INSERT INTO users (id) VALUES (?)", user.ID)
+	result := s.db.MustExecContext(ctx, s.db.Rebind(
+		"INSERT INTO users (id) VALUES (?) ON CONFLICT (id) DO UPDATE SET removed = FALSE",
+	), user.ID)
 	if _, err := result.RowsAffected(); err != nil {
 		return zaperr.Wrap(err, "failed to insert user")
 	}
@@ -25,7 +32,7 @@ func (s *sqliteRepository) AddUser(ctx context.Context, user *User) error {
 
 func (s *sqliteRepository) GetUser(ctx context.Context, userID string) (*User, error) {
 	user := &User{}
-	if err := s.db.GetContext(ctx, user, "SELECT * FROM users WHERE id = ?", userID); err != nil {
+	if err := s.db.GetContext(ctx, user, s.db.Rebind("SELECT id FROM users WHERE id = ? AND NOT removed"), userID); err != nil {
 		if err == sql.ErrNoRows {
 			return nil, nil
 		}
@@ -33,3 +40,18 @@ func (s *sqliteRepository) GetUser(ctx context.Context, userID string) (*User, e
 	}
 	return user, nil
 }
+
+func (s *sqliteRepository) ListUsers(ctx context.Context) ([]*User, error) {
+	var users []*User
+	if err := s.db.SelectContext(ctx, &users, "SELECT id FROM users WHERE NOT removed ORDER BY id"); err != nil {
+		return nil, zaperr.Wrap(err, "failed to select users")
+	}
+	return users, nil
+}
+
+func (s *sqliteRepository) RemoveUser(ctx context.Context, userID string) error {
+	if _, err := s.db.ExecContext(ctx, s.db.Rebind("UPDATE users SET removed = TRUE WHERE id = ?"), userID); err != nil {
+		return zaperr.Wrap(err, "failed to remove user")
+	}
+	return nil
+}
@@ -0,0 +1,169 @@
+package bot
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"html"
+	"strings"
+	"text/template"
+
+	"github.com/go-telegram/bot"
+	"github.com/go-telegram/bot/models"
+	"github.com/hori-ryota/zaperr"
+	"go.uber.org/zap"
+	"tg-podcastotron/service"
+)
+
+const defaultAnnouncementTemplate = "Episode #{{.EpisodeID}} ({{.EpisodeTitle}}) is now complete"
+
+const announcementTemplateHelp = `
+<b>Set new episode announcement:</b>
+<code>/template</code> &lt;template&gt;
+
+This message will be sent to you every time an episode is ready.
+Following placeholders are available:
+<code>{{.EpisodeID}}</code> - ID of the episode
+<code>{{.EpisodeTitle}}</code> - title of the episode
+<code>{{.FeedTitle}}</code> - title of the feed(s) the episode is published to
+<code>{{.URL}}</code> - URL of the episode file
+
+Send <code>/template -</code> to go back to the default announcement
+`
+
+// announcementData is what announcement template is rendered with
+type announcementData struct {
+	EpisodeID    string
+	EpisodeTitle string
+	FeedTitle    string
+	URL          string
+}
+
+// parseAnnouncementTemplate parses template and makes sure it renders given any episode
+func parseAnnouncementTemplate(text string) (*template.Template, error) {
+	tmpl, err := template.New("announcement").Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse template: %w", err)
+	}
+
+	b := &bytes.Buffer{}
+	if err := tmpl.Execute(b, announcementData{
+		EpisodeID:    "1",
+		EpisodeTitle: "Episode Title",
+		FeedTitle:    "Feed Title",
+		URL:          "https://example.com/episode.mp3",
+	}); err != nil {
+		return nil, fmt.Errorf("failed to render template: %w", err)
+	}
+	if strings.TrimSpace(b.String()) == "" {
+		return nil, fmt.Errorf("template renders to an empty message")
+	}
+
+	return tmpl, nil
+}
+
+func renderAnnouncement(text string, data announcementData) (string, error) {
+	tmpl, err := parseAnnouncementTemplate(text)
+	if err != nil {
+		return "", err
+	}
+
+	b := &bytes.Buffer{}
+	if err := tmpl.Execute(b, data); err != nil {
+		return "", fmt.Errorf("failed to render template: %w", err)
+	}
+	return b.String(), nil
+}
+
+func (ub *UndercastBot) announcementTemplateHandler(ctx context.Context, _ *bot.Bot, update *models.Update) {
+	chatID := ub.extractChatID(update)
+	userID := ub.extractUserID(update)
+
+	zapFields := []zap.Field{
+		zap.Int64("chat_id", chatID),
+		zap.String("user_id", userID),
+		zap.String("message_text", update.Message.Text),
+	}
+
+	text := strings.TrimSpace(strings.TrimPrefix(update.Message.Text, "/template"))
+	switch text {
+	case "":
+		current, err := ub.repository.GetAnnouncementTemplate(ctx, userID)
+		if err != nil {
+			ub.handleError(ctx, chatID, zaperr.Wrap(err, "failed to get announcement template", zapFields...))
+			return
+		}
+		if current == "" {
+			current = defaultAnnouncementTemplate
+		}
+		if _, err := ub.bot.SendMessage(ctx, &bot.SendMessageParams{
+			ChatID:    chatID,
+			Text:      announcementTemplateHelp + fmt.Sprintf("\nCurrent template:\n<code>%s</code>", html.EscapeString(current)),
+			ParseMode: models.ParseModeHTML,
+		}); err != nil {
+			ub.handleError(ctx, chatID, zaperr.Wrap(err, "failed to send message", zapFields...))
+		}
+		return
+	case "-":
+		if err := ub.repository.DeleteAnnouncementTemplate(ctx, userID); err != nil {
+			ub.handleError(ctx, chatID, zaperr.Wrap(err, "failed to delete announcement template", zapFields...))
+			return
+		}
+		ub.sendTextMessage(ctx, chatID, "Default announcement will be used")
+		return
+	}
+
+	if _, err := parseAnnouncementTemplate(text); err != nil {
+		ub.sendTextMessage(ctx, chatID, "Invalid template: %s", err)
+		return
+	}
+
+	if err := ub.repository.SetAnnouncementTemplate(ctx, userID, text); err != nil {
+		ub.handleError(ctx, chatID, zaperr.Wrap(err, "failed to save announcement template", zapFields...))
+		return
+	}
+
+	ub.sendTextMessage(ctx, chatID, "Announcement template was saved")
+}
+
+// renderEpisodeAnnouncement renders user's announcement template for a complete episode,
+// falling back to the default one if user's template can not be rendered
+func (ub *UndercastBot) renderEpisodeAnnouncement(ctx context.Context, userID string, ep *service.Episode) string {
+	zapFields := []zap.Field{
+		zap.String("user_id", userID),
+		zap.String("episode_id", ep.ID),
+	}
+
+	data := announcementData{
+		EpisodeID:    ep.ID,
+		EpisodeTitle: ep.Title,
+		URL:          ep.URL,
+	}
+	if feeds, err := ub.service.ListEpisodeFeeds(ctx, userID, ep.ID); err == nil {
+		feedTitles := make([]string, 0, len(feeds))
+		for _, f := range feeds {
+			feedTitles = append(feedTitles, f.Title)
+		}
+		data.FeedTitle = strings.Join(feedTitles, ", ")
+	} else {
+		zapFields := append(zapFields, zaperr.ToField(err))
+		ub.logger.Error("failed to list episode feeds for announcement", zapFields...)
+	}
+
+	tmpl, err := ub.repository.GetAnnouncementTemplate(ctx, userID)
+	if err != nil {
+		zapFields := append(zapFields, zaperr.ToField(err))
+		ub.logger.Error("failed to get announcement template", zapFields...)
+	}
+	if tmpl != "" {
+		if text, err := renderAnnouncement(tmpl, data); err == nil {
+			return text
+		} else {
+			zapFields := append(zapFields, zaperr.ToField(err))
+			ub.logger.Error("failed to render announcement template", zapFields...)
+		}
+	}
+
+	text, _ := renderAnnouncement(defaultAnnouncementTemplate, data)
+	return text
+}
@@ -0,0 +1,42 @@
+package bot
+
+import "testing"
+
+func TestRenderAnnouncement(t *testing.T) {
+	data := announcementData{
+		EpisodeID:    "42",
+		EpisodeTitle: "Some Episode",
+		FeedTitle:    "Some Feed",
+		URL:          "https://example.com/some-episode.mp3",
+	}
+
+	t.Run("custom template", func(t *testing.T) {
+		got, err := renderAnnouncement("New in {{.FeedTitle}}: {{.EpisodeTitle}} (#{{.EpisodeID}}) {{.URL}}", data)
+		if err != nil {
+			t.Fatal(err)
+		}
+		want := "New in Some Feed: Some Episode (#42) https://example.com/some-episode.mp3"
+		if got != want {
+			t.Errorf("expected %q, got %q", want, got)
+		}
+	})
+
+	t.Run("default template", func(t *testing.T) {
+		got, err := renderAnnouncement(defaultAnnouncementTemplate, data)
+		if err != nil {
+			t.Fatal(err)
+		}
+		want := "Episode #42 (Some Episode) is now complete"
+		if got != want {
+			t.Errorf("expected %q, got %q", want, got)
+		}
+	})
+
+	t.Run("invalid templates are rejected", func(t *testing.T) {
+		for _, tmpl := range []string{"{{.EpisodeTitle", "{{.NoSuchField}}", "   "} {
+			if _, err := parseAnnouncementTemplate(tmpl); err == nil {
+				t.Errorf("expected template %q to be rejected", tmpl)
+			}
+		}
+	})
+}
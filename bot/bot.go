@@ -3,6 +3,8 @@ package bot
 import (
 	"context"
 	"fmt"
+	"net/http"
+	"sync"
 	"time"
 
 	"github.com/go-telegram/bot"
@@ -27,12 +29,24 @@ func NewUndercastBot(
 		auth:       auth,
 		service:    service,
 		repository: repository,
+		errorLog:   newErrorLog(errorLogSize),
+
+		statusChanges: newStatusChangesBuffer(),
+
+		telegramServerURL: defaultTelegramServerURL,
+		httpClient:        &http.Client{Timeout: telegramFileDownloadTimeout},
 	}
 }
 
 type Repository interface {
 	SetChatID(ctx context.Context, userID string, chatID int64) error
 	GetChatID(ctx context.Context, userID string) (int64, error)
+	SetAnnouncementTemplate(ctx context.Context, userID string, template string) error
+	GetAnnouncementTemplate(ctx context.Context, userID string) (string, error)
+	DeleteAnnouncementTemplate(ctx context.Context, userID string) error
+	SetStatusMessageID(ctx context.Context, userID string, epID string, messageID int) error
+	GetStatusMessageID(ctx context.Context, userID string, epID string) (int, error)
+	DeleteStatusMessageID(ctx context.Context, userID string, epID string) error
 }
 
 type UndercastBot struct {
@@ -42,14 +56,26 @@ type UndercastBot struct {
 	auth       *auth.Service
 	service    *service.Service
 	repository Repository
+	errorLog   *errorLog
+
+	telegramServerURL string       // base URL of Telegram Bot API, used to download files sent to the bot
+	httpClient        *http.Client // used to download files sent to the bot
 
 	episodesStatusChangesChan chan []service.EpisodeStatusChange
+	statusChanges             *statusChangesBuffer // status changes waiting to be sent to users in a single message
+
+	chatHandlersMu sync.Mutex
+	chatHandlers   map[int64][]string // handlers of unfinished interactive flows by chat, unregistered on /cancel
+
+	episodesPagersMu sync.Mutex
+	episodesPagers   map[int64]string // handler of the latest /ep page view by chat, earlier views stop flipping
 }
 
 func (ub *UndercastBot) Start(ctx context.Context) error {
 	opts := []bot.Option{
 		bot.WithDefaultHandler(ub.urlHandler),
 		bot.WithMiddlewares(ub.authenticate, ub.setMenuMiddleware),
+		bot.WithServerURL(ub.telegramServerURL),
 	}
 
 	ub.episodesStatusChangesChan = ub.service.Start(ctx)
@@ -64,7 +90,7 @@ func (ub *UndercastBot) Start(ctx context.Context) error {
 		}
 	}()
 
-	go ub.pollExpiredEpisodes(ctx, time.NewTicker(24*time.Hour), 30*24*time.Hour)
+	go ub.pollExpiredEpisodes(ctx, time.NewTicker(24*time.Hour), defaultEpisodesRetention, service.DeletedEpisodesRetention)
 
 	var err error
 	ub.bot, err = bot.New(ub.token, opts...)
@@ -74,15 +100,44 @@ func (ub *UndercastBot) Start(ctx context.Context) error {
 
 	ub.bot.RegisterHandler(bot.HandlerTypeMessageText, "/help", bot.MatchTypeExact, ub.helpHandler)
 	ub.bot.RegisterHandler(bot.HandlerTypeMessageText, "/start", bot.MatchTypeExact, ub.helpHandler)
-	ub.bot.RegisterHandler(bot.HandlerTypeMessageText, "/ep", bot.MatchTypePrefix, ub.listEpisodesHandler)
+	ub.bot.RegisterHandler(bot.HandlerTypeMessageText, "/cancel", bot.MatchTypeExact, ub.cancelHandler)
+	ub.bot.RegisterHandler(bot.HandlerTypeMessageText, "/ep", bot.MatchTypeExact, ub.listEpisodesHandler)
+	ub.bot.RegisterHandler(bot.HandlerTypeMessageText, "/ep_", bot.MatchTypePrefix, ub.listEpisodesHandler)
+	ub.bot.RegisterHandler(bot.HandlerTypeMessageText, "/epfeeds_", bot.MatchTypePrefix, ub.episodeFeedsHandler)
+	ub.bot.RegisterHandler(bot.HandlerTypeMessageText, "/setfeeds", bot.MatchTypePrefix, ub.setFeedsHandler)
 	ub.bot.RegisterHandler(bot.HandlerTypeMessageText, "/ee", bot.MatchTypePrefix, ub.editEpisodesHandler)
-	ub.bot.RegisterHandler(bot.HandlerTypeMessageText, "/f", bot.MatchTypePrefix, ub.listFeedsHandler)
+	// "/f" is not registered as a prefix so that it doesn't shadow "/feedsettings_"
+	ub.bot.RegisterHandler(bot.HandlerTypeMessageText, "/f", bot.MatchTypeExact, ub.listFeedsHandler)
+	ub.bot.RegisterHandler(bot.HandlerTypeMessageText, "/f_", bot.MatchTypePrefix, ub.listFeedsHandler)
 	ub.bot.RegisterHandler(bot.HandlerTypeMessageText, "/ef", bot.MatchTypePrefix, ub.editFeedsHandler)
+	ub.bot.RegisterHandler(bot.HandlerTypeMessageText, "/deletefeeds", bot.MatchTypePrefix, ub.deleteFeedsHandler)
 	ub.bot.RegisterHandler(bot.HandlerTypeMessageText, "/nf", bot.MatchTypeExact, ub.newFeedHandler)
+	ub.bot.RegisterHandler(bot.HandlerTypeMessageText, "/reorderfeeds", bot.MatchTypePrefix, ub.reorderFeedsHandler)
+	ub.bot.RegisterHandler(bot.HandlerTypeMessageText, "/getfeed_", bot.MatchTypePrefix, ub.getFeedHandler)
+	ub.bot.RegisterHandler(bot.HandlerTypeMessageText, "/feedsettings_", bot.MatchTypePrefix, ub.feedSettingsHandler)
+	ub.bot.RegisterHandler(bot.HandlerTypeMessageText, "/checkfeed_", bot.MatchTypePrefix, ub.checkFeedHandler)
+	ub.bot.RegisterHandler(bot.HandlerTypeMessageText, "/import", bot.MatchTypePrefix, ub.importFeedHandler)
+	ub.bot.RegisterHandler(bot.HandlerTypeMessageText, "/template", bot.MatchTypePrefix, ub.announcementTemplateHandler)
 	ub.bot.RegisterHandler(bot.HandlerTypeMessageText, "/adduser", bot.MatchTypeExact, ub.addUserHandler)
+	ub.bot.RegisterHandler(bot.HandlerTypeMessageText, "/users", bot.MatchTypeExact, ub.usersHandler)
+	ub.bot.RegisterHandler(bot.HandlerTypeMessageText, "/removeuser", bot.MatchTypePrefix, ub.removeUserHandler)
+	ub.bot.RegisterHandler(bot.HandlerTypeMessageText, "/stuck", bot.MatchTypeExact, ub.stuckEpisodesHandler)
+	ub.bot.RegisterHandler(bot.HandlerTypeMessageText, "/mylogs", bot.MatchTypeExact, ub.myLogsHandler)
+	ub.bot.RegisterHandler(bot.HandlerTypeMessageText, "/as_", bot.MatchTypePrefix, ub.impersonateHandler)
+	ub.bot.RegisterHandler(bot.HandlerTypeMessageText, "/regenfeed_", bot.MatchTypePrefix, ub.regenFeedHandler)
+	ub.bot.RegisterHandler(bot.HandlerTypeMessageText, "/rebuildfeeds", bot.MatchTypePrefix, ub.rebuildFeedsHandler)
+	ub.bot.RegisterHandler(bot.HandlerTypeMessageText, "/transferfeed", bot.MatchTypePrefix, ub.transferFeedHandler)
+	ub.bot.RegisterHandler(bot.HandlerTypeMessageText, "/search", bot.MatchTypePrefix, ub.searchHandler)
+	ub.bot.RegisterHandler(bot.HandlerTypeMessageText, "/export", bot.MatchTypeExact, ub.exportHandler)
+	ub.bot.RegisterHandler(bot.HandlerTypeMessageText, "/webhook", bot.MatchTypePrefix, ub.webhookHandler)
+	ub.bot.RegisterHandler(bot.HandlerTypeMessageText, "/stats", bot.MatchTypePrefix, ub.statsHandler)
+	ub.bot.RegisterHandler(bot.HandlerTypeMessageText, "/retention", bot.MatchTypePrefix, ub.retentionHandler)
+	ub.bot.RegisterHandler(bot.HandlerTypeMessageText, "/trash", bot.MatchTypeExact, ub.trashHandler)
+	ub.bot.RegisterHandler(bot.HandlerTypeMessageText, "/restore_", bot.MatchTypePrefix, ub.restoreEpisodesHandler)
 	ub.bot.RegisterHandlerMatchFunc(func(update *models.Update) bool {
 		return update != nil && update.Message != nil && update.Message.Contact != nil
 	}, ub.addUserHandler)
+	ub.bot.RegisterHandlerMatchFunc(isUpload, ub.uploadHandler)
 	ub.bot.Start(ctx)
 
 	return nil
@@ -91,7 +146,8 @@ func (ub *UndercastBot) Start(ctx context.Context) error {
 func (ub *UndercastBot) pollExpiredEpisodes(
 	ctx context.Context,
 	pollingTicker *time.Ticker,
-	epExpirationAge time.Duration,
+	defaultEpExpirationAge time.Duration,
+	deletedEpRetention time.Duration,
 ) {
 	ub.logger.Info("starting expired episodes poller")
 	for {
@@ -100,7 +156,7 @@ func (ub *UndercastBot) pollExpiredEpisodes(
 			return
 		case <-pollingTicker.C:
 			ub.logger.Info("listing expired episodes")
-			expiredEps, err := ub.service.ListExpiredEpisodes(ctx, epExpirationAge)
+			expiredEps, err := ub.service.ListExpiredEpisodes(ctx, defaultEpExpirationAge)
 			if err != nil {
 				ub.logger.Error("error while listing expired episodes", zaperr.ToField(err))
 				continue
@@ -118,6 +174,13 @@ func (ub *UndercastBot) pollExpiredEpisodes(
 					)
 				}
 			}
+
+			purged, err := ub.service.PurgeDeletedEpisodes(ctx, deletedEpRetention)
+			if err != nil {
+				ub.logger.Error("error while purging deleted episodes", zaperr.ToField(err))
+			} else {
+				ub.logger.Info("purged deleted episodes", zap.Int("count", purged))
+			}
 		}
 	}
 }
@@ -125,6 +188,7 @@ func (ub *UndercastBot) pollExpiredEpisodes(
 func (ub *UndercastBot) handleError(ctx context.Context, chatID int64, err error) {
 	id := uuid.New().String()
 	ub.logger.Error("error", zap.String("id", id), zaperr.ToField(err))
+	ub.errorLog.Record(chatID, id, err)
 	ub.sendTextMessage(ctx, chatID, "An error occurred while processing your request. Please try again later. \nError ID: %s", id)
 }
 
@@ -0,0 +1,78 @@
+package bot
+
+import (
+	"context"
+
+	"github.com/go-telegram/bot"
+	"github.com/go-telegram/bot/models"
+)
+
+// cancelHandler abandons all interactive flows started in the chat, so that replies to their prompts
+// and presses of their buttons are no longer handled
+func (ub *UndercastBot) cancelHandler(ctx context.Context, _ *bot.Bot, update *models.Update) {
+	chatID := ub.extractChatID(update)
+
+	if ub.cancelChatHandlers(chatID) == 0 {
+		ub.sendTextMessage(ctx, chatID, "Nothing to cancel")
+		return
+	}
+	ub.sendTextMessage(ctx, chatID, "Cancelled")
+}
+
+// registerChatHandler registers a handler that belongs to an interactive flow in the chat,
+// so that the flow can be abandoned with /cancel
+func (ub *UndercastBot) registerChatHandler(chatID int64, handlerType bot.HandlerType, pattern string, matchType bot.MatchType, f bot.HandlerFunc) string {
+	handlerID := ub.bot.RegisterHandler(handlerType, pattern, matchType, f)
+	ub.trackChatHandler(chatID, handlerID)
+	return handlerID
+}
+
+// registerChatHandlerMatchFunc is registerChatHandler for handlers of replies to prompts
+func (ub *UndercastBot) registerChatHandlerMatchFunc(chatID int64, matchFunc func(update *models.Update) bool, f bot.HandlerFunc) string {
+	handlerID := ub.bot.RegisterHandlerMatchFunc(matchFunc, f)
+	ub.trackChatHandler(chatID, handlerID)
+	return handlerID
+}
+
+// trackChatHandler remembers handler registered elsewhere, e.g. by a keyboard, as belonging to the chat
+func (ub *UndercastBot) trackChatHandler(chatID int64, handlerID string) {
+	ub.chatHandlersMu.Lock()
+	defer ub.chatHandlersMu.Unlock()
+	if ub.chatHandlers == nil {
+		ub.chatHandlers = make(map[int64][]string)
+	}
+	ub.chatHandlers[chatID] = append(ub.chatHandlers[chatID], handlerID)
+}
+
+// unregisterChatHandler is used by flows that are finished
+func (ub *UndercastBot) unregisterChatHandler(chatID int64, handlerID string) {
+	ub.bot.UnregisterHandler(handlerID)
+
+	ub.chatHandlersMu.Lock()
+	defer ub.chatHandlersMu.Unlock()
+	handlerIDs := ub.chatHandlers[chatID]
+	for i, id := range handlerIDs {
+		if id == handlerID {
+			handlerIDs = append(handlerIDs[:i], handlerIDs[i+1:]...)
+			break
+		}
+	}
+	if len(handlerIDs) == 0 {
+		delete(ub.chatHandlers, chatID)
+	} else {
+		ub.chatHandlers[chatID] = handlerIDs
+	}
+}
+
+// cancelChatHandlers unregisters all handlers of the chat and returns how many there were
+func (ub *UndercastBot) cancelChatHandlers(chatID int64) int {
+	ub.chatHandlersMu.Lock()
+	handlerIDs := ub.chatHandlers[chatID]
+	delete(ub.chatHandlers, chatID)
+	ub.chatHandlersMu.Unlock()
+
+	for _, handlerID := range handlerIDs {
+		ub.bot.UnregisterHandler(handlerID)
+	}
+	return len(handlerIDs)
+}
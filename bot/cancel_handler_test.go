@@ -0,0 +1,100 @@
+package bot
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-telegram/bot"
+	"github.com/go-telegram/bot/models"
+	"go.uber.org/zap"
+	"tg-podcastotron/mediary/mediarymocks"
+	"tg-podcastotron/service"
+	"tg-podcastotron/service/servicemocks"
+)
+
+func TestUndercastBot__CancelHandler(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	token := "some-token"
+
+	// region fake Telegram Bot API delivering a reply to the feed name prompt on first poll for updates
+	var replyDelivered sync.Once
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if r.URL.Path != "/bot"+token+"/getUpdates" {
+			// feed name prompt is message 1
+			_, _ = w.Write([]byte(`{"ok":true,"result":{"message_id":1,"date":0,"chat":{"id":1,"type":"private"}}}`))
+			return
+		}
+
+		deliver := false
+		replyDelivered.Do(func() { deliver = true })
+		if !deliver {
+			time.Sleep(10 * time.Millisecond)
+			_, _ = w.Write([]byte(`{"ok":true,"result":[]}`))
+			return
+		}
+		_, _ = w.Write([]byte(`{"ok":true,"result":[{"update_id":1,"message":{
+			"message_id":2,"date":0,"chat":{"id":1,"type":"private"},"from":{"id":1,"is_bot":false,"first_name":"User"},
+			"text":"Stale Feed",
+			"reply_to_message":{"message_id":1,"date":0,"chat":{"id":1,"type":"private"}}
+		}}]}`))
+	}))
+	defer srv.Close()
+	// endregion
+
+	// reply that no handler is registered for ends up in default handler
+	unhandled := make(chan struct{})
+	var unhandledOnce sync.Once
+	b, err := bot.New(token, bot.WithServerURL(srv.URL), bot.WithSkipGetMe(), bot.WithDefaultHandler(
+		func(ctx context.Context, b *bot.Bot, update *models.Update) {
+			unhandledOnce.Do(func() { close(unhandled) })
+		},
+	))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	repo := getServiceRepo(t)
+	svc := service.New(
+		&mediarymocks.ServiceMock{}, repo, &servicemocks.MockS3Store{}, &fakeJobsQueue{},
+		"", "", false, func(s string) string { return s }, zap.NewNop(),
+	)
+	ub := &UndercastBot{
+		logger:            zap.NewNop(),
+		token:             token,
+		bot:               b,
+		service:           svc,
+		telegramServerURL: srv.URL,
+		httpClient:        srv.Client(),
+	}
+
+	chat := models.Chat{ID: 1}
+	from := &models.User{ID: 1}
+	ub.newFeedHandler(ctx, b, &models.Update{Message: &models.Message{Chat: chat, From: from, Text: "/nf"}})
+	ub.cancelHandler(ctx, b, &models.Update{Message: &models.Message{Chat: chat, From: from, Text: "/cancel"}})
+	if n := ub.cancelChatHandlers(chat.ID); n != 0 {
+		t.Fatalf("expected no handlers left after /cancel, got %d", n)
+	}
+
+	// updates are polled only now, so that the reply comes after /cancel
+	go b.Start(ctx)
+
+	select {
+	case <-unhandled:
+	case <-time.After(5 * time.Second):
+		t.Fatal("reply to cancelled prompt was handled")
+	}
+
+	feeds, err := repo.ListUserFeeds(ctx, "1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(feeds) != 0 {
+		t.Fatalf("expected no feeds to be created, got %d", len(feeds))
+	}
+}
@@ -0,0 +1,138 @@
+package bot
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"html"
+	"regexp"
+	"strings"
+
+	"github.com/go-telegram/bot"
+	"github.com/go-telegram/bot/models"
+	"github.com/hori-ryota/zaperr"
+	"go.uber.org/zap"
+	"tg-podcastotron/service"
+)
+
+// checkFeedHandler reports feed episodes whose files can't be downloaded,
+// offering to download them again or to remove them from the feed
+func (ub *UndercastBot) checkFeedHandler(ctx context.Context, _ *bot.Bot, update *models.Update) {
+	chatID := ub.extractChatID(update)
+	userID := ub.extractUserID(update)
+
+	zapFields := []zap.Field{
+		zap.Int64("chat_id", chatID),
+		zap.String("user_id", userID),
+		zap.String("message_text", update.Message.Text),
+	}
+
+	feedID, err := ub.parseCheckFeedCmd(update.Message.Text)
+	if err != nil {
+		ub.sendTextMessage(ctx, chatID, "Please specify feed ID, like so:\n/checkfeed_1")
+		return
+	}
+	zapFields = append(zapFields, zap.String("feed_id", feedID))
+
+	broken, err := ub.service.ValidateFeedEnclosures(ctx, userID, feedID)
+	if err != nil {
+		if errors.Is(err, service.ErrFeedNotFound) {
+			ub.sendTextMessage(ctx, chatID, "Feed #%s not found", feedID)
+			return
+		}
+		ub.handleError(ctx, chatID, zaperr.Wrap(err, "failed to validate feed enclosures", zapFields...))
+		return
+	}
+
+	if len(broken) == 0 {
+		ub.sendTextMessage(ctx, chatID, "All episodes of feed #%s are downloadable", feedID)
+		return
+	}
+
+	epIDs := make([]string, 0, len(broken))
+	for _, b := range broken {
+		epIDs = append(epIDs, b.Episode.ID)
+	}
+
+	prefix := fmt.Sprintf("checkFeed_%s_%s", userID, bot.RandomString(10))
+	cmdRedownload := "redownload"
+	cmdUnpublish := "unpublish"
+
+	kb := [][]models.InlineKeyboardButton{
+		{{
+			Text:         fmt.Sprintf("Re-download All (%d)", len(broken)),
+			CallbackData: prefix + cmdRedownload,
+		}},
+		{{
+			Text:         fmt.Sprintf("Unpublish All (%d)", len(broken)),
+			CallbackData: prefix + cmdUnpublish,
+		}},
+	}
+
+	initialMsg, err := ub.bot.SendMessage(ctx, &bot.SendMessageParams{
+		ChatID:      chatID,
+		Text:        formatBrokenEnclosuresMessage(feedID, broken),
+		ParseMode:   models.ParseModeHTML,
+		ReplyMarkup: &models.InlineKeyboardMarkup{InlineKeyboard: kb},
+	})
+	if err != nil {
+		ub.handleError(ctx, chatID, zaperr.Wrap(err, "failed to send message", zapFields...))
+		return
+	}
+
+	var handlerID string
+	handlerID = ub.registerChatHandler(chatID, bot.HandlerTypeCallbackQueryData, prefix, bot.MatchTypePrefix, func(ctx context.Context, b *bot.Bot, update *models.Update) {
+		ub.unregisterChatHandler(chatID, handlerID)
+
+		zapFields := append(zapFields, zap.Strings("episode_ids", epIDs))
+		switch strings.ReplaceAll(update.CallbackQuery.Data, prefix, "") {
+		case cmdRedownload:
+			count, err := ub.service.RedownloadEpisodes(ctx, userID, epIDs)
+			if err != nil {
+				ub.handleError(ctx, chatID, zaperr.Wrap(err, "failed to redownload episodes", zapFields...))
+				return
+			}
+			ub.sendTextMessage(ctx, chatID, "%d episodes are being downloaded again", count)
+		case cmdUnpublish:
+			if err := ub.service.UnpublishEpisodes(ctx, userID, feedID, epIDs); err != nil {
+				ub.handleError(ctx, chatID, zaperr.Wrap(err, "failed to unpublish episodes", zapFields...))
+				return
+			}
+			ub.sendTextMessage(ctx, chatID, "%d episodes were removed from feed #%s", len(epIDs), feedID)
+		}
+
+		if _, err := ub.bot.DeleteMessage(ctx, &bot.DeleteMessageParams{
+			ChatID:    chatID,
+			MessageID: initialMsg.ID,
+		}); err != nil {
+			zapFields := append(zapFields, zaperr.ToField(err))
+			ub.logger.Error("failed to delete broken episodes message", zapFields...)
+		}
+	})
+}
+
+func (ub *UndercastBot) parseCheckFeedCmd(text string) (string, error) {
+	re := regexp.MustCompile(`/checkfeed_(\d+)`)
+	matches := re.FindStringSubmatch(text)
+	if len(matches) != 2 {
+		return "", fmt.Errorf("invalid command")
+	}
+	return matches[1], nil
+}
+
+func formatBrokenEnclosuresMessage(feedID string, broken []*service.BrokenEnclosure) string {
+	lines := []string{fmt.Sprintf("<b>%d episodes of feed #%s can't be downloaded:</b>", len(broken), feedID)}
+	for _, b := range broken {
+		reason := html.EscapeString(b.Err.Error())
+		if b.StatusCode != 0 {
+			reason = fmt.Sprintf("HTTP %d", b.StatusCode)
+		}
+		line := fmt.Sprintf("- #<code>%s</code> (%s): %s", b.Episode.ID, html.EscapeString(b.Episode.Title), reason)
+		if len(strings.Join(lines, "\n"))+len(line) > 4000 {
+			lines = append(lines, "...")
+			break
+		}
+		lines = append(lines, line)
+	}
+	return strings.Join(lines, "\n")
+}
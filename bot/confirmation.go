@@ -0,0 +1,58 @@
+package bot
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/go-telegram/bot"
+	"github.com/go-telegram/bot/models"
+	"github.com/hori-ryota/zaperr"
+	"go.uber.org/zap"
+)
+
+// askConfirmation sends text with confirm and cancel buttons and calls onConfirm only if user confirms.
+// The question is deleted as soon as either button is tapped
+func (ub *UndercastBot) askConfirmation(
+	ctx context.Context,
+	chatID int64,
+	userID string,
+	text string,
+	confirmText string,
+	onConfirm func(ctx context.Context),
+	zapFields []zap.Field,
+) {
+	prefix := fmt.Sprintf("confirm_%s_%s", userID, bot.RandomString(10))
+	cmdConfirm := "confirm"
+	cmdCancel := "cancel"
+
+	confirmationMsg, err := ub.bot.SendMessage(ctx, &bot.SendMessageParams{
+		ChatID: chatID,
+		Text:   text,
+		ReplyMarkup: &models.InlineKeyboardMarkup{InlineKeyboard: [][]models.InlineKeyboardButton{
+			{{Text: confirmText, CallbackData: prefix + cmdConfirm}},
+			{{Text: "Cancel", CallbackData: prefix + cmdCancel}},
+		}},
+	})
+	if err != nil {
+		ub.handleError(ctx, chatID, zaperr.Wrap(err, "failed to send confirmation message", zapFields...))
+		return
+	}
+
+	var handlerID string
+	handlerID = ub.registerChatHandler(chatID, bot.HandlerTypeCallbackQueryData, prefix, bot.MatchTypePrefix, func(ctx context.Context, b *bot.Bot, update *models.Update) {
+		ub.unregisterChatHandler(chatID, handlerID)
+
+		if _, err := ub.bot.DeleteMessage(ctx, &bot.DeleteMessageParams{
+			ChatID:    chatID,
+			MessageID: confirmationMsg.ID,
+		}); err != nil {
+			zapFields := append(zapFields, zaperr.ToField(err))
+			ub.logger.Error("failed to delete confirmation message", zapFields...)
+		}
+
+		if strings.TrimPrefix(update.CallbackQuery.Data, prefix) == cmdConfirm {
+			onConfirm(ctx)
+		}
+	})
+}
@@ -0,0 +1,130 @@
+package bot
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/go-telegram/bot"
+	"github.com/go-telegram/bot/models"
+	"github.com/hori-ryota/zaperr"
+	"go.uber.org/zap"
+	"tg-podcastotron/service"
+)
+
+// deleteFeedsHandler deletes several feeds at once with `/deletefeeds 2 3 4`, asking whether episodes should go too
+func (ub *UndercastBot) deleteFeedsHandler(ctx context.Context, _ *bot.Bot, update *models.Update) {
+	chatID := ub.extractChatID(update)
+	userID := ub.extractUserID(update)
+
+	zapFields := []zap.Field{
+		zap.Int64("chat_id", chatID),
+		zap.String("user_id", userID),
+		zap.String("message_text", update.Message.Text),
+	}
+
+	feedIDs, err := ub.parseDeleteFeedsCmd(update.Message.Text)
+	if err != nil {
+		ub.sendTextMessage(ctx, chatID, "Please use /deletefeeds 2 3 4 to delete feeds with IDs 2, 3 and 4")
+		return
+	}
+	zapFields = append(zapFields, zap.Strings("feed_ids", feedIDs))
+
+	prefix := fmt.Sprintf("deleteFeeds_%s_%s", userID, bot.RandomString(10))
+	cmdDeleteFeeds := "deleteFeeds"
+	cmdDeleteFeedsAndEpisodes := "deleteFeedsAndEpisodes"
+	cmdCancel := "cancel"
+
+	kb := [][]models.InlineKeyboardButton{
+		{{
+			Text:         "Delete Feeds",
+			CallbackData: prefix + cmdDeleteFeeds,
+		}},
+		{{
+			Text:         "Delete Feeds and Episodes",
+			CallbackData: prefix + cmdDeleteFeedsAndEpisodes,
+		}},
+		{{
+			Text:         "Cancel",
+			CallbackData: prefix + cmdCancel,
+		}},
+	}
+
+	initialMsg, err := ub.bot.SendMessage(ctx, &bot.SendMessageParams{
+		ChatID:      chatID,
+		Text:        fmt.Sprintf("Delete feeds #%s?", strings.Join(feedIDs, ", #")),
+		ReplyMarkup: &models.InlineKeyboardMarkup{InlineKeyboard: kb},
+	})
+	if err != nil {
+		ub.handleError(ctx, chatID, zaperr.Wrap(err, "failed to send message", zapFields...))
+		return
+	}
+
+	var handlerID string
+	handlerID = ub.registerChatHandler(chatID, bot.HandlerTypeCallbackQueryData, prefix, bot.MatchTypePrefix, func(ctx context.Context, b *bot.Bot, update *models.Update) {
+		ub.unregisterChatHandler(chatID, handlerID)
+
+		defer func() {
+			if _, err := ub.bot.DeleteMessage(ctx, &bot.DeleteMessageParams{
+				ChatID:    chatID,
+				MessageID: initialMsg.ID,
+			}); err != nil {
+				zapFields := append(zapFields, zaperr.ToField(err))
+				ub.logger.Error("failed to delete delete feeds message", zapFields...)
+			}
+		}()
+
+		st := strings.ReplaceAll(update.CallbackQuery.Data, prefix, "")
+		if st == cmdCancel {
+			return
+		}
+		shouldDeleteEpisodes := st == cmdDeleteFeedsAndEpisodes
+
+		results, err := ub.service.DeleteFeeds(ctx, userID, feedIDs, shouldDeleteEpisodes)
+		if err != nil {
+			ub.handleError(ctx, chatID, zaperr.Wrap(err, "failed to delete feeds", zapFields...))
+			return
+		}
+		ub.sendTextMessage(ctx, chatID, "%s", formatDeleteFeedsResults(results, shouldDeleteEpisodes))
+	})
+}
+
+func (ub *UndercastBot) parseDeleteFeedsCmd(text string) ([]string, error) {
+	re := regexp.MustCompile(`^/deletefeeds((?:\s+\d+)+)\s*$`)
+	matches := re.FindStringSubmatch(text)
+	if len(matches) != 2 {
+		return nil, fmt.Errorf("invalid command")
+	}
+	return strings.Fields(matches[1]), nil
+}
+
+func formatDeleteFeedsResults(results []*service.DeleteFeedResult, episodesDeleted bool) string {
+	var deleted []string
+	var lines []string
+	for _, r := range results {
+		switch {
+		case r.Err == nil:
+			deleted = append(deleted, "#"+r.FeedID)
+		case errors.Is(r.Err, service.ErrDefaultFeedNotDeletable):
+			lines = append(lines, fmt.Sprintf("Feed #%s is your default feed, it can't be deleted", r.FeedID))
+		case errors.Is(r.Err, service.ErrFeedNotFound):
+			lines = append(lines, fmt.Sprintf("Feed #%s was not found", r.FeedID))
+		default:
+			lines = append(lines, fmt.Sprintf("Feed #%s was not deleted: %s", r.FeedID, r.Err))
+		}
+	}
+
+	if len(deleted) == 0 {
+		return strings.Join(append([]string{"No feeds were deleted"}, lines...), "\n")
+	}
+
+	summary := fmt.Sprintf("Feeds %s were deleted\n", strings.Join(deleted, ", "))
+	if episodesDeleted {
+		summary += "All their episodes were deleted, too"
+	} else {
+		summary += "All episodes are left in your library"
+	}
+	return strings.Join(append([]string{summary}, lines...), "\n")
+}
@@ -2,10 +2,12 @@ package bot
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"regexp"
 	"slices"
 	"strings"
+	"time"
 
 	"github.com/go-telegram/bot"
 	"github.com/go-telegram/bot/models"
@@ -22,11 +24,37 @@ or
 <code>/ee_</code>&lt;episode_id&gt;_to_&lt;episode_id&gt;
 
 <b>Possible actions:</b>
-- <b>Rename Episodes</b> - rename episodes. Use <code>%n</code> as placeholder for number as extracted from original name
+- <b>Rename Episodes</b> - rename episodes, new name can have placeholders, see below
 - <b>Manage Episodes Feeds</b> - add or remove episodes from feeds
+- <b>Move to Feeds</b> - remove episodes from all feeds they are in and add them to selected feeds only
+- <b>Add Tag</b>/<b>Remove Tag</b> - add or remove tags of all selected episodes, keeping their other tags
+- <b>Mark Explicit</b>/<b>Mark Clean</b> - mark episodes explicit or not regardless of their feeds, <b>Reset Explicit</b> makes them follow their feeds again
+- <b>Set Publish Date</b> - make an episode appear in feeds with another date, e.g. the original one of an old recording
 - <b>Delete Episodes</b> - delete episodes from your library, remove them from feeds and delete files from cloud storage
+- <b>Split Episode</b> - split an episode glued from several files into one episode per file
+- <b>Merge Episodes</b> - glue episodes from the same link into one episode, in order of their IDs
+- <b>Convert to Opus</b> - re-encode complete episodes to opus, which takes less space
+- <b>Cancel Processing</b> - stop creating episodes which are not ready yet, e.g. if you sent a wrong link
+
+` + renameEpisodesPlaceholders
+
+// renameEpisodesPlaceholders lists tokens expanded in new episode names
+const renameEpisodesPlaceholders = `<b>Placeholders in new names:</b>
+<code>%v</code> - part of the original name that differs between episodes
+<code>%id</code> - episode ID
+<code>%n</code> - episode number among the selected ones, in order of their IDs
+<code>%date</code> - episode publish date
+<code>%feed</code> - title of the feed the episode is in
 `
 
+const renameEpisodesPrompt = "Please enter new name for the episodes\n\n" + renameEpisodesPlaceholders
+
+// pubDateLayout is the format users enter episode publish dates in
+const pubDateLayout = "2006-01-02"
+
+// reencodeFormat is the format episodes are converted to by "Convert to Opus" action
+const reencodeFormat = "opus"
+
 func (ub *UndercastBot) editEpisodesHandler(ctx context.Context, b *bot.Bot, update *models.Update) {
 	userID := ub.extractUserID(update)
 	chatID := ub.extractChatID(update)
@@ -54,6 +82,11 @@ func (ub *UndercastBot) editEpisodesHandler(ctx context.Context, b *bot.Bot, upd
 
 	episodesMap, err := ub.service.GetEpisodesMap(ctx, userID, epIDs)
 	if err != nil {
+		var notFoundErr *service.EpisodesNotFoundError
+		if errors.As(err, &notFoundErr) {
+			ub.sendTextMessage(ctx, chatID, "Episodes %s do not exist. Please try again with different IDs", strings.Join(notFoundErr.IDs, ", "))
+			return
+		}
 		ub.sendTextMessage(ctx, chatID, "At least one of the episodes you are trying to edit does not exist. Please try again with different IDs")
 		return
 	}
@@ -78,6 +111,17 @@ func (ub *UndercastBot) editEpisodesHandler(ctx context.Context, b *bot.Bot, upd
 	cmdRename := "rename"
 	cmdDelete := "delete"
 	cmdManageFeeds := "manageFeeds"
+	cmdMoveToFeeds := "moveToFeeds"
+	cmdSplit := "split"
+	cmdReencode := "reencode"
+	cmdAddTag := "addTag"
+	cmdRemoveTag := "removeTag"
+	cmdCancel := "cancel"
+	cmdSetPubDate := "setPubDate"
+	cmdMarkExplicit := "markExplicit"
+	cmdMarkClean := "markClean"
+	cmdResetExplicit := "resetExplicit"
+	cmdMerge := "merge"
 
 	kb := [][]models.InlineKeyboardButton{
 		{{
@@ -88,11 +132,61 @@ func (ub *UndercastBot) editEpisodesHandler(ctx context.Context, b *bot.Bot, upd
 			Text:         "Manage Episodes Feeds",
 			CallbackData: prefix + cmdManageFeeds,
 		}},
+		{{
+			Text:         "Move to Feeds",
+			CallbackData: prefix + cmdMoveToFeeds,
+		}},
+		{
+			{Text: "Add Tag", CallbackData: prefix + cmdAddTag},
+			{Text: "Remove Tag", CallbackData: prefix + cmdRemoveTag},
+		},
+		{
+			{Text: "Mark Explicit", CallbackData: prefix + cmdMarkExplicit},
+			{Text: "Mark Clean", CallbackData: prefix + cmdMarkClean},
+			{Text: "Reset Explicit", CallbackData: prefix + cmdResetExplicit},
+		},
 		{{
 			Text:         "Delete Episodes",
 			CallbackData: prefix + cmdDelete,
 		}},
 	}
+	if len(epIDs) == 1 {
+		kb = append(kb, []models.InlineKeyboardButton{{
+			Text:         "Set Publish Date",
+			CallbackData: prefix + cmdSetPubDate,
+		}})
+	}
+	if len(epIDs) > 1 && haveSameSource(episodesMap) {
+		kb = append(kb, []models.InlineKeyboardButton{{
+			Text:         "Merge Episodes",
+			CallbackData: prefix + cmdMerge,
+		}})
+	}
+	if len(epIDs) == 1 && len(episodesMap[epIDs[0]].SourceFilepaths) > 1 {
+		kb = append(kb, []models.InlineKeyboardButton{{
+			Text:         "Split Episode",
+			CallbackData: prefix + cmdSplit,
+		}})
+	}
+	for _, ep := range episodesMap {
+		// episodes are converted by making them again, so ones without source files, like uploaded, can't be
+		if ep.Status == service.EpisodeStatusComplete && ep.Format != reencodeFormat && len(ep.SourceFilepaths) > 0 {
+			kb = append(kb, []models.InlineKeyboardButton{{
+				Text:         "Convert to Opus",
+				CallbackData: prefix + cmdReencode,
+			}})
+			break
+		}
+	}
+	for _, ep := range episodesMap {
+		if isEpisodeInProgress(ep) {
+			kb = append(kb, []models.InlineKeyboardButton{{
+				Text:         "Cancel Processing",
+				CallbackData: prefix + cmdCancel,
+			}})
+			break
+		}
+	}
 
 	initialMsg, err := ub.bot.SendMessage(ctx, &bot.SendMessageParams{
 		ChatID:      chatID,
@@ -116,14 +210,14 @@ func (ub *UndercastBot) editEpisodesHandler(ctx context.Context, b *bot.Bot, upd
 		}
 	}
 
-	ub.bot.RegisterHandler(bot.HandlerTypeCallbackQueryData, prefix, bot.MatchTypePrefix, func(ctx context.Context, b *bot.Bot, update *models.Update) {
+	ub.registerChatHandler(chatID, bot.HandlerTypeCallbackQueryData, prefix, bot.MatchTypePrefix, func(ctx context.Context, b *bot.Bot, update *models.Update) {
 		st := strings.ReplaceAll(update.CallbackQuery.Data, prefix, "")
 
 		switch st {
 		case cmdRename:
 			if renamePromptMsg, err := ub.bot.SendMessage(ctx, &bot.SendMessageParams{
 				ChatID:      chatID,
-				Text:        "Please enter new name for the episodes",
+				Text:        renameEpisodesPrompt,
 				ParseMode:   models.ParseModeHTML,
 				ReplyMarkup: &models.ForceReply{ForceReply: true},
 			}); err != nil {
@@ -131,13 +225,17 @@ func (ub *UndercastBot) editEpisodesHandler(ctx context.Context, b *bot.Bot, upd
 				ub.handleError(ctx, chatID, zaperr.Wrap(err, "failed to send message", zapFields...))
 				return
 			} else {
-				ub.bot.RegisterHandlerMatchFunc(
+				ub.registerChatHandlerMatchFunc(
+					chatID,
 					func(update *models.Update) bool {
 						return update.Message.ReplyToMessage != nil && update.Message.ReplyToMessage.ID == renamePromptMsg.ID
 					},
 					func(ctx context.Context, b *bot.Bot, update *models.Update) {
 						newTitlePattern := update.Message.Text
-						if err := ub.service.RenameEpisodes(ctx, userID, epIDs, newTitlePattern); err != nil {
+						if err := ub.service.RenameEpisodes(ctx, userID, epIDs, newTitlePattern); errors.Is(err, service.ErrInvalidTitle) {
+							ub.sendTextMessage(ctx, chatID, invalidTitleMessage)
+							return
+						} else if err != nil {
 							ub.handleError(ctx, chatID, zaperr.Wrap(err, "failed to rename episodes", zapFields...))
 							return
 						}
@@ -159,17 +257,224 @@ func (ub *UndercastBot) editEpisodesHandler(ctx context.Context, b *bot.Bot, upd
 						ub.sendTextMessage(ctx, chatID, strings.Join(msgTextParts, "\n"))
 					})
 			}
-		case cmdDelete:
-			if err := ub.service.DeleteEpisodes(ctx, userID, epIDs); err != nil {
-				ub.handleError(ctx, chatID, zaperr.Wrap(err, "failed to delete episodes", zapFields...))
+		case cmdAddTag, cmdRemoveTag:
+			adding := st == cmdAddTag
+			promptText := "Please enter comma-separated tags to add to the episodes"
+			if !adding {
+				promptText = "Please enter comma-separated tags to remove from the episodes"
+			}
+			if tagsPromptMsg, err := ub.bot.SendMessage(ctx, &bot.SendMessageParams{
+				ChatID:      chatID,
+				Text:        promptText,
+				ParseMode:   models.ParseModeHTML,
+				ReplyMarkup: &models.ForceReply{ForceReply: true},
+			}); err != nil {
+				zapFields = append(zapFields, zap.Any("message", tagsPromptMsg))
+				ub.handleError(ctx, chatID, zaperr.Wrap(err, "failed to send message", zapFields...))
 				return
+			} else {
+				ub.registerChatHandlerMatchFunc(
+					chatID,
+					func(update *models.Update) bool {
+						return update.Message.ReplyToMessage != nil && update.Message.ReplyToMessage.ID == tagsPromptMsg.ID
+					},
+					func(ctx context.Context, b *bot.Bot, update *models.Update) {
+						tags := strings.Split(update.Message.Text, ",")
+						var addTags, removeTags []string
+						if adding {
+							addTags = tags
+						} else {
+							removeTags = tags
+						}
+						if err := ub.service.SetEpisodeTags(ctx, userID, epIDs, addTags, removeTags); err != nil {
+							ub.handleError(ctx, chatID, zaperr.Wrap(err, "failed to set episode tags", zapFields...))
+							return
+						}
+
+						if _, err = ub.bot.DeleteMessage(ctx, &bot.DeleteMessageParams{ChatID: chatID, MessageID: tagsPromptMsg.ID}); err != nil {
+							zapFields := append(zapFields, zaperr.ToField(err))
+							ub.logger.Error("failed to delete tags prompt message", zapFields...)
+						}
+
+						if adding {
+							ub.sendTextMessage(ctx, chatID, "Tags were added to %d episodes", len(epIDs))
+						} else {
+							ub.sendTextMessage(ctx, chatID, "Tags were removed from %d episodes", len(epIDs))
+						}
+
+						deleteInitialMessage()
+					})
 			}
+		case cmdSetPubDate:
+			if pubDatePromptMsg, err := ub.bot.SendMessage(ctx, &bot.SendMessageParams{
+				ChatID:      chatID,
+				Text:        "Please enter publish date of the episode, like <code>2006-01-02</code>",
+				ParseMode:   models.ParseModeHTML,
+				ReplyMarkup: &models.ForceReply{ForceReply: true},
+			}); err != nil {
+				zapFields = append(zapFields, zap.Any("message", pubDatePromptMsg))
+				ub.handleError(ctx, chatID, zaperr.Wrap(err, "failed to send message", zapFields...))
+				return
+			} else {
+				var handlerID string
+				handlerID = ub.registerChatHandlerMatchFunc(
+					chatID,
+					func(update *models.Update) bool {
+						return update.Message != nil && update.Message.ReplyToMessage != nil && update.Message.ReplyToMessage.ID == pubDatePromptMsg.ID
+					},
+					func(ctx context.Context, b *bot.Bot, update *models.Update) {
+						pubDate, err := time.Parse(pubDateLayout, strings.TrimSpace(update.Message.Text))
+						if err != nil {
+							ub.sendTextMessage(ctx, chatID, "Invalid date, please reply with a date like 2006-01-02")
+							return
+						}
+						ub.unregisterChatHandler(chatID, handlerID)
 
-			statusMsgText := formatEpisodesDeletedStatusMessage(epIDs)
+						if err := ub.service.SetEpisodePubDate(ctx, userID, epIDs[0], pubDate); err != nil {
+							ub.handleError(ctx, chatID, zaperr.Wrap(err, "failed to set episode publish date", zapFields...))
+							return
+						}
+
+						if _, err = ub.bot.DeleteMessage(ctx, &bot.DeleteMessageParams{ChatID: chatID, MessageID: pubDatePromptMsg.ID}); err != nil {
+							zapFields := append(zapFields, zaperr.ToField(err))
+							ub.logger.Error("failed to delete publish date prompt message", zapFields...)
+						}
+
+						ub.sendTextMessage(ctx, chatID, "Episode #%s will be published as of %s", epIDs[0], pubDate.Format(pubDateLayout))
+
+						deleteInitialMessage()
+					})
+			}
+		case cmdMarkExplicit, cmdMarkClean, cmdResetExplicit:
+			var explicit *bool
+			statusMsgText := fmt.Sprintf("%d episodes will follow their feeds explicit setting", len(epIDs))
+			if st != cmdResetExplicit {
+				isExplicit := st == cmdMarkExplicit
+				explicit = &isExplicit
+				if isExplicit {
+					statusMsgText = fmt.Sprintf("%d episodes were marked explicit", len(epIDs))
+				} else {
+					statusMsgText = fmt.Sprintf("%d episodes were marked clean", len(epIDs))
+				}
+			}
+			if err := ub.service.SetEpisodesExplicit(ctx, userID, epIDs, explicit); err != nil {
+				ub.handleError(ctx, chatID, zaperr.Wrap(err, "failed to set episodes explicit", zapFields...))
+				return
+			}
 
 			ub.sendTextMessage(ctx, chatID, statusMsgText)
 
 			deleteInitialMessage()
+		case cmdDelete:
+			question, confirmText := formatDeleteEpisodesConfirmation(epIDs)
+			ub.askConfirmation(ctx, chatID, userID, question, confirmText, func(ctx context.Context) {
+				if err := ub.service.DeleteEpisodes(ctx, userID, epIDs); err != nil {
+					ub.handleError(ctx, chatID, zaperr.Wrap(err, "failed to delete episodes", zapFields...))
+					return
+				}
+
+				statusMsgText := formatEpisodesDeletedStatusMessage(epIDs)
+
+				ub.sendTextMessage(ctx, chatID, statusMsgText)
+
+				deleteInitialMessage()
+			}, zapFields)
+		case cmdSplit:
+			splitEpisodes, err := ub.service.SplitEpisode(ctx, userID, epIDs[0])
+			if err != nil {
+				ub.handleError(ctx, chatID, zaperr.Wrap(err, "failed to split episode", zapFields...))
+				return
+			}
+
+			msgTextParts := []string{fmt.Sprintf("Episode %s was split into %d episodes:", epIDs[0], len(splitEpisodes))}
+			for _, ep := range splitEpisodes {
+				msgTextParts = append(msgTextParts, ub.renderEpisodeShort(ep))
+			}
+			if _, err := ub.bot.SendMessage(ctx, &bot.SendMessageParams{
+				ChatID:    chatID,
+				Text:      strings.Join(msgTextParts, "\n"),
+				ParseMode: models.ParseModeHTML,
+			}); err != nil {
+				ub.handleError(ctx, chatID, zaperr.Wrap(err, "failed to send message", zapFields...))
+			}
+
+			deleteInitialMessage()
+		case cmdMerge:
+			merged, err := ub.service.MergeEpisodes(ctx, userID, epIDs, "")
+			if err != nil {
+				ub.handleError(ctx, chatID, zaperr.Wrap(err, "failed to merge episodes", zapFields...))
+				return
+			}
+
+			if _, err := ub.bot.SendMessage(ctx, &bot.SendMessageParams{
+				ChatID:    chatID,
+				Text:      fmt.Sprintf("%d episodes were merged into one:\n%s", len(epIDs), ub.renderEpisodeShort(merged)),
+				ParseMode: models.ParseModeHTML,
+			}); err != nil {
+				ub.handleError(ctx, chatID, zaperr.Wrap(err, "failed to send message", zapFields...))
+			}
+
+			deleteInitialMessage()
+		case cmdReencode:
+			reencoded, err := ub.service.ReencodeEpisodes(ctx, userID, epIDs, reencodeFormat)
+			if err != nil {
+				ub.handleError(ctx, chatID, zaperr.Wrap(err, "failed to re-encode episodes", zapFields...))
+				return
+			}
+
+			ub.sendTextMessage(ctx, chatID, "%d episodes are being converted to %s, you will be notified once they are ready", reencoded, reencodeFormat)
+
+			deleteInitialMessage()
+		case cmdCancel:
+			cancelled, err := ub.service.CancelEpisodes(ctx, userID, epIDs)
+			if err != nil {
+				ub.handleError(ctx, chatID, zaperr.Wrap(err, "failed to cancel episodes", zapFields...))
+				return
+			}
+
+			ub.sendTextMessage(ctx, chatID, "%d episodes were cancelled", cancelled)
+
+			deleteInitialMessage()
+		case cmdMoveToFeeds:
+			items := make([]*multiselect.Item, len(feeds))
+			for i, feed := range feeds {
+				items[i] = &multiselect.Item{ID: feed.ID, Text: feed.Title}
+			}
+			feedSelector := multiselect.New(
+				ub.bot,
+				items,
+				func(ctx context.Context, b *bot.Bot, mes *models.Message, items []*multiselect.Item) {
+					feedIDs := make([]string, 0, len(items))
+					for _, item := range items {
+						if item.Selected {
+							feedIDs = append(feedIDs, item.ID)
+						}
+					}
+					if len(feedIDs) == 0 {
+						ub.sendTextMessage(ctx, chatID, "Please select at least one feed to move episodes to")
+						return
+					}
+
+					if err := ub.service.MoveEpisodes(ctx, userID, epIDs, feedIDs); err != nil {
+						ub.handleError(ctx, chatID, zaperr.Wrap(err, "failed to move episodes", zapFields...))
+						return
+					}
+
+					ub.sendTextMessage(ctx, chatID, formatMoveToFeedsStatusMessage(epIDs, feedIDs))
+
+					deleteInitialMessage()
+				},
+				multiselect.WithItemFilters(multiselect.ItemFilter{}),
+			)
+			ub.trackChatHandler(chatID, feedSelector.HandlerID())
+			if _, err = ub.bot.SendMessage(ctx, &bot.SendMessageParams{
+				ChatID:      chatID,
+				Text:        "Select feeds to move episodes to. Episodes will be removed from all other feeds",
+				ParseMode:   models.ParseModeHTML,
+				ReplyMarkup: feedSelector,
+			}); err != nil {
+				ub.handleError(ctx, chatID, zaperr.Wrap(err, "failed to send message", zapFields...))
+			}
 		case cmdManageFeeds:
 			items := make([]*multiselect.Item, len(feeds))
 			epFeedsMap, err := ub.service.GetPublishedFeedsMap(ctx, userID, epIDs)
@@ -216,6 +521,7 @@ func (ub *UndercastBot) editEpisodesHandler(ctx context.Context, b *bot.Bot, upd
 				},
 				multiselect.WithItemFilters(multiselect.ItemFilter{}),
 			)
+			ub.trackChatHandler(chatID, feedSelector.HandlerID())
 			if _, err = ub.bot.SendMessage(ctx, &bot.SendMessageParams{
 				ChatID:      chatID,
 				Text:        "Select feeds to add/remove",
@@ -229,6 +535,14 @@ func (ub *UndercastBot) editEpisodesHandler(ctx context.Context, b *bot.Bot, upd
 
 }
 
+func formatDeleteEpisodesConfirmation(epIDs []string) (question string, confirmText string) {
+	if len(epIDs) == 1 {
+		return fmt.Sprintf("Delete episode %s?", epIDs[0]), "Yes, delete 1 episode"
+	}
+	return fmt.Sprintf("Delete %d episodes (%s)?", len(epIDs), strings.Join(epIDs, ", ")),
+		fmt.Sprintf("Yes, delete %d episodes", len(epIDs))
+}
+
 func formatEpisodesDeletedStatusMessage(epIDs []string) string {
 	statusMsgText := fmt.Sprintf("Episode %s was deleted", epIDs[0])
 	if len(epIDs) > 1 {
@@ -253,6 +567,21 @@ func formatManageFeedsStatusMessage(epIDs []string, feedIDs []string) string {
 	return statusMsgText
 }
 
+func formatMoveToFeedsStatusMessage(epIDs []string, feedIDs []string) string {
+	var statusMsgParts []string
+	if len(epIDs) == 1 {
+		statusMsgParts = []string{fmt.Sprintf("Episode %s was", epIDs[0])}
+	} else {
+		statusMsgParts = []string{fmt.Sprintf("%d episodes (%s) were", len(epIDs), strings.Join(epIDs, ", "))}
+	}
+	if len(feedIDs) == 1 {
+		statusMsgParts = append(statusMsgParts, fmt.Sprintf("moved to feed %s", feedIDs[0]))
+	} else {
+		statusMsgParts = append(statusMsgParts, fmt.Sprintf("moved to %d feeds (%s)", len(feedIDs), strings.Join(feedIDs, ", ")))
+	}
+	return strings.Join(statusMsgParts, " ")
+}
+
 func (ub *UndercastBot) formatInitialMessage(epIDs []string, episodesMap map[string]*service.Episode, feedMap map[string]*service.Feed) (string, error) {
 	var initialMessageParts []string
 	for _, epID := range epIDs {
@@ -288,3 +617,25 @@ func (ub *UndercastBot) parseEditEpisodesCmd(text string) (epIDs []string) {
 		return epIDs
 	}
 }
+
+// isEpisodeInProgress tells whether episode is still being created, so its creation can be cancelled
+func isEpisodeInProgress(ep *service.Episode) bool {
+	switch ep.Status {
+	case service.EpisodeStatusComplete, service.EpisodeStatusFailed, service.EpisodeStatusCancelled:
+		return false
+	default:
+		return true
+	}
+}
+
+// haveSameSource tells if episodes were all created from the same link, so that they can be merged
+func haveSameSource(episodesMap map[string]*service.Episode) bool {
+	var sourceURL string
+	for _, ep := range episodesMap {
+		if ep.SourceURL == "" || (sourceURL != "" && ep.SourceURL != sourceURL) {
+			return false
+		}
+		sourceURL = ep.SourceURL
+	}
+	return true
+}
@@ -0,0 +1,20 @@
+package bot
+
+import "testing"
+
+func TestFormatDeleteEpisodesConfirmation(t *testing.T) {
+	for _, tc := range []struct {
+		epIDs               []string
+		expectedQuestion    string
+		expectedConfirmText string
+	}{
+		{[]string{"7"}, "Delete episode 7?", "Yes, delete 1 episode"},
+		{[]string{"1", "2", "3"}, "Delete 3 episodes (1, 2, 3)?", "Yes, delete 3 episodes"},
+	} {
+		question, confirmText := formatDeleteEpisodesConfirmation(tc.epIDs)
+		if question != tc.expectedQuestion || confirmText != tc.expectedConfirmText {
+			t.Errorf("formatDeleteEpisodesConfirmation(%v) = %q, %q, want %q, %q",
+				tc.epIDs, question, confirmText, tc.expectedQuestion, tc.expectedConfirmText)
+		}
+	}
+}
@@ -2,14 +2,18 @@ package bot
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"regexp"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/go-telegram/bot"
 	"github.com/go-telegram/bot/models"
 	"github.com/hori-ryota/zaperr"
 	"go.uber.org/zap"
+	"tg-podcastotron/service"
 )
 
 const editFeedsHelp = `
@@ -18,10 +22,24 @@ const editFeedsHelp = `
 
 <b>Possible actions:</b>
 - <b>Rename Feed</b> - renames your feed 
+- <b>Set Copyright</b> - sets copyright notice of your feed
+- <b>Set Description</b> - sets description of your feed shown by podcast apps and directories
+- <b>Set Author</b> - sets author of your feed shown by podcast apps and directories
+- <b>Set Cover Image</b> - reply with a photo to use it as cover image of your feed
+- <b>Set Max Size</b> - limit total size of episodes in an ephemeral feed, oldest episodes are removed from the feed when it grows bigger
+- <b>Set Max Episodes</b> - keep only this many latest episodes in the feed, older ones are removed from the feed but kept in your library
+- <b>Enable Normalization</b>/<b>Disable Normalization</b> - choose whether loudness of glued episodes created for this feed should be evened out
+- <b>Make Link Private</b>/<b>Make Link Public</b> - move the feed to a secret link nobody can guess, or back to the plain one
+- <b>Change Private Link</b> - move the feed to a new secret link, so that whoever you shared the old one with loses access
 - <b>Delete Feed</b> - deletes your feed, but keeps the episodes in your library
 - <b>Delete Feed and Episodes</b> - deletes your feed and all episodes in it from your library and disk
 `
 
+const editFeedsAdminHelp = `- <b>Mark Permanent</b>/<b>Mark Ephemeral</b> - choose whether or not episodes should be auto-deleted after /retention period
+- <b>Mark Ephemeral, Keep Current Episodes</b> - only episodes added from now on will be auto-deleted
+- <b>Regenerate Feed</b> - regenerate feed XML file
+`
+
 func (ub *UndercastBot) editFeedsHandler(ctx context.Context, b *bot.Bot, update *models.Update) {
 	var editFeedsHelp = editFeedsHelp
 	chatID := ub.extractChatID(update)
@@ -54,12 +72,44 @@ func (ub *UndercastBot) editFeedsHandler(ctx context.Context, b *bot.Bot, update
 
 	zapFields = append(zapFields, zap.String("feed_id", feedID))
 
+	isAdmin, _ := ub.auth.IsAdmin(ctx, ub.extractUsername(update))
+	if isAdmin {
+		editFeedsHelp += editFeedsAdminHelp
+	}
+
+	ub.sendFeedEditor(ctx, chatID, userID, feed, isAdmin, editFeedsHelp, zapFields)
+}
+
+// sendFeedEditor sends a message with given text and buttons editing the feed
+func (ub *UndercastBot) sendFeedEditor(
+	ctx context.Context,
+	chatID int64,
+	userID string,
+	feed *service.Feed,
+	isAdmin bool,
+	text string,
+	zapFields []zap.Field,
+) {
+	feedID := feed.ID
 	prefix := fmt.Sprintf("editFeed_%s_%s", userID, bot.RandomString(10))
 	cmdRename := "rename"
+	cmdSetCopyright := "setCopyright"
+	cmdSetDescription := "setDescription"
+	cmdSetAuthor := "setAuthor"
+	cmdSetMaxSize := "setMaxSize"
+	cmdSetMaxEpisodes := "setMaxEpisodes"
+	cmdSetSchedule := "setSchedule"
+	cmdSetImage := "setImage"
+	cmdEnableNormalization := "enableNormalization"
+	cmdDisableNormalization := "disableNormalization"
+	cmdProtectWithToken := "protectWithToken"
+	cmdUnprotectWithToken := "unprotectWithToken"
+	cmdRotateToken := "rotateToken"
 	cmdDeleteFeed := "deleteFeed"
 	cmdDeleteFeedAndEpisodes := "deleteFeedAndEpisodes"
 	cmdMakePermanent := "makePermanent"
 	cmdMakeEphemeral := "makeEphemeral"
+	cmdMakeEphemeralPinExisting := "makeEphemeralPinExisting"
 	cmdRegenerateFeed := "regenerateFeed"
 
 	kb := [][]models.InlineKeyboardButton{
@@ -68,24 +118,84 @@ func (ub *UndercastBot) editFeedsHandler(ctx context.Context, b *bot.Bot, update
 			CallbackData: prefix + cmdRename,
 		}},
 		{{
+			Text:         "Set Copyright",
+			CallbackData: prefix + cmdSetCopyright,
+		}},
+		{{
+			Text:         "Set Description",
+			CallbackData: prefix + cmdSetDescription,
+		}},
+		{{
+			Text:         "Set Author",
+			CallbackData: prefix + cmdSetAuthor,
+		}},
+		{{
+			Text:         "Set Cover Image",
+			CallbackData: prefix + cmdSetImage,
+		}},
+		{{
+			Text:         "Set Max Size",
+			CallbackData: prefix + cmdSetMaxSize,
+		}},
+		{{
+			Text:         "Set Max Episodes",
+			CallbackData: prefix + cmdSetMaxEpisodes,
+		}},
+		{{
+			Text:         "Set Polling Schedule",
+			CallbackData: prefix + cmdSetSchedule,
+		}},
+	}
+
+	switch feed.Normalize {
+	case true:
+		kb = append(kb, []models.InlineKeyboardButton{{
+			Text:         "Disable Normalization",
+			CallbackData: prefix + cmdDisableNormalization,
+		}})
+	case false:
+		kb = append(kb, []models.InlineKeyboardButton{{
+			Text:         "Enable Normalization",
+			CallbackData: prefix + cmdEnableNormalization,
+		}})
+	}
+
+	switch feed.TokenProtected {
+	case true:
+		kb = append(kb, []models.InlineKeyboardButton{{
+			Text:         "Change Private Link",
+			CallbackData: prefix + cmdRotateToken,
+		}}, []models.InlineKeyboardButton{{
+			Text:         "Make Link Public",
+			CallbackData: prefix + cmdUnprotectWithToken,
+		}})
+	case false:
+		kb = append(kb, []models.InlineKeyboardButton{{
+			Text:         "Make Link Private",
+			CallbackData: prefix + cmdProtectWithToken,
+		}})
+	}
+
+	kb = append(kb,
+		[]models.InlineKeyboardButton{{
 			Text:         "Delete Feed",
 			CallbackData: prefix + cmdDeleteFeed,
 		}},
-		{{
+		[]models.InlineKeyboardButton{{
 			Text:         "Delete Feed and Episodes",
 			CallbackData: prefix + cmdDeleteFeedAndEpisodes,
 		}},
-	}
+	)
 
-	if isAdmin, _ := ub.auth.IsAdmin(ctx, ub.extractUsername(update)); isAdmin {
-		editFeedsHelp += `- <b>Mark Permanent</b>/<b>Mark Ephemeral</b> - choose whether or not episodes should be auto-deleted after 30 days
-- <b>Regenerate Feed</b> - regenerate feed XML file
-`
+	if isAdmin {
 		switch feed.IsPermanent {
 		case true:
 			kb = append(kb, []models.InlineKeyboardButton{{
 				Text:         "Make Ephemeral",
 				CallbackData: prefix + cmdMakeEphemeral,
+			}}, []models.InlineKeyboardButton{{
+				Text:         "Make Ephemeral, Keep Current Episodes",
+				CallbackData: prefix + cmdMakeEphemeralPinExisting,
 			}})
 		case false:
 			kb = append(kb, []models.InlineKeyboardButton{{
@@ -102,7 +212,7 @@ func (ub *UndercastBot) editFeedsHandler(ctx context.Context, b *bot.Bot, update
 
 	initialMessage, err := ub.bot.SendMessage(ctx, &bot.SendMessageParams{
 		ChatID:      chatID,
-		Text:        editFeedsHelp,
+		Text:        text,
 		ParseMode:   models.ParseModeHTML,
 		ReplyMarkup: &models.InlineKeyboardMarkup{InlineKeyboard: kb},
 	})
@@ -119,7 +229,7 @@ func (ub *UndercastBot) editFeedsHandler(ctx context.Context, b *bot.Bot, update
 		}
 	}
 
-	ub.bot.RegisterHandler(bot.HandlerTypeCallbackQueryData, prefix, bot.MatchTypePrefix, func(ctx context.Context, b *bot.Bot, update *models.Update) {
+	ub.registerChatHandler(chatID, bot.HandlerTypeCallbackQueryData, prefix, bot.MatchTypePrefix, func(ctx context.Context, b *bot.Bot, update *models.Update) {
 		st := strings.ReplaceAll(update.CallbackQuery.Data, prefix, "")
 
 		switch st {
@@ -135,13 +245,17 @@ func (ub *UndercastBot) editFeedsHandler(ctx context.Context, b *bot.Bot, update
 				ub.handleError(ctx, chatID, zaperr.Wrap(err, "failed to send message", zapFields...))
 				return
 			} else {
-				ub.bot.RegisterHandlerMatchFunc(
+				ub.registerChatHandlerMatchFunc(
+					chatID,
 					func(update *models.Update) bool {
 						return update.Message.ReplyToMessage != nil && update.Message.ReplyToMessage.ID == renamePromptMsg.ID
 					},
 					func(ctx context.Context, b *bot.Bot, update *models.Update) {
 						newTitle := update.Message.Text
-						if err := ub.service.RenameFeed(ctx, userID, feedID, newTitle); err != nil {
+						if err := ub.service.RenameFeed(ctx, userID, feedID, newTitle); errors.Is(err, service.ErrInvalidTitle) {
+							ub.sendTextMessage(ctx, chatID, invalidTitleMessage)
+							return
+						} else if err != nil {
 							ub.handleError(ctx, chatID, zaperr.Wrap(err, "failed to rename feed", zapFields...))
 							return
 						}
@@ -157,24 +271,326 @@ func (ub *UndercastBot) editFeedsHandler(ctx context.Context, b *bot.Bot, update
 					})
 			}
 
-		case cmdDeleteFeed, cmdDeleteFeedAndEpisodes:
-			shouldDeleteEpisodes := st == cmdDeleteFeedAndEpisodes
+		case cmdSetCopyright:
+			if copyrightPromptMsg, err := ub.bot.SendMessage(ctx, &bot.SendMessageParams{
+				ChatID:      chatID,
+				Text:        "Please enter copyright notice for the feed",
+				ParseMode:   models.ParseModeHTML,
+				ReplyMarkup: &models.ForceReply{ForceReply: true},
+			}); err != nil {
+				zapFields = append(zapFields, zap.Any("message", copyrightPromptMsg))
+				ub.handleError(ctx, chatID, zaperr.Wrap(err, "failed to send message", zapFields...))
+				return
+			} else {
+				ub.registerChatHandlerMatchFunc(
+					chatID,
+					func(update *models.Update) bool {
+						return update.Message.ReplyToMessage != nil && update.Message.ReplyToMessage.ID == copyrightPromptMsg.ID
+					},
+					func(ctx context.Context, b *bot.Bot, update *models.Update) {
+						copyright := update.Message.Text
+						if err := ub.service.SetFeedCopyright(ctx, userID, feedID, copyright); err != nil {
+							ub.handleError(ctx, chatID, zaperr.Wrap(err, "failed to set feed copyright", zapFields...))
+							return
+						}
 
-			if err := ub.service.DeleteFeed(ctx, userID, feedID, shouldDeleteEpisodes); err != nil {
-				ub.handleError(ctx, chatID, zaperr.Wrap(err, "failed to delete episodes", zapFields...))
+						if _, err = ub.bot.DeleteMessage(ctx, &bot.DeleteMessageParams{ChatID: chatID, MessageID: copyrightPromptMsg.ID}); err != nil {
+							zapFields := append(zapFields, zaperr.ToField(err))
+							ub.logger.Error("failed to delete copyright prompt message", zapFields...)
+						}
+
+						ub.sendTextMessage(ctx, chatID, "Feed %s copyright was set to \"%s\"", feedID, copyright)
+
+						deleteInitialMessage()
+					})
+			}
+
+		case cmdSetDescription, cmdSetAuthor:
+			field := "description"
+			if st == cmdSetAuthor {
+				field = "author"
+			}
+			if metadataPromptMsg, err := ub.bot.SendMessage(ctx, &bot.SendMessageParams{
+				ChatID:      chatID,
+				Text:        fmt.Sprintf("Please enter %s of the feed", field),
+				ParseMode:   models.ParseModeHTML,
+				ReplyMarkup: &models.ForceReply{ForceReply: true},
+			}); err != nil {
+				zapFields = append(zapFields, zap.Any("message", metadataPromptMsg))
+				ub.handleError(ctx, chatID, zaperr.Wrap(err, "failed to send message", zapFields...))
 				return
+			} else {
+				ub.registerChatHandlerMatchFunc(
+					chatID,
+					func(update *models.Update) bool {
+						return update.Message.ReplyToMessage != nil && update.Message.ReplyToMessage.ID == metadataPromptMsg.ID
+					},
+					func(ctx context.Context, b *bot.Bot, update *models.Update) {
+						// the other field might have been changed since the editor was sent
+						current, err := ub.service.GetFeed(ctx, userID, feedID)
+						if err != nil {
+							ub.handleError(ctx, chatID, zaperr.Wrap(err, "failed to get feed", zapFields...))
+							return
+						}
+						description, author := current.Description, current.Author
+						if st == cmdSetAuthor {
+							author = update.Message.Text
+						} else {
+							description = update.Message.Text
+						}
+						if err := ub.service.UpdateFeedMetadata(ctx, userID, feedID, description, author); err != nil {
+							ub.handleError(ctx, chatID, zaperr.Wrap(err, "failed to update feed metadata", zapFields...))
+							return
+						}
+
+						if _, err = ub.bot.DeleteMessage(ctx, &bot.DeleteMessageParams{ChatID: chatID, MessageID: metadataPromptMsg.ID}); err != nil {
+							zapFields := append(zapFields, zaperr.ToField(err))
+							ub.logger.Error("failed to delete metadata prompt message", zapFields...)
+						}
+
+						ub.sendTextMessage(ctx, chatID, "Feed %s %s was set to \"%s\"", feedID, field, update.Message.Text)
+
+						deleteInitialMessage()
+					})
 			}
 
-			replyText := fmt.Sprintf("Feed %s was deleted\n", feedID)
-			if shouldDeleteEpisodes {
-				replyText += "All feed episodes were deleted, too"
+		case cmdSetMaxSize:
+			if maxSizePromptMsg, err := ub.bot.SendMessage(ctx, &bot.SendMessageParams{
+				ChatID:      chatID,
+				Text:        "Please enter max total size of the feed in megabytes, or 0 to remove the limit",
+				ParseMode:   models.ParseModeHTML,
+				ReplyMarkup: &models.ForceReply{ForceReply: true},
+			}); err != nil {
+				zapFields = append(zapFields, zap.Any("message", maxSizePromptMsg))
+				ub.handleError(ctx, chatID, zaperr.Wrap(err, "failed to send message", zapFields...))
+				return
+			} else {
+				ub.registerChatHandlerMatchFunc(
+					chatID,
+					func(update *models.Update) bool {
+						return update.Message.ReplyToMessage != nil && update.Message.ReplyToMessage.ID == maxSizePromptMsg.ID
+					},
+					func(ctx context.Context, b *bot.Bot, update *models.Update) {
+						maxSizeMB, err := strconv.ParseInt(strings.TrimSpace(update.Message.Text), 10, 64)
+						if err != nil || maxSizeMB < 0 {
+							ub.sendTextMessage(ctx, chatID, "Max size should be a non-negative number of megabytes")
+							return
+						}
+
+						if err := ub.service.SetFeedMaxTotalBytes(ctx, userID, feedID, maxSizeMB*1024*1024); err != nil {
+							ub.handleError(ctx, chatID, zaperr.Wrap(err, "failed to set feed max total size", zapFields...))
+							return
+						}
+
+						if _, err = ub.bot.DeleteMessage(ctx, &bot.DeleteMessageParams{ChatID: chatID, MessageID: maxSizePromptMsg.ID}); err != nil {
+							zapFields := append(zapFields, zaperr.ToField(err))
+							ub.logger.Error("failed to delete max size prompt message", zapFields...)
+						}
+
+						if maxSizeMB == 0 {
+							ub.sendTextMessage(ctx, chatID, "Feed %s size is no longer limited", feedID)
+						} else {
+							ub.sendTextMessage(ctx, chatID, "Feed %s size was limited to %d MB", feedID, maxSizeMB)
+						}
+
+						deleteInitialMessage()
+					})
+			}
+
+		case cmdSetMaxEpisodes:
+			if maxEpisodesPromptMsg, err := ub.bot.SendMessage(ctx, &bot.SendMessageParams{
+				ChatID:      chatID,
+				Text:        "Please enter how many latest episodes the feed should keep, or 0 to remove the limit",
+				ParseMode:   models.ParseModeHTML,
+				ReplyMarkup: &models.ForceReply{ForceReply: true},
+			}); err != nil {
+				zapFields = append(zapFields, zap.Any("message", maxEpisodesPromptMsg))
+				ub.handleError(ctx, chatID, zaperr.Wrap(err, "failed to send message", zapFields...))
+				return
 			} else {
-				replyText += "All episodes are left in your library"
+				ub.registerChatHandlerMatchFunc(
+					chatID,
+					func(update *models.Update) bool {
+						return update.Message.ReplyToMessage != nil && update.Message.ReplyToMessage.ID == maxEpisodesPromptMsg.ID
+					},
+					func(ctx context.Context, b *bot.Bot, update *models.Update) {
+						maxEpisodes, err := strconv.Atoi(strings.TrimSpace(update.Message.Text))
+						if err != nil || maxEpisodes < 0 {
+							ub.sendTextMessage(ctx, chatID, "Max episodes should be a non-negative number")
+							return
+						}
+
+						if err := ub.service.SetFeedMaxEpisodes(ctx, userID, feedID, maxEpisodes); err != nil {
+							ub.handleError(ctx, chatID, zaperr.Wrap(err, "failed to set feed max episodes", zapFields...))
+							return
+						}
+
+						if _, err = ub.bot.DeleteMessage(ctx, &bot.DeleteMessageParams{ChatID: chatID, MessageID: maxEpisodesPromptMsg.ID}); err != nil {
+							zapFields := append(zapFields, zaperr.ToField(err))
+							ub.logger.Error("failed to delete max episodes prompt message", zapFields...)
+						}
+
+						if maxEpisodes == 0 {
+							ub.sendTextMessage(ctx, chatID, "Feed %s number of episodes is no longer limited", feedID)
+						} else {
+							ub.sendTextMessage(ctx, chatID, "Feed %s will keep %d latest episodes", feedID, maxEpisodes)
+						}
+
+						deleteInitialMessage()
+					})
+			}
+
+		case cmdSetSchedule:
+			if schedulePromptMsg, err := ub.bot.SendMessage(ctx, &bot.SendMessageParams{
+				ChatID:      chatID,
+				Text:        feedScheduleHelp,
+				ParseMode:   models.ParseModeHTML,
+				ReplyMarkup: &models.ForceReply{ForceReply: true},
+			}); err != nil {
+				zapFields = append(zapFields, zap.Any("message", schedulePromptMsg))
+				ub.handleError(ctx, chatID, zaperr.Wrap(err, "failed to send message", zapFields...))
+				return
+			} else {
+				ub.registerChatHandlerMatchFunc(
+					chatID,
+					func(update *models.Update) bool {
+						return update.Message.ReplyToMessage != nil && update.Message.ReplyToMessage.ID == schedulePromptMsg.ID
+					},
+					func(ctx context.Context, b *bot.Bot, update *models.Update) {
+						ttlMinutes, skipHours, skipDays, err := parseFeedSchedule(update.Message.Text)
+						if err != nil {
+							ub.sendTextMessage(ctx, chatID, "Could not understand the schedule: %s", err.Error())
+							return
+						}
+
+						if err := ub.service.SetFeedSchedule(ctx, userID, feedID, ttlMinutes, skipHours, skipDays); err != nil {
+							ub.handleError(ctx, chatID, zaperr.Wrap(err, "failed to set feed schedule", zapFields...))
+							return
+						}
+
+						if _, err = ub.bot.DeleteMessage(ctx, &bot.DeleteMessageParams{ChatID: chatID, MessageID: schedulePromptMsg.ID}); err != nil {
+							zapFields := append(zapFields, zaperr.ToField(err))
+							ub.logger.Error("failed to delete schedule prompt message", zapFields...)
+						}
+
+						ub.sendTextMessage(ctx, chatID, "Feed %s polling schedule was updated", feedID)
+
+						deleteInitialMessage()
+					})
+			}
+
+		case cmdSetImage:
+			if imagePromptMsg, err := ub.bot.SendMessage(ctx, &bot.SendMessageParams{
+				ChatID:      chatID,
+				Text:        "Please reply with a photo to use as cover image of the feed",
+				ParseMode:   models.ParseModeHTML,
+				ReplyMarkup: &models.ForceReply{ForceReply: true},
+			}); err != nil {
+				zapFields = append(zapFields, zap.Any("message", imagePromptMsg))
+				ub.handleError(ctx, chatID, zaperr.Wrap(err, "failed to send message", zapFields...))
+				return
+			} else {
+				ub.registerChatHandlerMatchFunc(
+					chatID,
+					func(update *models.Update) bool {
+						return update.Message.ReplyToMessage != nil && update.Message.ReplyToMessage.ID == imagePromptMsg.ID
+					},
+					func(ctx context.Context, b *bot.Bot, update *models.Update) {
+						if len(update.Message.Photo) == 0 {
+							ub.sendTextMessage(ctx, chatID, "Please send the image as a photo, not as a file or text")
+							return
+						}
+
+						if err := ub.setFeedImageFromPhoto(ctx, userID, feedID, update.Message.Photo); err != nil {
+							ub.handleError(ctx, chatID, zaperr.Wrap(err, "failed to set feed image", zapFields...))
+							return
+						}
+
+						if _, err = ub.bot.DeleteMessage(ctx, &bot.DeleteMessageParams{ChatID: chatID, MessageID: imagePromptMsg.ID}); err != nil {
+							zapFields := append(zapFields, zaperr.ToField(err))
+							ub.logger.Error("failed to delete image prompt message", zapFields...)
+						}
+
+						ub.sendTextMessage(ctx, chatID, "Feed %s cover image was updated", feedID)
+
+						deleteInitialMessage()
+					})
+			}
+
+		case cmdEnableNormalization, cmdDisableNormalization:
+			normalize := st == cmdEnableNormalization
+
+			if err := ub.service.SetFeedNormalize(ctx, userID, feedID, normalize); err != nil {
+				ub.handleError(ctx, chatID, zaperr.Wrap(err, "failed to set feed normalization", zapFields...))
+				return
+			}
+
+			if normalize {
+				ub.sendTextMessage(ctx, chatID, "Glued episodes of feed #%s (%s) will be normalized", feedID, feed.Title)
+			} else {
+				ub.sendTextMessage(ctx, chatID, "Glued episodes of feed #%s (%s) will not be normalized", feedID, feed.Title)
 			}
-			ub.sendTextMessage(ctx, chatID, replyText)
 
 			deleteInitialMessage()
 
+		case cmdProtectWithToken, cmdUnprotectWithToken:
+			protected := st == cmdProtectWithToken
+
+			updatedFeed, err := ub.service.SetFeedTokenProtected(ctx, userID, feedID, protected)
+			if err != nil {
+				ub.handleError(ctx, chatID, zaperr.Wrap(err, "failed to set feed token protection", zapFields...))
+				return
+			}
+
+			if protected {
+				ub.sendTextMessage(ctx, chatID, "Feed #%s (%s) is now available at a private link only, please resubscribe:\n%s", feedID, feed.Title, updatedFeed.URL)
+			} else {
+				ub.sendTextMessage(ctx, chatID, "Feed #%s (%s) is now available at a public link, please resubscribe:\n%s", feedID, feed.Title, updatedFeed.URL)
+			}
+
+			deleteInitialMessage()
+
+		case cmdRotateToken:
+			updatedFeed, err := ub.service.RotateFeedToken(ctx, userID, feedID)
+			if err != nil {
+				ub.handleError(ctx, chatID, zaperr.Wrap(err, "failed to rotate feed token", zapFields...))
+				return
+			}
+
+			ub.sendTextMessage(ctx, chatID, "Old link of feed #%s (%s) no longer works, please resubscribe:\n%s", feedID, feed.Title, updatedFeed.URL)
+
+			deleteInitialMessage()
+
+		case cmdDeleteFeed, cmdDeleteFeedAndEpisodes:
+			shouldDeleteEpisodes := st == cmdDeleteFeedAndEpisodes
+
+			deleteFeed := func(ctx context.Context) {
+				if err := ub.service.DeleteFeed(ctx, userID, feedID, shouldDeleteEpisodes); err != nil {
+					ub.handleError(ctx, chatID, zaperr.Wrap(err, "failed to delete episodes", zapFields...))
+					return
+				}
+
+				replyText := fmt.Sprintf("Feed %s was deleted\n", feedID)
+				if shouldDeleteEpisodes {
+					replyText += "All feed episodes were moved to /trash, too"
+				} else {
+					replyText += "All episodes are left in your library"
+				}
+				ub.sendTextMessage(ctx, chatID, replyText)
+
+				deleteInitialMessage()
+			}
+
+			// episodes leave every feed they were in, so it better be on purpose
+			if shouldDeleteEpisodes {
+				ub.askConfirmation(ctx, chatID, userID,
+					fmt.Sprintf("Delete feed %s and move all its episodes to trash?", feedID),
+					"Yes, delete feed and episodes",
+					deleteFeed, zapFields)
+			} else {
+				deleteFeed(ctx)
+			}
+
 		case cmdMakePermanent:
 			if err := ub.service.MarkFeedAsPermanent(ctx, userID, feedID); err != nil {
 				ub.handleError(ctx, chatID, zaperr.Wrap(err, "failed to mark feed as permanent", zapFields...))
@@ -186,7 +602,7 @@ func (ub *UndercastBot) editFeedsHandler(ctx context.Context, b *bot.Bot, update
 			deleteInitialMessage()
 
 		case cmdMakeEphemeral:
-			if err := ub.service.MarkFeedAsEphemeral(ctx, userID, feedID); err != nil {
+			if err := ub.service.MarkFeedAsEphemeral(ctx, userID, feedID, false); err != nil {
 				ub.handleError(ctx, chatID, zaperr.Wrap(err, "failed to mark feed as ephemeral", zapFields...))
 				return
 			}
@@ -195,6 +611,16 @@ func (ub *UndercastBot) editFeedsHandler(ctx context.Context, b *bot.Bot, update
 
 			deleteInitialMessage()
 
+		case cmdMakeEphemeralPinExisting:
+			if err := ub.service.MarkFeedAsEphemeral(ctx, userID, feedID, true); err != nil {
+				ub.handleError(ctx, chatID, zaperr.Wrap(err, "failed to mark feed as ephemeral", zapFields...))
+				return
+			}
+
+			ub.sendTextMessage(ctx, chatID, "Feed #%s (%s) was marked as ephemeral, its current episodes will be kept", feedID, feed.Title)
+
+			deleteInitialMessage()
+
 		case cmdRegenerateFeed:
 			if err := ub.service.RegenerateFeed(ctx, userID, feedID); err != nil {
 				ub.handleError(ctx, chatID, zaperr.Wrap(err, "failed to regenerate feed", zapFields...))
@@ -217,3 +643,74 @@ func (ub *UndercastBot) parseEditFeedsCmd(text string) (string, error) {
 	}
 	return matches[1], nil
 }
+
+const feedScheduleHelp = `Please reply with when podcast apps should not bother checking the feed for new episodes, like so:
+<code>ttl 60</code> - check at most once an hour
+<code>hours 0-6 23</code> - skip these hours, in GMT
+<code>days sat sun</code> - skip these days
+Put each on its own line, or reply <code>none</code> to remove the schedule`
+
+var weekdaysByPrefix = map[string]time.Weekday{
+	"sun": time.Sunday,
+	"mon": time.Monday,
+	"tue": time.Tuesday,
+	"wed": time.Wednesday,
+	"thu": time.Thursday,
+	"fri": time.Friday,
+	"sat": time.Saturday,
+}
+
+// parseFeedSchedule parses feedScheduleHelp format. Hours are only checked to be numbers,
+// their range is validated by service
+func parseFeedSchedule(text string) (ttlMinutes int, skipHours []int, skipDays []time.Weekday, err error) {
+	if strings.EqualFold(strings.TrimSpace(text), "none") {
+		return 0, nil, nil, nil
+	}
+
+	for _, line := range strings.Split(text, "\n") {
+		fields := strings.Fields(strings.ToLower(line))
+		if len(fields) == 0 {
+			continue
+		}
+		switch fields[0] {
+		case "ttl":
+			if len(fields) != 2 {
+				return 0, nil, nil, fmt.Errorf("ttl should be a single number of minutes")
+			}
+			if ttlMinutes, err = strconv.Atoi(fields[1]); err != nil {
+				return 0, nil, nil, fmt.Errorf("ttl should be a number of minutes")
+			}
+		case "hours":
+			for _, f := range fields[1:] {
+				from, to, isRange := strings.Cut(f, "-")
+				fromHour, err := strconv.Atoi(from)
+				if err != nil {
+					return 0, nil, nil, fmt.Errorf("%s is not an hour", f)
+				}
+				toHour := fromHour
+				if isRange {
+					if toHour, err = strconv.Atoi(to); err != nil || toHour < fromHour {
+						return 0, nil, nil, fmt.Errorf("%s is not a range of hours", f)
+					}
+				}
+				for h := fromHour; h <= toHour; h++ {
+					skipHours = append(skipHours, h)
+				}
+			}
+		case "days":
+			for _, f := range fields[1:] {
+				if len(f) < 3 {
+					return 0, nil, nil, fmt.Errorf("%s is not a day", f)
+				}
+				day, ok := weekdaysByPrefix[f[:3]]
+				if !ok {
+					return 0, nil, nil, fmt.Errorf("%s is not a day", f)
+				}
+				skipDays = append(skipDays, day)
+			}
+		default:
+			return 0, nil, nil, fmt.Errorf("unknown setting %s", fields[0])
+		}
+	}
+	return ttlMinutes, skipHours, skipDays, nil
+}
@@ -0,0 +1,89 @@
+package bot
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"html"
+	"regexp"
+	"strings"
+
+	"github.com/go-telegram/bot"
+	"github.com/go-telegram/bot/models"
+	"github.com/hori-ryota/zaperr"
+	"go.uber.org/zap"
+	"tg-podcastotron/service"
+)
+
+// episodeFeedsHandler lists feeds an episode is published to
+func (ub *UndercastBot) episodeFeedsHandler(ctx context.Context, _ *bot.Bot, update *models.Update) {
+	chatID := ub.extractChatID(update)
+	userID := ub.extractUserID(update)
+	if userID == "" || chatID == 0 {
+		return
+	}
+
+	zapFields := []zap.Field{
+		zap.Int64("chat_id", chatID),
+		zap.String("user_id", userID),
+		zap.String("message_text", update.Message.Text),
+	}
+
+	epID, err := ub.parseEpisodeFeedsCmd(update.Message.Text)
+	if err != nil {
+		ub.sendTextMessage(ctx, chatID, "Please specify episode ID, like so:\n/epfeeds_1")
+		return
+	}
+	zapFields = append(zapFields, zap.String("episode_id", epID))
+
+	epMap, err := ub.service.GetEpisodesMap(ctx, userID, []string{epID})
+	if err != nil {
+		if errors.Is(err, service.ErrEpisodeNotFound) {
+			ub.sendTextMessage(ctx, chatID, "Episode %s not found", epID)
+			return
+		}
+		ub.handleError(ctx, chatID, zaperr.Wrap(err, "failed to get episodes", zapFields...))
+		return
+	}
+	if _, ok := epMap[epID]; !ok {
+		ub.sendTextMessage(ctx, chatID, "Episode %s not found", epID)
+		return
+	}
+
+	feeds, err := ub.service.ListEpisodeFeeds(ctx, userID, epID)
+	if err != nil {
+		ub.handleError(ctx, chatID, zaperr.Wrap(err, "failed to list episode feeds", zapFields...))
+		return
+	}
+
+	if _, err := ub.bot.SendMessage(ctx, &bot.SendMessageParams{
+		ChatID:    chatID,
+		Text:      ub.renderEpisodeFeeds(epMap[epID], feeds),
+		ParseMode: models.ParseModeHTML,
+	}); err != nil {
+		ub.handleError(ctx, chatID, zaperr.Wrap(err, "failed to send message", zapFields...))
+	}
+}
+
+func (ub *UndercastBot) parseEpisodeFeedsCmd(text string) (string, error) {
+	re := regexp.MustCompile(`/epfeeds_(\d+)`)
+	matches := re.FindStringSubmatch(text)
+	if len(matches) != 2 {
+		return "", fmt.Errorf("invalid command")
+	}
+	return matches[1], nil
+}
+
+func (ub *UndercastBot) renderEpisodeFeeds(ep *service.Episode, feeds []*service.Feed) string {
+	if len(feeds) == 0 {
+		return fmt.Sprintf("%s\n\nIs not published to any feed", ub.renderEpisodeShort(ep))
+	}
+
+	lines := []string{ub.renderEpisodeShort(ep), "", "<b>Published to feeds:</b>"}
+	for _, f := range feeds {
+		lines = append(lines, fmt.Sprintf(
+			"- <code>%s</code> (%s) [info: /f_%s] [edit: /ef_%s]", f.ID, html.EscapeString(f.Title), f.ID, f.ID,
+		))
+	}
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,109 @@
+package bot
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/go-telegram/bot"
+	"github.com/go-telegram/bot/models"
+	"go.uber.org/zap"
+	"tg-podcastotron/mediary/mediarymocks"
+	"tg-podcastotron/service"
+	"tg-podcastotron/service/servicemocks"
+)
+
+func TestUndercastBot__EpisodeFeedsHandler(t *testing.T) {
+	ctx := context.Background()
+	token := "some-token"
+
+	// region fake Telegram Bot API recording sent messages
+	var mu sync.Mutex
+	var sentTexts []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/bot"+token+"/sendMessage" {
+			if err := r.ParseMultipartForm(1 << 20); err == nil {
+				mu.Lock()
+				sentTexts = append(sentTexts, r.FormValue("text"))
+				mu.Unlock()
+			}
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"ok":true,"result":{"message_id":1,"date":0,"chat":{"id":1,"type":"private"}}}`))
+	}))
+	defer srv.Close()
+	// endregion
+
+	svc := service.New(
+		&mediarymocks.ServiceMock{}, getServiceRepo(t), &servicemocks.MockS3Store{
+			PutFunc: func(ctx context.Context, key string, dataReader io.ReadSeeker, opts ...func(*service.PutOptions)) error {
+				return nil
+			},
+			URLFunc: func(key string) (string, error) { return "https://example.com/" + key, nil },
+		}, &fakeJobsQueue{},
+		"", "", false, func(s string) string { return s }, zap.NewNop(),
+	)
+
+	userID := "1"
+	ep, err := svc.CreateEpisodeFromUpload(ctx, userID, "some episode", strings.NewReader("some-mp3-data"), int64(len("some-mp3-data")), "audio/mpeg")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var feeds []*service.Feed
+	for _, title := range []string{"first feed", "second feed", "third feed"} {
+		feed, err := svc.CreateFeed(ctx, userID, title)
+		if err != nil {
+			t.Fatal(err)
+		}
+		feeds = append(feeds, feed)
+	}
+	if err := svc.PublishEpisodes(ctx, userID, []string{ep.ID}, []string{feeds[0].ID, feeds[2].ID}); err != nil {
+		t.Fatal(err)
+	}
+
+	b, err := bot.New(token, bot.WithServerURL(srv.URL), bot.WithSkipGetMe())
+	if err != nil {
+		t.Fatal(err)
+	}
+	ub := &UndercastBot{
+		logger:  zap.NewNop(),
+		token:   token,
+		bot:     b,
+		service: svc,
+	}
+
+	ub.episodeFeedsHandler(ctx, b, &models.Update{Message: &models.Message{
+		Chat: models.Chat{ID: 1},
+		From: &models.User{ID: 1},
+		Text: "/epfeeds_" + ep.ID,
+	}})
+
+	mu.Lock()
+	text := strings.Join(sentTexts, "\n")
+	mu.Unlock()
+
+	for _, f := range []*service.Feed{feeds[0], feeds[2]} {
+		if !strings.Contains(text, "/f_"+f.ID+"]") {
+			t.Errorf("expected feed %s to be listed, got:\n%s", f.ID, text)
+		}
+	}
+	if strings.Contains(text, "/f_"+feeds[1].ID+"]") || strings.Contains(text, feeds[1].Title) {
+		t.Errorf("expected feed %s not to be listed, got:\n%s", feeds[1].ID, text)
+	}
+}
+
+func TestRenderEpisodeFeeds__EscapesTitles(t *testing.T) {
+	ub := &UndercastBot{}
+	text := ub.renderEpisodeFeeds(
+		&service.Episode{ID: "1", Title: "some episode"},
+		[]*service.Feed{{ID: "2", Title: "Q&A <live>"}},
+	)
+
+	if !strings.Contains(text, "(Q&amp;A &lt;live&gt;)") {
+		t.Errorf("expected feed title to be escaped for HTML, got:\n%s", text)
+	}
+}
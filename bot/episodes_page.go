@@ -0,0 +1,131 @@
+package bot
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/go-telegram/bot"
+	"github.com/go-telegram/bot/models"
+	"github.com/hori-ryota/zaperr"
+	"go.uber.org/zap"
+	"tg-podcastotron/service"
+)
+
+// episodesPageSize is how many episodes bare /ep shows at once
+const episodesPageSize = 10
+
+// sendEpisodesPage sends first page of user episodes in a single message, with buttons to flip pages
+func (ub *UndercastBot) sendEpisodesPage(ctx context.Context, b *bot.Bot, chatID int64, userID string, zapFields []zap.Field) {
+	episodes, total, err := ub.service.ListUserEpisodesPaged(ctx, userID, 0, episodesPageSize)
+	if err != nil {
+		ub.handleError(ctx, chatID, zaperr.Wrap(err, "failed to list episodes", zapFields...))
+		return
+	}
+
+	if total == 0 {
+		if _, err := b.SendMessage(ctx, &bot.SendMessageParams{
+			ChatID: chatID,
+			Text:   "You have no episodes yet",
+		}); err != nil {
+			ub.handleError(ctx, chatID, zaperr.Wrap(err, "failed to send message", zapFields...))
+		}
+		return
+	}
+
+	prefix := fmt.Sprintf("episodesPage_%s_%s_", userID, bot.RandomString(10))
+
+	msg, err := b.SendMessage(ctx, &bot.SendMessageParams{
+		ChatID:      chatID,
+		Text:        ub.renderEpisodesPage(episodes, 0, total),
+		ParseMode:   models.ParseModeHTML,
+		ReplyMarkup: episodesPageKeyboard(prefix, 0, total),
+	})
+	if err != nil {
+		ub.handleError(ctx, chatID, zaperr.Wrap(err, "failed to send message", zapFields...))
+		return
+	}
+	if total <= episodesPageSize {
+		return
+	}
+
+	handlerID := ub.registerChatHandler(chatID, bot.HandlerTypeCallbackQueryData, prefix, bot.MatchTypePrefix, func(ctx context.Context, b *bot.Bot, update *models.Update) {
+		page, err := strconv.Atoi(strings.TrimPrefix(update.CallbackQuery.Data, prefix))
+		if err != nil || page < 0 {
+			return
+		}
+		zapFields := append(zapFields, zap.Int("page", page))
+
+		episodes, total, err := ub.service.ListUserEpisodesPaged(ctx, userID, page*episodesPageSize, episodesPageSize)
+		if err != nil {
+			ub.handleError(ctx, chatID, zaperr.Wrap(err, "failed to list episodes", zapFields...))
+			return
+		}
+		// episodes could have been deleted since the first page was shown
+		if lastPage := pagesCount(total, episodesPageSize) - 1; page > lastPage && lastPage >= 0 {
+			page = lastPage
+			if episodes, total, err = ub.service.ListUserEpisodesPaged(ctx, userID, page*episodesPageSize, episodesPageSize); err != nil {
+				ub.handleError(ctx, chatID, zaperr.Wrap(err, "failed to list episodes", zapFields...))
+				return
+			}
+		}
+
+		if _, err := b.EditMessageText(ctx, &bot.EditMessageTextParams{
+			ChatID:      chatID,
+			MessageID:   msg.ID,
+			Text:        ub.renderEpisodesPage(episodes, page, total),
+			ParseMode:   models.ParseModeHTML,
+			ReplyMarkup: episodesPageKeyboard(prefix, page, total),
+		}); err != nil {
+			ub.handleError(ctx, chatID, zaperr.Wrap(err, "failed to edit message", zapFields...))
+		}
+	})
+	ub.replaceEpisodesPager(chatID, handlerID)
+}
+
+// replaceEpisodesPager keeps a single page view flippable per chat, so that every /ep doesn't leave a handler behind
+func (ub *UndercastBot) replaceEpisodesPager(chatID int64, handlerID string) {
+	ub.episodesPagersMu.Lock()
+	previousID, ok := ub.episodesPagers[chatID]
+	if ub.episodesPagers == nil {
+		ub.episodesPagers = make(map[int64]string)
+	}
+	ub.episodesPagers[chatID] = handlerID
+	ub.episodesPagersMu.Unlock()
+
+	// previous pager might have been unregistered by /cancel already, which is fine to do again
+	if ok {
+		ub.unregisterChatHandler(chatID, previousID)
+	}
+}
+
+func (ub *UndercastBot) renderEpisodesPage(episodes []*service.Episode, page, total int) string {
+	lines := make([]string, 0, len(episodes)+2)
+	lines = append(lines, fmt.Sprintf("Episodes: %d (page %d of %d)", total, page+1, pagesCount(total, episodesPageSize)), "")
+	for _, ep := range episodes {
+		lines = append(lines, ub.renderEpisodeShort(ep))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// episodesPageKeyboard renders ◀️/▶️ buttons, each carrying number of page it leads to
+func episodesPageKeyboard(prefix string, page, total int) models.ReplyMarkup {
+	lastPage := pagesCount(total, episodesPageSize) - 1
+	if lastPage <= 0 {
+		return nil
+	}
+
+	var row []models.InlineKeyboardButton
+	if page > 0 {
+		row = append(row, models.InlineKeyboardButton{Text: "◀️", CallbackData: prefix + strconv.Itoa(page-1)})
+	}
+	if page < lastPage {
+		row = append(row, models.InlineKeyboardButton{Text: "▶️", CallbackData: prefix + strconv.Itoa(page+1)})
+	}
+	return &models.InlineKeyboardMarkup{InlineKeyboard: [][]models.InlineKeyboardButton{row}}
+}
+
+func pagesCount(total, pageSize int) int {
+	return (total + pageSize - 1) / pageSize
+}
@@ -0,0 +1,72 @@
+package bot
+
+import (
+	"context"
+	"testing"
+
+	"github.com/go-telegram/bot"
+	"github.com/go-telegram/bot/models"
+)
+
+func TestEpisodesPageKeyboard(t *testing.T) {
+	for _, tc := range []struct {
+		name     string
+		page     int
+		total    int
+		expected []string
+	}{
+		{name: "single page has no buttons", page: 0, total: episodesPageSize},
+		{name: "first page", page: 0, total: 25, expected: []string{"prefix_1"}},
+		{name: "middle page", page: 1, total: 25, expected: []string{"prefix_0", "prefix_2"}},
+		{name: "last page", page: 2, total: 25, expected: []string{"prefix_1"}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			markup := episodesPageKeyboard("prefix_", tc.page, tc.total)
+			if tc.expected == nil {
+				if markup != nil {
+					t.Fatalf("expected no keyboard, got %+v", markup)
+				}
+				return
+			}
+
+			kb, ok := markup.(*models.InlineKeyboardMarkup)
+			if !ok || len(kb.InlineKeyboard) != 1 {
+				t.Fatalf("expected a single row keyboard, got %+v", markup)
+			}
+			var callbacks []string
+			for _, btn := range kb.InlineKeyboard[0] {
+				callbacks = append(callbacks, btn.CallbackData)
+			}
+			if len(callbacks) != len(tc.expected) {
+				t.Fatalf("expected buttons %v, got %v", tc.expected, callbacks)
+			}
+			for i := range callbacks {
+				if callbacks[i] != tc.expected[i] {
+					t.Fatalf("expected buttons %v, got %v", tc.expected, callbacks)
+				}
+			}
+		})
+	}
+}
+
+func TestUndercastBot__ReplaceEpisodesPager(t *testing.T) {
+	b, err := bot.New("some-token", bot.WithSkipGetMe())
+	if err != nil {
+		t.Fatal(err)
+	}
+	ub := &UndercastBot{bot: b}
+	noop := func(ctx context.Context, b *bot.Bot, update *models.Update) {}
+
+	chatID := int64(1)
+	for i := 0; i < 3; i++ {
+		handlerID := ub.registerChatHandler(chatID, bot.HandlerTypeCallbackQueryData, "episodesPage_", bot.MatchTypePrefix, noop)
+		ub.replaceEpisodesPager(chatID, handlerID)
+	}
+
+	if handlers := ub.chatHandlers[chatID]; len(handlers) != 1 || handlers[0] != ub.episodesPagers[chatID] {
+		t.Fatalf("expected only the latest pager to stay registered, got %v", handlers)
+	}
+	if cancelled := ub.cancelChatHandlers(chatID); cancelled != 1 {
+		t.Fatalf("expected /cancel to unregister the pager, got %d handlers", cancelled)
+	}
+}
@@ -0,0 +1,86 @@
+package bot
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-telegram/bot"
+	"github.com/go-telegram/bot/models"
+)
+
+const errorLogSize = 10
+
+var urlRegexp = regexp.MustCompile(`(?i)\b([a-z][a-z0-9+.-]*://|magnet:)[^\s"']+`)
+
+type errorLogEntry struct {
+	ID        string
+	Message   string
+	CreatedAt time.Time
+}
+
+// errorLog keeps last few errors per chat in memory, so that users can include them in their bug reports
+type errorLog struct {
+	mu      sync.Mutex
+	size    int
+	entries map[int64][]errorLogEntry
+}
+
+func newErrorLog(size int) *errorLog {
+	return &errorLog{
+		size:    size,
+		entries: make(map[int64][]errorLogEntry),
+	}
+}
+
+func (l *errorLog) Record(chatID int64, id string, err error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	entries := append(l.entries[chatID], errorLogEntry{
+		ID:        id,
+		Message:   redactErrorMessage(err.Error()),
+		CreatedAt: time.Now().UTC(),
+	})
+	if len(entries) > l.size {
+		entries = entries[len(entries)-l.size:]
+	}
+	l.entries[chatID] = entries
+}
+
+// List returns errors recorded for a chat, newest first
+func (l *errorLog) List(chatID int64) []errorLogEntry {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	entries := l.entries[chatID]
+	result := make([]errorLogEntry, len(entries))
+	for i, e := range entries {
+		result[len(entries)-1-i] = e
+	}
+	return result
+}
+
+// redactErrorMessage strips URLs from error message, since they might contain signatures or other secrets
+func redactErrorMessage(msg string) string {
+	return urlRegexp.ReplaceAllString(msg, "<redacted>")
+}
+
+func (ub *UndercastBot) myLogsHandler(ctx context.Context, _ *bot.Bot, update *models.Update) {
+	chatID := ub.extractChatID(update)
+
+	entries := ub.errorLog.List(chatID)
+	if len(entries) == 0 {
+		ub.sendTextMessage(ctx, chatID, "No errors happened recently")
+		return
+	}
+
+	lines := []string{fmt.Sprintf("Last %d errors:", len(entries))}
+	for _, e := range entries {
+		lines = append(lines, fmt.Sprintf("%s [%s] %s", e.CreatedAt.Format(time.DateTime), e.ID, e.Message))
+	}
+	ub.sendTextMessage(ctx, chatID, "%s", strings.Join(lines, "\n"))
+}
@@ -0,0 +1,45 @@
+package bot
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func TestErrorLog(t *testing.T) {
+	t.Run("recorded error appears in chat logs", func(t *testing.T) {
+		l := newErrorLog(errorLogSize)
+		l.Record(1, "some-error-id", errors.New("failed to fetch https://example.com/file.mp3?X-Amz-Signature=secret"))
+
+		entries := l.List(1)
+		if len(entries) != 1 {
+			t.Fatalf("expected 1 entry, got %d", len(entries))
+		}
+		if entries[0].ID != "some-error-id" {
+			t.Errorf("expected entry id to be some-error-id, got %s", entries[0].ID)
+		}
+		if entries[0].Message != "failed to fetch <redacted>" {
+			t.Errorf("expected url to be redacted, got %q", entries[0].Message)
+		}
+
+		if other := l.List(2); len(other) != 0 {
+			t.Errorf("expected other chat to have no entries, got %v", other)
+		}
+	})
+
+	t.Run("only last entries are kept, newest first", func(t *testing.T) {
+		l := newErrorLog(3)
+		for i := 0; i < 5; i++ {
+			l.Record(1, fmt.Sprintf("id-%d", i), errors.New("some error"))
+		}
+
+		entries := l.List(1)
+		var ids []string
+		for _, e := range entries {
+			ids = append(ids, e.ID)
+		}
+		if fmt.Sprint(ids) != "[id-4 id-3 id-2]" {
+			t.Errorf("expected [id-4 id-3 id-2], got %v", ids)
+		}
+	})
+}
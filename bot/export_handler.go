@@ -0,0 +1,38 @@
+package bot
+
+import (
+	"context"
+
+	"github.com/go-telegram/bot"
+	"github.com/go-telegram/bot/models"
+	"github.com/hori-ryota/zaperr"
+	"go.uber.org/zap"
+)
+
+// exportHandler sends all user feeds as an OPML file, to subscribe to all of them in a podcast app at once
+func (ub *UndercastBot) exportHandler(ctx context.Context, _ *bot.Bot, update *models.Update) {
+	chatID := ub.extractChatID(update)
+	userID := ub.extractUserID(update)
+
+	zapFields := []zap.Field{
+		zap.Int64("chat_id", chatID),
+		zap.String("user_id", userID),
+	}
+
+	data, err := ub.service.ExportOPML(ctx, userID)
+	if err != nil {
+		ub.handleError(ctx, chatID, zaperr.Wrap(err, "failed to export opml", zapFields...))
+		return
+	}
+
+	if _, err := ub.bot.SendDocument(ctx, &bot.SendDocumentParams{
+		ChatID: chatID,
+		Document: &models.InputFileUpload{
+			Filename: "feeds.opml",
+			Data:     data,
+		},
+		Caption: "Your feeds, ready to be imported into a podcast app",
+	}); err != nil {
+		ub.handleError(ctx, chatID, zaperr.Wrap(err, "failed to send opml document", zapFields...))
+	}
+}
@@ -0,0 +1,89 @@
+package bot
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/go-telegram/bot"
+	"github.com/go-telegram/bot/models"
+	"github.com/hori-ryota/zaperr"
+	"go.uber.org/zap"
+)
+
+const (
+	defaultTelegramServerURL    = "https://api.telegram.org"
+	telegramFileDownloadTimeout = 2 * time.Minute // bots can get files of up to 20MB
+)
+
+// setFeedImageFromPhoto downloads the largest available size of a Telegram photo
+// and uses it as feed cover image
+func (ub *UndercastBot) setFeedImageFromPhoto(ctx context.Context, userID string, feedID string, photos []models.PhotoSize) error {
+	zapFields := []zap.Field{
+		zap.String("user_id", userID),
+		zap.String("feed_id", feedID),
+	}
+
+	photo := largestPhotoSize(photos)
+	if photo == nil {
+		return zaperr.New("message has no photo", zapFields...)
+	}
+	zapFields = append(zapFields, zap.String("file_id", photo.FileID))
+
+	data, err := ub.downloadTelegramFile(ctx, photo.FileID)
+	if err != nil {
+		return zaperr.Wrap(err, "failed to download photo", zapFields...)
+	}
+
+	// Telegram re-encodes all photos to JPEG
+	if err := ub.service.SetFeedImage(ctx, userID, feedID, bytes.NewReader(data), "image/jpeg"); err != nil {
+		return zaperr.Wrap(err, "failed to set feed image", zapFields...)
+	}
+
+	return nil
+}
+
+func (ub *UndercastBot) downloadTelegramFile(ctx context.Context, fileID string) ([]byte, error) {
+	file, err := ub.bot.GetFile(ctx, &bot.GetFileParams{FileID: fileID})
+	if err != nil {
+		return nil, zaperr.Wrap(err, "failed to get file info")
+	}
+
+	fileURL := fmt.Sprintf("%s/file/bot%s/%s", ub.telegramServerURL, ub.token, file.FilePath)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, fileURL, nil)
+	if err != nil {
+		return nil, zaperr.Wrap(err, "failed to create request")
+	}
+
+	resp, err := ub.httpClient.Do(req)
+	if err != nil {
+		return nil, zaperr.Wrap(err, "failed to download file")
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, zaperr.New("unexpected status code while downloading file", zap.Int("status_code", resp.StatusCode))
+	}
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, zaperr.Wrap(err, "failed to read file")
+	}
+	return data, nil
+}
+
+// largestPhotoSize picks the biggest of the sizes Telegram provides for every photo
+func largestPhotoSize(photos []models.PhotoSize) *models.PhotoSize {
+	var largest *models.PhotoSize
+	for i := range photos {
+		p := &photos[i]
+		if largest == nil || p.Width*p.Height > largest.Width*largest.Height ||
+			(p.Width*p.Height == largest.Width*largest.Height && p.FileSize > largest.FileSize) {
+			largest = p
+		}
+	}
+	return largest
+}
@@ -0,0 +1,131 @@
+package bot
+
+import (
+	"context"
+	"database/sql"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/go-telegram/bot"
+	"github.com/go-telegram/bot/models"
+	"github.com/rubenv/sql-migrate"
+	"go.uber.org/zap"
+	"tg-podcastotron/mediary/mediarymocks"
+	"tg-podcastotron/service"
+	"tg-podcastotron/service/servicemocks"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+func TestUndercastBot__SetFeedImageFromPhoto(t *testing.T) {
+	ctx := context.Background()
+	token := "some-token"
+
+	// region fake Telegram Bot API serving file info and file contents
+	var requestedFileID string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/bot" + token + "/getFile":
+			if err := r.ParseMultipartForm(1 << 20); err == nil {
+				requestedFileID = r.FormValue("file_id")
+			}
+			w.Header().Set("Content-Type", "application/json")
+			_, _ = w.Write([]byte(`{"ok":true,"result":{"file_id":"large","file_unique_id":"large","file_path":"photos/large.jpg"}}`))
+		case "/file/bot" + token + "/photos/large.jpg":
+			_, _ = w.Write([]byte("some-jpeg-data"))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer srv.Close()
+	// endregion
+
+	var uploadedKey string
+	var uploadedData []byte
+	s3Store := &servicemocks.MockS3Store{
+		PutFunc: func(ctx context.Context, key string, dataReader io.ReadSeeker, opts ...func(*service.PutOptions)) error {
+			uploadedKey = key
+			data, err := io.ReadAll(dataReader)
+			uploadedData = data
+			return err
+		},
+		URLFunc: func(key string) (string, error) {
+			return "https://example.com/" + key, nil
+		},
+	}
+	svc := service.New(
+		&mediarymocks.ServiceMock{}, getServiceRepo(t), s3Store, &fakeJobsQueue{},
+		"", "", false, func(s string) string { return s }, zap.NewNop(),
+	)
+
+	userID := "some-user"
+	feed, err := svc.CreateFeed(ctx, userID, "some feed")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	b, err := bot.New(token, bot.WithServerURL(srv.URL), bot.WithSkipGetMe())
+	if err != nil {
+		t.Fatal(err)
+	}
+	ub := &UndercastBot{
+		logger:            zap.NewNop(),
+		token:             token,
+		bot:               b,
+		service:           svc,
+		telegramServerURL: srv.URL,
+		httpClient:        srv.Client(),
+	}
+
+	if err := ub.setFeedImageFromPhoto(ctx, userID, feed.ID, []models.PhotoSize{
+		{FileID: "small", Width: 90, Height: 90},
+		{FileID: "large", Width: 1280, Height: 1280},
+		{FileID: "medium", Width: 320, Height: 320},
+	}); err != nil {
+		t.Fatalf("failed to set feed image: %v", err)
+	}
+
+	if requestedFileID != "large" {
+		t.Errorf("expected largest photo size to be downloaded, got %s", requestedFileID)
+	}
+	if string(uploadedData) != "some-jpeg-data" {
+		t.Errorf("expected downloaded photo to be uploaded, got %q", uploadedData)
+	}
+
+	feed, err = svc.GetFeed(ctx, userID, feed.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if feed.ImageURL != "https://example.com/"+uploadedKey {
+		t.Errorf("expected feed image url to point at uploaded image, got %s", feed.ImageURL)
+	}
+}
+
+func getServiceRepo(t *testing.T) service.Repository {
+	db, err := sql.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	migrations := &migrate.FileMigrationSource{
+		Dir: "../db/migrations",
+	}
+	if _, err := migrate.Exec(db, "sqlite3", migrations, migrate.Up); err != nil {
+		t.Fatalf("failed to apply migrations: %v", err)
+	}
+
+	return service.NewSqliteRepository(db)
+}
+
+type fakeJobsQueue struct{}
+
+func (q *fakeJobsQueue) Run() {}
+
+func (q *fakeJobsQueue) Subscribe(context.Context, string, func(payloadBytes []byte) error) {}
+
+func (q *fakeJobsQueue) Publish(context.Context, string, any) error { return nil }
+
+func (q *fakeJobsQueue) PublishDelayed(context.Context, string, any, time.Duration) error { return nil }
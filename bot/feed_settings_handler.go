@@ -0,0 +1,124 @@
+package bot
+
+import (
+	"context"
+	"fmt"
+	"html"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/go-telegram/bot"
+	"github.com/go-telegram/bot/models"
+	"github.com/hori-ryota/zaperr"
+	"go.uber.org/zap"
+	"tg-podcastotron/service"
+)
+
+// feedSettingsHandler shows all settings of a feed in one message,
+// with the same buttons /ef_ uses to edit them
+func (ub *UndercastBot) feedSettingsHandler(ctx context.Context, _ *bot.Bot, update *models.Update) {
+	chatID := ub.extractChatID(update)
+	userID := ub.extractUserID(update)
+
+	zapFields := []zap.Field{
+		zap.Int64("chat_id", chatID),
+		zap.String("user_id", userID),
+		zap.String("message_text", update.Message.Text),
+	}
+
+	feedID, err := ub.parseFeedSettingsCmd(update.Message.Text)
+	if err != nil {
+		ub.sendTextMessage(ctx, chatID, "Please specify feed ID, like so:\n/feedsettings_1")
+		return
+	}
+	zapFields = append(zapFields, zap.String("feed_id", feedID))
+
+	feed, err := ub.service.GetFeed(ctx, userID, feedID)
+	if err != nil {
+		ub.handleError(ctx, chatID, zaperr.Wrap(err, "failed to get feed", zapFields...))
+		return
+	}
+	if feed == nil {
+		ub.sendTextMessage(ctx, chatID, "Feed #%s not found", feedID)
+		return
+	}
+
+	isAdmin, _ := ub.auth.IsAdmin(ctx, ub.extractUsername(update))
+	ub.sendFeedEditor(ctx, chatID, userID, feed, isAdmin, renderFeedSettings(feed), zapFields)
+}
+
+func renderFeedSettings(feed *service.Feed) string {
+	valueOrNotSet := func(value string) string {
+		if value == "" {
+			return "<i>not set</i>"
+		}
+		return html.EscapeString(value)
+	}
+	enabledOrDisabled := func(value bool) string {
+		if value {
+			return "enabled"
+		}
+		return "disabled"
+	}
+
+	maxSize := "<i>no limit</i>"
+	if feed.MaxTotalBytes > 0 {
+		maxSize = fmt.Sprintf("%d MB", feed.MaxTotalBytes/(1024*1024))
+	}
+	maxEpisodes := "<i>no limit</i>"
+	if feed.MaxEpisodes > 0 {
+		maxEpisodes = strconv.Itoa(feed.MaxEpisodes)
+	}
+
+	lines := []string{
+		fmt.Sprintf("<b>Feed #%s settings</b>", feed.ID),
+		"",
+		fmt.Sprintf("<b>Title:</b> %s", valueOrNotSet(feed.Title)),
+		fmt.Sprintf("<b>URL:</b> %s", valueOrNotSet(feed.URL)),
+		fmt.Sprintf("<b>Cover image:</b> %s", valueOrNotSet(feed.ImageURL)),
+		fmt.Sprintf("<b>Description:</b> %s", valueOrNotSet(feed.Description)),
+		fmt.Sprintf("<b>Author:</b> %s", valueOrNotSet(feed.Author)),
+		fmt.Sprintf("<b>Copyright:</b> %s", valueOrNotSet(feed.Copyright)),
+		fmt.Sprintf("<b>Max size:</b> %s", maxSize),
+		fmt.Sprintf("<b>Max episodes:</b> %s", maxEpisodes),
+		fmt.Sprintf("<b>Polling schedule:</b> %s", renderFeedSchedule(feed)),
+		fmt.Sprintf("<b>Normalization:</b> %s", enabledOrDisabled(feed.Normalize)),
+		fmt.Sprintf("<b>Permanent:</b> %s", enabledOrDisabled(feed.IsPermanent)),
+	}
+	return strings.Join(lines, "\n")
+}
+
+func renderFeedSchedule(feed *service.Feed) string {
+	var bits []string
+	if feed.TTLMinutes > 0 {
+		bits = append(bits, fmt.Sprintf("ttl %d min", feed.TTLMinutes))
+	}
+	if len(feed.SkipHours) > 0 {
+		hours := make([]string, 0, len(feed.SkipHours))
+		for _, h := range feed.SkipHours {
+			hours = append(hours, strconv.Itoa(h))
+		}
+		bits = append(bits, fmt.Sprintf("skip hours %s GMT", strings.Join(hours, ", ")))
+	}
+	if len(feed.SkipDays) > 0 {
+		days := make([]string, 0, len(feed.SkipDays))
+		for _, d := range feed.SkipDays {
+			days = append(days, d.String())
+		}
+		bits = append(bits, fmt.Sprintf("skip %s", strings.Join(days, ", ")))
+	}
+	if len(bits) == 0 {
+		return "<i>not set</i>"
+	}
+	return strings.Join(bits, "; ")
+}
+
+func (ub *UndercastBot) parseFeedSettingsCmd(text string) (string, error) {
+	re := regexp.MustCompile(`/feedsettings_(\d+)`)
+	matches := re.FindStringSubmatch(text)
+	if len(matches) != 2 {
+		return "", fmt.Errorf("invalid command")
+	}
+	return matches[1], nil
+}
@@ -0,0 +1,63 @@
+package bot
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"go.uber.org/zap"
+	"tg-podcastotron/mediary/mediarymocks"
+	"tg-podcastotron/service"
+	"tg-podcastotron/service/servicemocks"
+)
+
+func TestRenderFeedSettings(t *testing.T) {
+	ctx := context.Background()
+	svc := service.New(
+		&mediarymocks.ServiceMock{}, getServiceRepo(t), &servicemocks.MockS3Store{
+			URLFunc: func(key string) (string, error) { return "https://example.com/" + key, nil },
+		}, &fakeJobsQueue{},
+		"", "", false, func(s string) string { return s }, zap.NewNop(),
+	)
+
+	userID := "some-user"
+	feed, err := svc.CreateFeed(ctx, userID, "Some <feed>")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := svc.SetFeedCopyright(ctx, userID, feed.ID, "© 2024 Some Author"); err != nil {
+		t.Fatal(err)
+	}
+	if err := svc.UpdateFeedMetadata(ctx, userID, feed.ID, "All about something", ""); err != nil {
+		t.Fatal(err)
+	}
+	if err := svc.SetFeedMaxTotalBytes(ctx, userID, feed.ID, 300*1024*1024); err != nil {
+		t.Fatal(err)
+	}
+	if err := svc.SetFeedNormalize(ctx, userID, feed.ID, true); err != nil {
+		t.Fatal(err)
+	}
+
+	feed, err = svc.GetFeed(ctx, userID, feed.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	text := renderFeedSettings(feed)
+
+	for _, expected := range []string{
+		"<b>Title:</b> Some &lt;feed&gt;",
+		"<b>Cover image:</b> <i>not set</i>",
+		"<b>Description:</b> All about something",
+		"<b>Author:</b> <i>not set</i>",
+		"<b>Copyright:</b> © 2024 Some Author",
+		"<b>Max size:</b> 300 MB",
+		"<b>Max episodes:</b> <i>no limit</i>",
+		"<b>Polling schedule:</b> <i>not set</i>",
+		"<b>Normalization:</b> enabled",
+		"<b>Permanent:</b> disabled",
+	} {
+		if !strings.Contains(text, expected) {
+			t.Errorf("expected settings to contain %q, got:\n%s", expected, text)
+		}
+	}
+}
@@ -0,0 +1,65 @@
+package bot
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+
+	"github.com/go-telegram/bot"
+	"github.com/go-telegram/bot/models"
+	"github.com/hori-ryota/zaperr"
+	"go.uber.org/zap"
+	"tg-podcastotron/service"
+)
+
+// getFeedHandler sends feed file as a document,
+// so that user has a copy of it even if S3 URL is not reachable from their network
+func (ub *UndercastBot) getFeedHandler(ctx context.Context, _ *bot.Bot, update *models.Update) {
+	chatID := ub.extractChatID(update)
+	userID := ub.extractUserID(update)
+
+	zapFields := []zap.Field{
+		zap.Int64("chat_id", chatID),
+		zap.String("user_id", userID),
+		zap.String("message_text", update.Message.Text),
+	}
+
+	feedID, err := ub.parseGetFeedCmd(update.Message.Text)
+	if err != nil {
+		ub.sendTextMessage(ctx, chatID, "Please specify feed ID, like so:\n/getfeed_1")
+		return
+	}
+	zapFields = append(zapFields, zap.String("feed_id", feedID))
+
+	data, err := ub.service.GetFeedFile(ctx, userID, feedID)
+	if err != nil {
+		if errors.Is(err, service.ErrFeedNotFound) {
+			ub.sendTextMessage(ctx, chatID, "Feed #%s not found", feedID)
+			return
+		}
+		ub.handleError(ctx, chatID, zaperr.Wrap(err, "failed to get feed file", zapFields...))
+		return
+	}
+
+	if _, err := ub.bot.SendDocument(ctx, &bot.SendDocumentParams{
+		ChatID: chatID,
+		Document: &models.InputFileUpload{
+			Filename: fmt.Sprintf("feed-%s.xml", feedID),
+			Data:     bytes.NewReader(data),
+		},
+		Caption: fmt.Sprintf("Feed #%s", feedID),
+	}); err != nil {
+		ub.handleError(ctx, chatID, zaperr.Wrap(err, "failed to send feed document", zapFields...))
+	}
+}
+
+func (ub *UndercastBot) parseGetFeedCmd(text string) (string, error) {
+	re := regexp.MustCompile(`/getfeed_(\d+)`)
+	matches := re.FindStringSubmatch(text)
+	if len(matches) != 2 {
+		return "", fmt.Errorf("invalid command")
+	}
+	return matches[1], nil
+}
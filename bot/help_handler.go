@@ -14,6 +14,8 @@ This bot allows you to create podcasts from torrent magnet links.
 <b>You just send it a link, choose files, and it will be published to your podcast feed</b>
 Subscribe to it and listen away!
 
+You can also send it an audio file up to 20 MB, as audio or as a document, it will be published as is.
+
 Bot will try to figure episode title to the best of its ability,
 but you can always edit episodes later: change title 
 or which podcast feeds they are published to, like so:
@@ -26,12 +28,39 @@ If you wonder where do you get episode IDs from, just run
 
 If you ever need more info about some episode, just run
 /ep_1 - get more info about episode 1
+/epfeeds_1 - list podcast feeds episode 1 is published to
+/setfeeds_ep_1_to_3_feeds_2_5 - publish episodes 1 to 3 to feeds 2 and 5 only
+
+Looking for a particular episode?
+/search some title - find episodes by title or link
+
+Deleted an episode by mistake?
+/trash will list deleted episodes, they can be restored for a week
 
 If you want to have more than one podcast feed,
 /nf will create a new podcast feed;
 /ef_1 will edit podcast feed with ID 1;
+/deletefeeds 2 3 will delete podcast feeds with IDs 2 and 3 at once;
+/feedsettings_1 will show all settings of podcast feed with ID 1, with buttons to edit them;
 /f will list all your podcast feeds;
 /f_1 will show more info about podcast feed with ID 1
+/reorderfeeds 3 1 will list podcast feeds with IDs 3 and 1 first
+/getfeed_1 will send podcast feed with ID 1 as a file, in case its URL is not reachable for you
+/checkfeed_1 will find episodes of podcast feed with ID 1 whose files can't be downloaded
+/export will send all your podcast feeds as an OPML file, to subscribe to them in a podcast app at once
+
+Moving from another podcast host?
+/import &lt;rss_url&gt; will create a new feed with all episodes of an existing podcast
+
+/template will let you customize the message you get when an episode is ready
+/webhook will let you get a request to your own URL when an episode is ready
+/retention will show or change how many days episodes are kept, unless they are in a permanent feed
+
+/stats will show how many feeds and episodes you have and how much space they take
+
+/cancel will abandon whatever you were asked to reply to or choose
+
+/mylogs will show your recent errors, please include them when reporting an issue
 
 /start or /help will render this message
 `
@@ -0,0 +1,69 @@
+package bot
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"tg-podcastotron/service"
+)
+
+func TestHumanizeBytes(t *testing.T) {
+	for _, tc := range []struct {
+		bytes    int64
+		expected string
+	}{
+		{0, "0 B"},
+		{1023, "1023 B"},
+		{1024, "1.0 KB"},
+		{1536, "1.5 KB"},
+		{47395635, "45.2 MB"},
+		{3 * 1024 * 1024 * 1024, "3.0 GB"},
+		{2 * 1024 * 1024 * 1024 * 1024, "2.0 TB"},
+	} {
+		if actual := humanizeBytes(tc.bytes); actual != tc.expected {
+			t.Errorf("humanizeBytes(%d) = %q, want %q", tc.bytes, actual, tc.expected)
+		}
+	}
+}
+
+func TestHumanizeDuration(t *testing.T) {
+	for _, tc := range []struct {
+		duration time.Duration
+		expected string
+	}{
+		{0, "0s"},
+		{42 * time.Second, "42s"},
+		{5*time.Minute + 3*time.Second, "5m03s"},
+		{59*time.Minute + 59*time.Second + 600*time.Millisecond, "1h00m"},
+		{time.Hour + 23*time.Minute + 45*time.Second, "1h23m"},
+		{26*time.Hour + 5*time.Minute, "26h05m"},
+	} {
+		if actual := humanizeDuration(tc.duration); actual != tc.expected {
+			t.Errorf("humanizeDuration(%v) = %q, want %q", tc.duration, actual, tc.expected)
+		}
+	}
+}
+
+func TestRenderEpisodeFull__DurationAndSize(t *testing.T) {
+	ub := &UndercastBot{}
+
+	complete := ub.renderEpisodeFull(&service.Episode{
+		ID:           "1",
+		Title:        "some episode",
+		Duration:     time.Hour + 23*time.Minute,
+		FileLenBytes: 47395635,
+	}, nil)
+	for _, expected := range []string{"<b>Duration:</b> 1h23m", "<b>Size:</b> 45.2 MB"} {
+		if !strings.Contains(complete, expected) {
+			t.Errorf("expected rendered episode to contain %q, got:\n%s", expected, complete)
+		}
+	}
+
+	processing := ub.renderEpisodeFull(&service.Episode{ID: "2", Title: "still processing"}, nil)
+	for _, unexpected := range []string{"Duration:", "Size:"} {
+		if strings.Contains(processing, unexpected) {
+			t.Errorf("expected episode that is not complete to have no %q, got:\n%s", unexpected, processing)
+		}
+	}
+}
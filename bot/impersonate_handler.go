@@ -0,0 +1,96 @@
+package bot
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strconv"
+
+	"github.com/go-telegram/bot"
+	"github.com/go-telegram/bot/models"
+	"github.com/hori-ryota/zaperr"
+	"go.uber.org/zap"
+	"tg-podcastotron/service"
+)
+
+// impersonateHandler lets admin look at another user's feeds and episodes for support purposes,
+// e.g. `/as_123 /f_1`. Only read-only commands are allowed, so admin can't change someone else's data
+func (ub *UndercastBot) impersonateHandler(ctx context.Context, b *bot.Bot, update *models.Update) {
+	chatID := ub.extractChatID(update)
+
+	zapFields := []zap.Field{
+		zap.Int64("chat_id", chatID),
+		zap.String("user_id", ub.extractUserID(update)),
+		zap.String("message_text", update.Message.Text),
+	}
+
+	isAdmin, err := ub.auth.IsAdmin(ctx, ub.extractUsername(update))
+	if err != nil {
+		ub.handleError(ctx, chatID, zaperr.Wrap(err, "failed to check if user is admin", zapFields...))
+		return
+	}
+	if !isAdmin {
+		ub.sendTextMessage(ctx, chatID, "unknown command")
+		return
+	}
+
+	targetUserID, cmd, err := ub.parseImpersonateCmd(update.Message.Text)
+	if err != nil {
+		ub.sendTextMessage(ctx, chatID, "Please specify user ID and a read-only command, like so:\n/as_123456 /f\n/as_123456 /f_1\n/as_123456 /ep\n/as_123456 /ep_1")
+		return
+	}
+	zapFields = append(zapFields, zap.Int64("target_user_id", targetUserID))
+	ub.logger.Info("admin is impersonating user", zapFields...)
+
+	// handlers take user id from message sender, so the message is re-addressed to be from target user.
+	// Edit links in responses still act on behalf of admin, so they can't touch target user's data
+	msg := *update.Message
+	from := *msg.From
+	from.ID = targetUserID
+	msg.From = &from
+	msg.Text = cmd
+	impersonatedUpdate := &models.Update{ID: update.ID, Message: &msg}
+	ctx = context.WithValue(ctx, impersonationCtxKey{}, true)
+
+	switch {
+	case readOnlyFeedsCmdRegexp.MatchString(cmd):
+		ub.listFeedsHandler(ctx, b, impersonatedUpdate)
+	case readOnlyEpisodesCmdRegexp.MatchString(cmd):
+		ub.listEpisodesHandler(ctx, b, impersonatedUpdate)
+	default:
+		ub.sendTextMessage(ctx, chatID, "Only /f and /ep commands are allowed while impersonating")
+	}
+}
+
+type impersonationCtxKey struct{}
+
+func isImpersonating(ctx context.Context) bool {
+	impersonating, _ := ctx.Value(impersonationCtxKey{}).(bool)
+	return impersonating
+}
+
+// listFeeds lists feeds to show to the user. While impersonating, missing default feed is not created,
+// so that admin does not change anyone's data
+func (ub *UndercastBot) listFeeds(ctx context.Context, userID string) ([]*service.Feed, error) {
+	if isImpersonating(ctx) {
+		return ub.service.ListUserFeeds(ctx, userID)
+	}
+	return ub.service.ListFeeds(ctx, userID)
+}
+
+var (
+	readOnlyFeedsCmdRegexp    = regexp.MustCompile(`^/f(_\d+)?$`)
+	readOnlyEpisodesCmdRegexp = regexp.MustCompile(`^/ep(_\d+)?$`)
+)
+
+func (ub *UndercastBot) parseImpersonateCmd(text string) (userID int64, cmd string, err error) {
+	re := regexp.MustCompile(`^/as_(\d+)\s+(\S+)\s*$`)
+	matches := re.FindStringSubmatch(text)
+	if len(matches) != 3 {
+		return 0, "", fmt.Errorf("invalid command")
+	}
+	if userID, err = strconv.ParseInt(matches[1], 10, 64); err != nil {
+		return 0, "", fmt.Errorf("invalid user id: %w", err)
+	}
+	return userID, matches[2], nil
+}
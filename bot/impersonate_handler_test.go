@@ -0,0 +1,114 @@
+package bot
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/go-telegram/bot"
+	"github.com/go-telegram/bot/models"
+	"go.uber.org/zap"
+	"tg-podcastotron/auth"
+	"tg-podcastotron/mediary/mediarymocks"
+	"tg-podcastotron/service"
+	"tg-podcastotron/service/servicemocks"
+)
+
+func TestUndercastBot__Impersonate(t *testing.T) {
+	ctx := context.Background()
+	token := "some-token"
+
+	// region fake Telegram Bot API recording sent messages
+	var mu sync.Mutex
+	var sentTexts []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/bot"+token+"/sendMessage" {
+			if err := r.ParseMultipartForm(1 << 20); err == nil {
+				mu.Lock()
+				sentTexts = append(sentTexts, r.FormValue("text"))
+				mu.Unlock()
+			}
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"ok":true,"result":{"message_id":1,"date":0,"chat":{"id":1,"type":"private"}}}`))
+	}))
+	defer srv.Close()
+	// endregion
+
+	svc := service.New(
+		&mediarymocks.ServiceMock{}, getServiceRepo(t), &servicemocks.MockS3Store{
+			URLFunc: func(key string) (string, error) { return "https://example.com/" + key, nil },
+		}, &fakeJobsQueue{},
+		"", "", false, func(s string) string { return s }, zap.NewNop(),
+	)
+	if _, err := svc.CreateFeed(ctx, "42", "someone else's feed"); err != nil {
+		t.Fatal(err)
+	}
+
+	b, err := bot.New(token, bot.WithServerURL(srv.URL), bot.WithSkipGetMe())
+	if err != nil {
+		t.Fatal(err)
+	}
+	ub := &UndercastBot{
+		logger:  zap.NewNop(),
+		token:   token,
+		bot:     b,
+		service: svc,
+		auth:    auth.New("admin", nil, zap.NewNop()),
+	}
+
+	impersonate := func(username string, text string) string {
+		mu.Lock()
+		sentTexts = nil
+		mu.Unlock()
+
+		ub.impersonateHandler(ctx, b, &models.Update{Message: &models.Message{
+			Chat: models.Chat{ID: 1},
+			From: &models.User{ID: 1, Username: username},
+			Text: text,
+		}})
+
+		mu.Lock()
+		defer mu.Unlock()
+		return strings.Join(sentTexts, "\n")
+	}
+
+	t.Run("non-admin cannot impersonate", func(t *testing.T) {
+		text := impersonate("not-admin", "/as_42 /f")
+		if strings.Contains(text, "someone else's feed") {
+			t.Fatalf("expected non-admin not to see other user's feeds, got %q", text)
+		}
+		if text != "unknown command" {
+			t.Fatalf("expected non-admin to get unknown command, got %q", text)
+		}
+	})
+
+	t.Run("admin can list other user's feeds", func(t *testing.T) {
+		text := impersonate("admin", "/as_42 /f")
+		if !strings.Contains(text, "someone else's feed") {
+			t.Fatalf("expected admin to see other user's feeds, got %q", text)
+		}
+	})
+
+	t.Run("listing feeds of user without any creates nothing", func(t *testing.T) {
+		impersonate("admin", "/as_43 /f")
+		impersonate("admin", "/as_43 /ep")
+		feeds, err := svc.ListUserFeeds(ctx, "43")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(feeds) != 0 {
+			t.Fatalf("expected impersonation not to create default feed, got %d feeds", len(feeds))
+		}
+	})
+
+	t.Run("admin cannot run write commands", func(t *testing.T) {
+		text := impersonate("admin", "/as_42 /ef_1")
+		if !strings.Contains(text, "Only /f and /ep commands are allowed") {
+			t.Fatalf("expected write command to be refused, got %q", text)
+		}
+	})
+}
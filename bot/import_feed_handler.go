@@ -0,0 +1,45 @@
+package bot
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/go-telegram/bot"
+	"github.com/go-telegram/bot/models"
+	"github.com/hori-ryota/zaperr"
+	"go.uber.org/zap"
+)
+
+func (ub *UndercastBot) importFeedHandler(ctx context.Context, _ *bot.Bot, update *models.Update) {
+	chatID := ub.extractChatID(update)
+	userID := ub.extractUserID(update)
+
+	zapFields := []zap.Field{
+		zap.Int64("chat_id", chatID),
+		zap.String("user_id", userID),
+		zap.String("message_text", update.Message.Text),
+	}
+
+	rssURL := strings.TrimSpace(strings.TrimPrefix(update.Message.Text, "/import"))
+	if rssURL == "" {
+		ub.sendTextMessage(ctx, chatID, "Please send RSS feed URL along with the command, like so:\n/import https://example.com/podcast.rss")
+		return
+	}
+
+	feed, err := ub.service.ImportRSSFeed(ctx, userID, rssURL)
+	if err != nil {
+		ub.handleError(ctx, chatID, zaperr.Wrap(err, "failed to import rss feed", zapFields...))
+		return
+	}
+
+	statusMsg := fmt.Sprintf("Feed was imported with %d episodes:\n\n%s", len(feed.EpisodeIDs), ub.renderFeedShort(feed, nil))
+	if _, err := ub.bot.SendMessage(ctx, &bot.SendMessageParams{
+		ChatID:    chatID,
+		Text:      statusMsg,
+		ParseMode: models.ParseModeHTML,
+	}); err != nil {
+		zapFields := append(zapFields, zap.String("message", statusMsg))
+		ub.handleError(ctx, chatID, zaperr.Wrap(err, "failed to send message", zapFields...))
+	}
+}
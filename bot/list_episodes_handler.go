@@ -31,31 +31,28 @@ func (ub *UndercastBot) listEpisodesHandler(ctx context.Context, b *bot.Bot, upd
 		zap.String("episode_id", epID),
 	}
 
-	var err error
+	if epID == "" {
+		ub.sendEpisodesPage(ctx, b, chatID, userID, zapFields)
+		return
+	}
+
 	var episodes []*service.Episode
 	feedMap := map[string]*service.Feed{}
-	if epID == "" {
-		if episodes, err = ub.service.ListUserEpisodes(ctx, userID); err != nil {
-			ub.handleError(ctx, chatID, zaperr.Wrap(err, "failed to list episodes", zapFields...))
+	if epMap, err := ub.service.GetEpisodesMap(ctx, userID, []string{epID}); err != nil {
+		if errors.Is(err, service.ErrEpisodeNotFound) {
+			ub.sendTextMessage(ctx, chatID, "Episode %s not found", epID)
 			return
 		}
+		ub.handleError(ctx, chatID, zaperr.Wrap(err, "failed to get episodes", zapFields...))
+		return
 	} else {
-		if epMap, err := ub.service.GetEpisodesMap(ctx, userID, []string{epID}); err != nil {
-			if errors.Is(err, service.ErrEpisodeNotFound) {
-				ub.sendTextMessage(ctx, chatID, "Episode %s not found", epID)
-				return
-			}
-			ub.handleError(ctx, chatID, zaperr.Wrap(err, "failed to get episodes", zapFields...))
+		episodes = append(episodes, epMap[epID])
+		if feeds, err := ub.listFeeds(ctx, userID); err != nil {
+			ub.handleError(ctx, chatID, zaperr.Wrap(err, "failed to list feeds", zapFields...))
 			return
 		} else {
-			episodes = append(episodes, epMap[epID])
-			if feeds, err := ub.service.ListFeeds(ctx, userID); err != nil {
-				ub.handleError(ctx, chatID, zaperr.Wrap(err, "failed to list feeds", zapFields...))
-				return
-			} else {
-				for _, f := range feeds {
-					feedMap[f.ID] = f
-				}
+			for _, f := range feeds {
+				feedMap[f.ID] = f
 			}
 		}
 	}
@@ -71,17 +68,12 @@ func (ub *UndercastBot) listEpisodesHandler(ctx context.Context, b *bot.Bot, upd
 	}
 
 	for _, ep := range episodes {
-		var text string
-		if epID == "" {
-			text = ub.renderEpisodeShort(ep)
-		} else {
-			feeds, err := ub.service.ListEpisodeFeeds(ctx, userID, ep.ID)
-			if err != nil {
-				ub.handleError(ctx, chatID, zaperr.Wrap(err, "failed to list episode feeds", zapFields...))
-				return
-			}
-			text = ub.renderEpisodeFull(ep, feeds)
+		feeds, err := ub.service.ListEpisodeFeeds(ctx, userID, ep.ID)
+		if err != nil {
+			ub.handleError(ctx, chatID, zaperr.Wrap(err, "failed to list episode feeds", zapFields...))
+			return
 		}
+		text := ub.renderEpisodeFull(ep, feeds)
 		if msg, err := b.SendMessage(ctx, &bot.SendMessageParams{
 			ChatID:    chatID,
 			ParseMode: models.ParseModeHTML,
@@ -111,8 +103,20 @@ func (ub *UndercastBot) renderEpisodeFull(ep *service.Episode, feeds []*service.
 	}
 	feedsDescription := strings.Join(feedsDescriptionBits, "\n")
 
-	return fmt.Sprintf(`<b>Episode #<code>%s</code> (%s)</b>
+	// duration and size are only known once episode is processed
+	var details string
+	if ep.Duration > 0 {
+		details += fmt.Sprintf("<b>Duration:</b> %s\n", humanizeDuration(ep.Duration))
+	}
+	if ep.FileLenBytes > 0 {
+		details += fmt.Sprintf("<b>Size:</b> %s\n", humanizeBytes(ep.FileLenBytes))
+	}
+	if details != "" {
+		details = "\n" + details
+	}
 
+	return fmt.Sprintf(`<b>Episode #<code>%s</code> (%s)</b>
+%s
 <b>Source:</b>
 <code>%s</code>
 
@@ -123,6 +127,7 @@ func (ub *UndercastBot) renderEpisodeFull(ep *service.Episode, feeds []*service.
 %s`,
 		ep.ID,
 		ep.Title,
+		details,
 		ep.SourceURL,
 		strings.Join(ep.SourceFilepaths, ", "),
 		feedsDescription,
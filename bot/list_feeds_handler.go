@@ -30,7 +30,7 @@ func (ub *UndercastBot) listFeedsHandler(ctx context.Context, b *bot.Bot, update
 		zap.String("feed_id", feedID),
 	}
 
-	feeds, err := ub.service.ListFeeds(ctx, userID)
+	feeds, err := ub.listFeeds(ctx, userID)
 	if err != nil {
 		ub.handleError(ctx, chatID, zaperr.Wrap(err, "failed to list feeds", zapFields...))
 		return
@@ -62,10 +62,20 @@ func (ub *UndercastBot) listFeedsHandler(ctx context.Context, b *bot.Bot, update
 		episodesMap[ep.ID] = ep
 	}
 
+	feedIDs := make([]string, 0, len(feeds))
+	for _, f := range feeds {
+		feedIDs = append(feedIDs, f.ID)
+	}
+	statusCounts, err := ub.service.CountFeedsEpisodesByStatus(ctx, userID, feedIDs)
+	if err != nil {
+		ub.handleError(ctx, chatID, zaperr.Wrap(err, "failed to count feeds episodes", zapFields...))
+		return
+	}
+
 	for _, f := range feeds {
 		var text string
 		if feedID == "" {
-			text = ub.renderFeedShort(f)
+			text = ub.renderFeedShort(f, statusCounts[f.ID])
 		} else {
 			feedEpisodes, err := ub.service.ListFeedEpisodes(ctx, userID, feedID)
 			if err != nil {
@@ -85,18 +95,59 @@ func (ub *UndercastBot) listFeedsHandler(ctx context.Context, b *bot.Bot, update
 
 }
 
-func (ub *UndercastBot) renderFeedShort(f *service.Feed) string {
+// renderFeedShort renders feed in one line; if statusCounts are provided, they are rendered as a badge
+func (ub *UndercastBot) renderFeedShort(f *service.Feed, statusCounts map[service.EpisodeStatus]int) string {
+	badge := ""
+	if len(statusCounts) > 0 {
+		badge = " " + renderFeedStatusBadge(statusCounts)
+	}
 	return fmt.Sprintf(
-		"Feed #<code>%s</code> - <b>%s</b> [info: /f_%s] [edit: /ef_%s]\n<code>%s</code>",
-		f.ID, f.Title, f.ID, f.ID, f.URL,
+		"Feed #<code>%s</code> - <b>%s</b>%s [info: /f_%s] [edit: /ef_%s]\n<code>%s</code>",
+		f.ID, f.Title, badge, f.ID, f.ID, f.URL,
 	)
 }
 
+// renderFeedStatusBadge renders something like "(3 complete / 1 processing / 1 failed)"
+func renderFeedStatusBadge(statusCounts map[service.EpisodeStatus]int) string {
+	var complete, processing, failed, cancelled int
+	for status, count := range statusCounts {
+		switch status {
+		case service.EpisodeStatusComplete:
+			complete += count
+		case service.EpisodeStatusFailed:
+			failed += count
+		case service.EpisodeStatusCancelled:
+			cancelled += count
+		default:
+			processing += count
+		}
+	}
+
+	bits := []string{fmt.Sprintf("%d complete", complete)}
+	if processing > 0 {
+		bits = append(bits, fmt.Sprintf("%d processing", processing))
+	}
+	if failed > 0 {
+		bits = append(bits, fmt.Sprintf("%d failed", failed))
+	}
+	if cancelled > 0 {
+		bits = append(bits, fmt.Sprintf("%d cancelled", cancelled))
+	}
+	return "(" + strings.Join(bits, " / ") + ")"
+}
+
 func (ub *UndercastBot) renderFeedFull(f *service.Feed, episodes []*service.Episode) string {
 	var renderedEpisodesBits []string
+	var totalBytes int64
 	episodeIDs := make([]string, 0, len(episodes))
 	for _, ep := range episodes {
-		renderedEpisodesBits = append(renderedEpisodesBits, ub.renderEpisodeShort(ep))
+		// size is only known once episode is processed
+		size := "<i>size unknown</i>"
+		if ep.FileLenBytes > 0 {
+			size = humanizeBytes(ep.FileLenBytes)
+		}
+		renderedEpisodesBits = append(renderedEpisodesBits, ub.renderEpisodeShort(ep)+" - "+size)
+		totalBytes += ep.FileLenBytes
 		episodeIDs = append(episodeIDs, ep.ID)
 	}
 	renderedEpisodes := strings.Join(renderedEpisodesBits, "\n")
@@ -113,6 +164,7 @@ func (ub *UndercastBot) renderFeedFull(f *service.Feed, episodes []*service.Epis
 		}
 		msgBits = append(msgBits, episodesTitle)
 		msgBits = append(msgBits, renderedEpisodes)
+		msgBits = append(msgBits, "", fmt.Sprintf("<b>Total size:</b> %s", humanizeBytes(totalBytes)))
 	} else {
 		msgBits = append(msgBits, "No episodes yet")
 	}
@@ -0,0 +1,32 @@
+package bot
+
+import (
+	"strings"
+	"testing"
+
+	"tg-podcastotron/service"
+)
+
+func TestRenderFeedFull__Sizes(t *testing.T) {
+	ub := &UndercastBot{}
+	feed := &service.Feed{ID: "1", Title: "some feed", URL: "https://example.com/feeds/1"}
+	episodes := []*service.Episode{
+		{ID: "1", Title: "first", FileLenBytes: 10 * 1024 * 1024},
+		{ID: "2", Title: "second", FileLenBytes: 512 * 1024},
+		{ID: "3", Title: "still processing", Status: service.EpisodeStatusCreated},
+	}
+
+	text := ub.renderFeedFull(feed, episodes)
+
+	for _, expected := range []string{
+		"/ee_1] - 10.0 MB",
+		"/ee_2] - 512.0 KB",
+		"/ee_3] - <i>size unknown</i>",
+		// 10 MB + 0.5 MB
+		"<b>Total size:</b> 10.5 MB",
+	} {
+		if !strings.Contains(text, expected) {
+			t.Errorf("expected rendered feed to contain %q, got:\n%s", expected, text)
+		}
+	}
+}
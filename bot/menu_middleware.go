@@ -27,6 +27,10 @@ func (ub *UndercastBot) setMenuMiddleware(next bot.HandlerFunc) bot.HandlerFunc
 			{Command: "ee", Description: "Edit episode(s)"},
 			{Command: "ef", Description: "Edit feed(s)"},
 			{Command: "nf", Description: "Create new podcast feed"},
+			{Command: "import", Description: "Import existing podcast RSS feed"},
+			{Command: "stats", Description: "Summary of your feeds and episodes"},
+			{Command: "trash", Description: "List deleted episodes to restore them"},
+			{Command: "cancel", Description: "Abandon current prompt or selection"},
 		}
 
 		isAdmin, err := ub.auth.IsAdmin(ctx, username)
@@ -34,6 +38,9 @@ func (ub *UndercastBot) setMenuMiddleware(next bot.HandlerFunc) bot.HandlerFunc
 			commands = append(commands, models.BotCommand{
 				Command:     "adduser",
 				Description: "Invite a friend to use the system",
+			}, models.BotCommand{
+				Command:     "stuck",
+				Description: "List episodes stuck in processing",
 			})
 		}
 
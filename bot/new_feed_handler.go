@@ -2,12 +2,18 @@ package bot
 
 import (
 	"context"
+	"errors"
 	"fmt"
 
 	"github.com/go-telegram/bot"
 	"github.com/go-telegram/bot/models"
 	"github.com/hori-ryota/zaperr"
 	"go.uber.org/zap"
+	"tg-podcastotron/service"
+)
+
+var invalidTitleMessage = fmt.Sprintf(
+	"Title should not be empty or longer than %d characters, please reply to the prompt again", service.MaxTitleLength,
 )
 
 func (ub *UndercastBot) newFeedHandler(ctx context.Context, b *bot.Bot, update *models.Update) {
@@ -25,26 +31,33 @@ func (ub *UndercastBot) newFeedHandler(ctx context.Context, b *bot.Bot, update *
 		ub.handleError(ctx, chatID, zaperr.Wrap(err, "failed to send message", zapFields...))
 		return
 	} else {
-		ub.bot.RegisterHandlerMatchFunc(
+		var handlerID string
+		handlerID = ub.registerChatHandlerMatchFunc(
+			chatID,
 			func(update *models.Update) bool {
-				return update.Message.ReplyToMessage != nil && update.Message.ReplyToMessage.ID == feedNamePromptMsg.ID
+				return update.Message != nil && update.Message.ReplyToMessage != nil && update.Message.ReplyToMessage.ID == feedNamePromptMsg.ID
 			},
 			func(ctx context.Context, b *bot.Bot, update *models.Update) {
 				feedTitle := update.Message.Text
 				userID := ub.extractUserID(update)
 				feed, err := ub.service.CreateFeed(ctx, userID, feedTitle)
+				if errors.Is(err, service.ErrInvalidTitle) {
+					ub.sendTextMessage(ctx, chatID, invalidTitleMessage)
+					return
+				}
 				if err != nil {
 					zapFields := append(zapFields, zap.String("feed_title", feedTitle))
 					ub.handleError(ctx, chatID, zaperr.Wrap(err, "failed to create feed", zapFields...))
 					return
 				}
+				ub.unregisterChatHandler(chatID, handlerID)
 
 				if _, err = ub.bot.DeleteMessage(ctx, &bot.DeleteMessageParams{ChatID: chatID, MessageID: feedNamePromptMsg.ID}); err != nil {
 					zapFields := append(zapFields, zaperr.ToField(err))
 					ub.logger.Error("failed to delete feed name prompt message", zapFields...)
 				}
 
-				statusMsg := fmt.Sprintf("Feed was created:\n\n%s", ub.renderFeedShort(feed))
+				statusMsg := fmt.Sprintf("Feed was created:\n\n%s", ub.renderFeedShort(feed, nil))
 
 				if _, err := ub.bot.SendMessage(ctx, &bot.SendMessageParams{
 					ChatID:    chatID,
@@ -0,0 +1,63 @@
+package bot
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/go-telegram/bot"
+	"github.com/go-telegram/bot/models"
+	"github.com/hori-ryota/zaperr"
+	"go.uber.org/zap"
+)
+
+// rebuildFeedsHandler lets admin upload every feed of a user anew, e.g. after feed files were edited or lost in S3.
+// Without user ID admin's own feeds are rebuilt
+func (ub *UndercastBot) rebuildFeedsHandler(ctx context.Context, _ *bot.Bot, update *models.Update) {
+	chatID := ub.extractChatID(update)
+	userID := ub.extractUserID(update)
+
+	zapFields := []zap.Field{
+		zap.Int64("chat_id", chatID),
+		zap.String("user_id", userID),
+		zap.String("message_text", update.Message.Text),
+	}
+
+	isAdmin, err := ub.auth.IsAdmin(ctx, ub.extractUsername(update))
+	if err != nil {
+		ub.handleError(ctx, chatID, zaperr.Wrap(err, "failed to check if user is admin", zapFields...))
+		return
+	}
+	if !isAdmin {
+		ub.sendTextMessage(ctx, chatID, "unknown command")
+		return
+	}
+
+	targetUserID, err := ub.parseRebuildFeedsCmd(update.Message.Text)
+	if err != nil {
+		ub.sendTextMessage(ctx, chatID, "Please specify user ID, or nothing to rebuild your own feeds, like so:\n/rebuildfeeds 123456\n/rebuildfeeds")
+		return
+	}
+	if targetUserID == "" {
+		targetUserID = userID
+	}
+	zapFields = append(zapFields, zap.String("target_user_id", targetUserID))
+
+	if err := ub.service.RegenerateAllFeeds(ctx, targetUserID); err != nil {
+		ub.handleError(ctx, chatID, zaperr.Wrap(err, "failed to regenerate all feeds", zapFields...))
+		return
+	}
+	ub.logger.Info("admin is rebuilding user feeds", zapFields...)
+
+	ub.sendTextMessage(ctx, chatID, "All feeds of user %s will be rebuilt shortly", targetUserID)
+}
+
+func (ub *UndercastBot) parseRebuildFeedsCmd(text string) (userID string, err error) {
+	re := regexp.MustCompile(`^/rebuildfeeds(?:\s+(\d+))?$`)
+	matches := re.FindStringSubmatch(strings.TrimSpace(text))
+	if len(matches) != 2 {
+		return "", fmt.Errorf("invalid command")
+	}
+	return matches[1], nil
+}
@@ -0,0 +1,69 @@
+package bot
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+
+	"github.com/go-telegram/bot"
+	"github.com/go-telegram/bot/models"
+	"github.com/hori-ryota/zaperr"
+	"go.uber.org/zap"
+	"tg-podcastotron/service"
+)
+
+// regenFeedHandler lets admin regenerate a feed once with incomplete episodes included,
+// to see how they would look in the feed while debugging
+func (ub *UndercastBot) regenFeedHandler(ctx context.Context, _ *bot.Bot, update *models.Update) {
+	chatID := ub.extractChatID(update)
+	userID := ub.extractUserID(update)
+
+	zapFields := []zap.Field{
+		zap.Int64("chat_id", chatID),
+		zap.String("user_id", userID),
+		zap.String("message_text", update.Message.Text),
+	}
+
+	isAdmin, err := ub.auth.IsAdmin(ctx, ub.extractUsername(update))
+	if err != nil {
+		ub.handleError(ctx, chatID, zaperr.Wrap(err, "failed to check if user is admin", zapFields...))
+		return
+	}
+	if !isAdmin {
+		ub.sendTextMessage(ctx, chatID, "unknown command")
+		return
+	}
+
+	feedID, err := ub.parseRegenFeedCmd(update.Message.Text)
+	if err != nil {
+		ub.sendTextMessage(ctx, chatID, "Please specify feed ID, like so:\n/regenfeed_1")
+		return
+	}
+	zapFields = append(zapFields, zap.String("feed_id", feedID))
+
+	feed, err := ub.service.GetFeed(ctx, userID, feedID)
+	if err != nil {
+		ub.handleError(ctx, chatID, zaperr.Wrap(err, "failed to get feed", zapFields...))
+		return
+	}
+	if feed == nil {
+		ub.sendTextMessage(ctx, chatID, "Feed #%s not found", feedID)
+		return
+	}
+
+	if err := ub.service.RegenerateFeed(ctx, userID, feedID, service.WithIncompleteEpisodes()); err != nil {
+		ub.handleError(ctx, chatID, zaperr.Wrap(err, "failed to regenerate feed", zapFields...))
+		return
+	}
+
+	ub.sendTextMessage(ctx, chatID, "Feed #%s will be regenerated with incomplete episodes included. Any further change to the feed will exclude them again", feedID)
+}
+
+func (ub *UndercastBot) parseRegenFeedCmd(text string) (string, error) {
+	re := regexp.MustCompile(`/regenfeed_(\d+)`)
+	matches := re.FindStringSubmatch(text)
+	if len(matches) != 2 {
+		return "", fmt.Errorf("invalid command")
+	}
+	return matches[1], nil
+}
@@ -0,0 +1,47 @@
+package bot
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"github.com/go-telegram/bot"
+	"github.com/go-telegram/bot/models"
+	"github.com/hori-ryota/zaperr"
+	"go.uber.org/zap"
+	"tg-podcastotron/service"
+)
+
+const reorderFeedsHelp = `Please specify feed IDs in the order they should be listed in, like so:
+/reorderfeeds 3 1
+
+Feeds you don't mention keep their order after the ones you do`
+
+// reorderFeedsHandler puts given feeds first in /f listing
+func (ub *UndercastBot) reorderFeedsHandler(ctx context.Context, _ *bot.Bot, update *models.Update) {
+	chatID := ub.extractChatID(update)
+	userID := ub.extractUserID(update)
+
+	zapFields := []zap.Field{
+		zap.Int64("chat_id", chatID),
+		zap.String("user_id", userID),
+		zap.String("message_text", update.Message.Text),
+	}
+
+	feedIDs := strings.Fields(strings.TrimPrefix(update.Message.Text, "/reorderfeeds"))
+	if len(feedIDs) == 0 {
+		ub.sendTextMessage(ctx, chatID, reorderFeedsHelp)
+		return
+	}
+
+	if err := ub.service.ReorderFeeds(ctx, userID, feedIDs); err != nil {
+		if errors.Is(err, service.ErrFeedNotFound) {
+			ub.sendTextMessage(ctx, chatID, "Some of the feeds were not found, please check their IDs with /f")
+			return
+		}
+		ub.handleError(ctx, chatID, zaperr.Wrap(err, "failed to reorder feeds", zapFields...))
+		return
+	}
+
+	ub.sendTextMessage(ctx, chatID, "Feeds were reordered, see /f")
+}
@@ -0,0 +1,78 @@
+package bot
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strconv"
+	"time"
+
+	"github.com/go-telegram/bot"
+	"github.com/go-telegram/bot/models"
+	"github.com/hori-ryota/zaperr"
+	"go.uber.org/zap"
+)
+
+// defaultEpisodesRetention is how long episodes of users who did not set their own retention are kept
+const defaultEpisodesRetention = 30 * 24 * time.Hour
+
+// retentionHandler shows how many days episodes are kept with `/retention`
+// and changes it with `/retention <days>`, zero brings back default
+func (ub *UndercastBot) retentionHandler(ctx context.Context, _ *bot.Bot, update *models.Update) {
+	chatID := ub.extractChatID(update)
+	userID := ub.extractUserID(update)
+
+	zapFields := []zap.Field{
+		zap.Int64("chat_id", chatID),
+		zap.String("user_id", userID),
+		zap.String("message_text", update.Message.Text),
+	}
+
+	defaultDays := int(defaultEpisodesRetention / (24 * time.Hour))
+	days, ok := ub.parseRetentionCmd(update.Message.Text)
+	if !ok {
+		ub.sendTextMessage(ctx, chatID, "Please use /retention 14 to keep episodes for 14 days, or /retention 0 to go back to %d days", defaultDays)
+		return
+	}
+
+	if days == nil {
+		current, err := ub.service.Retention(ctx, userID)
+		if err != nil {
+			ub.handleError(ctx, chatID, zaperr.Wrap(err, "failed to get retention", zapFields...))
+			return
+		}
+		ub.sendTextMessage(ctx, chatID, "%s\nUse /retention 14 to keep them for 14 days instead", renderRetention(current, defaultDays))
+		return
+	}
+
+	zapFields = append(zapFields, zap.Int("days", *days))
+	if err := ub.service.SetRetention(ctx, userID, *days); err != nil {
+		ub.handleError(ctx, chatID, zaperr.Wrap(err, "failed to set retention", zapFields...))
+		return
+	}
+	ub.sendTextMessage(ctx, chatID, renderRetention(*days, defaultDays))
+}
+
+// parseRetentionCmd returns nil days when command has no argument
+func (ub *UndercastBot) parseRetentionCmd(text string) (*int, bool) {
+	re := regexp.MustCompile(`^/retention(?:\s+(\d+))?\s*$`)
+	matches := re.FindStringSubmatch(text)
+	if len(matches) != 2 {
+		return nil, false
+	}
+	if matches[1] == "" {
+		return nil, true
+	}
+	days, err := strconv.Atoi(matches[1])
+	if err != nil {
+		return nil, false
+	}
+	return &days, true
+}
+
+func renderRetention(days int, defaultDays int) string {
+	if days == 0 {
+		days = defaultDays
+	}
+	return fmt.Sprintf("Episodes are deleted %d days after their last update, unless they are pinned or in a permanent feed", days)
+}
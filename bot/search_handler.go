@@ -0,0 +1,64 @@
+package bot
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/go-telegram/bot"
+	"github.com/go-telegram/bot/models"
+	"github.com/hori-ryota/zaperr"
+	"go.uber.org/zap"
+)
+
+// maxSearchResults keeps search results within a single Telegram message
+const maxSearchResults = 30
+
+const searchHelpMessage = "Please specify what to look for in episode titles and links, like so:\n/search some podcast"
+
+func (ub *UndercastBot) searchHandler(ctx context.Context, b *bot.Bot, update *models.Update) {
+	chatID := ub.extractChatID(update)
+	userID := ub.extractUserID(update)
+
+	zapFields := []zap.Field{
+		zap.Int64("chat_id", chatID),
+		zap.String("user_id", userID),
+		zap.String("message_text", update.Message.Text),
+	}
+
+	query := strings.TrimSpace(strings.TrimPrefix(update.Message.Text, "/search"))
+	if query == "" {
+		ub.sendTextMessage(ctx, chatID, searchHelpMessage)
+		return
+	}
+
+	episodes, err := ub.service.SearchEpisodes(ctx, userID, query)
+	if err != nil {
+		ub.handleError(ctx, chatID, zaperr.Wrap(err, "failed to search episodes", zapFields...))
+		return
+	}
+
+	if len(episodes) == 0 {
+		ub.sendTextMessage(ctx, chatID, "No episodes found for %q", query)
+		return
+	}
+
+	lines := make([]string, 0, maxSearchResults+2)
+	if len(episodes) > maxSearchResults {
+		lines = append(lines, fmt.Sprintf("Found %d episodes, showing first %d:", len(episodes), maxSearchResults), "")
+		episodes = episodes[:maxSearchResults]
+	} else {
+		lines = append(lines, fmt.Sprintf("Found %d episodes:", len(episodes)), "")
+	}
+	for _, ep := range episodes {
+		lines = append(lines, ub.renderEpisodeShort(ep))
+	}
+
+	if _, err := b.SendMessage(ctx, &bot.SendMessageParams{
+		ChatID:    chatID,
+		Text:      strings.Join(lines, "\n"),
+		ParseMode: models.ParseModeHTML,
+	}); err != nil {
+		ub.handleError(ctx, chatID, zaperr.Wrap(err, "failed to send message", zapFields...))
+	}
+}
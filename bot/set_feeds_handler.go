@@ -0,0 +1,117 @@
+package bot
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"html"
+	"regexp"
+	"strings"
+
+	"github.com/go-telegram/bot"
+	"github.com/go-telegram/bot/models"
+	"github.com/hori-ryota/zaperr"
+	"go.uber.org/zap"
+	"tg-podcastotron/service"
+)
+
+const setFeedsHelp = `Please specify episodes and feeds they should be published to, like so:
+/setfeeds_ep_1_to_3_feeds_2_5 - publish episodes 1 to 3 to feeds 2 and 5, and remove them from all other feeds`
+
+// setFeedsHandler is a text alternative to "Manage Feeds" of /ee for those who know episode and feed IDs by heart
+func (ub *UndercastBot) setFeedsHandler(ctx context.Context, _ *bot.Bot, update *models.Update) {
+	chatID := ub.extractChatID(update)
+	userID := ub.extractUserID(update)
+
+	zapFields := []zap.Field{
+		zap.Int64("chat_id", chatID),
+		zap.String("user_id", userID),
+		zap.String("message_text", update.Message.Text),
+	}
+
+	epIDs, feedIDs, err := ub.parseSetFeedsCmd(update.Message.Text)
+	if err != nil {
+		ub.sendTextMessage(ctx, chatID, setFeedsHelp)
+		return
+	}
+	zapFields = append(zapFields, zap.Strings("episode_ids", epIDs), zap.Strings("feed_ids", feedIDs))
+
+	if _, err := ub.service.GetEpisodesMap(ctx, userID, epIDs); err != nil {
+		var notFoundErr *service.EpisodesNotFoundError
+		if errors.As(err, &notFoundErr) {
+			ub.sendTextMessage(ctx, chatID, "Episodes %s do not exist. Please try again with different IDs", strings.Join(notFoundErr.IDs, ", "))
+			return
+		}
+		ub.sendTextMessage(ctx, chatID, "At least one of the episodes does not exist. Please try again with different IDs")
+		return
+	}
+
+	feeds, err := ub.service.ListFeeds(ctx, userID)
+	if err != nil {
+		ub.handleError(ctx, chatID, zaperr.Wrap(err, "failed to list feeds", zapFields...))
+		return
+	}
+	feedMap := make(map[string]*service.Feed, len(feeds))
+	for _, feed := range feeds {
+		feedMap[feed.ID] = feed
+	}
+	var missingFeedIDs []string
+	for _, feedID := range feedIDs {
+		if _, ok := feedMap[feedID]; !ok {
+			missingFeedIDs = append(missingFeedIDs, feedID)
+		}
+	}
+	if len(missingFeedIDs) > 0 {
+		ub.sendTextMessage(ctx, chatID, "Feeds %s do not exist. Please try again with different IDs", strings.Join(missingFeedIDs, ", "))
+		return
+	}
+
+	if err := ub.service.PublishEpisodes(ctx, userID, epIDs, feedIDs); err != nil {
+		ub.handleError(ctx, chatID, zaperr.Wrap(err, "failed to set episodes feeds", zapFields...))
+		return
+	}
+
+	if _, err := ub.bot.SendMessage(ctx, &bot.SendMessageParams{
+		ChatID:    chatID,
+		Text:      formatSetFeedsStatusMessage(epIDs, feedIDs, feedMap),
+		ParseMode: models.ParseModeHTML,
+	}); err != nil {
+		ub.handleError(ctx, chatID, zaperr.Wrap(err, "failed to send message", zapFields...))
+	}
+}
+
+func formatSetFeedsStatusMessage(epIDs []string, feedIDs []string, feedMap map[string]*service.Feed) string {
+	var subject string
+	if len(epIDs) == 1 {
+		subject = fmt.Sprintf("Episode %s is", epIDs[0])
+	} else {
+		subject = fmt.Sprintf("%d episodes (%s) are", len(epIDs), strings.Join(epIDs, ", "))
+	}
+
+	lines := []string{subject + " now published to:"}
+	for _, feedID := range feedIDs {
+		lines = append(lines, fmt.Sprintf("Feed #<code>%s</code> - <b>%s</b>", feedID, html.EscapeString(feedMap[feedID].Title)))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// compactIDsPattern matches IDs formatted by formatIDsCompactly, so that parseIDs never sees a dangling "to"
+const compactIDsPattern = `\d+(?:_(?:to_)?\d+)*`
+
+// parseSetFeedsCmd parses /setfeeds_ep_<episode IDs>_feeds_<feed IDs>, both sides are compactly formatted IDs.
+// Feeds are not separated from episodes by "_to_" as it would be ambiguous with ranges
+func (ub *UndercastBot) parseSetFeedsCmd(text string) (epIDs []string, feedIDs []string, err error) {
+	re := regexp.MustCompile(`^/setfeeds_ep_(` + compactIDsPattern + `)_feeds_(` + compactIDsPattern + `)$`)
+	matches := re.FindStringSubmatch(strings.TrimSpace(text))
+	if len(matches) != 3 {
+		return nil, nil, fmt.Errorf("invalid command")
+	}
+
+	if epIDs, err = parseIDs(matches[1]); err != nil {
+		return nil, nil, fmt.Errorf("invalid episode IDs: %w", err)
+	}
+	if feedIDs, err = parseIDs(matches[2]); err != nil {
+		return nil, nil, fmt.Errorf("invalid feed IDs: %w", err)
+	}
+	return epIDs, feedIDs, nil
+}
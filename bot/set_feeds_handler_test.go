@@ -0,0 +1,56 @@
+package bot
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"tg-podcastotron/service"
+)
+
+func TestParseSetFeedsCmd(t *testing.T) {
+	ub := &UndercastBot{}
+	for _, tc := range []struct {
+		text            string
+		expectedEpIDs   []string
+		expectedFeedIDs []string
+		expectedErr     bool
+	}{
+		{text: "/setfeeds_ep_1_feeds_2", expectedEpIDs: []string{"1"}, expectedFeedIDs: []string{"2"}},
+		{text: "/setfeeds_ep_1_to_3_feeds_2_5", expectedEpIDs: []string{"1", "2", "3"}, expectedFeedIDs: []string{"2", "5"}},
+		{text: "/setfeeds_ep_1_7_to_9_feeds_2_to_4_10", expectedEpIDs: []string{"1", "7", "8", "9"}, expectedFeedIDs: []string{"2", "3", "4", "10"}},
+		{text: "/setfeeds_ep_1_to_3", expectedErr: true},
+		{text: "/setfeeds_ep_1_to_feeds_2", expectedErr: true},
+		{text: "/setfeeds_ep__feeds_2", expectedErr: true},
+		{text: "/setfeeds", expectedErr: true},
+	} {
+		t.Run(tc.text, func(t *testing.T) {
+			epIDs, feedIDs, err := ub.parseSetFeedsCmd(tc.text)
+			if tc.expectedErr {
+				if err == nil {
+					t.Fatalf("expected error, got episodes %v and feeds %v", epIDs, feedIDs)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(epIDs, tc.expectedEpIDs) {
+				t.Errorf("expected episodes %v, got %v", tc.expectedEpIDs, epIDs)
+			}
+			if !reflect.DeepEqual(feedIDs, tc.expectedFeedIDs) {
+				t.Errorf("expected feeds %v, got %v", tc.expectedFeedIDs, feedIDs)
+			}
+		})
+	}
+}
+
+func TestFormatSetFeedsStatusMessage__EscapesTitles(t *testing.T) {
+	text := formatSetFeedsStatusMessage([]string{"1"}, []string{"2"}, map[string]*service.Feed{
+		"2": {ID: "2", Title: "Q&A <live>"},
+	})
+
+	if !strings.Contains(text, "<b>Q&amp;A &lt;live&gt;</b>") {
+		t.Errorf("expected feed title to be escaped for HTML, got:\n%s", text)
+	}
+}
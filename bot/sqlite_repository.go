@@ -11,15 +11,20 @@ func NewSqliteRepository(db *sql.DB) Repository {
 	return &sqliteRepository{db: sqlx.NewDb(db, "sqlite3")}
 }
 
+// NewPostgresRepository shares queries with sqlite, postgres schema has the same tables and placeholders are rebound
+func NewPostgresRepository(db *sql.DB) Repository {
+	return &sqliteRepository{db: sqlx.NewDb(db, "postgres")}
+}
+
 type sqliteRepository struct {
 	db *sqlx.DB
 }
 
 func (s *sqliteRepository) SetChatID(ctx context.Context, userID string, chatID int64) error {
-	result := s.db.MustExecContext(ctx, `
+	result := s.db.MustExecContext(ctx, s.db.Rebind(`
 		INSERT INTO chats (user_id, chat_id) VALUES (?, ?) 
 		ON CONFLICT(user_id) DO UPDATE SET chat_id = ?
-		`, userID, chatID, chatID,
+		`), userID, chatID, chatID,
 	)
 	if _, err := result.RowsAffected(); err != nil {
 		return zaperr.Wrap(err, "failed to insert chat")
@@ -29,7 +34,7 @@ func (s *sqliteRepository) SetChatID(ctx context.Context, userID string, chatID
 
 func (s *sqliteRepository) GetChatID(ctx context.Context, userID string) (int64, error) {
 	var chatID int64
-	if err := s.db.GetContext(ctx, &chatID, "SELECT chat_id FROM chats WHERE user_id = ?", userID); err != nil {
+	if err := s.db.GetContext(ctx, &chatID, s.db.Rebind("SELECT chat_id FROM chats WHERE user_id = ?"), userID); err != nil {
 		if err == sql.ErrNoRows {
 			return -1, nil
 		}
@@ -37,3 +42,64 @@ func (s *sqliteRepository) GetChatID(ctx context.Context, userID string) (int64,
 	}
 	return chatID, nil
 }
+
+func (s *sqliteRepository) SetAnnouncementTemplate(ctx context.Context, userID string, template string) error {
+	if _, err := s.db.ExecContext(ctx, s.db.Rebind(`
+		INSERT INTO announcement_templates (user_id, template) VALUES (?, ?)
+		ON CONFLICT(user_id) DO UPDATE SET template = ?
+		`), userID, template, template,
+	); err != nil {
+		return zaperr.Wrap(err, "failed to save announcement template")
+	}
+	return nil
+}
+
+// GetAnnouncementTemplate returns user's announcement template or empty string if it was never set
+func (s *sqliteRepository) GetAnnouncementTemplate(ctx context.Context, userID string) (string, error) {
+	var template string
+	if err := s.db.GetContext(ctx, &template, s.db.Rebind("SELECT template FROM announcement_templates WHERE user_id = ?"), userID); err != nil {
+		if err == sql.ErrNoRows {
+			return "", nil
+		}
+		return "", zaperr.Wrap(err, "failed to select announcement template")
+	}
+	return template, nil
+}
+
+func (s *sqliteRepository) DeleteAnnouncementTemplate(ctx context.Context, userID string) error {
+	if _, err := s.db.ExecContext(ctx, s.db.Rebind("DELETE FROM announcement_templates WHERE user_id = ?"), userID); err != nil {
+		return zaperr.Wrap(err, "failed to delete announcement template")
+	}
+	return nil
+}
+
+// SetStatusMessageID remembers message telling about episode status, so that it's edited instead of sending new ones
+func (s *sqliteRepository) SetStatusMessageID(ctx context.Context, userID string, epID string, messageID int) error {
+	if _, err := s.db.ExecContext(ctx, s.db.Rebind(`
+		INSERT INTO status_messages (user_id, episode_id, message_id) VALUES (?, ?, ?)
+		ON CONFLICT(user_id, episode_id) DO UPDATE SET message_id = ?
+		`), userID, epID, messageID, messageID,
+	); err != nil {
+		return zaperr.Wrap(err, "failed to save status message id")
+	}
+	return nil
+}
+
+// GetStatusMessageID returns ID of episode status message or 0 if there is none
+func (s *sqliteRepository) GetStatusMessageID(ctx context.Context, userID string, epID string) (int, error) {
+	var messageID int
+	if err := s.db.GetContext(ctx, &messageID, s.db.Rebind("SELECT message_id FROM status_messages WHERE user_id = ? AND episode_id = ?"), userID, epID); err != nil {
+		if err == sql.ErrNoRows {
+			return 0, nil
+		}
+		return 0, zaperr.Wrap(err, "failed to select status message id")
+	}
+	return messageID, nil
+}
+
+func (s *sqliteRepository) DeleteStatusMessageID(ctx context.Context, userID string, epID string) error {
+	if _, err := s.db.ExecContext(ctx, s.db.Rebind("DELETE FROM status_messages WHERE user_id = ? AND episode_id = ?"), userID, epID); err != nil {
+		return zaperr.Wrap(err, "failed to delete status message id")
+	}
+	return nil
+}
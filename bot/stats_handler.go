@@ -0,0 +1,116 @@
+package bot
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/go-telegram/bot"
+	"github.com/go-telegram/bot/models"
+	"github.com/hori-ryota/zaperr"
+	"go.uber.org/zap"
+	"tg-podcastotron/service"
+)
+
+// statsStatuses is the order episode counts are listed in
+var statsStatuses = []service.EpisodeStatus{
+	service.EpisodeStatusComplete,
+	service.EpisodeStatusCreated,
+	service.EpisodeStatusPending,
+	service.EpisodeStatusDownloading,
+	service.EpisodeStatusProcessing,
+	service.EpisodeStatusUploading,
+	service.EpisodeStatusFailed,
+	service.EpisodeStatusCancelled,
+}
+
+// statsHandler shows summary of user's library. Admin can look at someone else's with `/stats <user_id>`
+func (ub *UndercastBot) statsHandler(ctx context.Context, _ *bot.Bot, update *models.Update) {
+	chatID := ub.extractChatID(update)
+	userID := ub.extractUserID(update)
+
+	zapFields := []zap.Field{
+		zap.Int64("chat_id", chatID),
+		zap.String("user_id", userID),
+		zap.String("message_text", update.Message.Text),
+	}
+
+	targetUserID, err := ub.parseStatsCmd(update.Message.Text)
+	if err != nil {
+		ub.sendTextMessage(ctx, chatID, "Please use /stats to see your library summary")
+		return
+	}
+	if targetUserID == "" {
+		targetUserID = userID
+	}
+	if targetUserID != userID {
+		isAdmin, err := ub.auth.IsAdmin(ctx, ub.extractUsername(update))
+		if err != nil {
+			ub.handleError(ctx, chatID, zaperr.Wrap(err, "failed to check if user is admin", zapFields...))
+			return
+		}
+		if !isAdmin {
+			ub.sendTextMessage(ctx, chatID, "Please use /stats to see your library summary")
+			return
+		}
+	}
+	zapFields = append(zapFields, zap.String("target_user_id", targetUserID))
+
+	stats, err := ub.service.UserStats(ctx, targetUserID)
+	if err != nil {
+		ub.handleError(ctx, chatID, zaperr.Wrap(err, "failed to get user stats", zapFields...))
+		return
+	}
+
+	title := "Your library"
+	if targetUserID != userID {
+		title = fmt.Sprintf("Library of user %s", targetUserID)
+	}
+	if _, err := ub.bot.SendMessage(ctx, &bot.SendMessageParams{
+		ChatID:    chatID,
+		Text:      renderUserStats(title, stats),
+		ParseMode: models.ParseModeHTML,
+	}); err != nil {
+		ub.handleError(ctx, chatID, zaperr.Wrap(err, "failed to send message", zapFields...))
+	}
+}
+
+func (ub *UndercastBot) parseStatsCmd(text string) (string, error) {
+	re := regexp.MustCompile(`^/stats(?:\s+(\d+))?\s*$`)
+	matches := re.FindStringSubmatch(text)
+	if len(matches) != 2 {
+		return "", fmt.Errorf("invalid command")
+	}
+	return matches[1], nil
+}
+
+func renderUserStats(title string, stats *service.UserStats) string {
+	lines := []string{
+		fmt.Sprintf("<b>%s</b>", title),
+		fmt.Sprintf("Feeds: %d", stats.FeedsCount),
+	}
+
+	total := 0
+	var statusBits []string
+	for _, status := range statsStatuses {
+		if count := stats.EpisodesByStatus[status]; count > 0 {
+			total += count
+			statusBits = append(statusBits, fmt.Sprintf("%d %s", count, status))
+		}
+	}
+	episodesLine := fmt.Sprintf("Episodes: %d", total)
+	if len(statusBits) > 0 {
+		episodesLine += " (" + strings.Join(statusBits, " / ") + ")"
+	}
+	lines = append(lines, episodesLine, fmt.Sprintf("Storage: %s", humanizeBytes(stats.TotalBytes)))
+
+	if !stats.OldestEpisodeAt.IsZero() {
+		lines = append(lines,
+			fmt.Sprintf("Oldest episode: %s", stats.OldestEpisodeAt.Format("2006-01-02")),
+			fmt.Sprintf("Newest episode: %s", stats.NewestEpisodeAt.Format("2006-01-02")),
+		)
+	}
+
+	return strings.Join(lines, "\n")
+}
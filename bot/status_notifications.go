@@ -0,0 +1,142 @@
+package bot
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"tg-podcastotron/service"
+)
+
+// statusNotificationsDebounce is how long status changes are collected before notifying the user,
+// so that rapid successive polls end up in a single message
+const statusNotificationsDebounce = 3 * time.Second
+
+// statusSummaryOrder defines the order of statuses in aggregated status messages,
+// final statuses go first as they are the most interesting ones
+var statusSummaryOrder = []service.EpisodeStatus{
+	service.EpisodeStatusComplete,
+	service.EpisodeStatusFailed,
+	service.EpisodeStatusCancelled,
+	service.EpisodeStatusUploading,
+	service.EpisodeStatusProcessing,
+	service.EpisodeStatusDownloading,
+	service.EpisodeStatusPending,
+}
+
+// statusChangesBuffer collects pending status changes per user until they are flushed
+type statusChangesBuffer struct {
+	mu      sync.Mutex
+	pending map[string][]service.EpisodeStatusChange
+}
+
+func newStatusChangesBuffer() *statusChangesBuffer {
+	return &statusChangesBuffer{
+		pending: make(map[string][]service.EpisodeStatusChange),
+	}
+}
+
+// Add buffers changes for the user, replacing earlier changes of the same episodes.
+// Replaced change's old status is kept, so that a status change followed by progress is not mistaken for progress only.
+// Returns true if the user had no pending changes, i.e. the caller should schedule a flush
+func (b *statusChangesBuffer) Add(userID string, changes []service.EpisodeStatusChange) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	pending, exists := b.pending[userID]
+	for _, change := range changes {
+		replaced := false
+		for i := range pending {
+			if pending[i].Episode.ID == change.Episode.ID {
+				change.OldStatus = pending[i].OldStatus
+				pending[i] = change
+				replaced = true
+				break
+			}
+		}
+		if !replaced {
+			pending = append(pending, change)
+		}
+	}
+	b.pending[userID] = pending
+
+	return !exists
+}
+
+// Take returns and forgets pending changes of the user
+func (b *statusChangesBuffer) Take(userID string) []service.EpisodeStatusChange {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	changes := b.pending[userID]
+	delete(b.pending, userID)
+
+	return changes
+}
+
+// queueStatusChanges buffers changes and notifies the user about all of them at once after a short delay
+func (ub *UndercastBot) queueStatusChanges(ctx context.Context, userID string, chatID int64, changes []service.EpisodeStatusChange) {
+	if !ub.statusChanges.Add(userID, changes) {
+		return
+	}
+	time.AfterFunc(statusNotificationsDebounce, func() {
+		if ctx.Err() != nil {
+			return
+		}
+		ub.notifyStatusChanged(ctx, userID, chatID, ub.statusChanges.Take(userID))
+	})
+}
+
+// formatStatusChangesSummary renders several status changes as a single message, e.g.
+// "3 episodes now complete, 2 downloading" followed by episode links grouped by status
+func formatStatusChangesSummary(changes []service.EpisodeStatusChange) string {
+	statusToChanges := make(map[service.EpisodeStatus][]service.EpisodeStatusChange)
+	statuses := append([]service.EpisodeStatus{}, statusSummaryOrder...)
+	for _, change := range changes {
+		if !slices.Contains(statuses, change.NewStatus) {
+			statuses = append(statuses, change.NewStatus)
+		}
+		statusToChanges[change.NewStatus] = append(statusToChanges[change.NewStatus], change)
+	}
+
+	var counts, details []string
+	for _, status := range statuses {
+		statusChanges := statusToChanges[status]
+		if len(statusChanges) == 0 {
+			continue
+		}
+
+		if len(counts) == 0 {
+			noun := "episodes"
+			if len(statusChanges) == 1 {
+				noun = "episode"
+			}
+			counts = append(counts, fmt.Sprintf("%d %s now %s", len(statusChanges), noun, status))
+		} else {
+			counts = append(counts, fmt.Sprintf("%d %s", len(statusChanges), status))
+		}
+
+		lines := make([]string, 0, len(statusChanges))
+		for _, change := range statusChanges {
+			line := fmt.Sprintf("/ep_%s %s", change.Episode.ID, change.Episode.Title)
+			if change.Err != nil {
+				line += fmt.Sprintf(": %s", change.Err)
+			} else if change.Progress > 0 {
+				line += fmt.Sprintf(": %d%%", int(math.Round(change.Progress*100)))
+			}
+			lines = append(lines, line)
+		}
+		details = append(details, fmt.Sprintf("%s%s:\n%s", strings.ToUpper(string(status[:1])), status[1:], strings.Join(lines, "\n")))
+	}
+
+	text := strings.Join(counts, ", ") + "\n\n" + strings.Join(details, "\n\n")
+	if len(statusToChanges[service.EpisodeStatusFailed]) > 0 {
+		text += "\n\nPlease try creating failed episodes again"
+	}
+
+	return text
+}
@@ -0,0 +1,107 @@
+package bot
+
+import (
+	"errors"
+	"testing"
+
+	"tg-podcastotron/service"
+)
+
+func TestFormatStatusChangesSummary(t *testing.T) {
+	change := func(id string, status service.EpisodeStatus, progress float64, err error) service.EpisodeStatusChange {
+		return service.EpisodeStatusChange{
+			Episode:   &service.Episode{ID: id, Title: "Ep " + id},
+			NewStatus: status,
+			Progress:  progress,
+			Err:       err,
+		}
+	}
+
+	for _, tc := range []struct {
+		name     string
+		changes  []service.EpisodeStatusChange
+		expected string
+	}{
+		{
+			name: "mixed",
+			changes: []service.EpisodeStatusChange{
+				change("4", service.EpisodeStatusDownloading, 0.45, nil),
+				change("1", service.EpisodeStatusComplete, 0, nil),
+				change("2", service.EpisodeStatusComplete, 0, nil),
+				change("6", service.EpisodeStatusFailed, 0, errors.New("no seeders")),
+				change("3", service.EpisodeStatusComplete, 0, nil),
+				change("5", service.EpisodeStatusDownloading, 0, nil),
+			},
+			expected: "3 episodes now complete, 1 failed, 2 downloading\n\n" +
+				"Complete:\n/ep_1 Ep 1\n/ep_2 Ep 2\n/ep_3 Ep 3\n\n" +
+				"Failed:\n/ep_6 Ep 6: no seeders\n\n" +
+				"Downloading:\n/ep_4 Ep 4: 45%\n/ep_5 Ep 5\n\n" +
+				"Please try creating failed episodes again",
+		},
+		{
+			name: "single status",
+			changes: []service.EpisodeStatusChange{
+				change("1", service.EpisodeStatusUploading, 0.5, nil),
+				change("2", service.EpisodeStatusUploading, 0, nil),
+			},
+			expected: "2 episodes now uploading\n\nUploading:\n/ep_1 Ep 1: 50%\n/ep_2 Ep 2",
+		},
+		{
+			name: "one episode first",
+			changes: []service.EpisodeStatusChange{
+				change("2", service.EpisodeStatusPending, 0, nil),
+				change("1", service.EpisodeStatusComplete, 0, nil),
+			},
+			expected: "1 episode now complete, 1 pending\n\nComplete:\n/ep_1 Ep 1\n\nPending:\n/ep_2 Ep 2",
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			if actual := formatStatusChangesSummary(tc.changes); actual != tc.expected {
+				t.Errorf("formatStatusChangesSummary() =\n%q\nwant\n%q", actual, tc.expected)
+			}
+		})
+	}
+}
+
+func TestStatusChangesBuffer(t *testing.T) {
+	buf := newStatusChangesBuffer()
+	ep1 := &service.Episode{ID: "1"}
+	ep2 := &service.Episode{ID: "2"}
+
+	if !buf.Add("user", []service.EpisodeStatusChange{{Episode: ep1, NewStatus: service.EpisodeStatusDownloading}}) {
+		t.Error("expected first Add to request a flush")
+	}
+	if buf.Add("user", []service.EpisodeStatusChange{
+		{Episode: ep1, NewStatus: service.EpisodeStatusComplete},
+		{Episode: ep2, NewStatus: service.EpisodeStatusPending},
+	}) {
+		t.Error("expected subsequent Add not to request a flush")
+	}
+
+	changes := buf.Take("user")
+	if len(changes) != 2 || changes[0].NewStatus != service.EpisodeStatusComplete || changes[1].Episode.ID != "2" {
+		t.Errorf("unexpected pending changes: %+v", changes)
+	}
+	if changes := buf.Take("user"); len(changes) != 0 {
+		t.Errorf("expected no pending changes after Take, got %+v", changes)
+	}
+}
+
+func TestStatusChangesBuffer__KeepsOldStatus(t *testing.T) {
+	buf := newStatusChangesBuffer()
+	ep := &service.Episode{ID: "1"}
+
+	buf.Add("user", []service.EpisodeStatusChange{{Episode: ep, OldStatus: service.EpisodeStatusPending, NewStatus: service.EpisodeStatusDownloading}})
+	buf.Add("user", []service.EpisodeStatusChange{{Episode: ep, OldStatus: service.EpisodeStatusDownloading, NewStatus: service.EpisodeStatusDownloading, Progress: 0.5}})
+
+	changes := buf.Take("user")
+	if len(changes) != 1 || changes[0].OldStatus != service.EpisodeStatusPending || changes[0].Progress != 0.5 {
+		t.Fatalf("expected status change to be kept along with progress, got %+v", changes)
+	}
+	if isProgressOnly(changes[0]) {
+		t.Errorf("expected merged change not to be progress only")
+	}
+	if !isProgressOnly(service.EpisodeStatusChange{Episode: ep, OldStatus: service.EpisodeStatusDownloading, NewStatus: service.EpisodeStatusDownloading, Progress: 0.6}) {
+		t.Errorf("expected change of progress only to be progress only")
+	}
+}
@@ -0,0 +1,140 @@
+package bot
+
+import (
+	"context"
+	"fmt"
+	"html"
+	"strings"
+	"time"
+
+	"github.com/go-telegram/bot"
+	"github.com/go-telegram/bot/models"
+	"github.com/hori-ryota/zaperr"
+	"go.uber.org/zap"
+	"tg-podcastotron/service"
+)
+
+const stuckEpisodesAge = 24 * time.Hour
+
+var stuckEpisodesStatuses = []service.EpisodeStatus{
+	service.EpisodeStatusCreated,
+	service.EpisodeStatusPending,
+	service.EpisodeStatusDownloading,
+	service.EpisodeStatusProcessing,
+	service.EpisodeStatusUploading,
+}
+
+func (ub *UndercastBot) stuckEpisodesHandler(ctx context.Context, _ *bot.Bot, update *models.Update) {
+	chatID := ub.extractChatID(update)
+
+	zapFields := []zap.Field{
+		zap.Int64("chat_id", chatID),
+		zap.String("user_id", ub.extractUserID(update)),
+		zap.String("message_text", update.Message.Text),
+	}
+
+	isAdmin, err := ub.auth.IsAdmin(ctx, ub.extractUsername(update))
+	if err != nil {
+		ub.handleError(ctx, chatID, zaperr.Wrap(err, "failed to check if user is admin", zapFields...))
+		return
+	}
+	if !isAdmin {
+		ub.sendTextMessage(ctx, chatID, "unknown command")
+		return
+	}
+
+	episodes, err := ub.service.ListStuckEpisodes(ctx, stuckEpisodesAge, stuckEpisodesStatuses)
+	if err != nil {
+		ub.handleError(ctx, chatID, zaperr.Wrap(err, "failed to list stuck episodes", zapFields...))
+		return
+	}
+
+	if len(episodes) == 0 {
+		ub.sendTextMessage(ctx, chatID, "There are no stuck episodes")
+		return
+	}
+
+	prefix := fmt.Sprintf("stuckEpisodes_%s", bot.RandomString(10))
+	cmdRetry := "retry"
+	cmdDelete := "delete"
+
+	kb := [][]models.InlineKeyboardButton{
+		{{
+			Text:         fmt.Sprintf("Retry All (%d)", len(episodes)),
+			CallbackData: prefix + cmdRetry,
+		}},
+		{{
+			Text:         fmt.Sprintf("Delete All (%d)", len(episodes)),
+			CallbackData: prefix + cmdDelete,
+		}},
+	}
+
+	initialMsg, err := ub.bot.SendMessage(ctx, &bot.SendMessageParams{
+		ChatID:      chatID,
+		Text:        formatStuckEpisodesMessage(episodes),
+		ParseMode:   models.ParseModeHTML,
+		ReplyMarkup: &models.InlineKeyboardMarkup{InlineKeyboard: kb},
+	})
+	if err != nil {
+		ub.handleError(ctx, chatID, zaperr.Wrap(err, "failed to send message", zapFields...))
+		return
+	}
+
+	userToEpIDs := make(map[string][]string)
+	for _, ep := range episodes {
+		userToEpIDs[ep.UserID] = append(userToEpIDs[ep.UserID], ep.ID)
+	}
+
+	var handlerID string
+	handlerID = ub.registerChatHandler(chatID, bot.HandlerTypeCallbackQueryData, prefix, bot.MatchTypePrefix, func(ctx context.Context, b *bot.Bot, update *models.Update) {
+		ub.unregisterChatHandler(chatID, handlerID)
+
+		var count int
+		switch strings.ReplaceAll(update.CallbackQuery.Data, prefix, "") {
+		case cmdRetry:
+			for userID, epIDs := range userToEpIDs {
+				retried, err := ub.service.RetryEpisodes(ctx, userID, epIDs)
+				count += retried
+				if err != nil {
+					zapFields := append(zapFields, zap.String("episodes_user_id", userID), zap.Strings("episode_ids", epIDs))
+					ub.handleError(ctx, chatID, zaperr.Wrap(err, "failed to retry stuck episodes", zapFields...))
+				}
+			}
+			ub.sendTextMessage(ctx, chatID, "%d stuck episodes were retried", count)
+		case cmdDelete:
+			for userID, epIDs := range userToEpIDs {
+				if err := ub.service.DeleteEpisodes(ctx, userID, epIDs); err != nil {
+					zapFields := append(zapFields, zap.String("episodes_user_id", userID), zap.Strings("episode_ids", epIDs))
+					ub.handleError(ctx, chatID, zaperr.Wrap(err, "failed to delete stuck episodes", zapFields...))
+					continue
+				}
+				count += len(epIDs)
+			}
+			ub.sendTextMessage(ctx, chatID, "%d stuck episodes were deleted", count)
+		}
+
+		if _, err := ub.bot.DeleteMessage(ctx, &bot.DeleteMessageParams{
+			ChatID:    chatID,
+			MessageID: initialMsg.ID,
+		}); err != nil {
+			zapFields := append(zapFields, zaperr.ToField(err))
+			ub.logger.Error("failed to delete stuck episodes message", zapFields...)
+		}
+	})
+}
+
+func formatStuckEpisodesMessage(episodes []*service.Episode) string {
+	lines := []string{fmt.Sprintf("<b>%d episodes are stuck for more than %s:</b>", len(episodes), stuckEpisodesAge)}
+	for _, ep := range episodes {
+		line := fmt.Sprintf(
+			"- #<code>%s</code> (%s) of user <code>%s</code>: %s since %s",
+			ep.ID, html.EscapeString(ep.Title), ep.UserID, ep.Status, ep.UpdatedAt.Format(time.DateTime),
+		)
+		if len(strings.Join(lines, "\n"))+len(line) > 4000 {
+			lines = append(lines, "...")
+			break
+		}
+		lines = append(lines, line)
+	}
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,23 @@
+package bot
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"tg-podcastotron/service"
+)
+
+func TestFormatStuckEpisodesMessage(t *testing.T) {
+	text := formatStuckEpisodesMessage([]*service.Episode{{
+		ID:        "1",
+		UserID:    "some-user",
+		Title:     "Q&A <live>",
+		Status:    service.EpisodeStatusDownloading,
+		UpdatedAt: time.Now(),
+	}})
+
+	if !strings.Contains(text, "(Q&amp;A &lt;live&gt;)") {
+		t.Errorf("expected title to be escaped for HTML, got:\n%s", text)
+	}
+}
@@ -0,0 +1,67 @@
+package bot
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+
+	"github.com/go-telegram/bot"
+	"github.com/go-telegram/bot/models"
+	"github.com/hori-ryota/zaperr"
+	"go.uber.org/zap"
+	"tg-podcastotron/service"
+)
+
+// transferFeedHandler lets admin move a feed with its episodes to another user,
+// e.g. when someone switched to a new Telegram account
+func (ub *UndercastBot) transferFeedHandler(ctx context.Context, _ *bot.Bot, update *models.Update) {
+	chatID := ub.extractChatID(update)
+	userID := ub.extractUserID(update)
+
+	zapFields := []zap.Field{
+		zap.Int64("chat_id", chatID),
+		zap.String("user_id", userID),
+		zap.String("message_text", update.Message.Text),
+	}
+
+	isAdmin, err := ub.auth.IsAdmin(ctx, ub.extractUsername(update))
+	if err != nil {
+		ub.handleError(ctx, chatID, zaperr.Wrap(err, "failed to check if user is admin", zapFields...))
+		return
+	}
+	if !isAdmin {
+		ub.sendTextMessage(ctx, chatID, "unknown command")
+		return
+	}
+
+	fromUserID, feedID, toUserID, err := ub.parseTransferFeedCmd(update.Message.Text)
+	if err != nil {
+		ub.sendTextMessage(ctx, chatID, "Please specify source user ID, feed ID and destination user ID, like so:\n/transferfeed 123456 2 654321")
+		return
+	}
+
+	feed, err := ub.service.TransferFeed(ctx, userID, feedID, fromUserID, toUserID)
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrFeedNotFound):
+			ub.sendTextMessage(ctx, chatID, "User %s has no feed #%s", fromUserID, feedID)
+		case errors.Is(err, service.ErrFeedBusy):
+			ub.sendTextMessage(ctx, chatID, "Feed #%s has episodes that are still being created, please try again once they are done", feedID)
+		default:
+			ub.handleError(ctx, chatID, zaperr.Wrap(err, "failed to transfer feed", zapFields...))
+		}
+		return
+	}
+
+	ub.sendTextMessage(ctx, chatID, "Feed #%s of user %s is now feed #%s of user %s:\n%s", feedID, fromUserID, feed.ID, toUserID, feed.URL)
+}
+
+func (ub *UndercastBot) parseTransferFeedCmd(text string) (fromUserID string, feedID string, toUserID string, err error) {
+	re := regexp.MustCompile(`^/transferfeed\s+(\d+)\s+(\d+)\s+(\d+)\s*$`)
+	matches := re.FindStringSubmatch(text)
+	if len(matches) != 4 {
+		return "", "", "", fmt.Errorf("invalid command")
+	}
+	return matches[1], matches[2], matches[3], nil
+}
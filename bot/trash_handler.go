@@ -0,0 +1,95 @@
+package bot
+
+import (
+	"context"
+	"fmt"
+	"html"
+	"strings"
+	"time"
+
+	"github.com/go-telegram/bot"
+	"github.com/go-telegram/bot/models"
+	"github.com/hori-ryota/zaperr"
+	"go.uber.org/zap"
+	"tg-podcastotron/service"
+)
+
+// trashHandler lists deleted episodes that can still be restored
+func (ub *UndercastBot) trashHandler(ctx context.Context, _ *bot.Bot, update *models.Update) {
+	chatID := ub.extractChatID(update)
+	userID := ub.extractUserID(update)
+
+	zapFields := []zap.Field{
+		zap.Int64("chat_id", chatID),
+		zap.String("user_id", userID),
+		zap.String("message_text", update.Message.Text),
+	}
+
+	episodes, err := ub.service.ListDeletedEpisodes(ctx, userID)
+	if err != nil {
+		ub.handleError(ctx, chatID, zaperr.Wrap(err, "failed to list deleted episodes", zapFields...))
+		return
+	}
+
+	if len(episodes) == 0 {
+		ub.sendTextMessage(ctx, chatID, "Trash is empty")
+		return
+	}
+
+	if _, err := ub.bot.SendMessage(ctx, &bot.SendMessageParams{
+		ChatID:    chatID,
+		Text:      renderTrash(episodes),
+		ParseMode: models.ParseModeHTML,
+	}); err != nil {
+		ub.handleError(ctx, chatID, zaperr.Wrap(err, "failed to send message", zapFields...))
+	}
+}
+
+// restoreEpisodesHandler brings episodes back from trash with `/restore_1_to_3`
+func (ub *UndercastBot) restoreEpisodesHandler(ctx context.Context, _ *bot.Bot, update *models.Update) {
+	chatID := ub.extractChatID(update)
+	userID := ub.extractUserID(update)
+
+	zapFields := []zap.Field{
+		zap.Int64("chat_id", chatID),
+		zap.String("user_id", userID),
+		zap.String("message_text", update.Message.Text),
+	}
+
+	epIDs, err := parseIDs(strings.TrimPrefix(update.Message.Text, "/restore_"))
+	if err != nil || len(epIDs) == 0 {
+		ub.sendTextMessage(ctx, chatID, "Please use /trash to see which episodes can be restored")
+		return
+	}
+	zapFields = append(zapFields, zap.Strings("episode_ids", epIDs))
+
+	if err := ub.service.RestoreEpisodes(ctx, userID, epIDs); err != nil {
+		ub.handleError(ctx, chatID, zaperr.Wrap(err, "failed to restore episodes", zapFields...))
+		return
+	}
+
+	ub.sendTextMessage(ctx, chatID, "Episodes are back in your library. Use /ee_%s to publish them to feeds again", strings.Join(epIDs, "_"))
+}
+
+func renderTrash(episodes []*service.Episode) string {
+	lines := []string{
+		fmt.Sprintf("<b>Deleted episodes are purged %d days after deletion</b>", int(service.DeletedEpisodesRetention/(24*time.Hour))),
+	}
+
+	epIDs := make([]string, 0, len(episodes))
+	for _, ep := range episodes {
+		epIDs = append(epIDs, ep.ID)
+		lines = append(lines, fmt.Sprintf(
+			"%s. %s (deleted %s)",
+			ep.ID,
+			html.EscapeString(ep.Title),
+			ep.DeletedAt.Format("2006-01-02"),
+		))
+	}
+
+	if formatted, err := formatIDsCompactly(epIDs); err == nil {
+		lines = append(lines, "", fmt.Sprintf("/restore_%s - restore all of them", formatted))
+	}
+
+	return strings.Join(lines, "\n")
+}
@@ -90,6 +90,11 @@ func New(b *bot.Bot, items []*Item, onConfirmSelection OnConfirmSelectionHandler
 	return multiSelect
 }
 
+// HandlerID returns ID of callback handler registered for the keyboard, e.g. to unregister it when selection is abandoned
+func (ms *MultiSelect) HandlerID() string {
+	return ms.callbackHandlerID
+}
+
 func (ms *MultiSelect) MarshalJSON() ([]byte, error) {
 	return json.Marshal(&models.InlineKeyboardMarkup{InlineKeyboard: ms.buildKeyboard()})
 }
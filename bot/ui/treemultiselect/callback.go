@@ -50,6 +50,10 @@ func (tms *TreeMultiSelect) selectNode(ctx context.Context, b *bot.Bot, mes *mod
 
 		if node.IsLeaf() {
 			node.Selected = !node.Selected
+			// whether everything in folder is selected might have changed
+			if tms.dynamicFilterButtons != nil {
+				tms.filterButtons = tms.dynamicFilterButtons(maps.Values(tms.currentNode.Children))
+			}
 			if tms.dynamicActionButtons != nil {
 				tms.actionButtons = tms.dynamicActionButtons(tms.getAllSelectedNodes())
 			}
@@ -5,33 +5,58 @@ import (
 
 	"github.com/go-telegram/bot"
 	"github.com/go-telegram/bot/models"
+	"golang.org/x/exp/maps"
 )
 
 type FilterButton struct {
 	Text string
 	Fn   func(node *TreeNode) bool
+	// Subtree buttons call Fn with current node and select or deselect every leaf under it, however deep,
+	// instead of calling Fn for each of its children
+	Subtree bool
 }
 
 var FilterButtonSelectAll = FilterButton{Text: "Select All", Fn: func(item *TreeNode) bool { return true }}
 var FilterButtonSelectNone = FilterButton{Text: "Select None", Fn: func(item *TreeNode) bool { return false }}
+var FilterButtonSelectSubtree = FilterButton{Text: "Select all in folder", Fn: func(item *TreeNode) bool { return true }, Subtree: true}
+var FilterButtonDeselectSubtree = FilterButton{Text: "Deselect all in folder", Fn: func(item *TreeNode) bool { return false }, Subtree: true}
 
 func (tms *TreeMultiSelect) selectByFilter(ctx context.Context, b *bot.Bot, message *models.Message, idx int) {
-	filterBtn := tms.filterButtons[idx]
+	tms.applyFilter(tms.filterButtons[idx])
+	tms.sendUpdatedMarkup(ctx, b, message)
+}
 
+func (tms *TreeMultiSelect) applyFilter(filterBtn FilterButton) {
 	func() {
 		tms.nodesLock.Lock()
 		defer tms.nodesLock.Unlock()
 
+		if filterBtn.Subtree {
+			selectSubtree(tms.currentNode, filterBtn.Fn(tms.currentNode))
+			return
+		}
 		for _, node := range tms.currentNode.Children {
 			node.Selected = filterBtn.Fn(node)
 		}
 	}()
 
+	if tms.dynamicFilterButtons != nil {
+		tms.filterButtons = tms.dynamicFilterButtons(maps.Values(tms.currentNode.Children))
+	}
 	if tms.dynamicActionButtons != nil {
 		tms.actionButtons = tms.dynamicActionButtons(tms.getAllSelectedNodes())
 	}
+}
 
-	tms.sendUpdatedMarkup(ctx, b, message)
+// selectSubtree selects or deselects every leaf under node, folders themselves are left as they are
+func selectSubtree(node *TreeNode, selected bool) {
+	if node.IsLeaf() {
+		node.Selected = selected
+		return
+	}
+	for _, child := range node.Children {
+		selectSubtree(child, selected)
+	}
 }
 
 func (tms *TreeMultiSelect) buildFiltersRow() []models.InlineKeyboardButton {
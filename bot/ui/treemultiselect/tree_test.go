@@ -1,6 +1,7 @@
 package treemultiselect
 
 import (
+	"reflect"
 	"testing"
 )
 
@@ -38,3 +39,58 @@ func TestItemTree(t *testing.T) {
 		}
 	})
 }
+
+func TestSelectSubtree(t *testing.T) {
+	tms := TreeMultiSelect{separator: "/"}
+	tms.initializeTree([]string{
+		"foo/bar/1.txt",
+		"foo/bar/2.txt",
+		"foo/3.txt",
+		"foo2/4.txt",
+	})
+	foo := tms.root.Children["foo"]
+	selectedPaths := func() []string {
+		var paths []string
+		for _, node := range tms.getAllSelectedNodes() {
+			paths = append(paths, nodeToPath(node))
+		}
+		return paths
+	}
+
+	// region selecting folder selects every leaf under it, however deep, and nothing else
+	tms.currentNode = foo
+	tms.applyFilter(FilterButtonSelectSubtree)
+	if paths := selectedPaths(); !reflect.DeepEqual(paths, []string{"foo/bar/1.txt", "foo/bar/2.txt", "foo/3.txt"}) {
+		t.Fatalf("expected every file in foo to be selected, got %v", paths)
+	}
+	if !foo.AllLeavesSelected() {
+		t.Errorf("expected foo to have all leaves selected")
+	}
+	if tms.root.AllLeavesSelected() {
+		t.Errorf("expected root not to have all leaves selected, foo2/4.txt is not")
+	}
+	// endregion
+
+	// region deselecting folder leaves leaves outside of it alone
+	tms.root.Children["foo2"].Children["4.txt"].Selected = true
+	tms.applyFilter(FilterButtonDeselectSubtree)
+	if paths := selectedPaths(); !reflect.DeepEqual(paths, []string{"foo2/4.txt"}) {
+		t.Fatalf("expected only foo2/4.txt to stay selected, got %v", paths)
+	}
+	// endregion
+
+	// region dynamic filter buttons follow selection
+	tms.dynamicFilterButtons = func(nodes []*TreeNode) []FilterButton {
+		for _, n := range nodes {
+			if !n.AllLeavesSelected() {
+				return []FilterButton{FilterButtonSelectSubtree}
+			}
+		}
+		return []FilterButton{FilterButtonDeselectSubtree}
+	}
+	tms.applyFilter(FilterButtonSelectSubtree)
+	if len(tms.filterButtons) != 1 || tms.filterButtons[0].Text != FilterButtonDeselectSubtree.Text {
+		t.Errorf("expected folder with everything selected to offer deselecting, got %+v", tms.filterButtons)
+	}
+	// endregion
+}
@@ -39,6 +39,19 @@ func (n *TreeNode) IsLeaf() bool {
 	return len(n.Children) == 0
 }
 
+// AllLeavesSelected tells whether every leaf under node, or node itself if it is a leaf, is selected
+func (n *TreeNode) AllLeavesSelected() bool {
+	if n.IsLeaf() {
+		return n.Selected
+	}
+	for _, child := range n.Children {
+		if !child.AllLeavesSelected() {
+			return false
+		}
+	}
+	return true
+}
+
 type TreeMultiSelect struct {
 	// configurable params
 	maxNodesPerPage      int
@@ -117,6 +130,11 @@ func New(b *bot.Bot, paths []string, onConfirmSelection OnConfirmSelectionHandle
 	return tms
 }
 
+// HandlerID returns ID of callback handler registered for the keyboard, e.g. to unregister it when selection is abandoned
+func (tms *TreeMultiSelect) HandlerID() string {
+	return tms.callbackHandlerID
+}
+
 func (tms *TreeMultiSelect) MarshalJSON() ([]byte, error) {
 	return json.Marshal(&models.InlineKeyboardMarkup{InlineKeyboard: tms.buildKeyboard()})
 }
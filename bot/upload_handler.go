@@ -0,0 +1,141 @@
+package bot
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"path/filepath"
+	"strings"
+
+	"github.com/go-telegram/bot"
+	"github.com/go-telegram/bot/models"
+	"github.com/hori-ryota/zaperr"
+	"go.uber.org/zap"
+	"tg-podcastotron/service"
+)
+
+// maxTelegramDownloadBytes is the largest file Bot API lets bots download
+const maxTelegramDownloadBytes = 20 * 1024 * 1024
+
+// uploadHandler creates an episode out of audio file sent to the bot directly, either as audio or as a document.
+// It is published to the default feed right away, since there is nothing to wait for
+func (ub *UndercastBot) uploadHandler(ctx context.Context, b *bot.Bot, update *models.Update) {
+	chatID := ub.extractChatID(update)
+	userID := ub.extractUserID(update)
+	upload := extractUpload(update.Message)
+
+	zapFields := []zap.Field{
+		zap.Int64("chat_id", chatID),
+		zap.String("user_id", userID),
+		zap.String("file_id", upload.fileID),
+		zap.String("mime_type", upload.mimeType),
+		zap.Int64("file_size", upload.fileSize),
+	}
+
+	if upload.fileSize > maxTelegramDownloadBytes {
+		ub.sendTextMessage(ctx, chatID, "The file is too big, Telegram only lets bots download files up to 20 MB. Please upload it somewhere and send me a link instead")
+		return
+	}
+
+	data, err := ub.downloadTelegramFile(ctx, upload.fileID)
+	if err != nil {
+		ub.handleError(ctx, chatID, zaperr.Wrap(err, "failed to download audio", zapFields...))
+		return
+	}
+
+	ep, err := ub.service.CreateEpisodeFromUpload(ctx, userID, upload.title, bytes.NewReader(data), int64(len(data)), upload.mimeType)
+	if err != nil {
+		if errors.Is(err, service.ErrUnsupportedFormat) {
+			ub.sendTextMessage(ctx, chatID, "Sorry, I can't make an episode out of %s files", upload.describeType())
+			return
+		}
+		ub.handleError(ctx, chatID, zaperr.Wrap(err, "failed to create episode from upload", zapFields...))
+		return
+	}
+	zapFields = append(zapFields, zap.String("episode_id", ep.ID))
+
+	defaultFeed, err := ub.service.DefaultFeed(ctx, userID)
+	if err != nil {
+		ub.handleError(ctx, chatID, zaperr.Wrap(err, "failed to get default feed", zapFields...))
+		return
+	}
+
+	if err := ub.service.PublishEpisodes(ctx, userID, []string{ep.ID}, []string{defaultFeed.ID}); err != nil {
+		ub.handleError(ctx, chatID, zaperr.Wrap(err, "failed to publish episode", zapFields...))
+		return
+	}
+
+	message, err := formatEpisodesCreatedMessage([]string{ep.ID}, defaultFeed)
+	if err != nil {
+		ub.logger.Error("failed to format episodes created message", zaperr.ToField(err))
+		message = "Accepted"
+	}
+	if _, err := b.SendMessage(ctx, &bot.SendMessageParams{
+		ChatID:    chatID,
+		Text:      message,
+		ParseMode: models.ParseModeHTML,
+	}); err != nil {
+		ub.handleError(ctx, chatID, zaperr.Wrap(err, "failed to send message", zapFields...))
+	}
+}
+
+// uploadedFile is what uploadHandler needs to know about audio or document sent to the bot
+type uploadedFile struct {
+	fileID   string
+	mimeType string
+	fileSize int64
+	title    string
+}
+
+func (f uploadedFile) describeType() string {
+	if f.mimeType == "" {
+		return "such"
+	}
+	return f.mimeType
+}
+
+func isUpload(update *models.Update) bool {
+	return update != nil && update.Message != nil && (update.Message.Audio != nil || update.Message.Document != nil)
+}
+
+func extractUpload(msg *models.Message) uploadedFile {
+	if audio := msg.Audio; audio != nil {
+		mimeType := audio.MimeType
+		if mimeType == "" {
+			mimeType = "audio/mpeg"
+		}
+		return uploadedFile{
+			fileID:   audio.FileID,
+			mimeType: mimeType,
+			fileSize: audio.FileSize,
+			title:    uploadTitle(audio.Performer, audio.Title, audio.FileName),
+		}
+	}
+
+	doc := msg.Document
+	mimeType := doc.MimeType
+	// documents are sent as is, so their MIME type is often missing or generic
+	if mimeType == "" || mimeType == "application/octet-stream" {
+		mimeType = service.MIMETypeByExtension(filepath.Ext(doc.FileName))
+	}
+	return uploadedFile{
+		fileID:   doc.FileID,
+		mimeType: mimeType,
+		fileSize: doc.FileSize,
+		title:    uploadTitle("", "", doc.FileName),
+	}
+}
+
+// uploadTitle prefers title from audio tags, falling back to file name
+func uploadTitle(performer, title, fileName string) string {
+	if title != "" {
+		if performer != "" {
+			return performer + " - " + title
+		}
+		return title
+	}
+	if fileName != "" {
+		return strings.TrimSuffix(fileName, filepath.Ext(fileName))
+	}
+	return "Audio upload"
+}
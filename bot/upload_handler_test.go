@@ -0,0 +1,138 @@
+package bot
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/go-telegram/bot"
+	"github.com/go-telegram/bot/models"
+	"go.uber.org/zap"
+	"tg-podcastotron/mediary/mediarymocks"
+	"tg-podcastotron/service"
+	"tg-podcastotron/service/servicemocks"
+)
+
+func TestUndercastBot__UploadHandler(t *testing.T) {
+	ctx := context.Background()
+	token := "some-token"
+
+	// region fake Telegram Bot API serving file info and file contents
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/bot" + token + "/getFile":
+			w.Header().Set("Content-Type", "application/json")
+			_, _ = w.Write([]byte(`{"ok":true,"result":{"file_id":"audio","file_unique_id":"audio","file_path":"music/file_1.mp3"}}`))
+		case "/file/bot" + token + "/music/file_1.mp3":
+			_, _ = w.Write([]byte("some-mp3-data"))
+		default:
+			w.Header().Set("Content-Type", "application/json")
+			_, _ = w.Write([]byte(`{"ok":true,"result":{"message_id":1,"date":0,"chat":{"id":1,"type":"private"}}}`))
+		}
+	}))
+	defer srv.Close()
+	// endregion
+
+	var mu sync.Mutex
+	uploaded := map[string][]byte{}
+	s3Store := &servicemocks.MockS3Store{
+		PutFunc: func(ctx context.Context, key string, dataReader io.ReadSeeker, opts ...func(*service.PutOptions)) error {
+			data, err := io.ReadAll(dataReader)
+			mu.Lock()
+			uploaded[key] = data
+			mu.Unlock()
+			return err
+		},
+		URLFunc: func(key string) (string, error) {
+			return "https://example.com/" + key, nil
+		},
+	}
+	svc := service.New(
+		&mediarymocks.ServiceMock{}, getServiceRepo(t), s3Store, &fakeJobsQueue{},
+		"", "", false, func(s string) string { return s }, zap.NewNop(),
+	)
+
+	b, err := bot.New(token, bot.WithServerURL(srv.URL), bot.WithSkipGetMe())
+	if err != nil {
+		t.Fatal(err)
+	}
+	ub := &UndercastBot{
+		logger:            zap.NewNop(),
+		token:             token,
+		bot:               b,
+		service:           svc,
+		telegramServerURL: srv.URL,
+		httpClient:        srv.Client(),
+	}
+
+	userID := "1"
+	ub.uploadHandler(ctx, b, &models.Update{Message: &models.Message{
+		Chat: models.Chat{ID: 1},
+		From: &models.User{ID: 1},
+		Audio: &models.Audio{
+			FileID:   "audio",
+			Title:    "Some Song",
+			MimeType: "audio/mpeg",
+			FileSize: int64(len("some-mp3-data")),
+		},
+	}})
+
+	episodes, err := svc.ListUserEpisodes(ctx, userID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(episodes) != 1 {
+		t.Fatalf("expected 1 episode, got %d", len(episodes))
+	}
+	ep := episodes[0]
+	if ep.Status != service.EpisodeStatusComplete {
+		t.Errorf("expected uploaded episode to be complete, got %s", ep.Status)
+	}
+	if ep.Title != "Some Song" || ep.Format != "mp3" {
+		t.Errorf("unexpected episode: %+v", ep)
+	}
+	if string(uploaded[ep.StorageKey]) != "some-mp3-data" {
+		t.Errorf("expected uploaded file to be stored under episode key, got %q", uploaded[ep.StorageKey])
+	}
+	if ep.FileLenBytes != int64(len("some-mp3-data")) {
+		t.Errorf("expected episode size to match uploaded file, got %d", ep.FileLenBytes)
+	}
+}
+
+func TestExtractUpload(t *testing.T) {
+	for _, tc := range []struct {
+		name     string
+		msg      *models.Message
+		expected uploadedFile
+	}{
+		{
+			name:     "audio with tags",
+			msg:      &models.Message{Audio: &models.Audio{FileID: "a", Performer: "Some Band", Title: "Some Song", FileName: "track.mp3", MimeType: "audio/mpeg", FileSize: 10}},
+			expected: uploadedFile{fileID: "a", mimeType: "audio/mpeg", fileSize: 10, title: "Some Band - Some Song"},
+		},
+		{
+			name:     "document with MIME type",
+			msg:      &models.Message{Document: &models.Document{FileID: "d", FileName: "Some Talk.mp3", MimeType: "audio/mpeg", FileSize: 10}},
+			expected: uploadedFile{fileID: "d", mimeType: "audio/mpeg", fileSize: 10, title: "Some Talk"},
+		},
+		{
+			name:     "document with generic MIME type",
+			msg:      &models.Message{Document: &models.Document{FileID: "d", FileName: "Some Talk.M4A", MimeType: "application/octet-stream"}},
+			expected: uploadedFile{fileID: "d", mimeType: "audio/mp4", title: "Some Talk"},
+		},
+		{
+			name:     "document of unsupported type",
+			msg:      &models.Message{Document: &models.Document{FileID: "d", FileName: "notes.txt"}},
+			expected: uploadedFile{fileID: "d", title: "notes"},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			if upload := extractUpload(tc.msg); upload != tc.expected {
+				t.Errorf("expected %+v, got %+v", tc.expected, upload)
+			}
+		})
+	}
+}
@@ -2,15 +2,18 @@ package bot
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"github.com/go-telegram/bot"
 	"github.com/go-telegram/bot/models"
 	"github.com/hori-ryota/zaperr"
 	"go.uber.org/zap"
+	"math"
 	"path/filepath"
 	"strings"
 	"tg-podcastotron/bot/ui/multiselect"
 	"tg-podcastotron/bot/ui/treemultiselect"
+	"tg-podcastotron/mediary"
 	"tg-podcastotron/service"
 )
 
@@ -35,6 +38,10 @@ func (ub *UndercastBot) urlHandler(ctx context.Context, _ *bot.Bot, update *mode
 	}
 	url := update.Message.Text
 	isValid, err := ub.service.IsValidURL(ctx, url)
+	if errors.Is(err, mediary.ErrServiceUnavailable) {
+		ub.sendMediaServiceUnavailable(ctx, chatID, zaperr.Wrap(err, "failed to check if URL is valid", zapFields...))
+		return
+	}
 	if err != nil {
 		ub.handleError(ctx, chatID, zaperr.Wrap(err, "failed to check if URL is valid", zapFields...))
 		return
@@ -45,6 +52,10 @@ func (ub *UndercastBot) urlHandler(ctx context.Context, _ *bot.Bot, update *mode
 	}
 
 	metadata, err := ub.service.FetchMetadata(ctx, url)
+	if errors.Is(err, mediary.ErrServiceUnavailable) {
+		ub.sendMediaServiceUnavailable(ctx, chatID, zaperr.Wrap(err, "failed to fetch metadata", zapFields...))
+		return
+	}
 	if err != nil {
 		ub.handleError(ctx, chatID, zaperr.Wrap(err, "failed to fetch metadata", zapFields...))
 		return
@@ -70,6 +81,13 @@ func (ub *UndercastBot) urlHandler(ctx context.Context, _ *bot.Bot, update *mode
 
 }
 
+// sendMediaServiceUnavailable tells user to retry later instead of reporting an error ID,
+// since there's nothing wrong with the URL and nothing for the user to report
+func (ub *UndercastBot) sendMediaServiceUnavailable(ctx context.Context, chatID int64, err error) {
+	ub.logger.Warn("media service is unavailable", zaperr.ToField(err))
+	ub.sendTextMessage(ctx, chatID, "The media service is temporarily unavailable, please try again later")
+}
+
 func (ub *UndercastBot) startTorrentFlow(ctx context.Context, metadata *service.Metadata, userID string, chatID int64) error {
 	var variants []string
 	for _, v := range metadata.Variants {
@@ -95,6 +113,11 @@ func (ub *UndercastBot) startTorrentFlow(ctx context.Context, metadata *service.
 			if len(buttons) > 0 {
 				buttons = append(buttons, treemultiselect.FilterButtonSelectNone)
 			}
+			if folderSelected(selectedNodes) {
+				buttons = append(buttons, treemultiselect.FilterButtonDeselectSubtree)
+			} else if len(selectedNodes) > 0 {
+				buttons = append(buttons, treemultiselect.FilterButtonSelectSubtree)
+			}
 			return buttons
 		}),
 		treemultiselect.WithDynamicActionButtons(func(selectedNodes []*treemultiselect.TreeNode) [][]treemultiselect.ActionButton {
@@ -108,7 +131,7 @@ func (ub *UndercastBot) startTorrentFlow(ctx context.Context, metadata *service.
 					{treemultiselect.NewConfirmButton(
 						"Create Episode",
 						func(ctx context.Context, bot *bot.Bot, mes *models.Message, paths []string) {
-							ub.createEpisodes(ctx, userID, mes.Chat.ID, metadata.URL, [][]string{{paths[0]}}, service.ProcessingTypeUploadOriginal)
+							ub.askTagsAndCreateEpisodes(ctx, userID, mes.Chat.ID, metadata.URL, [][]string{{paths[0]}}, service.ProcessingTypeUploadOriginal)
 						},
 					)},
 					{cancelBtn},
@@ -122,13 +145,20 @@ func (ub *UndercastBot) startTorrentFlow(ctx context.Context, metadata *service.
 							for i, path := range paths {
 								episodesPaths[i] = []string{path}
 							}
-							ub.createEpisodes(ctx, userID, mes.Chat.ID, metadata.URL, episodesPaths, service.ProcessingTypeUploadOriginal)
+							episodesPaths, excludedPaths := ub.service.ExcludeSmallFiles(metadata, episodesPaths)
+							if len(excludedPaths) > 0 {
+								ub.sendTextMessage(ctx, mes.Chat.ID, "These files are too small to become episodes, skipping them:\n%s", strings.Join(excludedPaths, "\n"))
+							}
+							if len(episodesPaths) == 0 {
+								return
+							}
+							ub.askTagsAndCreateEpisodes(ctx, userID, mes.Chat.ID, metadata.URL, episodesPaths, service.ProcessingTypeUploadOriginal)
 						},
 					)},
 					{treemultiselect.NewConfirmButton(
 						"Glue Into 1 Episode",
 						func(ctx context.Context, bot *bot.Bot, mes *models.Message, paths []string) {
-							ub.createEpisodes(ctx, userID, mes.Chat.ID, metadata.URL, [][]string{paths}, service.ProcessingTypeConcatenate)
+							ub.askTagsAndCreateEpisodes(ctx, userID, mes.Chat.ID, metadata.URL, [][]string{paths}, service.ProcessingTypeConcatenate)
 						},
 					)},
 					{cancelBtn},
@@ -137,6 +167,8 @@ func (ub *UndercastBot) startTorrentFlow(ctx context.Context, metadata *service.
 		}),
 	)
 
+	ub.trackChatHandler(chatID, kb.HandlerID())
+
 	if msg, err := ub.bot.SendMessage(ctx, &bot.SendMessageParams{
 		ChatID:      chatID,
 		Text:        "Please choose which files to include in the episode",
@@ -165,7 +197,7 @@ func (ub *UndercastBot) startYtdlFlow(ctx context.Context, metadata *service.Met
 					break
 				}
 			}
-			ub.createEpisodes(ctx, userID, mes.Chat.ID, metadata.URL, [][]string{{variant}}, service.ProcessingTypeUploadOriginal)
+			ub.createEpisodes(ctx, userID, mes.Chat.ID, metadata.URL, [][]string{{variant}}, service.ProcessingTypeUploadOriginal, nil)
 		},
 		multiselect.WithOnItemSelectedHandler(func(itemID string) *multiselect.StateChange {
 			for _, v := range items {
@@ -176,6 +208,8 @@ func (ub *UndercastBot) startYtdlFlow(ctx context.Context, metadata *service.Met
 		multiselect.WithItemFilters(),
 	)
 
+	ub.trackChatHandler(chatID, kb.HandlerID())
+
 	if msg, err := ub.bot.SendMessage(ctx, &bot.SendMessageParams{
 		ChatID:      chatID,
 		Text:        "Please choose variant",
@@ -187,14 +221,60 @@ func (ub *UndercastBot) startYtdlFlow(ctx context.Context, metadata *service.Met
 	return nil
 }
 
-func (ub *UndercastBot) createEpisodes(ctx context.Context, userID string, chatID int64, url string, variants [][]string, processingType service.ProcessingType) {
-	if err := ub.service.CreateEpisodesAsync(ctx, userID, url, variants, processingType); err != nil {
+// askTagsAndCreateEpisodes prompts user for comma-separated tags and creates episodes once they reply
+func (ub *UndercastBot) askTagsAndCreateEpisodes(ctx context.Context, userID string, chatID int64, url string, variants [][]string, processingType service.ProcessingType) {
+	zapFields := []zap.Field{
+		zap.Int64("chat_id", chatID),
+		zap.String("user_id", userID),
+		zap.String("url", url),
+		zap.Any("variants", variants),
+	}
+
+	tagsPromptMsg, err := ub.bot.SendMessage(ctx, &bot.SendMessageParams{
+		ChatID:      chatID,
+		Text:        "Please enter comma-separated tags for the episodes, or send <code>-</code> to skip",
+		ParseMode:   models.ParseModeHTML,
+		ReplyMarkup: &models.ForceReply{ForceReply: true},
+	})
+	if err != nil {
+		zapFields := append(zapFields, zaperr.ToField(err))
+		ub.logger.Error("failed to send tags prompt, creating episodes without tags", zapFields...)
+		ub.createEpisodes(ctx, userID, chatID, url, variants, processingType, nil)
+		return
+	}
+
+	var handlerID string
+	handlerID = ub.registerChatHandlerMatchFunc(
+		chatID,
+		func(update *models.Update) bool {
+			return update.Message != nil && update.Message.ReplyToMessage != nil && update.Message.ReplyToMessage.ID == tagsPromptMsg.ID
+		},
+		func(ctx context.Context, b *bot.Bot, update *models.Update) {
+			ub.unregisterChatHandler(chatID, handlerID)
+
+			var tags []string
+			if text := strings.TrimSpace(update.Message.Text); text != "-" {
+				tags = strings.Split(text, ",")
+			}
+
+			if _, err := ub.bot.DeleteMessage(ctx, &bot.DeleteMessageParams{ChatID: chatID, MessageID: tagsPromptMsg.ID}); err != nil {
+				zapFields := append(zapFields, zaperr.ToField(err))
+				ub.logger.Error("failed to delete tags prompt message", zapFields...)
+			}
+
+			ub.createEpisodes(ctx, userID, chatID, url, variants, processingType, tags)
+		})
+}
+
+func (ub *UndercastBot) createEpisodes(ctx context.Context, userID string, chatID int64, url string, variants [][]string, processingType service.ProcessingType, tags []string) {
+	if err := ub.service.CreateEpisodesAsync(ctx, userID, url, variants, processingType, tags, service.EpisodeOptions{}); err != nil {
 		ub.handleError(ctx, chatID, zaperr.Wrap(
 			err, "failed to enqueue episodes creation",
 			zap.Int64("chat_id", chatID),
 			zap.String("user_id", userID),
 			zap.String("url", url),
 			zap.Any("variants", variants),
+			zap.Strings("tags", tags),
 		))
 	}
 }
@@ -225,7 +305,7 @@ func (ub *UndercastBot) onEpisodesStatusChanges(ctx context.Context, episodeStat
 			otherChanges = append(otherChanges, changes...)
 		}
 		if len(otherChanges) > 0 {
-			ub.notifyStatusChanged(ctx, userID, chatID, otherChanges)
+			ub.queueStatusChanges(ctx, userID, chatID, otherChanges)
 		}
 	}
 }
@@ -269,12 +349,136 @@ func (ub *UndercastBot) handleEpisodesCreated(ctx context.Context, userID string
 	}
 }
 
+// notifyStatusChanged sends a detailed message when a single episode has changed,
+// several changes are aggregated into one message to stay within Telegram rate limits
 func (ub *UndercastBot) notifyStatusChanged(ctx context.Context, userID string, chatID int64, changes []service.EpisodeStatusChange) {
+	if len(changes) > 1 {
+		// progress of several episodes changes on every poll, so it only updates status messages sent earlier,
+		// otherwise a new summary would be sent every time
+		statusChanges := make([]service.EpisodeStatusChange, 0, len(changes))
+		for _, change := range changes {
+			if isProgressOnly(change) {
+				ub.editStatusMessage(ctx, userID, chatID, change)
+			} else {
+				statusChanges = append(statusChanges, change)
+			}
+		}
+		changes = statusChanges
+	}
+
+	if len(changes) > 1 {
+		for _, change := range changes {
+			if change.Err != nil || change.NewStatus == service.EpisodeStatusComplete {
+				ub.forgetStatusMessage(ctx, userID, change.Episode.ID)
+			}
+		}
+		ub.sendTextMessage(ctx, chatID, "%s", formatStatusChangesSummary(changes))
+		return
+	}
+
 	for _, change := range changes {
-		ub.sendTextMessage(ctx, chatID, "Episode #%s (%s) is now %s", change.Episode.ID, change.Episode.Title, change.NewStatus)
+		if errors.Is(change.Err, service.ErrReencodeFailed) {
+			ub.sendTextMessage(ctx, chatID, "Episode #%s (%s) was not converted: %s", change.Episode.ID, change.Episode.Title, change.Err)
+			continue
+		}
+		if change.Err != nil {
+			ub.forgetStatusMessage(ctx, userID, change.Episode.ID)
+			ub.sendTextMessage(
+				ctx, chatID, "Episode #%s (%s) is now %s: %s\nPlease try creating it again",
+				change.Episode.ID, change.Episode.Title, change.NewStatus, change.Err,
+			)
+			continue
+		}
+		if change.NewStatus == service.EpisodeStatusComplete {
+			ub.forgetStatusMessage(ctx, userID, change.Episode.ID)
+			ub.sendTextMessage(ctx, chatID, "%s", ub.renderEpisodeAnnouncement(ctx, userID, change.Episode))
+			continue
+		}
+		ub.updateStatusMessage(ctx, userID, chatID, change)
 	}
 }
 
+// updateStatusMessage keeps a single message per episode up to date while it's being created,
+// so that progress reports don't flood the chat
+func (ub *UndercastBot) updateStatusMessage(ctx context.Context, userID string, chatID int64, change service.EpisodeStatusChange) {
+	zapFields := []zap.Field{
+		zap.String("user_id", userID),
+		zap.Int64("chat_id", chatID),
+		zap.String("episode_id", change.Episode.ID),
+	}
+
+	if ub.editStatusMessage(ctx, userID, chatID, change) {
+		return
+	}
+
+	msg, err := ub.bot.SendMessage(ctx, &bot.SendMessageParams{
+		ChatID: chatID,
+		Text:   formatStatusMessage(change),
+	})
+	if err != nil {
+		ub.logger.Error("failed to send status message", append(zapFields, zaperr.ToField(err))...)
+		return
+	}
+	if err := ub.repository.SetStatusMessageID(ctx, userID, change.Episode.ID, msg.ID); err != nil {
+		ub.logger.Error("failed to save status message id", append(zapFields, zaperr.ToField(err))...)
+	}
+}
+
+// editStatusMessage updates status message of the episode if one was sent before, and tells whether it did
+func (ub *UndercastBot) editStatusMessage(ctx context.Context, userID string, chatID int64, change service.EpisodeStatusChange) bool {
+	zapFields := []zap.Field{
+		zap.String("user_id", userID),
+		zap.Int64("chat_id", chatID),
+		zap.String("episode_id", change.Episode.ID),
+	}
+
+	messageID, err := ub.repository.GetStatusMessageID(ctx, userID, change.Episode.ID)
+	if err != nil {
+		ub.logger.Error("failed to get status message id", append(zapFields, zaperr.ToField(err))...)
+		return false
+	}
+	if messageID == 0 {
+		return false
+	}
+
+	if _, err := ub.bot.EditMessageText(ctx, &bot.EditMessageTextParams{
+		ChatID:    chatID,
+		MessageID: messageID,
+		Text:      formatStatusMessage(change),
+	}); err != nil && !isMessageNotModified(err) {
+		// message could be deleted by user
+		ub.logger.Warn("failed to edit status message", append(zapFields, zaperr.ToField(err))...)
+		return false
+	}
+	return true
+}
+
+// isProgressOnly tells whether episode is still in the same status, only further along
+func isProgressOnly(change service.EpisodeStatusChange) bool {
+	// complete episodes don't make progress, they stay complete only when re-encoded
+	return change.Err == nil && change.OldStatus == change.NewStatus && change.NewStatus != service.EpisodeStatusComplete
+}
+
+// isMessageNotModified tells whether edit failed only because message already has the same text,
+// which is what the edit was after anyway
+func isMessageNotModified(err error) bool {
+	return strings.Contains(err.Error(), "message is not modified")
+}
+
+func (ub *UndercastBot) forgetStatusMessage(ctx context.Context, userID string, epID string) {
+	if err := ub.repository.DeleteStatusMessageID(ctx, userID, epID); err != nil {
+		ub.logger.Error("failed to delete status message id", zap.String("user_id", userID), zap.String("episode_id", epID), zaperr.ToField(err))
+	}
+}
+
+func formatStatusMessage(change service.EpisodeStatusChange) string {
+	text := fmt.Sprintf("Episode #%s (%s) is now %s", change.Episode.ID, change.Episode.Title, change.NewStatus)
+	if change.Progress > 0 {
+		text += fmt.Sprintf(": %d%%", int(math.Round(change.Progress*100)))
+	}
+	return text
+}
+
 func formatEpisodesCreatedMessage(epIDs []string, defaultFeed *service.Feed) (string, error) {
 	if len(epIDs) == 0 {
 		return "", nil
@@ -311,6 +515,19 @@ To change the feed or name, send /ee_%s`,
 	return strings.Join(strBits, "\n"), nil
 }
 
+// folderSelected tells whether every file in folder with given children is selected, even in nested folders
+func folderSelected(children []*treemultiselect.TreeNode) bool {
+	if len(children) == 0 {
+		return false
+	}
+	for _, n := range children {
+		if !n.AllLeavesSelected() {
+			return false
+		}
+	}
+	return true
+}
+
 func getNTopExtensions(selectedNodes []*treemultiselect.TreeNode, n int) []string {
 	extCounter := make(map[string]int)
 	for _, n := range selectedNodes {
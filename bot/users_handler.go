@@ -0,0 +1,101 @@
+package bot
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/go-telegram/bot"
+	"github.com/go-telegram/bot/models"
+	"github.com/hori-ryota/zaperr"
+	"go.uber.org/zap"
+	"tg-podcastotron/auth"
+)
+
+// usersHandler lists users admin has let in
+func (ub *UndercastBot) usersHandler(ctx context.Context, _ *bot.Bot, update *models.Update) {
+	chatID := ub.extractChatID(update)
+
+	zapFields := []zap.Field{
+		zap.Int64("chat_id", chatID),
+		zap.String("user_id", ub.extractUserID(update)),
+		zap.String("message_text", update.Message.Text),
+	}
+
+	isAdmin, err := ub.auth.IsAdmin(ctx, ub.extractUsername(update))
+	if err != nil {
+		ub.handleError(ctx, chatID, zaperr.Wrap(err, "failed to check if user is admin", zapFields...))
+		return
+	}
+	if !isAdmin {
+		ub.sendTextMessage(ctx, chatID, "unknown command")
+		return
+	}
+
+	users, err := ub.auth.ListUsers(ctx)
+	if err != nil {
+		ub.handleError(ctx, chatID, zaperr.Wrap(err, "failed to list users", zapFields...))
+		return
+	}
+	if len(users) == 0 {
+		ub.sendTextMessage(ctx, chatID, "There are no users yet, share a contact with me to add one")
+		return
+	}
+
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("Users (%d):\n", len(users)))
+	for _, u := range users {
+		sb.WriteString(fmt.Sprintf("%s\n", u.ID))
+	}
+	sb.WriteString("\nUse /removeuser 123456 to revoke access of a user, their feeds and episodes are kept")
+	ub.sendTextMessage(ctx, chatID, sb.String())
+}
+
+// removeUserHandler revokes access of a user, adding the user back restores their feeds and episodes
+func (ub *UndercastBot) removeUserHandler(ctx context.Context, _ *bot.Bot, update *models.Update) {
+	chatID := ub.extractChatID(update)
+
+	zapFields := []zap.Field{
+		zap.Int64("chat_id", chatID),
+		zap.String("user_id", ub.extractUserID(update)),
+		zap.String("message_text", update.Message.Text),
+	}
+
+	isAdmin, err := ub.auth.IsAdmin(ctx, ub.extractUsername(update))
+	if err != nil {
+		ub.handleError(ctx, chatID, zaperr.Wrap(err, "failed to check if user is admin", zapFields...))
+		return
+	}
+	if !isAdmin {
+		ub.sendTextMessage(ctx, chatID, "unknown command")
+		return
+	}
+
+	userIDToRemove, err := ub.parseRemoveUserCmd(update.Message.Text)
+	if err != nil {
+		ub.sendTextMessage(ctx, chatID, "Please specify user ID, like so:\n/removeuser 123456\n\n/users will list all users")
+		return
+	}
+
+	if err := ub.auth.RemoveUser(ctx, userIDToRemove); err != nil {
+		if errors.Is(err, auth.ErrUserNotFound) {
+			ub.sendTextMessage(ctx, chatID, "There is no user %s", userIDToRemove)
+			return
+		}
+		ub.handleError(ctx, chatID, zaperr.Wrap(err, "failed to remove user", zapFields...))
+		return
+	}
+
+	ub.sendTextMessage(ctx, chatID, "User %s removed, share their contact with me to add them back", userIDToRemove)
+}
+
+func (ub *UndercastBot) parseRemoveUserCmd(text string) (userID string, err error) {
+	re := regexp.MustCompile(`^/removeuser\s+(\d+)\s*$`)
+	matches := re.FindStringSubmatch(text)
+	if len(matches) != 2 {
+		return "", fmt.Errorf("invalid command")
+	}
+	return matches[1], nil
+}
@@ -0,0 +1,63 @@
+package bot
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"github.com/go-telegram/bot"
+	"github.com/go-telegram/bot/models"
+	"github.com/hori-ryota/zaperr"
+	"go.uber.org/zap"
+	"tg-podcastotron/service"
+)
+
+const webhookHelpMessage = `Webhook gets a JSON POST request every time an episode is complete.
+Requests are signed with HMAC-SHA256 of request body, keyed by your signing key, in ` + service.WebhookSignatureHeader + ` header.
+
+/webhook https://example.com/hook - set webhook URL
+/webhook off - stop sending requests`
+
+// webhookHandler shows or changes URL user's webhook is sent to
+func (ub *UndercastBot) webhookHandler(ctx context.Context, _ *bot.Bot, update *models.Update) {
+	chatID := ub.extractChatID(update)
+	userID := ub.extractUserID(update)
+
+	zapFields := []zap.Field{
+		zap.Int64("chat_id", chatID),
+		zap.String("user_id", userID),
+		zap.String("message_text", update.Message.Text),
+	}
+
+	webhookURL := strings.TrimSpace(strings.TrimPrefix(update.Message.Text, "/webhook"))
+	switch webhookURL {
+	case "":
+		current, err := ub.service.GetWebhookURL(ctx, userID)
+		if err != nil {
+			ub.handleError(ctx, chatID, zaperr.Wrap(err, "failed to get webhook url", zapFields...))
+			return
+		}
+		if current == "" {
+			current = "not set"
+		}
+		ub.sendTextMessage(ctx, chatID, "Current webhook: %s\nSigning key: %s\n\n%s", current, ub.service.WebhookSigningKey(userID), webhookHelpMessage)
+		return
+	case "off":
+		webhookURL = ""
+	}
+
+	if err := ub.service.SetWebhookURL(ctx, userID, webhookURL); err != nil {
+		if errors.Is(err, service.ErrInvalidWebhookURL) {
+			ub.sendTextMessage(ctx, chatID, "Webhook URL must be an http or https URL\n\n%s", webhookHelpMessage)
+			return
+		}
+		ub.handleError(ctx, chatID, zaperr.Wrap(err, "failed to set webhook url", zapFields...))
+		return
+	}
+
+	if webhookURL == "" {
+		ub.sendTextMessage(ctx, chatID, "Webhook is off")
+		return
+	}
+	ub.sendTextMessage(ctx, chatID, "Webhook set to %s\nSigning key: %s", webhookURL, ub.service.WebhookSigningKey(userID))
+}
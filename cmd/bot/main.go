@@ -6,9 +6,12 @@ import (
 	"database/sql"
 	"encoding/hex"
 	"github.com/hori-ryota/zaperr"
+	_ "github.com/lib/pq"
 	_ "github.com/mattn/go-sqlite3"
 	"os"
 	"os/signal"
+	"strconv"
+	"time"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/config"
@@ -20,7 +23,9 @@ import (
 	"go.uber.org/zap"
 	"tg-podcastotron/auth"
 	"tg-podcastotron/bot"
+	"tg-podcastotron/db/migrations"
 	"tg-podcastotron/mediary"
+	"tg-podcastotron/monitoring"
 	"tg-podcastotron/service"
 	jobsqueue "tg-podcastotron/service/jobs_queue"
 )
@@ -49,9 +54,59 @@ func main() {
 	awsBucketName := mustGetEnv("AWS_BUCKET_NAME")
 	userPathSecret := mustGetEnv("USER_PATH_SECRET") // just some random string, we'll use it to salt user id and take a hash as part of the path
 	defaultFeedTitle := os.Getenv("DEFAULT_FEED_TITLE")
-	dbPath := os.Getenv("DB_PATH")
-	if dbPath == "" {
-		dbPath = "./db/sqlite.db"
+	feedGenerator := os.Getenv("FEED_GENERATOR")
+	compressFeeds := os.Getenv("COMPRESS_FEEDS") == "true"
+	acceptNewUserPathSecret := os.Getenv("ACCEPT_NEW_USER_PATH_SECRET") == "true"
+	dedupeEpisodes := os.Getenv("DEDUPE_EPISODES") == "true"
+	mediaryMaxParallelRequests := mediary.DefaultMaxParallelRequests
+	if value := os.Getenv("MEDIARY_MAX_PARALLEL_REQUESTS"); value != "" {
+		if mediaryMaxParallelRequests, err = strconv.Atoi(value); err != nil {
+			logger.Fatal("error parsing MEDIARY_MAX_PARALLEL_REQUESTS", zaperr.ToField(err))
+		}
+	}
+	presignTTL := service.DefaultPresignTTL
+	if value := os.Getenv("PRESIGN_TTL"); value != "" {
+		if presignTTL, err = time.ParseDuration(value); err != nil {
+			logger.Fatal("error parsing PRESIGN_TTL", zaperr.ToField(err))
+		}
+	}
+	var s3PutOptions []func(*service.PutOptions)
+	if value := os.Getenv("S3_STORAGE_CLASS"); value != "" {
+		s3PutOptions = append(s3PutOptions, service.WithStorageClass(value))
+	}
+	if value := os.Getenv("S3_SSE"); value != "" {
+		s3PutOptions = append(s3PutOptions, service.WithSSE(value))
+	}
+	if os.Getenv("S3_DISABLE_ACL") == "true" {
+		s3PutOptions = append(s3PutOptions, service.WithoutACL())
+	}
+	var minEpisodeFileBytes int64
+	if value := os.Getenv("MIN_EPISODE_FILE_BYTES"); value != "" {
+		if minEpisodeFileBytes, err = strconv.ParseInt(value, 10, 64); err != nil {
+			logger.Fatal("error parsing MIN_EPISODE_FILE_BYTES", zaperr.ToField(err))
+		}
+	}
+	createEpisodesConcurrency := service.DefaultCreateEpisodesConcurrency
+	if value := os.Getenv("CREATE_EPISODES_CONCURRENCY"); value != "" {
+		if createEpisodesConcurrency, err = strconv.Atoi(value); err != nil {
+			logger.Fatal("error parsing CREATE_EPISODES_CONCURRENCY", zaperr.ToField(err))
+		}
+	}
+	monitoringAddr := os.Getenv("MONITORING_ADDR")
+	dbDriver := os.Getenv("DB_DRIVER")
+	if dbDriver == "" {
+		dbDriver = migrations.DialectSqlite
+	}
+	var dbDataSource string
+	switch dbDriver {
+	case migrations.DialectSqlite:
+		if dbDataSource = os.Getenv("DB_PATH"); dbDataSource == "" {
+			dbDataSource = "./db/sqlite.db"
+		}
+	case migrations.DialectPostgres:
+		dbDataSource = mustGetEnv("DB_URL")
+	default:
+		logger.Fatal("unsupported DB_DRIVER, expected sqlite3 or postgres", zap.String("db_driver", dbDriver))
 	}
 	// endregion
 
@@ -117,21 +172,64 @@ func main() {
 	}
 	// endregion
 
-	mediaryService := mediary.New(mediaryURL, logger)
-	db, err := sql.Open("sqlite3", dbPath)
+	mediaryService := mediary.New(mediaryURL, logger, mediary.WithMaxParallelRequests(mediaryMaxParallelRequests))
+	db, err := sql.Open(dbDriver, dbDataSource)
 	if err != nil {
 		logger.Fatal("error opening db", zaperr.ToField(err))
 	}
+	appliedMigrations, err := migrations.Apply(db, dbDriver)
+	if err != nil {
+		logger.Fatal("error migrating db, schema does not match this build", zaperr.ToField(err))
+	}
+	logger.Info("db is up to date", zap.String("db_driver", dbDriver), zap.Int("applied_migrations", appliedMigrations))
 	svcRepo := service.NewSqliteRepository(db)
-	s3Store := service.NewS3Store(s3Client, awsBucketName)
+	botStore := bot.NewSqliteRepository(db)
+	authRepo := auth.NewSqliteRepository(db)
+	if dbDriver == migrations.DialectPostgres {
+		svcRepo = service.NewPostgresRepository(db)
+		botStore = bot.NewPostgresRepository(db)
+		authRepo = auth.NewPostgresRepository(db)
+	}
+	s3Store := service.NewS3Store(s3Client, awsBucketName,
+		service.WithPresignTTL(presignTTL),
+		service.WithDefaultPutOptions(s3PutOptions...),
+	)
 	obfuscateIDs := func(id string) string {
 		hash := sha256.Sum256([]byte(userPathSecret + id))
 		return hex.EncodeToString(hash[:])
 	}
-	svc := service.New(mediaryService, svcRepo, s3Store, jobsQueue, defaultFeedTitle, obfuscateIDs, logger)
+	svcOpts := []func(*service.Options){
+		service.WithMinEpisodeFileBytes(minEpisodeFileBytes),
+		service.WithWebhookSecret(userPathSecret),
+		service.WithCreateEpisodesConcurrency(createEpisodesConcurrency),
+		service.WithRegenerationDebounce(
+			service.NewRedisRegenerationSchedule(bgJobsRedisClient, "undercast:regen_scheduled", service.DefaultRegenerationDebounceWindow),
+			service.DefaultRegenerationDebounceWindow,
+		),
+	}
+	if dedupeEpisodes {
+		svcOpts = append(svcOpts, service.WithEpisodeDedupe())
+	}
+	svc := service.New(mediaryService, svcRepo, s3Store, jobsQueue, defaultFeedTitle, feedGenerator, compressFeeds, obfuscateIDs, logger, svcOpts...)
+	if err := svc.VerifyObfuscationFingerprint(ctx, acceptNewUserPathSecret); err != nil {
+		logger.Fatal("error verifying user path secret", zaperr.ToField(err))
+	}
+
+	if monitoringAddr != "" {
+		monitoringServer := monitoring.New(
+			logger,
+			monitoring.WithCheck("db", monitoring.DBCheck(db)),
+			monitoring.WithCheck("redis", monitoring.RedisCheck(bgJobsRedisClient)),
+			monitoring.WithCollector(monitoring.ServiceCollector(svc)),
+			monitoring.WithCollector(monitoring.JobsQueueCollector(jobsQueue)),
+		)
+		go func() {
+			if err := monitoringServer.ListenAndServe(ctx, monitoringAddr); err != nil {
+				logger.Error("error serving monitoring endpoints", zaperr.ToField(err))
+			}
+		}()
+	}
 
-	botStore := bot.NewSqliteRepository(db)
-	authRepo := auth.NewSqliteRepository(db)
 	botAuthService := auth.New(adminUsername, authRepo, logger)
 	ubot := bot.NewUndercastBot(botToken, botAuthService, botStore, svc, logger)
 	if err := ubot.Start(ctx); err != nil {
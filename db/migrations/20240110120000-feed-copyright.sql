@@ -0,0 +1,5 @@
+-- +migrate Up
+ALTER TABLE feeds ADD COLUMN copyright TEXT NOT NULL DEFAULT '';
+
+-- +migrate Down
+ALTER TABLE feeds DROP COLUMN copyright;
@@ -0,0 +1,5 @@
+-- +migrate Up
+ALTER TABLE feeds ADD COLUMN normalize BOOLEAN NOT NULL DEFAULT FALSE;
+
+-- +migrate Down
+ALTER TABLE feeds DROP COLUMN normalize;
@@ -0,0 +1,5 @@
+-- +migrate Up
+ALTER TABLE episodes ADD COLUMN tags TEXT NOT NULL DEFAULT '';
+
+-- +migrate Down
+ALTER TABLE episodes DROP COLUMN tags;
@@ -0,0 +1,8 @@
+-- +migrate Up
+CREATE TABLE IF NOT EXISTS announcement_templates (
+    user_id TEXT REFERENCES users(id) PRIMARY KEY,
+    template TEXT NOT NULL
+);
+
+-- +migrate Down
+DROP TABLE IF EXISTS announcement_templates;
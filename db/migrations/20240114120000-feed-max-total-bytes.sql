@@ -0,0 +1,5 @@
+-- +migrate Up
+ALTER TABLE feeds ADD COLUMN max_total_bytes INTEGER NOT NULL DEFAULT 0;
+
+-- +migrate Down
+ALTER TABLE feeds DROP COLUMN max_total_bytes;
@@ -0,0 +1,5 @@
+-- +migrate Up
+ALTER TABLE feeds ADD COLUMN image_url TEXT NOT NULL DEFAULT '';
+
+-- +migrate Down
+ALTER TABLE feeds DROP COLUMN image_url;
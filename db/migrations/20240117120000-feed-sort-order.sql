@@ -0,0 +1,5 @@
+-- +migrate Up
+ALTER TABLE feeds ADD COLUMN sort_order INTEGER NOT NULL DEFAULT 0;
+
+-- +migrate Down
+ALTER TABLE feeds DROP COLUMN sort_order;
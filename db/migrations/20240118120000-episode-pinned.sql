@@ -0,0 +1,5 @@
+-- +migrate Up
+ALTER TABLE episodes ADD COLUMN pinned BOOLEAN NOT NULL DEFAULT false;
+
+-- +migrate Down
+ALTER TABLE episodes DROP COLUMN pinned;
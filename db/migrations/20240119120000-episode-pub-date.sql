@@ -0,0 +1,5 @@
+-- +migrate Up
+ALTER TABLE episodes ADD COLUMN pub_date TEXT NOT NULL DEFAULT '';
+
+-- +migrate Down
+ALTER TABLE episodes DROP COLUMN pub_date;
@@ -0,0 +1,5 @@
+-- +migrate Up
+ALTER TABLE episodes ADD COLUMN explicit BOOLEAN;
+
+-- +migrate Down
+ALTER TABLE episodes DROP COLUMN explicit;
@@ -0,0 +1,8 @@
+-- +migrate Up
+CREATE TABLE IF NOT EXISTS webhooks (
+    user_id TEXT REFERENCES users(id) PRIMARY KEY,
+    url TEXT NOT NULL
+);
+
+-- +migrate Down
+DROP TABLE IF EXISTS webhooks;
@@ -0,0 +1,9 @@
+-- +migrate Up
+ALTER TABLE feeds ADD COLUMN ttl_minutes INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE feeds ADD COLUMN skip_hours TEXT NOT NULL DEFAULT '';
+ALTER TABLE feeds ADD COLUMN skip_days TEXT NOT NULL DEFAULT '';
+
+-- +migrate Down
+ALTER TABLE feeds DROP COLUMN skip_days;
+ALTER TABLE feeds DROP COLUMN skip_hours;
+ALTER TABLE feeds DROP COLUMN ttl_minutes;
@@ -0,0 +1,10 @@
+-- +migrate Up
+CREATE TABLE IF NOT EXISTS status_messages (
+    user_id TEXT NOT NULL,
+    episode_id TEXT NOT NULL,
+    message_id INTEGER NOT NULL,
+    PRIMARY KEY (user_id, episode_id)
+);
+
+-- +migrate Down
+DROP TABLE IF EXISTS status_messages;
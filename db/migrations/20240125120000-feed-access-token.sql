@@ -0,0 +1,7 @@
+-- +migrate Up
+ALTER TABLE feeds ADD COLUMN token_protected BOOLEAN NOT NULL DEFAULT FALSE;
+ALTER TABLE feeds ADD COLUMN access_token TEXT NOT NULL DEFAULT '';
+
+-- +migrate Down
+ALTER TABLE feeds DROP COLUMN access_token;
+ALTER TABLE feeds DROP COLUMN token_protected;
@@ -0,0 +1,5 @@
+-- +migrate Up
+ALTER TABLE episodes ADD COLUMN deleted_at TEXT NOT NULL DEFAULT '';
+
+-- +migrate Down
+ALTER TABLE episodes DROP COLUMN deleted_at;
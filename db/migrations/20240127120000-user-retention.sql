@@ -0,0 +1,5 @@
+-- +migrate Up
+ALTER TABLE users ADD COLUMN retention_days INTEGER NOT NULL DEFAULT 0;
+
+-- +migrate Down
+ALTER TABLE users DROP COLUMN retention_days;
@@ -0,0 +1,7 @@
+-- +migrate Up
+ALTER TABLE episodes ADD COLUMN segment_durations TEXT NOT NULL DEFAULT '';
+ALTER TABLE episodes ADD COLUMN chapters_url TEXT NOT NULL DEFAULT '';
+
+-- +migrate Down
+ALTER TABLE episodes DROP COLUMN chapters_url;
+ALTER TABLE episodes DROP COLUMN segment_durations;
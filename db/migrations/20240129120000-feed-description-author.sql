@@ -0,0 +1,7 @@
+-- +migrate Up
+ALTER TABLE feeds ADD COLUMN description TEXT NOT NULL DEFAULT '';
+ALTER TABLE feeds ADD COLUMN author TEXT NOT NULL DEFAULT '';
+
+-- +migrate Down
+ALTER TABLE feeds DROP COLUMN author;
+ALTER TABLE feeds DROP COLUMN description;
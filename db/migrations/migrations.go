@@ -0,0 +1,89 @@
+// Package migrations embeds SQL migrations, so that the bot can bring database schema up to date on its own
+package migrations
+
+import (
+	"database/sql"
+	"embed"
+	"fmt"
+
+	"github.com/hori-ryota/zaperr"
+	"github.com/rubenv/sql-migrate"
+	"go.uber.org/zap"
+)
+
+// Dialects are named after database/sql drivers, each one has its own migrations
+const (
+	DialectSqlite   = "sqlite3"
+	DialectPostgres = "postgres"
+)
+
+//go:embed *.sql
+var sqliteFiles embed.FS
+
+//go:embed postgres/*.sql
+var postgresFiles embed.FS
+
+var sources = map[string]migrate.MigrationSource{
+	DialectSqlite:   &migrate.EmbedFileSystemMigrationSource{FileSystem: sqliteFiles, Root: "."},
+	DialectPostgres: &migrate.EmbedFileSystemMigrationSource{FileSystem: postgresFiles, Root: "postgres"},
+}
+
+// ErrUnknownDialect means there are no migrations for the database driver
+var ErrUnknownDialect = fmt.Errorf("unknown database dialect")
+
+// ErrUnknownMigration means database was migrated by a newer build, which this one can't work with
+var ErrUnknownMigration = fmt.Errorf("database has migration unknown to this build")
+
+// ErrPendingMigration means database schema is behind this build
+var ErrPendingMigration = fmt.Errorf("database is missing migration")
+
+// Apply runs pending migrations and then makes sure database is at exactly the version this build expects.
+// It returns the number of applied migrations
+func Apply(db *sql.DB, dialect string) (int, error) {
+	source, ok := sources[dialect]
+	if !ok {
+		return 0, zaperr.Wrap(ErrUnknownDialect, "", zap.String("dialect", dialect))
+	}
+	applied, err := migrate.Exec(db, dialect, source, migrate.Up)
+	if err != nil {
+		return applied, zaperr.Wrap(err, "failed to apply migrations", zap.Int("applied", applied))
+	}
+	if err := Verify(db, dialect); err != nil {
+		return applied, err
+	}
+	return applied, nil
+}
+
+// Verify checks that database has all embedded migrations applied and nothing else
+func Verify(db *sql.DB, dialect string) error {
+	source, ok := sources[dialect]
+	if !ok {
+		return zaperr.Wrap(ErrUnknownDialect, "", zap.String("dialect", dialect))
+	}
+	known, err := source.FindMigrations()
+	if err != nil {
+		return zaperr.Wrap(err, "failed to find embedded migrations")
+	}
+	records, err := migrate.GetMigrationRecords(db, dialect)
+	if err != nil {
+		return zaperr.Wrap(err, "failed to get applied migrations")
+	}
+
+	knownIDs := make(map[string]struct{}, len(known))
+	for _, m := range known {
+		knownIDs[m.Id] = struct{}{}
+	}
+	appliedIDs := make(map[string]struct{}, len(records))
+	for _, r := range records {
+		if _, ok := knownIDs[r.Id]; !ok {
+			return zaperr.Wrap(ErrUnknownMigration, "", zap.String("migration_id", r.Id))
+		}
+		appliedIDs[r.Id] = struct{}{}
+	}
+	for _, m := range known {
+		if _, ok := appliedIDs[m.Id]; !ok {
+			return zaperr.Wrap(ErrPendingMigration, "", zap.String("migration_id", m.Id))
+		}
+	}
+	return nil
+}
@@ -0,0 +1,81 @@
+package migrations
+
+import (
+	"database/sql"
+	"errors"
+	"testing"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+func TestApply(t *testing.T) {
+	db, err := sql.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// every connection to :memory: gets its own database, so all queries must share a single one
+	db.SetMaxOpenConns(1)
+
+	known, err := sources[DialectSqlite].FindMigrations()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(known) == 0 {
+		t.Fatal("expected migrations to be embedded")
+	}
+
+	// region fresh database gets all migrations
+	if err := Verify(db, DialectSqlite); !errors.Is(err, ErrPendingMigration) {
+		t.Fatalf("expected fresh database to have pending migrations, got %v", err)
+	}
+	applied, err := Apply(db, DialectSqlite)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if applied != len(known) {
+		t.Fatalf("expected %d migrations to be applied, got %d", len(known), applied)
+	}
+	if _, err := db.Exec("SELECT id, user_id, title FROM feeds"); err != nil {
+		t.Fatalf("expected feeds table to exist: %v", err)
+	}
+	// endregion
+
+	// region up to date database is left as is
+	applied, err = Apply(db, DialectSqlite)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if applied != 0 {
+		t.Fatalf("expected no migrations to be applied again, got %d", applied)
+	}
+	// endregion
+
+	// region database migrated by a newer build is rejected
+	if _, err := db.Exec("INSERT INTO gorp_migrations (id, applied_at) VALUES ('99990101000000-from-the-future.sql', CURRENT_TIMESTAMP)"); err != nil {
+		t.Fatal(err)
+	}
+	if err := Verify(db, DialectSqlite); !errors.Is(err, ErrUnknownMigration) {
+		t.Fatalf("expected unknown migration to be detected, got %v", err)
+	}
+	// endregion
+}
+
+func TestSources(t *testing.T) {
+	for _, dialect := range []string{DialectSqlite, DialectPostgres} {
+		known, err := sources[dialect].FindMigrations()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(known) == 0 {
+			t.Errorf("expected %s migrations to be embedded", dialect)
+		}
+	}
+
+	db, err := sql.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Apply(db, "mysql"); !errors.Is(err, ErrUnknownDialect) {
+		t.Fatalf("expected unknown dialect to be rejected, got %v", err)
+	}
+}
@@ -0,0 +1,97 @@
+-- +migrate Up
+-- Mirrors schema built by sqlite migrations, so that repositories can share queries:
+-- times are RFC3339 text, and users are not referenced, as sqlite never enforced it and admin is not stored as a user
+CREATE TABLE IF NOT EXISTS users (
+    id TEXT PRIMARY KEY,
+    retention_days INTEGER NOT NULL DEFAULT 0
+);
+
+CREATE TABLE IF NOT EXISTS chats (
+    user_id TEXT PRIMARY KEY,
+    chat_id BIGINT
+);
+
+CREATE TABLE IF NOT EXISTS feeds (
+    id TEXT NOT NULL,
+    user_id TEXT NOT NULL,
+    title TEXT,
+    url TEXT,
+    is_permanent BOOLEAN NOT NULL DEFAULT FALSE,
+    copyright TEXT NOT NULL DEFAULT '',
+    normalize BOOLEAN NOT NULL DEFAULT FALSE,
+    max_total_bytes BIGINT NOT NULL DEFAULT 0,
+    image_url TEXT NOT NULL DEFAULT '',
+    sort_order INTEGER NOT NULL DEFAULT 0,
+    max_episodes INTEGER NOT NULL DEFAULT 0,
+    ttl_minutes INTEGER NOT NULL DEFAULT 0,
+    skip_hours TEXT NOT NULL DEFAULT '',
+    skip_days TEXT NOT NULL DEFAULT '',
+    token_protected BOOLEAN NOT NULL DEFAULT FALSE,
+    access_token TEXT NOT NULL DEFAULT '',
+    description TEXT NOT NULL DEFAULT '',
+    author TEXT NOT NULL DEFAULT '',
+    PRIMARY KEY (id, user_id)
+);
+
+CREATE TABLE IF NOT EXISTS episodes (
+    id TEXT NOT NULL,
+    user_id TEXT NOT NULL,
+    title TEXT NOT NULL,
+    created_at TEXT NOT NULL,
+    updated_at TEXT NOT NULL,
+    source_url TEXT,
+    source_filepaths TEXT,
+    mediary_id TEXT,
+    url TEXT,
+    status TEXT,
+    duration BIGINT,
+    file_len_bytes BIGINT,
+    format TEXT,
+    storage_key TEXT,
+    tags TEXT NOT NULL DEFAULT '',
+    pinned BOOLEAN NOT NULL DEFAULT FALSE,
+    pub_date TEXT NOT NULL DEFAULT '',
+    explicit BOOLEAN,
+    deleted_at TEXT NOT NULL DEFAULT '',
+    segment_durations TEXT NOT NULL DEFAULT '',
+    chapters_url TEXT NOT NULL DEFAULT '',
+    PRIMARY KEY (id, user_id)
+);
+
+CREATE TABLE IF NOT EXISTS publications (
+    id BIGSERIAL PRIMARY KEY,
+    feed_id TEXT NOT NULL,
+    episode_id TEXT NOT NULL,
+    user_id TEXT NOT NULL,
+    created_at TEXT
+);
+
+CREATE TABLE IF NOT EXISTS local_ids (
+    user_id TEXT PRIMARY KEY,
+    episode_id BIGINT NOT NULL,
+    feed_id BIGINT NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS announcement_templates (
+    user_id TEXT PRIMARY KEY,
+    template TEXT NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS settings (
+    key TEXT PRIMARY KEY,
+    value TEXT NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS webhooks (
+    user_id TEXT PRIMARY KEY,
+    url TEXT NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS status_messages (
+    user_id TEXT NOT NULL,
+    episode_id TEXT NOT NULL,
+    message_id BIGINT NOT NULL,
+    PRIMARY KEY (user_id, episode_id)
+);
+
+-- +migrate Down
@@ -0,0 +1,7 @@
+-- +migrate Up
+ALTER TABLE episodes ADD COLUMN content_hash TEXT NOT NULL DEFAULT '';
+CREATE INDEX IF NOT EXISTS episodes_user_id_content_hash_idx ON episodes (user_id, content_hash);
+
+-- +migrate Down
+DROP INDEX IF EXISTS episodes_user_id_content_hash_idx;
+ALTER TABLE episodes DROP COLUMN content_hash;
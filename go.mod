@@ -11,9 +11,9 @@ require (
 	github.com/go-telegram/bot v0.8.0
 	github.com/google/uuid v1.3.1
 	github.com/hori-ryota/zaperr v0.0.0-20210301022522-bfd0551d7f64
-	github.com/jbub/podcasts v0.2.0
 	github.com/jmoiron/sqlx v1.3.5
 	github.com/joho/godotenv v1.5.1
+	github.com/lib/pq v1.10.9
 	github.com/mattn/go-sqlite3 v1.14.24
 	github.com/redis/go-redis/v9 v9.5.1
 	github.com/rubenv/sql-migrate v1.7.0
@@ -7,8 +7,11 @@ import (
 	"fmt"
 	"github.com/hori-ryota/zaperr"
 	"io"
+	"net"
 	"net/http"
+	"net/url"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"go.uber.org/zap"
@@ -20,18 +23,84 @@ type Service interface {
 	FetchMetadataLongPolling(ctx context.Context, mediaURL string) (*Metadata, error)
 	CreateUploadJob(ctx context.Context, params *CreateUploadJobParams) (jobID string, err error)
 	FetchJobStatusMap(ctx context.Context, jobIDs []string) (map[string]*JobStatus, error)
+	FetchJobStatusBatch(ctx context.Context, jobIDs []string) (map[string]*JobStatus, error)
+	CancelJob(ctx context.Context, jobID string) error
 }
 
-func New(mediaryURL string, logger *zap.Logger) Service {
+// ErrServiceUnavailable means mediary could not be reached or failed on its side,
+// as opposed to rejecting the request, so the same request may succeed later
+var ErrServiceUnavailable = fmt.Errorf("mediary service is unavailable")
+
+const DefaultMaxParallelRequests = 8
+
+// defaultRequestTimeout bounds a single request, generous since metadata is fetched by long polling
+const defaultRequestTimeout = 10 * time.Minute
+
+type Options struct {
+	// MaxParallelRequests limits number of simultaneous requests made while fetching job statuses
+	MaxParallelRequests int
+	// HTTPClient is used for all requests to mediary, a pooled client is created when nil
+	HTTPClient *http.Client
+}
+
+func WithMaxParallelRequests(maxParallelRequests int) func(*Options) {
+	return func(o *Options) {
+		o.MaxParallelRequests = maxParallelRequests
+	}
+}
+
+func WithHTTPClient(httpClient *http.Client) func(*Options) {
+	return func(o *Options) {
+		o.HTTPClient = httpClient
+	}
+}
+
+func New(mediaryURL string, logger *zap.Logger, opts ...func(*Options)) Service {
+	options := &Options{MaxParallelRequests: DefaultMaxParallelRequests}
+	for _, opt := range opts {
+		opt(options)
+	}
+	if options.MaxParallelRequests <= 0 {
+		options.MaxParallelRequests = DefaultMaxParallelRequests
+	}
+	if options.HTTPClient == nil {
+		options.HTTPClient = newHTTPClient(options.MaxParallelRequests)
+	}
+
 	return &service{
-		logger:  logger,
-		baseURL: mediaryURL,
+		logger:              logger,
+		baseURL:             mediaryURL,
+		maxParallelRequests: options.MaxParallelRequests,
+		httpClient:          options.HTTPClient,
+	}
+}
+
+// newHTTPClient keeps enough idle connections to mediary for all parallel requests to reuse them
+func newHTTPClient(maxParallelRequests int) *http.Client {
+	return &http.Client{
+		Transport: &http.Transport{
+			Proxy: http.ProxyFromEnvironment,
+			DialContext: (&net.Dialer{
+				Timeout:   10 * time.Second,
+				KeepAlive: 30 * time.Second,
+			}).DialContext,
+			MaxIdleConns:          100,
+			MaxIdleConnsPerHost:   maxParallelRequests * 2,
+			IdleConnTimeout:       90 * time.Second,
+			TLSHandshakeTimeout:   10 * time.Second,
+			ExpectContinueTimeout: 1 * time.Second,
+		},
+		Timeout: defaultRequestTimeout,
 	}
 }
 
 type service struct {
-	logger  *zap.Logger
-	baseURL string
+	logger              *zap.Logger
+	baseURL             string
+	maxParallelRequests int
+	httpClient          *http.Client
+
+	batchStatusUnsupported atomic.Bool // set once mediary turns out to have no batch job status route
 }
 
 type Metadata struct {
@@ -61,9 +130,11 @@ const (
 )
 
 type ConcatenateJobParams struct {
-	Variants   []string `json:"variants"`
-	AudioCodec string   `json:"audioCodec"`
-	UploadURL  string   `json:"uploadUrl"`
+	Variants    []string `json:"variants"`
+	AudioCodec  string   `json:"audioCodec"`
+	BitrateKbps int      `json:"bitrateKbps,omitempty"`
+	UploadURL   string   `json:"uploadUrl"`
+	Normalize   bool     `json:"normalize,omitempty"` // whether loudness of concatenated tracks should be normalized
 }
 
 type UploadOriginalJobParams struct {
@@ -76,6 +147,9 @@ type JobStatus struct {
 	Status              JobStatusName `json:"status"`
 	ResultMediaDuration time.Duration `json:"result_media_duration"`
 	ResultFileBytes     int64         `json:"result_file_bytes"`
+	// SegmentDurations are durations of concatenated files in order, empty unless there was more than one
+	SegmentDurations []time.Duration `json:"segment_durations,omitempty"`
+	Progress         float64         `json:"progress"` // from 0 to 1, progress of the current status
 }
 
 type JobStatusName string
@@ -87,14 +161,19 @@ const (
 	JobStatusProcessing  JobStatusName = "processing"
 	JobStatusUploading   JobStatusName = "uploading"
 	JobStatusComplete    JobStatusName = "complete"
+	JobStatusFailed      JobStatusName = "failed"
 )
 
 func (svc *service) IsValidURL(ctx context.Context, mediaURL string) (bool, error) {
 	// TODO: should not depend on metadata endpoint, implement /is_valid in mediary
-	fullURL := fmt.Sprintf("%s/metadata/long-polling?url=%s", svc.baseURL, mediaURL)
+	fullURL := svc.metadataURL(mediaURL)
 	svc.logger.Debug("checking if URL is valid", zap.String("url", fullURL))
 
-	resp, err := http.Get(fullURL)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, fullURL, nil)
+	if err != nil {
+		return false, fmt.Errorf("failed to create request: %w", err)
+	}
+	resp, err := svc.do(req)
 	if err != nil {
 		return false, fmt.Errorf("failed to call mediary API: %w", err)
 	}
@@ -113,7 +192,7 @@ func (svc *service) IsValidURL(ctx context.Context, mediaURL string) (bool, erro
 }
 
 func (svc *service) FetchMetadataLongPolling(ctx context.Context, mediaURL string) (*Metadata, error) {
-	fullURL := fmt.Sprintf("%s/metadata/long-polling?url=%s", svc.baseURL, mediaURL)
+	fullURL := svc.metadataURL(mediaURL)
 	svc.logger.Debug("fetching metadata", zap.String("url", fullURL))
 
 	bodyBytes, err := json.Marshal(map[string]string{
@@ -123,8 +202,12 @@ func (svc *service) FetchMetadataLongPolling(ctx context.Context, mediaURL strin
 		return nil, fmt.Errorf("failed to marshal payload: %w", err)
 	}
 
-	reqBody := bytes.NewBufferString(string(bodyBytes))
-	resp, err := http.Post(fullURL, "application/json", reqBody)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, fullURL, bytes.NewReader(bodyBytes))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := svc.do(req)
 	if err != nil {
 		return nil, fmt.Errorf("failed to call mediary API: %w", err)
 	}
@@ -156,7 +239,12 @@ func (svc *service) CreateUploadJob(ctx context.Context, params *CreateUploadJob
 		return "", fmt.Errorf("failed to marshal payload: %w", err)
 	}
 
-	resp, err := http.Post(fullURL, "application/json", bytes.NewReader(payload))
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, fullURL, bytes.NewReader(payload))
+	if err != nil {
+		return "", fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := svc.do(req)
 	if err != nil {
 		return "", fmt.Errorf("failed to call mediary API: %w", err)
 	}
@@ -182,35 +270,34 @@ func (svc *service) CreateUploadJob(ctx context.Context, params *CreateUploadJob
 	return respBody.ID, nil
 }
 
+// FetchJobStatusMap fetches job statuses one by one, making at most maxParallelRequests requests at a time
 func (svc *service) FetchJobStatusMap(ctx context.Context, jobIDs []string) (map[string]*JobStatus, error) {
-	// TODO: implement bulk job status fetching on mediary side
+	jobIDsChan := make(chan string, len(jobIDs))
+	for _, jobID := range jobIDs {
+		jobIDsChan <- jobID
+	}
+	close(jobIDsChan)
+
+	workersCount := svc.maxParallelRequests
+	if len(jobIDs) < workersCount {
+		workersCount = len(jobIDs)
+	}
+
 	var wg sync.WaitGroup
 	jobStatusChan := make(chan *JobStatus, len(jobIDs))
-	for _, jobID := range jobIDs {
+	for i := 0; i < workersCount; i++ {
 		wg.Add(1)
 
-		go func(jobID string) {
+		go func() {
 			defer wg.Done()
-
-			fullURL := fmt.Sprintf("%s/jobs/%s", svc.baseURL, jobID)
-			svc.logger.Debug("fetching job status", zap.String("url", fullURL))
-			req, err := http.NewRequestWithContext(ctx, http.MethodGet, fullURL, nil)
-			if err != nil {
-				svc.logger.Error("failed to create request", zaperr.ToField(err))
-				return
-			}
-			resp, err := http.DefaultClient.Do(req)
-			if err != nil {
-				svc.logger.Error("failed to call mediary API", zaperr.ToField(err))
-				return
+			for jobID := range jobIDsChan {
+				if jobStatus, err := svc.fetchJobStatus(ctx, jobID); err == nil {
+					jobStatusChan <- jobStatus
+				} else {
+					svc.logger.Error("failed to fetch job status", zap.String("job_id", jobID), zaperr.ToField(err))
+				}
 			}
-			var jobStatus JobStatus
-			if err := json.NewDecoder(resp.Body).Decode(&jobStatus); err != nil {
-				svc.logger.Error("error decoding mediary response", zaperr.ToField(err))
-				return
-			}
-			jobStatusChan <- &jobStatus
-		}(jobID)
+		}()
 	}
 
 	wg.Wait()
@@ -223,3 +310,117 @@ func (svc *service) FetchJobStatusMap(ctx context.Context, jobIDs []string) (map
 
 	return jobStatusMap, nil
 }
+
+// FetchJobStatusBatch fetches statuses of all jobs in a single request.
+// Older mediary versions lack the batch route, in which case statuses are fetched one by one
+func (svc *service) FetchJobStatusBatch(ctx context.Context, jobIDs []string) (map[string]*JobStatus, error) {
+	if len(jobIDs) == 0 {
+		return map[string]*JobStatus{}, nil
+	}
+	if svc.batchStatusUnsupported.Load() {
+		return svc.FetchJobStatusMap(ctx, jobIDs)
+	}
+
+	fullURL := fmt.Sprintf("%s/jobs/status", svc.baseURL)
+	svc.logger.Debug("fetching job statuses", zap.String("url", fullURL), zap.Int("jobs_count", len(jobIDs)))
+
+	payload, err := json.Marshal(map[string][]string{"ids": jobIDs})
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal payload: %w", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, fullURL, bytes.NewReader(payload))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := svc.do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to call mediary API: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		svc.logger.Info("mediary does not support batch job status, falling back to fetching jobs one by one")
+		svc.batchStatusUnsupported.Store(true)
+		return svc.FetchJobStatusMap(ctx, jobIDs)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("mediary returned status code %d", resp.StatusCode)
+	}
+
+	jobStatusMap := make(map[string]*JobStatus, len(jobIDs))
+	if err := json.NewDecoder(resp.Body).Decode(&jobStatusMap); err != nil {
+		return nil, fmt.Errorf("error decoding mediary response: %w", err)
+	}
+	for jobID, jobStatus := range jobStatusMap {
+		if jobStatus == nil {
+			delete(jobStatusMap, jobID)
+		} else if jobStatus.Id == "" {
+			jobStatus.Id = jobID
+		}
+	}
+	return jobStatusMap, nil
+}
+
+// CancelJob stops a job which is not complete yet. Jobs mediary doesn't know about are considered cancelled already
+func (svc *service) CancelJob(ctx context.Context, jobID string) error {
+	fullURL := fmt.Sprintf("%s/jobs/%s", svc.baseURL, url.PathEscape(jobID))
+	svc.logger.Debug("cancelling job", zap.String("url", fullURL))
+	req, err := http.NewRequestWithContext(ctx, http.MethodDelete, fullURL, nil)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	resp, err := svc.do(req)
+	if err != nil {
+		return fmt.Errorf("failed to call mediary API: %w", err)
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK, http.StatusAccepted, http.StatusNoContent, http.StatusNotFound:
+		return nil
+	default:
+		return fmt.Errorf("mediary returned status code %d", resp.StatusCode)
+	}
+}
+
+func (svc *service) fetchJobStatus(ctx context.Context, jobID string) (*JobStatus, error) {
+	fullURL := fmt.Sprintf("%s/jobs/%s", svc.baseURL, url.PathEscape(jobID))
+	svc.logger.Debug("fetching job status", zap.String("url", fullURL))
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, fullURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	resp, err := svc.do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to call mediary API: %w", err)
+	}
+	defer resp.Body.Close()
+
+	var jobStatus JobStatus
+	if err := json.NewDecoder(resp.Body).Decode(&jobStatus); err != nil {
+		return nil, fmt.Errorf("error decoding mediary response: %w", err)
+	}
+	return &jobStatus, nil
+}
+
+// do sends request to mediary, reporting connection failures and server errors as ErrServiceUnavailable
+func (svc *service) do(req *http.Request) (*http.Response, error) {
+	resp, err := svc.httpClient.Do(req)
+	if err != nil {
+		if req.Context().Err() != nil {
+			return nil, err // request was cancelled by caller, mediary is not to blame
+		}
+		return nil, fmt.Errorf("%w: %w", ErrServiceUnavailable, err)
+	}
+	if resp.StatusCode >= http.StatusInternalServerError {
+		resp.Body.Close()
+		return nil, fmt.Errorf("%w: mediary returned status code %d", ErrServiceUnavailable, resp.StatusCode)
+	}
+	return resp, nil
+}
+
+// metadataURL builds metadata endpoint URL, escaping media URL since magnet links are full of & and =
+func (svc *service) metadataURL(mediaURL string) string {
+	return fmt.Sprintf("%s/metadata/long-polling?%s", svc.baseURL, url.Values{"url": {mediaURL}}.Encode())
+}
@@ -0,0 +1,294 @@
+package mediary
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+func TestService__FetchJobStatusMap__BoundedParallelism(t *testing.T) {
+	const maxParallelRequests = 3
+
+	var mu sync.Mutex
+	inFlight, maxInFlight := 0, 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		inFlight++
+		if inFlight > maxInFlight {
+			maxInFlight = inFlight
+		}
+		mu.Unlock()
+
+		time.Sleep(20 * time.Millisecond)
+
+		mu.Lock()
+		inFlight--
+		mu.Unlock()
+
+		jobID := strings.TrimPrefix(r.URL.Path, "/jobs/")
+		_, _ = fmt.Fprintf(w, `{"id": "%s", "status": "complete"}`, jobID)
+	}))
+	defer srv.Close()
+
+	svc := New(srv.URL, zap.NewNop(), WithMaxParallelRequests(maxParallelRequests))
+
+	jobIDs := make([]string, 20)
+	for i := range jobIDs {
+		jobIDs[i] = fmt.Sprintf("job-%d", i)
+	}
+
+	jobStatusMap, err := svc.FetchJobStatusMap(context.Background(), jobIDs)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(jobStatusMap) != len(jobIDs) {
+		t.Fatalf("expected statuses of all %d jobs, got %d", len(jobIDs), len(jobStatusMap))
+	}
+	if jobStatusMap["job-7"] == nil || jobStatusMap["job-7"].Status != JobStatusComplete {
+		t.Fatalf("expected job-7 to be complete, got %+v", jobStatusMap["job-7"])
+	}
+	if maxInFlight > maxParallelRequests {
+		t.Fatalf("expected at most %d requests in flight, got %d", maxParallelRequests, maxInFlight)
+	}
+}
+
+func TestService__FetchJobStatusBatch(t *testing.T) {
+	var batchRequestedIDs []string
+	var singleRequestsCount int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPost && r.URL.Path == "/jobs/status" {
+			var body struct {
+				IDs []string `json:"ids"`
+			}
+			if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			batchRequestedIDs = body.IDs
+			_, _ = fmt.Fprint(w, `{"job-1": {"id": "job-1", "status": "complete"}, "job-2": {"id": "job-2", "status": "processing"}}`)
+			return
+		}
+		singleRequestsCount++
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer srv.Close()
+
+	svc := New(srv.URL, zap.NewNop())
+
+	jobStatusMap, err := svc.FetchJobStatusBatch(context.Background(), []string{"job-1", "job-2"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(batchRequestedIDs) != 2 {
+		t.Fatalf("expected both job ids to be requested at once, got %v", batchRequestedIDs)
+	}
+	if singleRequestsCount != 0 {
+		t.Fatalf("expected no per-job requests, got %d", singleRequestsCount)
+	}
+	if jobStatusMap["job-1"].Status != JobStatusComplete || jobStatusMap["job-2"].Status != JobStatusProcessing {
+		t.Fatalf("unexpected job statuses: %+v", jobStatusMap)
+	}
+}
+
+func TestService__FetchJobStatus__Progress(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/jobs/status" {
+			_, _ = fmt.Fprint(w, `{"job-1": {"id": "job-1", "status": "downloading", "progress": 0.42}, "job-2": {"id": "job-2", "status": "accepted"}}`)
+			return
+		}
+		_, _ = fmt.Fprint(w, `{"id": "job-1", "status": "processing", "progress": 0.75}`)
+	}))
+	defer srv.Close()
+
+	svc := New(srv.URL, zap.NewNop())
+
+	batchStatusMap, err := svc.FetchJobStatusBatch(context.Background(), []string{"job-1", "job-2"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if batchStatusMap["job-1"].Progress != 0.42 {
+		t.Errorf("expected batch status progress to be 0.42, got %v", batchStatusMap["job-1"].Progress)
+	}
+	if batchStatusMap["job-2"].Progress != 0 {
+		t.Errorf("expected missing progress to be 0, got %v", batchStatusMap["job-2"].Progress)
+	}
+
+	jobStatusMap, err := svc.FetchJobStatusMap(context.Background(), []string{"job-1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if jobStatusMap["job-1"].Progress != 0.75 {
+		t.Errorf("expected job status progress to be 0.75, got %v", jobStatusMap["job-1"].Progress)
+	}
+}
+
+func TestService__FetchJobStatusBatch__Fallback(t *testing.T) {
+	var mu sync.Mutex
+	var batchRequestsCount int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/jobs/status" {
+			mu.Lock()
+			batchRequestsCount++
+			mu.Unlock()
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		jobID := strings.TrimPrefix(r.URL.Path, "/jobs/")
+		_, _ = fmt.Fprintf(w, `{"id": "%s", "status": "complete"}`, jobID)
+	}))
+	defer srv.Close()
+
+	svc := New(srv.URL, zap.NewNop())
+
+	for i := 0; i < 2; i++ {
+		jobStatusMap, err := svc.FetchJobStatusBatch(context.Background(), []string{"job-1", "job-2"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(jobStatusMap) != 2 || jobStatusMap["job-2"].Status != JobStatusComplete {
+			t.Fatalf("expected statuses to be fetched one by one, got %+v", jobStatusMap)
+		}
+	}
+
+	// once mediary turned out to lack the batch route, it is not asked again
+	if batchRequestsCount != 1 {
+		t.Fatalf("expected batch route to be tried once, got %d", batchRequestsCount)
+	}
+}
+
+func TestService__MediaURLEncoding(t *testing.T) {
+	magnet := "magnet:?xt=urn:btih:c9e15763f722f23e98a29decdfae341b98d53056&dn=Some Audiobook&tr=udp://tracker.example.com:1337/announce&tr=udp://other.example.com:80"
+
+	var receivedURLs []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		receivedURLs = append(receivedURLs, r.URL.Query().Get("url"))
+		_, _ = fmt.Fprint(w, `{"name": "Some Audiobook"}`)
+	}))
+	defer srv.Close()
+
+	svc := New(srv.URL, zap.NewNop())
+
+	isValid, err := svc.IsValidURL(context.Background(), magnet)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !isValid {
+		t.Fatalf("expected magnet to be valid")
+	}
+	if _, err := svc.FetchMetadataLongPolling(context.Background(), magnet); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(receivedURLs) != 2 {
+		t.Fatalf("expected 2 requests, got %d", len(receivedURLs))
+	}
+	for _, received := range receivedURLs {
+		if received != magnet {
+			t.Fatalf("expected mediary to receive magnet intact, got %q", received)
+		}
+	}
+}
+
+func TestService__ServiceUnavailable(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer srv.Close()
+
+	// nothing listens on the address of a closed server
+	closedSrv := httptest.NewServer(http.NotFoundHandler())
+	closedSrv.Close()
+
+	for _, tc := range []struct {
+		name       string
+		mediaryURL string
+	}{
+		{name: "503", mediaryURL: srv.URL},
+		{name: "connection refused", mediaryURL: closedSrv.URL},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			svc := New(tc.mediaryURL, zap.NewNop())
+
+			if _, err := svc.IsValidURL(context.Background(), "https://example.com/audio"); !errors.Is(err, ErrServiceUnavailable) {
+				t.Errorf("expected IsValidURL to return ErrServiceUnavailable, got %v", err)
+			}
+			if _, err := svc.FetchMetadataLongPolling(context.Background(), "https://example.com/audio"); !errors.Is(err, ErrServiceUnavailable) {
+				t.Errorf("expected FetchMetadataLongPolling to return ErrServiceUnavailable, got %v", err)
+			}
+		})
+	}
+
+	t.Run("invalid URL is not unavailability", func(t *testing.T) {
+		badRequestSrv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusBadRequest)
+		}))
+		defer badRequestSrv.Close()
+
+		isValid, err := New(badRequestSrv.URL, zap.NewNop()).IsValidURL(context.Background(), "not-a-url")
+		if err != nil || isValid {
+			t.Fatalf("expected URL to be invalid without error, got %v, %v", isValid, err)
+		}
+	})
+}
+
+type countingRoundTripper struct {
+	count atomic.Int32
+}
+
+func (rt *countingRoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	rt.count.Add(1)
+	return http.DefaultTransport.RoundTrip(req)
+}
+
+func TestService__HTTPClient(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/jobs" && r.Method == http.MethodPost:
+			w.WriteHeader(http.StatusAccepted)
+			_, _ = fmt.Fprint(w, `{"id": "job-1", "status": "accepted"}`)
+		case r.URL.Path == "/jobs/job-1" && r.Method == http.MethodDelete:
+			w.WriteHeader(http.StatusNoContent)
+		case r.URL.Path == "/jobs/job-1":
+			_, _ = fmt.Fprint(w, `{"id": "job-1", "status": "complete"}`)
+		default:
+			_, _ = fmt.Fprint(w, `{"name": "Some Audiobook"}`)
+		}
+	}))
+	defer srv.Close()
+
+	roundTripper := &countingRoundTripper{}
+	svc := New(srv.URL, zap.NewNop(), WithHTTPClient(&http.Client{Transport: roundTripper}))
+
+	ctx := context.Background()
+	if _, err := svc.IsValidURL(ctx, "https://example.com/some.mp3"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := svc.FetchMetadataLongPolling(ctx, "https://example.com/some.mp3"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := svc.CreateUploadJob(ctx, &CreateUploadJobParams{URL: "https://example.com/some.mp3"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := svc.FetchJobStatusMap(ctx, []string{"job-1"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := svc.CancelJob(ctx, "job-1"); err != nil {
+		t.Fatal(err)
+	}
+
+	if count := roundTripper.count.Load(); count != 5 {
+		t.Fatalf("expected all 5 requests to go through injected client, got %d", count)
+	}
+}
@@ -19,9 +19,15 @@ var _ mediary.Service = &ServiceMock{}
 //
 //		// make and configure a mocked mediary.Service
 //		mockedService := &ServiceMock{
+//			CancelJobFunc: func(ctx context.Context, jobID string) error {
+//				panic("mock out the CancelJob method")
+//			},
 //			CreateUploadJobFunc: func(ctx context.Context, params *mediary.CreateUploadJobParams) (string, error) {
 //				panic("mock out the CreateUploadJob method")
 //			},
+//			FetchJobStatusBatchFunc: func(ctx context.Context, jobIDs []string) (map[string]*mediary.JobStatus, error) {
+//				panic("mock out the FetchJobStatusBatch method")
+//			},
 //			FetchJobStatusMapFunc: func(ctx context.Context, jobIDs []string) (map[string]*mediary.JobStatus, error) {
 //				panic("mock out the FetchJobStatusMap method")
 //			},
@@ -38,9 +44,15 @@ var _ mediary.Service = &ServiceMock{}
 //
 //	}
 type ServiceMock struct {
+	// CancelJobFunc mocks the CancelJob method.
+	CancelJobFunc func(ctx context.Context, jobID string) error
+
 	// CreateUploadJobFunc mocks the CreateUploadJob method.
 	CreateUploadJobFunc func(ctx context.Context, params *mediary.CreateUploadJobParams) (string, error)
 
+	// FetchJobStatusBatchFunc mocks the FetchJobStatusBatch method.
+	FetchJobStatusBatchFunc func(ctx context.Context, jobIDs []string) (map[string]*mediary.JobStatus, error)
+
 	// FetchJobStatusMapFunc mocks the FetchJobStatusMap method.
 	FetchJobStatusMapFunc func(ctx context.Context, jobIDs []string) (map[string]*mediary.JobStatus, error)
 
@@ -52,6 +64,13 @@ type ServiceMock struct {
 
 	// calls tracks calls to the methods.
 	calls struct {
+		// CancelJob holds details about calls to the CancelJob method.
+		CancelJob []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// JobID is the jobID argument value.
+			JobID string
+		}
 		// CreateUploadJob holds details about calls to the CreateUploadJob method.
 		CreateUploadJob []struct {
 			// Ctx is the ctx argument value.
@@ -59,6 +78,13 @@ type ServiceMock struct {
 			// Params is the params argument value.
 			Params *mediary.CreateUploadJobParams
 		}
+		// FetchJobStatusBatch holds details about calls to the FetchJobStatusBatch method.
+		FetchJobStatusBatch []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// JobIDs is the jobIDs argument value.
+			JobIDs []string
+		}
 		// FetchJobStatusMap holds details about calls to the FetchJobStatusMap method.
 		FetchJobStatusMap []struct {
 			// Ctx is the ctx argument value.
@@ -81,12 +107,50 @@ type ServiceMock struct {
 			MediaURL string
 		}
 	}
+	lockCancelJob                sync.RWMutex
 	lockCreateUploadJob          sync.RWMutex
+	lockFetchJobStatusBatch      sync.RWMutex
 	lockFetchJobStatusMap        sync.RWMutex
 	lockFetchMetadataLongPolling sync.RWMutex
 	lockIsValidURL               sync.RWMutex
 }
 
+// CancelJob calls CancelJobFunc.
+func (mock *ServiceMock) CancelJob(ctx context.Context, jobID string) error {
+	if mock.CancelJobFunc == nil {
+		panic("ServiceMock.CancelJobFunc: method is nil but Service.CancelJob was just called")
+	}
+	callInfo := struct {
+		Ctx   context.Context
+		JobID string
+	}{
+		Ctx:   ctx,
+		JobID: jobID,
+	}
+	mock.lockCancelJob.Lock()
+	mock.calls.CancelJob = append(mock.calls.CancelJob, callInfo)
+	mock.lockCancelJob.Unlock()
+	return mock.CancelJobFunc(ctx, jobID)
+}
+
+// CancelJobCalls gets all the calls that were made to CancelJob.
+// Check the length with:
+//
+//	len(mockedService.CancelJobCalls())
+func (mock *ServiceMock) CancelJobCalls() []struct {
+	Ctx   context.Context
+	JobID string
+} {
+	var calls []struct {
+		Ctx   context.Context
+		JobID string
+	}
+	mock.lockCancelJob.RLock()
+	calls = mock.calls.CancelJob
+	mock.lockCancelJob.RUnlock()
+	return calls
+}
+
 // CreateUploadJob calls CreateUploadJobFunc.
 func (mock *ServiceMock) CreateUploadJob(ctx context.Context, params *mediary.CreateUploadJobParams) (string, error) {
 	if mock.CreateUploadJobFunc == nil {
@@ -123,6 +187,42 @@ func (mock *ServiceMock) CreateUploadJobCalls() []struct {
 	return calls
 }
 
+// FetchJobStatusBatch calls FetchJobStatusBatchFunc.
+func (mock *ServiceMock) FetchJobStatusBatch(ctx context.Context, jobIDs []string) (map[string]*mediary.JobStatus, error) {
+	if mock.FetchJobStatusBatchFunc == nil {
+		panic("ServiceMock.FetchJobStatusBatchFunc: method is nil but Service.FetchJobStatusBatch was just called")
+	}
+	callInfo := struct {
+		Ctx    context.Context
+		JobIDs []string
+	}{
+		Ctx:    ctx,
+		JobIDs: jobIDs,
+	}
+	mock.lockFetchJobStatusBatch.Lock()
+	mock.calls.FetchJobStatusBatch = append(mock.calls.FetchJobStatusBatch, callInfo)
+	mock.lockFetchJobStatusBatch.Unlock()
+	return mock.FetchJobStatusBatchFunc(ctx, jobIDs)
+}
+
+// FetchJobStatusBatchCalls gets all the calls that were made to FetchJobStatusBatch.
+// Check the length with:
+//
+//	len(mockedService.FetchJobStatusBatchCalls())
+func (mock *ServiceMock) FetchJobStatusBatchCalls() []struct {
+	Ctx    context.Context
+	JobIDs []string
+} {
+	var calls []struct {
+		Ctx    context.Context
+		JobIDs []string
+	}
+	mock.lockFetchJobStatusBatch.RLock()
+	calls = mock.calls.FetchJobStatusBatch
+	mock.lockFetchJobStatusBatch.RUnlock()
+	return calls
+}
+
 // FetchJobStatusMap calls FetchJobStatusMapFunc.
 func (mock *ServiceMock) FetchJobStatusMap(ctx context.Context, jobIDs []string) (map[string]*mediary.JobStatus, error) {
 	if mock.FetchJobStatusMapFunc == nil {
@@ -0,0 +1,290 @@
+package monitoring
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/hori-ryota/zaperr"
+	"github.com/redis/go-redis/v9"
+	"go.uber.org/zap"
+	"tg-podcastotron/service"
+)
+
+const checkTimeout = 5 * time.Second
+
+// Check returns an error when a dependency the bot can't work without is unhealthy
+type Check func(ctx context.Context) error
+
+// Metric is a single sample, written out in Prometheus text exposition format
+type Metric struct {
+	Name   string
+	Help   string
+	Type   string // gauge or counter
+	Labels map[string]string
+	Value  float64
+}
+
+// Collector returns current values of metrics, it's called on every scrape
+type Collector func(ctx context.Context) ([]Metric, error)
+
+// Server exposes /healthz and /metrics for deployment monitoring. Everything it serves is read-only
+type Server struct {
+	checks     map[string]Check
+	collectors []Collector
+	logger     *zap.Logger
+}
+
+type Options struct {
+	Checks     map[string]Check
+	Collectors []Collector
+}
+
+// WithCheck adds a named check to /healthz
+func WithCheck(name string, check Check) func(*Options) {
+	return func(o *Options) {
+		o.Checks[name] = check
+	}
+}
+
+// WithCollector adds metrics to /metrics
+func WithCollector(collector Collector) func(*Options) {
+	return func(o *Options) {
+		o.Collectors = append(o.Collectors, collector)
+	}
+}
+
+func New(logger *zap.Logger, opts ...func(*Options)) *Server {
+	options := &Options{Checks: make(map[string]Check)}
+	for _, opt := range opts {
+		opt(options)
+	}
+
+	return &Server{
+		checks:     options.Checks,
+		collectors: options.Collectors,
+		logger:     logger,
+	}
+}
+
+func (s *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/healthz", s.healthzHandler)
+	mux.HandleFunc("/metrics", s.metricsHandler)
+	return mux
+}
+
+// ListenAndServe serves until ctx is done
+func (s *Server) ListenAndServe(ctx context.Context, addr string) error {
+	httpServer := &http.Server{
+		Addr:              addr,
+		Handler:           s.Handler(),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), checkTimeout)
+		defer cancel()
+		if err := httpServer.Shutdown(shutdownCtx); err != nil {
+			s.logger.Error("failed to shut down monitoring server", zaperr.ToField(err))
+		}
+	}()
+
+	s.logger.Info("serving monitoring endpoints", zap.String("addr", addr))
+	if err := httpServer.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return zaperr.Wrap(err, "failed to serve monitoring endpoints", zap.String("addr", addr))
+	}
+	return nil
+}
+
+// healthzHandler responds with 503 if any of the checks fails, listing every check's result either way
+func (s *Server) healthzHandler(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), checkTimeout)
+	defer cancel()
+
+	names := make([]string, 0, len(s.checks))
+	for name := range s.checks {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	healthy := true
+	lines := make([]string, 0, len(names))
+	for _, name := range names {
+		if err := s.checks[name](ctx); err != nil {
+			s.logger.Warn("health check failed", zap.String("check", name), zaperr.ToField(err))
+			lines = append(lines, fmt.Sprintf("%s: %s", name, err))
+			healthy = false
+		} else {
+			lines = append(lines, fmt.Sprintf("%s: ok", name))
+		}
+	}
+
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	if !healthy {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}
+	_, _ = fmt.Fprintln(w, strings.Join(lines, "\n"))
+}
+
+// metricsHandler fails the whole scrape if any collector fails, so that missing metrics are not mistaken for zeros
+func (s *Server) metricsHandler(w http.ResponseWriter, r *http.Request) {
+	var metrics []Metric
+	for _, collect := range s.collectors {
+		collected, err := collect(r.Context())
+		if err != nil {
+			s.logger.Error("failed to collect metrics", zaperr.ToField(err))
+			http.Error(w, "failed to collect metrics", http.StatusInternalServerError)
+			return
+		}
+		metrics = append(metrics, collected...)
+	}
+
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	_, _ = w.Write([]byte(formatMetrics(metrics)))
+}
+
+// formatMetrics groups samples by name, so that HELP and TYPE are written once per metric.
+// Samples of a metric are ordered by labels, as collectors build them from maps
+func formatMetrics(metrics []Metric) string {
+	sort.SliceStable(metrics, func(i, j int) bool {
+		if metrics[i].Name != metrics[j].Name {
+			return metrics[i].Name < metrics[j].Name
+		}
+		return formatLabels(metrics[i].Labels) < formatLabels(metrics[j].Labels)
+	})
+
+	var sb strings.Builder
+	for i, m := range metrics {
+		if i == 0 || metrics[i-1].Name != m.Name {
+			if m.Help != "" {
+				sb.WriteString(fmt.Sprintf("# HELP %s %s\n", m.Name, m.Help))
+			}
+			if m.Type != "" {
+				sb.WriteString(fmt.Sprintf("# TYPE %s %s\n", m.Name, m.Type))
+			}
+		}
+		sb.WriteString(m.Name)
+		sb.WriteString(formatLabels(m.Labels))
+		sb.WriteString(" ")
+		sb.WriteString(strconv.FormatFloat(m.Value, 'g', -1, 64))
+		sb.WriteString("\n")
+	}
+	return sb.String()
+}
+
+var labelValueReplacer = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func formatLabels(labels map[string]string) string {
+	if len(labels) == 0 {
+		return ""
+	}
+
+	keys := make([]string, 0, len(labels))
+	for k := range labels {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	pairs := make([]string, 0, len(keys))
+	for _, k := range keys {
+		pairs = append(pairs, fmt.Sprintf(`%s="%s"`, k, labelValueReplacer.Replace(labels[k])))
+	}
+	return "{" + strings.Join(pairs, ",") + "}"
+}
+
+// region checks
+
+func DBCheck(db *sql.DB) Check {
+	return func(ctx context.Context) error {
+		return db.PingContext(ctx)
+	}
+}
+
+func RedisCheck(redisClient *redis.Client) Check {
+	return func(ctx context.Context) error {
+		return redisClient.Ping(ctx).Err()
+	}
+}
+
+// endregion
+
+// region collectors
+
+type EpisodesStats interface {
+	CountEpisodesByStatus(ctx context.Context) (map[service.EpisodeStatus]int, error)
+	FeedRegenerationsCount() int64
+}
+
+// ServiceCollector reports episodes of all users by status and how many times feeds were regenerated
+func ServiceCollector(stats EpisodesStats) Collector {
+	return func(ctx context.Context) ([]Metric, error) {
+		counts, err := stats.CountEpisodesByStatus(ctx)
+		if err != nil {
+			return nil, zaperr.Wrap(err, "failed to count episodes by status")
+		}
+
+		metrics := make([]Metric, 0, len(counts)+1)
+		for status, count := range counts {
+			metrics = append(metrics, Metric{
+				Name:   "podcastotron_episodes",
+				Help:   "Number of episodes by status",
+				Type:   "gauge",
+				Labels: map[string]string{"status": string(status)},
+				Value:  float64(count),
+			})
+		}
+		metrics = append(metrics, Metric{
+			Name:  "podcastotron_feed_regenerations_total",
+			Help:  "Number of feed files uploaded since start",
+			Type:  "counter",
+			Value: float64(stats.FeedRegenerationsCount()),
+		})
+		return metrics, nil
+	}
+}
+
+type JobsQueueStats interface {
+	QueueDepths(ctx context.Context) (map[string]int64, error)
+	CountDeadLetters(ctx context.Context) (int64, error)
+}
+
+// JobsQueueCollector reports how many jobs are pending and how many ran out of retries
+func JobsQueueCollector(stats JobsQueueStats) Collector {
+	return func(ctx context.Context) ([]Metric, error) {
+		depths, err := stats.QueueDepths(ctx)
+		if err != nil {
+			return nil, zaperr.Wrap(err, "failed to get queue depths")
+		}
+		deadLetters, err := stats.CountDeadLetters(ctx)
+		if err != nil {
+			return nil, zaperr.Wrap(err, "failed to count dead letters")
+		}
+
+		metrics := make([]Metric, 0, len(depths)+1)
+		for jobType, depth := range depths {
+			metrics = append(metrics, Metric{
+				Name:   "podcastotron_jobs_queue_depth",
+				Help:   "Number of jobs waiting to be handled, including scheduled retries",
+				Type:   "gauge",
+				Labels: map[string]string{"job_type": jobType},
+				Value:  float64(depth),
+			})
+		}
+		metrics = append(metrics, Metric{
+			Name:  "podcastotron_dead_letters",
+			Help:  "Number of jobs that ran out of retries",
+			Type:  "gauge",
+			Value: float64(deadLetters),
+		})
+		return metrics, nil
+	}
+}
+
+// endregion
@@ -0,0 +1,130 @@
+package monitoring
+
+import (
+	"context"
+	"database/sql"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	_ "github.com/mattn/go-sqlite3"
+	"github.com/redis/go-redis/v9"
+	"go.uber.org/zap"
+	"tg-podcastotron/db/migrations"
+	"tg-podcastotron/service"
+	jobsqueue "tg-podcastotron/service/jobs_queue"
+	tests "tg-podcastotron/testutils"
+)
+
+// repoStats counts episodes with a real repository, as Service would
+type repoStats struct {
+	service.Repository
+}
+
+func (repoStats) FeedRegenerationsCount() int64 { return 3 }
+
+func TestServer(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 1*time.Minute)
+	defer cancel()
+
+	// region live sqlite and fake redis
+	db, err := sql.Open(migrations.DialectSqlite, ":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	db.SetMaxOpenConns(1)
+	if _, err := migrations.Apply(db, migrations.DialectSqlite); err != nil {
+		t.Fatal(err)
+	}
+
+	redisURL, teardown, err := tests.GetFakeRedisURL(ctx)
+	defer teardown()
+	if err != nil {
+		t.Fatalf("error getting redis url: %v", err)
+	}
+	opt, _ := redis.ParseURL(redisURL)
+	redisClient := redis.NewClient(opt)
+	defer func() { _ = redisClient.Close() }()
+	// endregion
+
+	repo := service.NewSqliteRepository(db)
+	jobsQueue, err := jobsqueue.NewRedisJobsQueue(redisClient, 1, "monitoring-test", zap.NewNop())
+	if err != nil {
+		t.Fatal(err)
+	}
+	jobsQueue.Subscribe(ctx, "some-job-type", func(payloadBytes []byte) error { return nil })
+	if err := jobsQueue.Publish(ctx, "some-job-type", map[string]string{"foo": "bar"}); err != nil {
+		t.Fatal(err)
+	}
+	for _, ep := range []*service.Episode{
+		{ID: "1", UserID: "some-user", Status: service.EpisodeStatusComplete, CreatedAt: time.Now()},
+		{ID: "2", UserID: "other-user", Status: service.EpisodeStatusComplete, CreatedAt: time.Now()},
+		{ID: "3", UserID: "other-user", Status: service.EpisodeStatusFailed, CreatedAt: time.Now()},
+	} {
+		if _, err := repo.SaveEpisode(ctx, ep); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	srv := httptest.NewServer(New(
+		zap.NewNop(),
+		WithCheck("db", DBCheck(db)),
+		WithCheck("redis", RedisCheck(redisClient)),
+		WithCollector(ServiceCollector(repoStats{repo})),
+		WithCollector(JobsQueueCollector(jobsQueue)),
+	).Handler())
+	defer srv.Close()
+
+	get := func(path string) (int, string) {
+		resp, err := http.Get(srv.URL + path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer func() { _ = resp.Body.Close() }()
+		body, err := io.ReadAll(resp.Body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return resp.StatusCode, string(body)
+	}
+
+	// region healthy
+	if status, body := get("/healthz"); status != http.StatusOK || body != "db: ok\nredis: ok\n" {
+		t.Fatalf("expected healthz to be ok, got %d %q", status, body)
+	}
+	// endregion
+
+	// region metrics
+	status, body := get("/metrics")
+	if status != http.StatusOK {
+		t.Fatalf("expected metrics to be served, got %d %q", status, body)
+	}
+	for _, line := range []string{
+		"# TYPE podcastotron_episodes gauge",
+		`podcastotron_episodes{status="complete"} 2`,
+		`podcastotron_episodes{status="failed"} 1`,
+		"podcastotron_feed_regenerations_total 3",
+		`podcastotron_jobs_queue_depth{job_type="some-job-type"} 1`,
+		"podcastotron_dead_letters 0",
+	} {
+		if !strings.Contains(body, line+"\n") {
+			t.Errorf("expected metrics to contain %q, got\n%s", line, body)
+		}
+	}
+	if strings.Count(body, "# HELP podcastotron_episodes ") != 1 {
+		t.Errorf("expected HELP to be written once per metric, got\n%s", body)
+	}
+	// endregion
+
+	// region unhealthy once db is gone
+	if err := db.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if status, body := get("/healthz"); status != http.StatusServiceUnavailable || !strings.Contains(body, "redis: ok") || strings.Contains(body, "db: ok") {
+		t.Fatalf("expected healthz to report db failure, got %d %q", status, body)
+	}
+	// endregion
+}
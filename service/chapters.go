@@ -0,0 +1,88 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"path"
+	"strings"
+
+	"github.com/hori-ryota/zaperr"
+	"go.uber.org/zap"
+)
+
+// chaptersMIMEType is the type of Podcasting 2.0 chapters file, see
+// https://github.com/Podcastindex-org/podcast-namespace/blob/main/chapters/jsonChapters.md
+const chaptersMIMEType = "application/json+chapters"
+
+type podcastChapters struct {
+	Version  string            `json:"version"`
+	Chapters []*podcastChapter `json:"chapters"`
+}
+
+type podcastChapter struct {
+	StartTime float64 `json:"startTime"` // seconds from the start of episode
+	Title     string  `json:"title"`
+}
+
+// hasChapters tells whether episode was concatenated from several files whose boundaries are known
+func hasChapters(ep *Episode) bool {
+	return len(ep.SegmentDurations) > 1 && len(ep.SegmentDurations) == len(ep.SourceFilepaths)
+}
+
+// generateChapters makes a chapter out of every source file of concatenated episode
+func generateChapters(ep *Episode) (io.ReadSeeker, error) {
+	if !hasChapters(ep) {
+		return nil, fmt.Errorf("episode has %d segments for %d source files", len(ep.SegmentDurations), len(ep.SourceFilepaths))
+	}
+
+	chapters := &podcastChapters{Version: "1.2.0"}
+	var startTime float64
+	for i, fp := range ep.SourceFilepaths {
+		chapters.Chapters = append(chapters.Chapters, &podcastChapter{
+			StartTime: startTime,
+			Title:     titleFromFilepaths([]string{fp}),
+		})
+		startTime += ep.SegmentDurations[i].Seconds()
+	}
+
+	b, err := json.Marshal(chapters)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal chapters: %w", err)
+	}
+	return bytes.NewReader(b), nil
+}
+
+// uploadChapters puts chapters file next to episode file and sets episode's ChaptersURL, episode is not saved
+func (svc *Service) uploadChapters(ctx context.Context, ep *Episode) error {
+	zapFields := []zap.Field{
+		zap.String("episode_id", ep.ID),
+		zap.String("user_id", ep.UserID),
+	}
+
+	episodeKey := svc.extractEpisodeS3Key(ep)
+	if episodeKey == "" {
+		return zaperr.New("episode has no file to put chapters next to", zapFields...)
+	}
+	chaptersKey := constructS3ChaptersKey(episodeKey)
+	zapFields = append(zapFields, zap.String("chapters_key", chaptersKey))
+
+	chapters, err := generateChapters(ep)
+	if err != nil {
+		return zaperr.Wrap(err, "failed to generate chapters", zapFields...)
+	}
+	if err := svc.s3Store.Put(ctx, chaptersKey, chapters, WithContentType(chaptersMIMEType)); err != nil {
+		return zaperr.Wrap(err, "failed to upload chapters", zapFields...)
+	}
+	if ep.ChaptersURL, err = svc.s3Store.URL(chaptersKey); err != nil {
+		return zaperr.Wrap(err, "failed to get chapters url", zapFields...)
+	}
+	return nil
+}
+
+// constructS3ChaptersKey places chapters file next to episode file, so that prefix-based policies apply to both
+func constructS3ChaptersKey(episodeKey string) string {
+	return strings.TrimSuffix(episodeKey, path.Ext(episodeKey)) + ".chapters.json"
+}
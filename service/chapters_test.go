@@ -0,0 +1,117 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"tg-podcastotron/mediary"
+	"tg-podcastotron/mediary/mediarymocks"
+)
+
+func TestService__PollEpisodes__Chapters(t *testing.T) {
+	ctx := context.Background()
+	segmentDurations := []time.Duration{90 * time.Second, 60 * time.Second, 30 * time.Second}
+	svc, _, s3Store := newTestService(t, &mediarymocks.ServiceMock{
+		FetchJobStatusBatchFunc: func(ctx context.Context, jobIDs []string) (map[string]*mediary.JobStatus, error) {
+			return map[string]*mediary.JobStatus{
+				"mediary-1": {
+					Id:                  "mediary-1",
+					Status:              mediary.JobStatusComplete,
+					ResultMediaDuration: 180 * time.Second,
+					SegmentDurations:    segmentDurations,
+				},
+			}, nil
+		},
+	})
+
+	userID := "some-user"
+	feed, err := svc.CreateFeed(ctx, userID, "some feed")
+	if err != nil {
+		t.Fatal(err)
+	}
+	episodeKey := svc.constructS3EpisodeKey(userID, "album.mp3")
+	saveTestEpisode(t, svc, &Episode{
+		ID:              "1",
+		UserID:          userID,
+		Title:           "album",
+		MediaryID:       "mediary-1",
+		URL:             "https://example.com/" + episodeKey,
+		StorageKey:      episodeKey,
+		SourceFilepaths: []string{"album/01 - Intro.mp3", "album/02 - Song.mp3", "album/03 - Outro.mp3"},
+		Status:          EpisodeStatusProcessing,
+	})
+	if err := svc.PublishEpisodes(ctx, userID, []string{"1"}, []string{feed.ID}); err != nil {
+		t.Fatal(err)
+	}
+
+	pollEpisodes(t, svc, &PollEpisodesStatusQueuePayload{EpisodeIDs: []string{"1"}, UserID: userID})
+	<-svc.episodeStatusChangesChan
+
+	// region chapters file has a chapter per segment
+	chaptersKey := constructS3ChaptersKey(episodeKey)
+	chaptersFile, ok := s3Store.objects[chaptersKey]
+	if !ok {
+		t.Fatalf("expected chapters to be uploaded to %s", chaptersKey)
+	}
+	var chapters podcastChapters
+	if err := json.Unmarshal(chaptersFile, &chapters); err != nil {
+		t.Fatal(err)
+	}
+	if len(chapters.Chapters) != len(segmentDurations) {
+		t.Fatalf("expected %d chapters, got %d", len(segmentDurations), len(chapters.Chapters))
+	}
+	for i, expected := range []podcastChapter{
+		{StartTime: 0, Title: "01 - Intro"},
+		{StartTime: 90, Title: "02 - Song"},
+		{StartTime: 150, Title: "03 - Outro"},
+	} {
+		if *chapters.Chapters[i] != expected {
+			t.Errorf("expected chapter %d to be %+v, got %+v", i, expected, *chapters.Chapters[i])
+		}
+	}
+	// endregion
+
+	// region episode references chapters in feed
+	episodesMap, err := svc.repository.GetEpisodesMap(ctx, userID, []string{"1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ep := episodesMap["1"]
+	if ep.ChaptersURL != "https://example.com/"+chaptersKey {
+		t.Fatalf("expected chapters url to be saved, got %q", ep.ChaptersURL)
+	}
+	if len(ep.SegmentDurations) != len(segmentDurations) {
+		t.Fatalf("expected segment durations to be saved, got %v", ep.SegmentDurations)
+	}
+
+	feedReader, err := generateFeed(feed, []*Episode{ep}, DefaultFeedGenerator, time.Time{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	feedXML, err := io.ReadAll(feedReader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, expected := range []string{
+		`xmlns:podcast="https://podcastindex.org/namespace/1.0"`,
+		`<podcast:chapters url="https://example.com/` + chaptersKey + `" type="application/json+chapters">`,
+	} {
+		if !strings.Contains(string(feedXML), expected) {
+			t.Errorf("expected feed to contain %s, got\n%s", expected, feedXML)
+		}
+	}
+	// endregion
+}
+
+func TestGenerateChapters__SingleFile(t *testing.T) {
+	if _, err := generateChapters(&Episode{
+		SourceFilepaths:  []string{"song.mp3"},
+		SegmentDurations: []time.Duration{time.Minute},
+	}); err == nil {
+		t.Fatal("expected episode of a single file to have no chapters")
+	}
+}
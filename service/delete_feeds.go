@@ -0,0 +1,136 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/hori-ryota/zaperr"
+	"go.uber.org/zap"
+	"golang.org/x/exp/maps"
+	"golang.org/x/exp/slices"
+)
+
+var ErrDefaultFeedNotDeletable = fmt.Errorf("default feed can not be deleted")
+
+// DeleteFeedResult is the outcome of deleting one of the feeds passed to DeleteFeeds
+type DeleteFeedResult struct {
+	FeedID string
+	Err    error // nil when feed was deleted, ErrFeedNotFound or ErrDefaultFeedNotDeletable when it was skipped
+}
+
+// DeleteFeeds deletes several feeds at once, optionally moving their episodes to trash.
+// Feeds that can't be deleted are skipped and reported in their results, the rest are deleted in a single transaction,
+// so error is only returned when none of them were deleted
+func (svc *Service) DeleteFeeds(ctx context.Context, userID string, feedIDs []string, deleteEpisodes bool) ([]*DeleteFeedResult, error) {
+	zapFields := []zap.Field{
+		zap.Strings("feed_ids", feedIDs),
+		zap.String("user_id", userID),
+		zap.Bool("delete_episodes", deleteEpisodes),
+	}
+
+	// region find feeds that can be deleted
+	results := make([]*DeleteFeedResult, 0, len(feedIDs))
+	feeds := make(map[string]*Feed, len(feedIDs))
+	for _, feedID := range feedIDs {
+		result := &DeleteFeedResult{FeedID: feedID}
+		results = append(results, result)
+
+		if feedID == DefaultFeedID {
+			result.Err = ErrDefaultFeedNotDeletable
+			continue
+		}
+		feed, err := svc.repository.GetFeed(ctx, userID, feedID)
+		if err != nil {
+			return nil, zaperr.Wrap(err, "failed to get feed", append(zapFields, zap.String("feed_id", feedID))...)
+		}
+		if feed == nil {
+			result.Err = ErrFeedNotFound
+			continue
+		}
+		feeds[feedID] = feed
+	}
+	if len(feeds) == 0 {
+		return results, nil
+	}
+	// endregion
+
+	// region collect publications, and episodes if they go too
+	epIDsSet := make(map[string]struct{})
+	for feedID := range feeds {
+		episodes, err := svc.repository.ListFeedEpisodesJoined(ctx, userID, feedID)
+		if err != nil {
+			return nil, zaperr.Wrap(err, "failed to list feed episodes", append(zapFields, zap.String("feed_id", feedID))...)
+		}
+		for _, ep := range episodes {
+			epIDsSet[ep.ID] = struct{}{}
+		}
+	}
+	epIDs := maps.Keys(epIDsSet)
+	slices.Sort(epIDs)
+
+	publications, err := svc.repository.ListPublicationsByEpisodeIDs(ctx, userID, epIDs)
+	if err != nil {
+		return nil, zaperr.Wrap(err, "failed to list publications", zapFields...)
+	}
+	var publicationIDs []string
+	otherFeedIDs := make(map[string]struct{}) // feeds that lose deleted episodes but stay
+	for _, p := range publications {
+		_, feedIsDeleted := feeds[p.FeedID]
+		if feedIsDeleted || deleteEpisodes {
+			publicationIDs = append(publicationIDs, p.ID)
+		}
+		if deleteEpisodes && !feedIsDeleted {
+			otherFeedIDs[p.FeedID] = struct{}{}
+		}
+	}
+
+	// endregion
+
+	if err := svc.repository.Transaction(ctx, func(ctx context.Context) error {
+		if err := svc.repository.DeletePublications(ctx, userID, publicationIDs); err != nil {
+			return zaperr.Wrap(err, "failed to delete publications")
+		}
+		// episodes go to trash like any other deleted ones, so their files stay until they are purged
+		if deleteEpisodes {
+			if err := svc.repository.SoftDeleteEpisodes(ctx, userID, epIDs, time.Now().UTC()); err != nil {
+				return zaperr.Wrap(err, "failed to soft delete episodes")
+			}
+		}
+		for feedID := range feeds {
+			if err := svc.repository.DeleteFeed(ctx, userID, feedID); err != nil {
+				return zaperr.Wrap(err, "failed to delete feed", zap.String("feed_id", feedID))
+			}
+		}
+		return nil
+	}); err != nil {
+		return nil, zaperr.Wrap(err, "failed to delete feeds", zapFields...)
+	}
+
+	// region files go only after database is consistent, failing to delete them leaves garbage but nothing broken
+	for feedID, feed := range feeds {
+		zapFields := append(zapFields, zap.String("feed_id", feedID))
+		if err := svc.s3Store.Delete(ctx, svc.constructS3FeedKey(userID, feedID, feed.AccessToken)); err != nil {
+			svc.logger.Error("failed to delete feed file", append(zapFields, zaperr.ToField(err))...)
+		}
+		if feed.ImageURL != "" {
+			if err := svc.s3Store.Delete(ctx, svc.constructS3FeedImageKey(userID, feedID)); err != nil {
+				svc.logger.Error("failed to delete feed image", append(zapFields, zaperr.ToField(err))...)
+			}
+		}
+	}
+	// endregion
+
+	if len(otherFeedIDs) > 0 {
+		otherFeedIDs := maps.Keys(otherFeedIDs)
+		slices.Sort(otherFeedIDs)
+		if err := publish(ctx, svc.jobsQueue, queueEventRegenerateFeed, &RegenerateFeedQueuePayload{
+			FeedIDs: otherFeedIDs,
+			UserID:  userID,
+		}); err != nil {
+			svc.logger.Error("failed to enqueue regeneration of feeds deleted episodes were in", append(zapFields, zaperr.ToField(err))...)
+		}
+	}
+
+	return results, nil
+}
@@ -0,0 +1,128 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"tg-podcastotron/mediary/mediarymocks"
+)
+
+func TestService__DeleteFeeds(t *testing.T) {
+	ctx := context.Background()
+	svc, jobsQueue, s3Store := newTestService(t, &mediarymocks.ServiceMock{})
+
+	userID := "some-user"
+	var feeds []*Feed
+	for _, title := range []string{"first feed", "second feed", "kept feed"} {
+		feed, err := svc.CreateFeed(ctx, userID, title)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := svc.regenerateFeedFile(ctx, feed); err != nil {
+			t.Fatal(err)
+		}
+		feeds = append(feeds, feed)
+	}
+	first, second, kept := feeds[0], feeds[1], feeds[2]
+
+	keys := make(map[string]string)
+	for _, epID := range []string{"1", "2"} {
+		key := svc.constructS3EpisodeKey(userID, "file-"+epID+".mp3")
+		s3Store.objects[key] = []byte("episode " + epID)
+		keys[epID] = key
+		saveTestEpisode(t, svc, &Episode{
+			ID:         epID,
+			UserID:     userID,
+			Title:      "episode " + epID,
+			URL:        "https://example.com/" + key,
+			StorageKey: key,
+			Status:     EpisodeStatusComplete,
+		})
+	}
+	if err := svc.PublishEpisodes(ctx, userID, []string{"1"}, []string{first.ID}); err != nil {
+		t.Fatal(err)
+	}
+	if err := svc.PublishEpisodes(ctx, userID, []string{"2"}, []string{second.ID, kept.ID}); err != nil {
+		t.Fatal(err)
+	}
+	jobsQueue.published = nil
+
+	results, err := svc.DeleteFeeds(ctx, userID, []string{first.ID, "missing-feed-id", second.ID}, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// region missing feed is reported, the others are deleted
+	if len(results) != 3 {
+		t.Fatalf("expected 3 results, got %d", len(results))
+	}
+	for i, expected := range []struct {
+		feedID string
+		err    error
+	}{
+		{first.ID, nil},
+		{"missing-feed-id", ErrFeedNotFound},
+		{second.ID, nil},
+	} {
+		if results[i].FeedID != expected.feedID || !errors.Is(results[i].Err, expected.err) {
+			t.Errorf("expected result %d to be %s: %v, got %s: %v", i, expected.feedID, expected.err, results[i].FeedID, results[i].Err)
+		}
+	}
+
+	remaining, err := svc.repository.ListUserFeeds(ctx, userID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(remaining) != 1 || remaining[0].ID != kept.ID {
+		t.Fatalf("expected only %s to remain, got %+v", kept.ID, remaining)
+	}
+	for _, feed := range []*Feed{first, second} {
+		if _, ok := s3Store.objects[svc.constructS3FeedKey(userID, feed.ID, "")]; ok {
+			t.Errorf("expected file of feed %s to be deleted", feed.ID)
+		}
+	}
+	if _, ok := s3Store.objects[svc.constructS3FeedKey(userID, kept.ID, "")]; !ok {
+		t.Errorf("expected file of feed %s to be kept", kept.ID)
+	}
+	// endregion
+
+	// region episodes go to trash keeping their files, even if they were in a feed that stays
+	episodes, err := svc.repository.ListUserEpisodes(ctx, userID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(episodes) != 0 {
+		t.Fatalf("expected episodes to be deleted, got %d", len(episodes))
+	}
+	deleted, err := svc.ListDeletedEpisodes(ctx, userID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(deleted) != 2 {
+		t.Fatalf("expected episodes to be moved to trash, got %d", len(deleted))
+	}
+	for epID, key := range keys {
+		if _, ok := s3Store.objects[key]; !ok {
+			t.Errorf("expected file of episode %s to be kept until purge", epID)
+		}
+	}
+	regenerated := publishedOf(t, jobsQueue, queueEventRegenerateFeed)
+	if len(regenerated) != 1 || len(regenerated[0].FeedIDs) != 1 || regenerated[0].FeedIDs[0] != kept.ID {
+		t.Errorf("expected feed %s to be regenerated, got %+v", kept.ID, regenerated)
+	}
+	// endregion
+
+	// region default feed is skipped
+	defaultFeed, err := svc.DefaultFeed(ctx, userID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if results, err = svc.DeleteFeeds(ctx, userID, []string{defaultFeed.ID}, false); err != nil {
+		t.Fatal(err)
+	}
+	if !errors.Is(results[0].Err, ErrDefaultFeedNotDeletable) {
+		t.Errorf("expected default feed to be skipped, got %v", results[0].Err)
+	}
+	// endregion
+}
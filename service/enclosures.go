@@ -0,0 +1,129 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sync"
+
+	"github.com/hori-ryota/zaperr"
+	"go.uber.org/zap"
+)
+
+// maxParallelEnclosureChecks limits number of simultaneous requests made while validating a feed
+const maxParallelEnclosureChecks = 8
+
+// BrokenEnclosure is a feed episode whose file podcast clients can't download
+type BrokenEnclosure struct {
+	Episode    *Episode
+	StatusCode int   // zero when request failed altogether
+	Err        error // why enclosure is considered broken
+}
+
+// ValidateFeedEnclosures checks that files of all complete feed episodes are still downloadable
+func (svc *Service) ValidateFeedEnclosures(ctx context.Context, userID string, feedID string) ([]*BrokenEnclosure, error) {
+	zapFields := []zap.Field{
+		zap.String("feed_id", feedID),
+		zap.String("user_id", userID),
+	}
+
+	feed, err := svc.repository.GetFeed(ctx, userID, feedID)
+	if err != nil {
+		return nil, zaperr.Wrap(err, "failed to get feed", zapFields...)
+	}
+	if feed == nil {
+		return nil, zaperr.Wrap(ErrFeedNotFound, "", zapFields...)
+	}
+
+	episodes, err := svc.repository.ListFeedEpisodesJoined(ctx, userID, feedID)
+	if err != nil {
+		return nil, zaperr.Wrap(err, "failed to list feed episodes", zapFields...)
+	}
+
+	episodesChan := make(chan *Episode, len(episodes))
+	for _, ep := range episodes {
+		// incomplete episodes are not in the feed, so their files are not expected to exist yet
+		if ep.Status == EpisodeStatusComplete {
+			episodesChan <- ep
+		}
+	}
+	close(episodesChan)
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	brokenMap := make(map[string]*BrokenEnclosure)
+	for i := 0; i < maxParallelEnclosureChecks; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for ep := range episodesChan {
+				if broken := svc.checkEnclosure(ctx, ep); broken != nil {
+					mu.Lock()
+					brokenMap[ep.ID] = broken
+					mu.Unlock()
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	// keep feed order, it's what user sees in /f_ and in podcast app
+	broken := make([]*BrokenEnclosure, 0, len(brokenMap))
+	for _, ep := range episodes {
+		if b, ok := brokenMap[ep.ID]; ok {
+			broken = append(broken, b)
+		}
+	}
+	return broken, nil
+}
+
+// checkEnclosure returns nil when episode file is downloadable
+func (svc *Service) checkEnclosure(ctx context.Context, ep *Episode) *BrokenEnclosure {
+	if ep.URL == "" {
+		return &BrokenEnclosure{Episode: ep, Err: fmt.Errorf("episode has no url")}
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodHead, ep.URL, nil)
+	if err != nil {
+		return &BrokenEnclosure{Episode: ep, Err: err}
+	}
+	resp, err := svc.httpClient.Do(req)
+	if err != nil {
+		return &BrokenEnclosure{Episode: ep, Err: err}
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 400 {
+		return &BrokenEnclosure{
+			Episode:    ep,
+			StatusCode: resp.StatusCode,
+			Err:        fmt.Errorf("enclosure returned status code %d", resp.StatusCode),
+		}
+	}
+	return nil
+}
+
+// UnpublishEpisodes removes episodes from a feed, keeping them in the rest of their feeds
+func (svc *Service) UnpublishEpisodes(ctx context.Context, userID string, feedID string, epIDs []string) error {
+	zapFields := []zap.Field{
+		zap.String("feed_id", feedID),
+		zap.String("user_id", userID),
+		zap.Strings("episode_ids", epIDs),
+	}
+
+	publications, err := svc.repository.ListPublicationsByEpisodeIDs(ctx, userID, epIDs)
+	if err != nil {
+		return zaperr.Wrap(err, "failed to list publications", zapFields...)
+	}
+	publicationIDs := make([]string, 0, len(epIDs))
+	for _, p := range publications {
+		if p.FeedID == feedID {
+			publicationIDs = append(publicationIDs, p.ID)
+		}
+	}
+	if err := svc.repository.DeletePublications(ctx, userID, publicationIDs); err != nil {
+		return zaperr.Wrap(err, "failed to delete publications", zapFields...)
+	}
+
+	return svc.RegenerateFeed(ctx, userID, feedID)
+}
@@ -0,0 +1,50 @@
+package service
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"tg-podcastotron/mediary/mediarymocks"
+)
+
+func TestService__ValidateFeedEnclosures(t *testing.T) {
+	ctx := context.Background()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodHead {
+			t.Errorf("expected enclosures to be checked with HEAD, got %s", r.Method)
+		}
+		if r.URL.Path == "/missing.mp3" {
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer srv.Close()
+
+	svc, _, _ := newTestService(t, &mediarymocks.ServiceMock{})
+
+	userID := "some-user"
+	feed, err := svc.CreateFeed(ctx, userID, "some feed")
+	if err != nil {
+		t.Fatal(err)
+	}
+	saveTestEpisode(t, svc, &Episode{ID: "1", UserID: userID, Title: "healthy", URL: srv.URL + "/healthy.mp3", Status: EpisodeStatusComplete})
+	saveTestEpisode(t, svc, &Episode{ID: "2", UserID: userID, Title: "missing", URL: srv.URL + "/missing.mp3", Status: EpisodeStatusComplete})
+	// not uploaded yet, so its absence is expected
+	saveTestEpisode(t, svc, &Episode{ID: "3", UserID: userID, Title: "processing", URL: srv.URL + "/missing.mp3", Status: EpisodeStatusProcessing})
+	if err := svc.PublishEpisodes(ctx, userID, []string{"1", "2", "3"}, []string{feed.ID}); err != nil {
+		t.Fatal(err)
+	}
+
+	broken, err := svc.ValidateFeedEnclosures(ctx, userID, feed.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(broken) != 1 {
+		t.Fatalf("expected exactly one broken enclosure, got %d", len(broken))
+	}
+	if broken[0].Episode.ID != "2" || broken[0].StatusCode != http.StatusNotFound {
+		t.Fatalf("expected episode 2 to be broken with 404, got episode %s with %d", broken[0].Episode.ID, broken[0].StatusCode)
+	}
+}
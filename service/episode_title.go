@@ -5,6 +5,7 @@ import (
 	"path"
 	"regexp"
 	"sort"
+	"strconv"
 	"strings"
 )
 
@@ -60,13 +61,25 @@ func titleFromSourceURL(sourceURL string) string {
 	return u.Query().Get("dn") // magnet link title
 }
 
-func getUpdatedEpisodeTitle(episodes []*Episode, newTitlePattern string) map[string]string {
+// getUpdatedEpisodeTitle expands new title pattern for each episode. Supported tokens are:
+//   - %v: part of old title that differs between episodes
+//   - %id: episode ID, padded with zeros to the length of the longest ID
+//   - %n: position of episode in the set ordered by ID, starting with 1 and padded to the length of the set size
+//   - %date: episode publication date as YYYY-MM-DD
+//   - %feed: title of the feed episode is published to, taken from feedTitles keyed by episode ID
+//
+// All tokens are expanded in a single pass, left to right, so text substituted for one token
+// is never expanded again, e.g. old title containing "%id" stays as is when it comes through %v
+func getUpdatedEpisodeTitle(episodes []*Episode, newTitlePattern string, feedTitles map[string]string) map[string]string {
 	result := make(map[string]string, len(episodes))
 
 	hasVariablePart := strings.Contains(newTitlePattern, "%v")
 	hasID := strings.Contains(newTitlePattern, "%id")
+	hasN := strings.Contains(newTitlePattern, "%n")
+	hasDate := strings.Contains(newTitlePattern, "%date")
+	hasFeed := strings.Contains(newTitlePattern, "%feed")
 
-	if !hasVariablePart && !hasID {
+	if !hasVariablePart && !hasID && !hasN && !hasDate && !hasFeed {
 		for _, e := range episodes {
 			result[e.ID] = newTitlePattern
 		}
@@ -92,25 +105,58 @@ func getUpdatedEpisodeTitle(episodes []*Episode, newTitlePattern string) map[str
 		}
 	}
 
+	// episodes come in no particular order, so they are numbered in order of their IDs
+	positions := make(map[string]int, len(episodes))
+	if hasN {
+		sorted := make([]*Episode, len(episodes))
+		copy(sorted, episodes)
+		sort.Slice(sorted, func(i, j int) bool {
+			if len(sorted[i].ID) != len(sorted[j].ID) {
+				return len(sorted[i].ID) < len(sorted[j].ID)
+			}
+			return sorted[i].ID < sorted[j].ID
+		})
+		for i, e := range sorted {
+			positions[e.ID] = i + 1
+		}
+	}
+	maxNLength := len(strconv.Itoa(len(episodes)))
+
 	for _, e := range episodes {
-		newTitle := newTitlePattern
+		var oldNew []string
 		if hasVariablePart {
 			variablePart := strings.TrimSuffix(strings.TrimPrefix(e.Title, prefix), suffix)
-			newTitle = strings.Replace(newTitle, "%v", variablePart, -1)
+			oldNew = append(oldNew, "%v", variablePart)
 		}
 		if hasID {
-			newID := e.ID
-			for len(newID) < maxIDLength {
-				newID = "0" + newID
+			oldNew = append(oldNew, "%id", padWithZeros(e.ID, maxIDLength))
+		}
+		if hasN {
+			oldNew = append(oldNew, "%n", padWithZeros(strconv.Itoa(positions[e.ID]), maxNLength))
+		}
+		if hasDate {
+			pubDate := e.CreatedAt
+			if !e.PubDate.IsZero() {
+				pubDate = e.PubDate
 			}
-			newTitle = strings.Replace(newTitle, "%id", newID, -1)
+			oldNew = append(oldNew, "%date", pubDate.Format("2006-01-02"))
+		}
+		if hasFeed {
+			oldNew = append(oldNew, "%feed", feedTitles[e.ID])
 		}
-		result[e.ID] = newTitle
+		result[e.ID] = strings.NewReplacer(oldNew...).Replace(newTitlePattern)
 	}
 
 	return result
 }
 
+func padWithZeros(s string, length int) string {
+	for len(s) < length {
+		s = "0" + s
+	}
+	return s
+}
+
 func longestCommonPrefixAndSuffix(strs []string) (longestPrefix string, longestSuffix string) {
 	if len(strs) < 2 {
 		return longestPrefix, longestSuffix
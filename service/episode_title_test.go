@@ -2,7 +2,9 @@ package service
 
 import (
 	"reflect"
+	"strconv"
 	"testing"
+	"time"
 )
 
 func TestGenerateEpisodeTitle(t *testing.T) {
@@ -62,6 +64,7 @@ func TestGetUpdatedEpisodeTitle(t *testing.T) {
 	tests := []struct {
 		episodes         []*Episode
 		newTitlePattern  string
+		feedTitles       map[string]string
 		expectedTitleMap map[string]string
 	}{
 		{
@@ -88,11 +91,79 @@ func TestGetUpdatedEpisodeTitle(t *testing.T) {
 				"512": "Bar - 512",
 			},
 		},
+		{
+			episodes: []*Episode{
+				{ID: "12", Title: "FOO"},
+				{ID: "9", Title: "FOO"},
+				{ID: "10", Title: "FOO"},
+			},
+			newTitlePattern: "Chapter %n",
+			expectedTitleMap: map[string]string{
+				"9":  "Chapter 1",
+				"10": "Chapter 2",
+				"12": "Chapter 3",
+			},
+		},
+		{
+			episodes:        makeNumberedEpisodes(10),
+			newTitlePattern: "Part %n",
+			expectedTitleMap: map[string]string{
+				"1": "Part 01", "2": "Part 02", "3": "Part 03", "4": "Part 04", "5": "Part 05",
+				"6": "Part 06", "7": "Part 07", "8": "Part 08", "9": "Part 09", "10": "Part 10",
+			},
+		},
+		{
+			episodes: []*Episode{
+				{ID: "1", Title: "FOO", CreatedAt: time.Date(2024, 1, 2, 10, 0, 0, 0, time.UTC)},
+				{ID: "2", Title: "FOO", CreatedAt: time.Date(2024, 1, 3, 10, 0, 0, 0, time.UTC), PubDate: time.Date(1999, 12, 31, 0, 0, 0, 0, time.UTC)},
+			},
+			newTitlePattern: "Broadcast of %date",
+			expectedTitleMap: map[string]string{
+				"1": "Broadcast of 2024-01-02",
+				"2": "Broadcast of 1999-12-31",
+			},
+		},
+		{
+			episodes:        []*Episode{{ID: "1", Title: "FOO"}, {ID: "2", Title: "FOO"}},
+			newTitlePattern: "%feed: episode",
+			feedTitles:      map[string]string{"1": "Some Feed"},
+			expectedTitleMap: map[string]string{
+				"1": "Some Feed: episode",
+				"2": ": episode",
+			},
+		},
+		{
+			episodes: []*Episode{
+				{ID: "7", Title: "Show - Part A", PubDate: time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)},
+				{ID: "11", Title: "Show - Part B", PubDate: time.Date(2024, 5, 8, 0, 0, 0, 0, time.UTC)},
+			},
+			newTitlePattern: "%feed %n/%id (%date) %v",
+			feedTitles:      map[string]string{"7": "Show", "11": "Show"},
+			expectedTitleMap: map[string]string{
+				"7":  "Show 1/07 (2024-05-01) A",
+				"11": "Show 2/11 (2024-05-08) B",
+			},
+		},
+		{
+			// substituted text is never expanded again
+			episodes:         []*Episode{{ID: "1", Title: "Old %id %n"}},
+			newTitlePattern:  "%v by %feed",
+			feedTitles:       map[string]string{"1": "%date"},
+			expectedTitleMap: map[string]string{"1": "Old %id %n by %date"},
+		},
 	}
 	for _, test := range tests {
-		titleMap := getUpdatedEpisodeTitle(test.episodes, test.newTitlePattern)
+		titleMap := getUpdatedEpisodeTitle(test.episodes, test.newTitlePattern, test.feedTitles)
 		if !reflect.DeepEqual(test.expectedTitleMap, titleMap) {
 			t.Errorf("expected title map %v, got %v", test.expectedTitleMap, titleMap)
 		}
 	}
 }
+
+func makeNumberedEpisodes(n int) []*Episode {
+	episodes := make([]*Episode, n)
+	for i := range episodes {
+		episodes[i] = &Episode{ID: strconv.Itoa(i + 1), Title: "FOO"}
+	}
+	return episodes
+}
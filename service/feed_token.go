@@ -0,0 +1,108 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/hori-ryota/zaperr"
+	"go.uber.org/zap"
+)
+
+var ErrFeedNotTokenProtected = fmt.Errorf("feed is not token protected")
+
+// SetFeedTokenProtected moves feed to an unguessable URL with a fresh access token, or back to its plain URL.
+// Either way the old URL stops working, so subscribers have to get the new one
+func (svc *Service) SetFeedTokenProtected(ctx context.Context, userID string, feedID string, protected bool) (*Feed, error) {
+	zapFields := []zap.Field{
+		zap.String("feed_id", feedID),
+		zap.String("user_id", userID),
+		zap.Bool("token_protected", protected),
+	}
+
+	feed, err := svc.repository.GetFeed(ctx, userID, feedID)
+	if err != nil {
+		return nil, zaperr.Wrap(err, "failed to get feed", zapFields...)
+	}
+	if feed == nil {
+		return nil, zaperr.Wrap(ErrFeedNotFound, "", zapFields...)
+	}
+	if feed.TokenProtected == protected {
+		return feed, nil
+	}
+
+	accessToken := ""
+	if protected {
+		accessToken = newAccessToken()
+	}
+	if err := svc.moveFeedFile(ctx, feed, protected, accessToken); err != nil {
+		return nil, zaperr.Wrap(err, "failed to move feed file", zapFields...)
+	}
+	return feed, nil
+}
+
+// RotateFeedToken gives token protected feed a new access token, revoking access of everyone who had the old URL
+func (svc *Service) RotateFeedToken(ctx context.Context, userID string, feedID string) (*Feed, error) {
+	zapFields := []zap.Field{
+		zap.String("feed_id", feedID),
+		zap.String("user_id", userID),
+	}
+
+	feed, err := svc.repository.GetFeed(ctx, userID, feedID)
+	if err != nil {
+		return nil, zaperr.Wrap(err, "failed to get feed", zapFields...)
+	}
+	if feed == nil {
+		return nil, zaperr.Wrap(ErrFeedNotFound, "", zapFields...)
+	}
+	if !feed.TokenProtected {
+		return nil, zaperr.Wrap(ErrFeedNotTokenProtected, "", zapFields...)
+	}
+
+	if err := svc.moveFeedFile(ctx, feed, true, newAccessToken()); err != nil {
+		return nil, zaperr.Wrap(err, "failed to move feed file", zapFields...)
+	}
+	return feed, nil
+}
+
+// moveFeedFile uploads feed file under the key of new access token right away rather than through the queue,
+// so that the new URL works as soon as user gets it, then saves the feed and deletes the old file
+func (svc *Service) moveFeedFile(ctx context.Context, feed *Feed, protected bool, accessToken string) error {
+	oldKey := svc.constructS3FeedKey(feed.UserID, feed.ID, feed.AccessToken)
+
+	feed.TokenProtected = protected
+	feed.AccessToken = accessToken
+	var err error
+	if feed.URL, err = svc.feedURL(feed); err != nil {
+		return zaperr.Wrap(err, "failed to get feed url")
+	}
+
+	if err := svc.regenerateFeedFile(ctx, feed); err != nil {
+		return zaperr.Wrap(err, "failed to regenerate feed")
+	}
+	if _, err := svc.repository.SaveFeed(ctx, feed); err != nil {
+		return zaperr.Wrap(err, "failed to save feed")
+	}
+	if err := svc.s3Store.Delete(ctx, oldKey); err != nil {
+		return zaperr.Wrap(err, "failed to delete old feed file", zap.String("key", oldKey))
+	}
+	return nil
+}
+
+// feedURL is the URL to subscribe to feed with. Token protected feeds have the token in their URL twice:
+// as a part of the key, which makes it unguessable, and as a query parameter, which tells it apart at a glance
+func (svc *Service) feedURL(feed *Feed) (string, error) {
+	feedURL, err := svc.s3Store.URL(svc.constructS3FeedKey(feed.UserID, feed.ID, feed.AccessToken))
+	if err != nil {
+		return "", err
+	}
+	if feed.TokenProtected {
+		feedURL += "?token=" + feed.AccessToken
+	}
+	return feedURL, nil
+}
+
+func newAccessToken() string {
+	return strings.ReplaceAll(uuid.New().String(), "-", "")
+}
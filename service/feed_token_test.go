@@ -0,0 +1,100 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"tg-podcastotron/mediary/mediarymocks"
+)
+
+func TestService__FeedToken(t *testing.T) {
+	ctx := context.Background()
+	svc, _, s3Store := newTestService(t, &mediarymocks.ServiceMock{})
+
+	userID := "some-user"
+	feed, err := svc.CreateFeed(ctx, userID, "some feed")
+	if err != nil {
+		t.Fatal(err)
+	}
+	plainKey := svc.constructS3FeedKey(userID, feed.ID, "")
+	if feed.URL != "https://example.com/"+plainKey {
+		t.Fatalf("expected unprotected feed url to have no token, got %s", feed.URL)
+	}
+	if err := svc.regenerateFeedFile(ctx, feed); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := svc.RotateFeedToken(ctx, userID, feed.ID); !errors.Is(err, ErrFeedNotTokenProtected) {
+		t.Fatalf("expected rotating token of unprotected feed to fail, got %v", err)
+	}
+
+	// region protecting feed moves it under a key with the token
+	protected, err := svc.SetFeedTokenProtected(ctx, userID, feed.ID, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !protected.TokenProtected || protected.AccessToken == "" {
+		t.Fatalf("expected feed to get access token, got %+v", protected)
+	}
+	protectedKey := svc.constructS3FeedKey(userID, feed.ID, protected.AccessToken)
+	if !strings.Contains(protectedKey, protected.AccessToken) {
+		t.Fatalf("expected key %s to contain token", protectedKey)
+	}
+	if expected := "https://example.com/" + protectedKey + "?token=" + protected.AccessToken; protected.URL != expected {
+		t.Fatalf("expected url %s, got %s", expected, protected.URL)
+	}
+	if _, ok := s3Store.objects[protectedKey]; !ok {
+		t.Fatalf("expected feed to be uploaded to %s", protectedKey)
+	}
+	if _, ok := s3Store.objects[plainKey]; ok {
+		t.Fatalf("expected feed to be removed from %s", plainKey)
+	}
+
+	stored, err := svc.repository.GetFeed(ctx, userID, feed.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !stored.TokenProtected || stored.AccessToken != protected.AccessToken || stored.URL != protected.URL {
+		t.Fatalf("expected token to be saved, got %+v", stored)
+	}
+	// endregion
+
+	// region rotation moves feed to a key with a new token
+	oldToken := protected.AccessToken
+	rotated, err := svc.RotateFeedToken(ctx, userID, feed.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rotated.AccessToken == "" || rotated.AccessToken == oldToken {
+		t.Fatalf("expected new token, got %q", rotated.AccessToken)
+	}
+	if strings.Contains(rotated.URL, oldToken) {
+		t.Fatalf("expected url %s to have no old token", rotated.URL)
+	}
+	rotatedKey := svc.constructS3FeedKey(userID, feed.ID, rotated.AccessToken)
+	if _, ok := s3Store.objects[rotatedKey]; !ok {
+		t.Fatalf("expected feed to be uploaded to %s", rotatedKey)
+	}
+	if _, ok := s3Store.objects[protectedKey]; ok {
+		t.Fatalf("expected feed to be removed from %s", protectedKey)
+	}
+	// endregion
+
+	// region unprotecting feed brings it back to plain key
+	unprotected, err := svc.SetFeedTokenProtected(ctx, userID, feed.ID, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if unprotected.TokenProtected || unprotected.AccessToken != "" || unprotected.URL != feed.URL {
+		t.Fatalf("expected feed to be back at %s, got %+v", feed.URL, unprotected)
+	}
+	if _, ok := s3Store.objects[plainKey]; !ok {
+		t.Fatalf("expected feed to be uploaded to %s", plainKey)
+	}
+	if _, ok := s3Store.objects[rotatedKey]; ok {
+		t.Fatalf("expected feed to be removed from %s", rotatedKey)
+	}
+	// endregion
+}
@@ -2,6 +2,9 @@ package jobsqueue
 
 import (
 	"context"
+	"fmt"
+	"runtime/debug"
+	"sync"
 	"time"
 
 	"github.com/hori-ryota/zaperr"
@@ -10,17 +13,59 @@ import (
 	"go.uber.org/zap"
 )
 
+var (
+	ErrHandlerPanicked    = fmt.Errorf("job handler panicked")
+	ErrDeadLetterNotFound = fmt.Errorf("dead letter not found")
+	ErrInvalidDeadLetter  = fmt.Errorf("invalid dead letter")
+)
+
+const (
+	DefaultMaxRetries       = 8
+	deadLettersStreamSuffix = ":dead"
+)
+
 type RJQ struct {
+	redisClient *redis.Client
 	work2Queue  work2.RedisQueue
 	work2Worker *work2.Worker
 	namespace   string
 	concurrency int
+	maxRetries  int
 	logger      *zap.Logger
+
+	jobTypesMu sync.Mutex
+	jobTypes   []string
+}
+
+type Options struct {
+	// MaxRetries is how many times a failing job is retried before it's moved to dead letters
+	MaxRetries int
+}
+
+func WithMaxRetries(maxRetries int) func(*Options) {
+	return func(o *Options) {
+		o.MaxRetries = maxRetries
+	}
 }
 
-func NewRedisJobsQueue(redisClient *redis.Client, concurrency int, namespace string, logger *zap.Logger) (*RJQ, error) {
+// DeadLetter is a job that kept failing until it ran out of retries
+type DeadLetter struct {
+	ID        string
+	JobType   string
+	Payload   []byte
+	LastError string
+	FailedAt  time.Time
+}
+
+func NewRedisJobsQueue(redisClient *redis.Client, concurrency int, namespace string, logger *zap.Logger, opts ...func(*Options)) (*RJQ, error) {
+	options := &Options{MaxRetries: DefaultMaxRetries}
+	for _, opt := range opts {
+		opt(options)
+	}
+
 	jobsQueue := &RJQ{
-		work2Queue: work2.NewRedisQueue(redisClient),
+		redisClient: redisClient,
+		work2Queue:  work2.NewRedisQueue(redisClient),
 		work2Worker: work2.NewWorker(&work2.WorkerOptions{
 			Namespace: namespace,
 			Queue:     work2.NewRedisQueue(redisClient),
@@ -30,6 +75,7 @@ func NewRedisJobsQueue(redisClient *redis.Client, concurrency int, namespace str
 		}),
 		namespace:   namespace,
 		concurrency: concurrency,
+		maxRetries:  options.MaxRetries,
 		logger:      logger,
 	}
 	return jobsQueue, nil
@@ -49,6 +95,20 @@ func (r *RJQ) Publish(ctx context.Context, jobType string, payload any) error {
 		return zaperr.Wrap(err, "failed to marshal payload")
 	}
 
+	return r.enqueue(job, jobType)
+}
+
+// PublishDelayed enqueues a job that isn't dequeued until delay passes
+func (r *RJQ) PublishDelayed(ctx context.Context, jobType string, payload any, delay time.Duration) error {
+	job := work2.NewJob().Delay(delay)
+	if err := job.MarshalJSONPayload(payload); err != nil {
+		return zaperr.Wrap(err, "failed to marshal payload")
+	}
+
+	return r.enqueue(job, jobType)
+}
+
+func (r *RJQ) enqueue(job *work2.Job, jobType string) error {
 	if err := r.work2Queue.Enqueue(job, &work2.EnqueueOptions{Namespace: r.namespace, QueueID: jobType}); err != nil {
 		return zaperr.Wrap(err, "failed to enqueue job")
 	}
@@ -57,10 +117,31 @@ func (r *RJQ) Publish(ctx context.Context, jobType string, payload any) error {
 }
 
 func (r *RJQ) Subscribe(ctx context.Context, jobType string, f func(payloadBytes []byte) error) {
+	r.jobTypesMu.Lock()
+	r.jobTypes = append(r.jobTypes, jobType)
+	r.jobTypesMu.Unlock()
+
 	err := r.work2Worker.Register(jobType, func(job *work2.Job, opt *work2.DequeueOptions) error {
-		if err := f(job.Payload); err != nil {
-			r.logger.Error("failed to handle job", zaperr.ToField(err))
-			return err
+		if err := callRecovering(f, job.Payload); err != nil {
+			zapFields := []zap.Field{
+				zap.String("job_type", jobType),
+				zap.String("job_id", job.ID),
+				zap.Int64("retries", job.Retries),
+				zaperr.ToField(err),
+			}
+			r.logger.Error("failed to handle job", zapFields...)
+
+			// job.Retries counts previous failures, so this one was the last attempt
+			if job.Retries+1 < int64(r.maxRetries) {
+				return err
+			}
+			if dlErr := r.addDeadLetter(ctx, jobType, job, err); dlErr != nil {
+				// job is kept retrying rather than lost
+				r.logger.Error("failed to add dead letter", append(zapFields, zap.NamedError("dead_letter_error", dlErr))...)
+				return err
+			}
+			r.logger.Warn("job ran out of retries, moved to dead letters", zapFields...)
+			return work2.ErrUnrecoverable
 		}
 		return nil
 	}, &work2.JobOptions{
@@ -72,3 +153,137 @@ func (r *RJQ) Subscribe(ctx context.Context, jobType string, f func(payloadBytes
 		r.logger.Error("failed to register job", zaperr.ToField(err))
 	}
 }
+
+// callRecovering turns handler panic into an error, so that the job is retried like any other failed one
+// instead of crashing the worker
+func callRecovering(f func(payloadBytes []byte) error, payloadBytes []byte) (err error) {
+	defer func() {
+		if recovered := recover(); recovered != nil {
+			err = zaperr.Wrap(
+				ErrHandlerPanicked, "recovered from job handler panic",
+				zap.Any("panic", recovered),
+				zap.ByteString("stack", debug.Stack()),
+			)
+		}
+	}()
+	return f(payloadBytes)
+}
+
+// QueueDepths returns how many jobs of each subscribed type are waiting to be handled, including scheduled retries
+func (r *RJQ) QueueDepths(ctx context.Context) (map[string]int64, error) {
+	r.jobTypesMu.Lock()
+	jobTypes := append([]string(nil), r.jobTypes...)
+	r.jobTypesMu.Unlock()
+
+	depths := make(map[string]int64, len(jobTypes))
+	for _, jobType := range jobTypes {
+		// work keeps every queue in a sorted set of job IDs
+		depth, err := r.redisClient.ZCard(ctx, r.namespace+":queue:"+jobType).Result()
+		if err != nil {
+			return nil, zaperr.Wrap(err, "failed to get queue depth", zap.String("job_type", jobType))
+		}
+		depths[jobType] = depth
+	}
+	return depths, nil
+}
+
+// region dead letters
+
+func (r *RJQ) addDeadLetter(ctx context.Context, jobType string, job *work2.Job, jobErr error) error {
+	if err := r.redisClient.XAdd(ctx, &redis.XAddArgs{
+		Stream: r.deadLettersStream(),
+		Values: map[string]interface{}{
+			"job_type":   jobType,
+			"payload":    string(job.Payload),
+			"last_error": jobErr.Error(),
+			"failed_at":  time.Now().UTC().Format(time.RFC3339),
+		},
+	}).Err(); err != nil {
+		return zaperr.Wrap(err, "failed to add dead letter")
+	}
+	return nil
+}
+
+// ListDeadLetters returns jobs that ran out of retries, oldest first
+func (r *RJQ) ListDeadLetters(ctx context.Context) ([]DeadLetter, error) {
+	messages, err := r.redisClient.XRange(ctx, r.deadLettersStream(), "-", "+").Result()
+	if err != nil {
+		return nil, zaperr.Wrap(err, "failed to list dead letters")
+	}
+
+	deadLetters := make([]DeadLetter, 0, len(messages))
+	for _, m := range messages {
+		dl, err := parseDeadLetter(m)
+		if err != nil {
+			return nil, zaperr.Wrap(err, "failed to parse dead letter", zap.String("dead_letter_id", m.ID))
+		}
+		deadLetters = append(deadLetters, dl)
+	}
+	return deadLetters, nil
+}
+
+// CountDeadLetters returns how many jobs ran out of retries and are waiting in dead letters
+func (r *RJQ) CountDeadLetters(ctx context.Context) (int64, error) {
+	count, err := r.redisClient.XLen(ctx, r.deadLettersStream()).Result()
+	if err != nil {
+		return 0, zaperr.Wrap(err, "failed to count dead letters")
+	}
+	return count, nil
+}
+
+// RequeueDeadLetter publishes dead letter's job again, with retries starting from scratch
+func (r *RJQ) RequeueDeadLetter(ctx context.Context, id string) error {
+	zapFields := []zap.Field{
+		zap.String("dead_letter_id", id),
+	}
+
+	messages, err := r.redisClient.XRange(ctx, r.deadLettersStream(), id, id).Result()
+	if err != nil {
+		return zaperr.Wrap(err, "failed to get dead letter", zapFields...)
+	}
+	if len(messages) == 0 {
+		return zaperr.Wrap(ErrDeadLetterNotFound, "", zapFields...)
+	}
+	dl, err := parseDeadLetter(messages[0])
+	if err != nil {
+		return zaperr.Wrap(err, "failed to parse dead letter", zapFields...)
+	}
+
+	job := work2.NewJob()
+	job.Payload = dl.Payload
+	if err := r.enqueue(job, dl.JobType); err != nil {
+		return zaperr.Wrap(err, "failed to requeue dead letter", zapFields...)
+	}
+
+	if err := r.redisClient.XDel(ctx, r.deadLettersStream(), id).Err(); err != nil {
+		return zaperr.Wrap(err, "failed to delete dead letter", zapFields...)
+	}
+	return nil
+}
+
+func (r *RJQ) deadLettersStream() string {
+	return r.namespace + deadLettersStreamSuffix
+}
+
+func parseDeadLetter(m redis.XMessage) (DeadLetter, error) {
+	value := func(key string) string {
+		s, _ := m.Values[key].(string)
+		return s
+	}
+
+	dl := DeadLetter{
+		ID:        m.ID,
+		JobType:   value("job_type"),
+		Payload:   []byte(value("payload")),
+		LastError: value("last_error"),
+	}
+	if dl.JobType == "" {
+		return DeadLetter{}, ErrInvalidDeadLetter
+	}
+	if failedAt, err := time.Parse(time.RFC3339, value("failed_at")); err == nil {
+		dl.FailedAt = failedAt
+	}
+	return dl, nil
+}
+
+// endregion
@@ -3,8 +3,10 @@ package jobsqueue
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"math/rand"
+	"reflect"
 	"sync"
 	"testing"
 	"time"
@@ -69,6 +71,36 @@ func TestRedisJobsQueue(t *testing.T) {
 		}
 	})
 
+	t.Run("delayed job is not delivered before delay passes", func(t *testing.T) {
+		queue, err := NewRedisJobsQueue(redisClient, 10, randomPrefix(), logger)
+		if err != nil {
+			t.Errorf("error creating redis job queue: %v", err)
+		}
+		defer queue.Shutdown()
+
+		const delay = 5 * time.Second
+		publishedAt := time.Now()
+		if err := queue.PublishDelayed(ctx, "some-job-type", map[string]string{"foo": "bar"}, delay); err != nil {
+			t.Errorf("error publishing job: %v", err)
+		}
+
+		delivered := make(chan time.Time, 1)
+		queue.Subscribe(ctx, "some-job-type", func(payloadBytes []byte) error {
+			delivered <- time.Now()
+			return nil
+		})
+		queue.Run()
+
+		select {
+		case deliveredAt := <-delivered:
+			if deliveredAt.Sub(publishedAt) < delay {
+				t.Errorf("job was delivered after %s, before its delay of %s", deliveredAt.Sub(publishedAt), delay)
+			}
+		case <-time.After(20 * time.Second):
+			t.Errorf("delayed job was never delivered to subscriber")
+		}
+	})
+
 	t.Run("job is retried", func(t *testing.T) {
 		// If job is failed once, it will be retried shortly after
 		queue, err := NewRedisJobsQueue(redisClient, 10, randomPrefix(), logger)
@@ -106,6 +138,154 @@ func TestRedisJobsQueue(t *testing.T) {
 			t.Errorf("job was never retried")
 		}
 	})
+
+	t.Run("panicking job is retried", func(t *testing.T) {
+		// Panic in a handler fails the job instead of crashing the worker, so it's retried as usual
+		queue, err := NewRedisJobsQueue(redisClient, 10, randomPrefix(), logger)
+		if err != nil {
+			t.Errorf("error creating redis job queue: %v", err)
+		}
+		defer queue.Shutdown()
+
+		err = queue.Publish(ctx, "some-job-type", map[string]string{"foo": "bar"})
+		if err != nil {
+			t.Errorf("error publishing job: %v", err)
+		}
+
+		var callCountMutex sync.RWMutex
+		callCount := 0
+		queue.Subscribe(ctx, "some-job-type", func(payloadBytes []byte) error {
+			callCountMutex.Lock()
+			callCount++
+			currentCallCount := callCount
+			callCountMutex.Unlock()
+			if currentCallCount < 2 {
+				var variants []string
+				_ = variants[0]
+			}
+			return nil
+		})
+
+		queue.Run()
+
+		if eventually(60*time.Second, func() bool {
+			callCountMutex.RLock()
+			defer callCountMutex.RUnlock()
+			return callCount == 2
+		}) != true {
+			t.Errorf("job was never retried after panic")
+		}
+	})
+
+	t.Run("queue depth counts jobs waiting to be handled", func(t *testing.T) {
+		// Worker is never started, so published jobs stay in the queue
+		queue, err := NewRedisJobsQueue(redisClient, 10, randomPrefix(), logger)
+		if err != nil {
+			t.Errorf("error creating redis job queue: %v", err)
+		}
+
+		for i := 0; i < 2; i++ {
+			if err := queue.Publish(ctx, "some-job-type", map[string]string{"foo": "bar"}); err != nil {
+				t.Errorf("error publishing job: %v", err)
+			}
+		}
+		queue.Subscribe(ctx, "some-job-type", func(payloadBytes []byte) error { return nil })
+		queue.Subscribe(ctx, "other-job-type", func(payloadBytes []byte) error { return nil })
+
+		depths, err := queue.QueueDepths(ctx)
+		if err != nil {
+			t.Fatalf("error getting queue depths: %v", err)
+		}
+		if expected := map[string]int64{"some-job-type": 2, "other-job-type": 0}; !reflect.DeepEqual(expected, depths) {
+			t.Errorf("expected queue depths %v, got %v", expected, depths)
+		}
+	})
+
+	t.Run("job out of retries is dead-lettered", func(t *testing.T) {
+		// Job failing every attempt ends up in dead letters, from where it can be requeued once the cause is fixed
+		queue, err := NewRedisJobsQueue(redisClient, 10, randomPrefix(), logger, WithMaxRetries(2))
+		if err != nil {
+			t.Errorf("error creating redis job queue: %v", err)
+		}
+		defer queue.Shutdown()
+
+		err = queue.Publish(ctx, "some-job-type", map[string]string{"foo": "bar"})
+		if err != nil {
+			t.Errorf("error publishing job: %v", err)
+		}
+
+		var callCountMutex sync.RWMutex
+		callCount := 0
+		fixed := false
+		queue.Subscribe(ctx, "some-job-type", func(payloadBytes []byte) error {
+			callCountMutex.Lock()
+			defer callCountMutex.Unlock()
+			callCount++
+			if !fixed {
+				return fmt.Errorf("permanent error")
+			}
+			return nil
+		})
+
+		queue.Run()
+
+		var deadLetters []DeadLetter
+		if eventually(60*time.Second, func() bool {
+			deadLetters, err = queue.ListDeadLetters(ctx)
+			return err == nil && len(deadLetters) == 1
+		}) != true {
+			t.Fatalf("job never got to dead letters, last error: %v", err)
+		}
+		callCountMutex.RLock()
+		if callCount != 2 {
+			t.Errorf("expected job to be attempted 2 times, got %d", callCount)
+		}
+		callCountMutex.RUnlock()
+
+		if count, err := queue.CountDeadLetters(ctx); err != nil || count != 1 {
+			t.Errorf("expected 1 dead letter to be counted, got %d, %v", count, err)
+		}
+
+		dl := deadLetters[0]
+		if dl.JobType != "some-job-type" || dl.LastError != "permanent error" || string(dl.Payload) != `{"foo":"bar"}` {
+			t.Errorf("unexpected dead letter: %+v", dl)
+		}
+
+		callCountMutex.Lock()
+		fixed = true
+		callCountMutex.Unlock()
+		if err := queue.RequeueDeadLetter(ctx, dl.ID); err != nil {
+			t.Fatalf("error requeueing dead letter: %v", err)
+		}
+
+		if eventually(20*time.Second, func() bool {
+			callCountMutex.RLock()
+			defer callCountMutex.RUnlock()
+			return callCount == 3
+		}) != true {
+			t.Errorf("requeued job was never delivered to subscriber")
+		}
+		if deadLetters, err := queue.ListDeadLetters(ctx); err != nil || len(deadLetters) != 0 {
+			t.Errorf("expected requeued dead letter to be removed, got %v, %v", deadLetters, err)
+		}
+		if err := queue.RequeueDeadLetter(ctx, dl.ID); !errors.Is(err, ErrDeadLetterNotFound) {
+			t.Errorf("expected ErrDeadLetterNotFound, got %v", err)
+		}
+	})
+}
+
+func TestCallRecovering(t *testing.T) {
+	err := callRecovering(func(payloadBytes []byte) error {
+		panic("some panic")
+	}, nil)
+	if !errors.Is(err, ErrHandlerPanicked) {
+		t.Fatalf("expected panic to be turned into ErrHandlerPanicked, got %v", err)
+	}
+
+	someErr := fmt.Errorf("some error")
+	if err := callRecovering(func(payloadBytes []byte) error { return someErr }, nil); err != someErr {
+		t.Fatalf("expected handler error to be returned as is, got %v", err)
+	}
 }
 
 func eventually(timeout time.Duration, f func() bool) bool {
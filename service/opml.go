@@ -0,0 +1,72 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"encoding/xml"
+	"io"
+	"time"
+
+	"github.com/hori-ryota/zaperr"
+	"go.uber.org/zap"
+)
+
+type opml struct {
+	XMLName xml.Name `xml:"opml"`
+	Version string   `xml:"version,attr"`
+	Head    opmlHead `xml:"head"`
+	Body    opmlBody `xml:"body"`
+}
+
+type opmlHead struct {
+	Title       string `xml:"title"`
+	DateCreated string `xml:"dateCreated"`
+}
+
+type opmlBody struct {
+	Outlines []opmlOutline `xml:"outline"`
+}
+
+type opmlOutline struct {
+	Type   string `xml:"type,attr"`
+	Text   string `xml:"text,attr"`
+	Title  string `xml:"title,attr"`
+	XMLURL string `xml:"xmlUrl,attr"`
+}
+
+// ExportOPML renders all user feeds as an OPML 2.0 subscription list,
+// which podcast apps can import in one go.
+// Unlike ListFeeds, exporting does not create the default feed for users who have none
+func (svc *Service) ExportOPML(ctx context.Context, userID string) (io.Reader, error) {
+	feeds, err := svc.repository.ListUserFeeds(ctx, userID)
+	if err != nil {
+		return nil, zaperr.Wrap(err, "failed to list user feeds", zap.String("user_id", userID))
+	}
+
+	doc := &opml{
+		Version: "2.0",
+		Head: opmlHead{
+			Title:       svc.defaultFeedTitle + " feeds",
+			DateCreated: time.Now().UTC().Format(time.RFC1123Z),
+		},
+		Body: opmlBody{Outlines: make([]opmlOutline, 0, len(feeds))},
+	}
+	for _, feed := range feeds {
+		doc.Body.Outlines = append(doc.Body.Outlines, opmlOutline{
+			Type:   "rss",
+			Text:   feed.Title,
+			Title:  feed.Title,
+			XMLURL: feed.URL,
+		})
+	}
+
+	b := &bytes.Buffer{}
+	b.WriteString(xml.Header)
+	enc := xml.NewEncoder(b)
+	enc.Indent("", "  ")
+	if err := enc.Encode(doc); err != nil {
+		return nil, zaperr.Wrap(err, "failed to write opml", zap.String("user_id", userID))
+	}
+
+	return b, nil
+}
@@ -0,0 +1,59 @@
+package service
+
+import (
+	"context"
+	"encoding/xml"
+	"io"
+	"testing"
+
+	"tg-podcastotron/mediary/mediarymocks"
+)
+
+func TestService__ExportOPML(t *testing.T) {
+	ctx := context.Background()
+	svc, _, _ := newTestService(t, &mediarymocks.ServiceMock{})
+
+	exportOPML := func(userID string) *opml {
+		t.Helper()
+		r, err := svc.ExportOPML(ctx, userID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		data, err := io.ReadAll(r)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var doc opml
+		if err := xml.Unmarshal(data, &doc); err != nil {
+			t.Fatalf("expected valid opml, got %v:\n%s", err, data)
+		}
+		return &doc
+	}
+
+	t.Run("no feeds", func(t *testing.T) {
+		doc := exportOPML("user-without-feeds")
+		if doc.Version != "2.0" {
+			t.Errorf("expected opml 2.0, got %q", doc.Version)
+		}
+		if len(doc.Body.Outlines) != 0 {
+			t.Errorf("expected no outlines, got %+v", doc.Body.Outlines)
+		}
+	})
+
+	t.Run("some feeds", func(t *testing.T) {
+		userID := "user-with-feeds"
+		feed, err := svc.CreateFeed(ctx, userID, "Lectures & Talks")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		doc := exportOPML(userID)
+		if len(doc.Body.Outlines) != 1 {
+			t.Fatalf("expected 1 outline, got %+v", doc.Body.Outlines)
+		}
+		outline := doc.Body.Outlines[0]
+		if outline.Type != "rss" || outline.Title != "Lectures & Talks" || outline.XMLURL != feed.URL {
+			t.Errorf("unexpected outline: %+v", outline)
+		}
+	})
+}
@@ -2,41 +2,288 @@ package service
 
 import (
 	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
 	"fmt"
 	"io"
 	"strconv"
-
-	"github.com/jbub/podcasts"
+	"strings"
+	"time"
 )
 
-func generateFeed(feed *Feed, episodes []*Episode) (io.ReadSeeker, error) {
-	p := &podcasts.Podcast{
-		Title: feed.Title,
+const DefaultFeedGenerator = "tg-podcastotron"
+
+const podcastNamespace = "https://podcastindex.org/namespace/1.0"
+
+const atomNamespace = "http://www.w3.org/2005/Atom"
+
+// region rss structure
+
+type podcastRSS struct {
+	XMLName     xml.Name `xml:"rss"`
+	Version     string   `xml:"version,attr"`
+	ItunesXMLNS string   `xml:"xmlns:itunes,attr"`
+	AtomXMLNS   string   `xml:"xmlns:atom,attr"`
+	// PodcastXMLNS is only declared when feed uses Podcasting 2.0 tags, so that feeds without them stay the same
+	PodcastXMLNS string          `xml:"xmlns:podcast,attr,omitempty"`
+	Channel      *podcastChannel `xml:"channel"`
+}
+
+type podcastChannel struct {
+	Title          string         `xml:"title"`
+	Link           string         `xml:"link"`
+	Description    string         `xml:"description"`
+	AtomLink       *atomLink      `xml:"atom:link"`
+	LastBuildDate  string         `xml:"lastBuildDate,omitempty"`
+	Copyright      string         `xml:"copyright,omitempty"`
+	Generator      string         `xml:"generator,omitempty"`
+	TTL            int            `xml:"ttl,omitempty"`
+	SkipHours      *skipHours     `xml:"skipHours,omitempty"`
+	SkipDays       *skipDays      `xml:"skipDays,omitempty"`
+	ItunesAuthor   string         `xml:"itunes:author"`
+	ItunesSummary  string         `xml:"itunes:summary"`
+	ItunesExplicit string         `xml:"itunes:explicit"`
+	ItunesImage    *itunesImage   `xml:"itunes:image,omitempty"`
+	Items          []*podcastItem `xml:"item"`
+}
+
+// atomLink points feed to its own URL, validators warn when it's missing
+type atomLink struct {
+	Href string `xml:"href,attr"`
+	Rel  string `xml:"rel,attr"`
+	Type string `xml:"type,attr"`
+}
+
+type skipHours struct {
+	Hours []int `xml:"hour"`
+}
+
+type skipDays struct {
+	Days []string `xml:"day"`
+}
+
+type itunesImage struct {
+	Href string `xml:"href,attr"`
+}
+
+type podcastItem struct {
+	Title     string              `xml:"title"`
+	GUID      *podcastGUID        `xml:"guid"`
+	PubDate   string              `xml:"pubDate"`
+	Duration  string              `xml:"itunes:duration,omitempty"`
+	Explicit  string              `xml:"itunes:explicit,omitempty"` // channel's value applies when omitted
+	Image     *itunesImage        `xml:"itunes:image,omitempty"`
+	Enclosure *podcastEnclosure   `xml:"enclosure"`
+	Chapters  *podcastChaptersRef `xml:"podcast:chapters,omitempty"`
+}
+
+type podcastChaptersRef struct {
+	URL  string `xml:"url,attr"`
+	Type string `xml:"type,attr"`
+}
+
+type podcastGUID struct {
+	IsPermaLink string `xml:"isPermaLink,attr"`
+	Value       string `xml:",chardata"`
+}
+
+type podcastEnclosure struct {
+	URL    string `xml:"url,attr"`
+	Length string `xml:"length,attr,omitempty"`
+	Type   string `xml:"type,attr"`
+}
+
+// endregion
+
+// generateFeed renders feed as RSS. Zero buildDate omits <lastBuildDate>,
+// which makes output depend on feed contents only
+func generateFeed(feed *Feed, episodes []*Episode, generator string, buildDate time.Time) (io.ReadSeeker, error) {
+	channel := &podcastChannel{
+		Title:     feed.Title,
+		Link:      feed.URL,
+		AtomLink:  &atomLink{Href: feed.URL, Rel: "self", Type: "application/rss+xml"},
+		Copyright: feed.Copyright,
+		Generator: generator,
+		// podcast clients refuse feeds lacking author or description, so title is used when they are not set
+		Description:    valueOrTitle(feed.Description, feed),
+		ItunesAuthor:   valueOrTitle(feed.Author, feed),
+		ItunesSummary:  valueOrTitle(feed.Description, feed),
+		ItunesExplicit: "false",
+		TTL:            feed.TTLMinutes,
+	}
+	if !buildDate.IsZero() {
+		channel.LastBuildDate = buildDate.Format(time.RFC1123Z)
+	}
+	if len(feed.SkipHours) > 0 {
+		channel.SkipHours = &skipHours{Hours: feed.SkipHours}
+	}
+	if len(feed.SkipDays) > 0 {
+		channel.SkipDays = &skipDays{}
+		for _, d := range feed.SkipDays {
+			channel.SkipDays.Days = append(channel.SkipDays.Days, d.String())
+		}
+	}
+
+	// episodes have no images of their own, so they inherit feed's one
+	var image *itunesImage
+	if feed.ImageURL != "" {
+		image = &itunesImage{Href: feed.ImageURL}
+		channel.ItunesImage = image
 	}
 
+	podcastXMLNS := ""
 	for _, e := range episodes {
-		p.AddItem(&podcasts.Item{
+		pubDate := e.CreatedAt
+		if !e.PubDate.IsZero() {
+			pubDate = e.PubDate
+		}
+		item := &podcastItem{
 			Title:    fmt.Sprintf("%s (#%s)", e.Title, e.ID),
-			GUID:     e.ID,
-			PubDate:  podcasts.NewPubDate(e.CreatedAt),
-			Duration: podcasts.NewDuration(e.Duration),
-			Enclosure: &podcasts.Enclosure{
+			GUID:     &podcastGUID{IsPermaLink: "false", Value: episodeGUID(e)},
+			PubDate:  pubDate.Format(time.RFC1123Z),
+			Duration: formatItunesDuration(e.Duration),
+			Explicit: itunesExplicit(e.Explicit),
+			Image:    image,
+			Enclosure: &podcastEnclosure{
 				URL:    e.URL,
 				Length: strconv.FormatInt(e.FileLenBytes, 10),
-				Type:   e.Format,
+				Type:   enclosureType(e.Format),
 			},
-		})
+		}
+		if e.ChaptersURL != "" {
+			item.Chapters = &podcastChaptersRef{URL: e.ChaptersURL, Type: chaptersMIMEType}
+			podcastXMLNS = podcastNamespace
+		}
+		channel.Items = append(channel.Items, item)
 	}
 
-	podcastFeed, err := p.Feed()
-	if err != nil {
-		return nil, fmt.Errorf("failed to generate feed: %w", err)
+	b := &bytes.Buffer{}
+	b.WriteString(xml.Header)
+	enc := xml.NewEncoder(b)
+	enc.Indent("", "  ")
+	if err := enc.Encode(&podcastRSS{
+		Version:      "2.0",
+		ItunesXMLNS:  "http://www.itunes.com/dtds/podcast-1.0.dtd",
+		AtomXMLNS:    atomNamespace,
+		PodcastXMLNS: podcastXMLNS,
+		Channel:      channel,
+	}); err != nil {
+		return nil, fmt.Errorf("failed to write feed: %w", err)
 	}
 
+	return bytes.NewReader(b.Bytes()), nil
+}
+
+func valueOrTitle(value string, feed *Feed) string {
+	if value == "" {
+		return feed.Title
+	}
+	return value
+}
+
+// itunesExplicit renders optional explicit flag, empty string means the flag is not set
+func itunesExplicit(explicit *bool) string {
+	if explicit == nil {
+		return ""
+	}
+	return strconv.FormatBool(*explicit)
+}
+
+// episodeFormatMIMETypes lists formats episodes can be encoded to
+var episodeFormatMIMETypes = map[string]string{
+	"mp3":  "audio/mpeg",
+	"opus": "audio/ogg",
+	"m4a":  "audio/mp4",
+	"aac":  "audio/aac",
+}
+
+// formatFromMIMEType is the reverse of enclosureType, it returns empty string for unsupported MIME types
+func formatFromMIMEType(mimeType string) string {
+	// mp3 files are occasionally reported with non-standard MIME type
+	if mimeType == "audio/mp3" {
+		return "mp3"
+	}
+	for format, formatMIMEType := range episodeFormatMIMETypes {
+		if formatMIMEType == mimeType {
+			return format
+		}
+	}
+	return ""
+}
+
+// MIMETypeByExtension returns MIME type of a file with supported episode format, or empty string otherwise.
+// It's for files whose MIME type wasn't reported, like documents sent to the bot
+func MIMETypeByExtension(ext string) string {
+	return episodeFormatMIMETypes[strings.TrimPrefix(strings.ToLower(ext), ".")]
+}
+
+// enclosureType returns MIME type of an episode format.
+// Imported episodes store MIME type as their format already, so unknown formats are returned as is
+func enclosureType(format string) string {
+	if mimeType, ok := episodeFormatMIMETypes[format]; ok {
+		return mimeType
+	}
+	return format
+}
+
+// formatItunesDuration formats duration as HH:MM:SS.
+// Unknown (zero) duration is formatted as empty string, so that the tag is omitted altogether
+func formatItunesDuration(d time.Duration) string {
+	if d <= 0 {
+		return ""
+	}
+	totalSeconds := int64(d.Round(time.Second) / time.Second)
+	hours := totalSeconds / 3600
+	minutes := (totalSeconds % 3600) / 60
+	seconds := totalSeconds % 60
+	return fmt.Sprintf("%02d:%02d:%02d", hours, minutes, seconds)
+}
+
+// gzipFeed compresses generated feed so that it can be stored with Content-Encoding: gzip
+func gzipFeed(feed io.Reader) (io.ReadSeeker, error) {
 	b := &bytes.Buffer{}
-	if err = podcastFeed.Write(b); err != nil {
-		return nil, fmt.Errorf("failed to write feed: %w", err)
+	gz := gzip.NewWriter(b)
+	if _, err := io.Copy(gz, feed); err != nil {
+		return nil, fmt.Errorf("failed to compress feed: %w", err)
 	}
+	if err := gz.Close(); err != nil {
+		return nil, fmt.Errorf("failed to finish feed compression: %w", err)
+	}
+	return bytes.NewReader(b.Bytes()), nil
+}
+
+// feedHashMetadataKey is a name of stored feed's metadata holding hash of its contents
+const feedHashMetadataKey = "hash"
+
+// hashFeed returns hex-encoded sha256 of feed contents and rewinds it back for upload
+func hashFeed(feed io.ReadSeeker) (string, error) {
+	h := sha256.New()
+	if _, err := io.Copy(h, feed); err != nil {
+		return "", fmt.Errorf("failed to read feed: %w", err)
+	}
+	if _, err := feed.Seek(0, io.SeekStart); err != nil {
+		return "", fmt.Errorf("failed to rewind feed: %w", err)
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// gunzipFeed decompresses feed if it is gzipped and returns it as is otherwise
+func gunzipFeed(data []byte) ([]byte, error) {
+	if len(data) < 2 || data[0] != 0x1f || data[1] != 0x8b {
+		return data, nil
+	}
+	gz, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read compressed feed: %w", err)
+	}
+	defer func() { _ = gz.Close() }()
+	return io.ReadAll(gz)
+}
 
-	return bytes.NewReader(b.Bytes()), nil // TODO: there must be a better way to do this
+// episodeGUID identifies episode across all users' feeds, it must not change on rename,
+// otherwise podcast clients would download episode again
+func episodeGUID(e *Episode) string {
+	return fmt.Sprintf("%s-%s", e.UserID, e.ID)
 }
@@ -0,0 +1,257 @@
+package service
+
+import (
+	"encoding/xml"
+	"io"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestGenerateFeed(t *testing.T) {
+	feed := &Feed{
+		ID:        "1",
+		UserID:    "some-user",
+		Title:     "Some feed",
+		URL:       "https://example.com/feeds/some-user/1",
+		Copyright: "© 2024 Some Author",
+	}
+	episodes := []*Episode{{
+		ID:           "1",
+		UserID:       "some-user",
+		Title:        "Some episode",
+		CreatedAt:    time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC),
+		URL:          "https://example.com/episodes/some-user/1.mp3",
+		Duration:     90 * time.Second,
+		FileLenBytes: 1000,
+		Format:       "mp3",
+	}}
+
+	t.Run("Copyright and generator", func(t *testing.T) {
+		xml := renderFeed(t, feed, episodes, "some-generator")
+
+		if !strings.Contains(xml, "<copyright>© 2024 Some Author</copyright>") {
+			t.Fatalf("expected feed to contain copyright, got %s", xml)
+		}
+		if !strings.Contains(xml, "<generator>some-generator</generator>") {
+			t.Fatalf("expected feed to contain generator, got %s", xml)
+		}
+	})
+
+	t.Run("Description and author", func(t *testing.T) {
+		feedWithMetadata := *feed
+		feedWithMetadata.Description = "All about something"
+		feedWithMetadata.Author = "Some Author"
+
+		xml := renderFeed(t, &feedWithMetadata, episodes, "")
+
+		for _, expected := range []string{
+			"<description>All about something</description>",
+			"<itunes:summary>All about something</itunes:summary>",
+			"<itunes:author>Some Author</itunes:author>",
+		} {
+			if !strings.Contains(xml, expected) {
+				t.Errorf("expected feed to contain %s, got %s", expected, xml)
+			}
+		}
+	})
+
+	t.Run("Description falls back to title", func(t *testing.T) {
+		xml := renderFeed(t, feed, episodes, "")
+
+		if !strings.Contains(xml, "<description>Some feed</description>") {
+			t.Fatalf("expected title to be used as description, got %s", xml)
+		}
+	})
+
+	t.Run("Cover image at channel and item level", func(t *testing.T) {
+		feedWithImage := *feed
+		feedWithImage.ImageURL = "https://example.com/feed-images/some-user/1"
+
+		xml := renderFeed(t, &feedWithImage, episodes, "")
+
+		if strings.Count(xml, `<itunes:image href="https://example.com/feed-images/some-user/1"></itunes:image>`) != 2 {
+			t.Fatalf("expected both channel and item to reference the image, got %s", xml)
+		}
+	})
+
+	t.Run("Cover image is omitted when not set", func(t *testing.T) {
+		xml := renderFeed(t, feed, episodes, "")
+
+		if strings.Contains(xml, "itunes:image") {
+			t.Fatalf("expected no image, got %s", xml)
+		}
+	})
+
+	t.Run("TTL and skip hours and days", func(t *testing.T) {
+		scheduledFeed := *feed
+		scheduledFeed.TTLMinutes = 60
+		scheduledFeed.SkipHours = []int{0, 1, 23}
+		scheduledFeed.SkipDays = []time.Weekday{time.Sunday, time.Saturday}
+
+		xml := renderFeed(t, &scheduledFeed, episodes, "")
+
+		for _, expected := range []string{
+			"<ttl>60</ttl>",
+			"<skipHours>\n      <hour>0</hour>\n      <hour>1</hour>\n      <hour>23</hour>\n    </skipHours>",
+			"<skipDays>\n      <day>Sunday</day>\n      <day>Saturday</day>\n    </skipDays>",
+		} {
+			if !strings.Contains(xml, expected) {
+				t.Fatalf("expected feed to contain %q, got %s", expected, xml)
+			}
+		}
+	})
+
+	t.Run("Schedule is omitted when not set", func(t *testing.T) {
+		xml := renderFeed(t, feed, episodes, "")
+
+		for _, unexpected := range []string{"<ttl>", "<skipHours>", "<skipDays>"} {
+			if strings.Contains(xml, unexpected) {
+				t.Fatalf("expected feed not to contain %q, got %s", unexpected, xml)
+			}
+		}
+	})
+
+	t.Run("Duration is omitted when unknown", func(t *testing.T) {
+		unknownDurationEp := *episodes[0]
+		unknownDurationEp.ID = "2"
+		unknownDurationEp.Duration = 0
+
+		xml := renderFeed(t, feed, []*Episode{episodes[0], &unknownDurationEp}, "")
+
+		if strings.Count(xml, "<itunes:duration>") != 1 {
+			t.Fatalf("expected only one episode to have duration, got %s", xml)
+		}
+		if !strings.Contains(xml, "<itunes:duration>00:01:30</itunes:duration>") {
+			t.Fatalf("expected known duration to be formatted, got %s", xml)
+		}
+	})
+}
+
+func TestGenerateFeed__Itunes(t *testing.T) {
+	const itunesNS = "http://www.itunes.com/dtds/podcast-1.0.dtd"
+	type parsedItem struct {
+		Duration  string  `xml:"http://www.itunes.com/dtds/podcast-1.0.dtd duration"`
+		Explicit  *string `xml:"http://www.itunes.com/dtds/podcast-1.0.dtd explicit"`
+		Enclosure struct {
+			Length string `xml:"length,attr"`
+			Type   string `xml:"type,attr"`
+		} `xml:"enclosure"`
+	}
+	type parsedFeed struct {
+		Channel struct {
+			Author   *string      `xml:"http://www.itunes.com/dtds/podcast-1.0.dtd author"`
+			Summary  *string      `xml:"http://www.itunes.com/dtds/podcast-1.0.dtd summary"`
+			Explicit *string      `xml:"http://www.itunes.com/dtds/podcast-1.0.dtd explicit"`
+			Items    []parsedItem `xml:"item"`
+		} `xml:"channel"`
+	}
+
+	feed := &Feed{ID: "1", UserID: "some-user", Title: "Some feed", URL: "https://example.com/feeds/some-user/1"}
+	explicit := true
+	episodes := []*Episode{
+		{ID: "1", Title: "First", URL: "https://example.com/1.mp3", Duration: 90 * time.Second, FileLenBytes: 1000, Format: "mp3", Explicit: &explicit},
+		{ID: "2", Title: "Second", URL: "https://example.com/2.opus", Duration: 2*time.Hour + 3*time.Minute + 4*time.Second, FileLenBytes: 2000, Format: "opus"},
+	}
+
+	var parsed parsedFeed
+	if err := xml.Unmarshal([]byte(renderFeed(t, feed, episodes, "")), &parsed); err != nil {
+		t.Fatalf("failed to parse generated feed: %v", err)
+	}
+	if len(parsed.Channel.Items) != 2 {
+		t.Fatalf("expected 2 items, got %d", len(parsed.Channel.Items))
+	}
+	first, second := parsed.Channel.Items[0], parsed.Channel.Items[1]
+
+	for _, tc := range []struct {
+		element  string
+		value    *string
+		expected string
+	}{
+		{element: "itunes:author", value: parsed.Channel.Author, expected: "Some feed"},
+		{element: "itunes:summary", value: parsed.Channel.Summary, expected: "Some feed"},
+		{element: "itunes:explicit", value: parsed.Channel.Explicit, expected: "false"},
+		{element: "first itunes:explicit", value: first.Explicit, expected: "true"},
+		{element: "first itunes:duration", value: &first.Duration, expected: "00:01:30"},
+		{element: "second itunes:duration", value: &second.Duration, expected: "02:03:04"},
+		{element: "first enclosure length", value: &first.Enclosure.Length, expected: "1000"},
+		{element: "second enclosure length", value: &second.Enclosure.Length, expected: "2000"},
+		{element: "first enclosure type", value: &first.Enclosure.Type, expected: "audio/mpeg"},
+		{element: "second enclosure type", value: &second.Enclosure.Type, expected: "audio/ogg"},
+	} {
+		t.Run(tc.element, func(t *testing.T) {
+			if tc.value == nil {
+				t.Fatalf("expected %s to be present in %s namespace", tc.element, itunesNS)
+			}
+			if *tc.value != tc.expected {
+				t.Fatalf("expected %s to be %q, got %q", tc.element, tc.expected, *tc.value)
+			}
+		})
+	}
+
+	t.Run("second itunes:explicit", func(t *testing.T) {
+		if second.Explicit != nil {
+			t.Fatalf("expected episode without explicit flag to follow channel's one, got %q", *second.Explicit)
+		}
+	})
+}
+
+func TestGenerateFeed__SelfLinkAndBuildDate(t *testing.T) {
+	type parsedFeed struct {
+		AtomXMLNS string `xml:"xmlns atom,attr"`
+		Channel   struct {
+			AtomLink *struct {
+				Href string `xml:"href,attr"`
+				Rel  string `xml:"rel,attr"`
+				Type string `xml:"type,attr"`
+			} `xml:"http://www.w3.org/2005/Atom link"`
+			LastBuildDate *string `xml:"lastBuildDate"`
+		} `xml:"channel"`
+	}
+
+	feed := &Feed{ID: "1", UserID: "some-user", Title: "Some feed", URL: "https://example.com/feeds/some-user/1"}
+	buildDate := time.Date(2024, 2, 3, 4, 5, 6, 0, time.UTC)
+
+	reader, err := generateFeed(feed, nil, "", buildDate)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var parsed parsedFeed
+	if err := xml.NewDecoder(reader).Decode(&parsed); err != nil {
+		t.Fatalf("failed to parse generated feed: %v", err)
+	}
+
+	if parsed.AtomXMLNS != "http://www.w3.org/2005/Atom" {
+		t.Errorf("expected atom namespace to be declared on root element, got %q", parsed.AtomXMLNS)
+	}
+	if link := parsed.Channel.AtomLink; link == nil {
+		t.Errorf("expected atom:link to be present")
+	} else if link.Href != feed.URL || link.Rel != "self" || link.Type != "application/rss+xml" {
+		t.Errorf("expected atom:link to point to feed itself, got %+v", *link)
+	}
+	if parsed.Channel.LastBuildDate == nil {
+		t.Errorf("expected lastBuildDate to be present")
+	} else if *parsed.Channel.LastBuildDate != "Sat, 03 Feb 2024 04:05:06 +0000" {
+		t.Errorf("expected lastBuildDate to be build date, got %q", *parsed.Channel.LastBuildDate)
+	}
+
+	t.Run("no build date", func(t *testing.T) {
+		xml := renderFeed(t, feed, nil, "")
+		if strings.Contains(xml, "lastBuildDate") {
+			t.Fatalf("expected lastBuildDate to be omitted, got %s", xml)
+		}
+	})
+}
+
+func renderFeed(t *testing.T, feed *Feed, episodes []*Episode, generator string) string {
+	t.Helper()
+	reader, err := generateFeed(feed, episodes, generator, time.Time{})
+	if err != nil {
+		t.Fatalf("failed to generate feed: %v", err)
+	}
+	b, err := io.ReadAll(reader)
+	if err != nil {
+		t.Fatalf("failed to read feed: %v", err)
+	}
+	return string(b)
+}
@@ -0,0 +1,68 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/hori-ryota/zaperr"
+	"github.com/jmoiron/sqlx"
+)
+
+// NewPostgresRepository works with the schema from postgres migrations, which mirrors sqlite one,
+// so most queries are shared with sqliteRepository and only rebound for the driver
+func NewPostgresRepository(db *sql.DB) Repository {
+	return &postgresRepository{sqliteRepository: &sqliteRepository{db: sqlx.NewDb(db, "postgres")}}
+}
+
+type postgresRepository struct {
+	*sqliteRepository
+}
+
+// region episodes
+
+// ListExpiredEpisodes lists episodes that were not updated for longer than their user's retention period,
+// defaultMaxAge applies to users who did not set one
+func (r *postgresRepository) ListExpiredEpisodes(ctx context.Context, defaultMaxAge time.Duration) ([]*Episode, error) {
+	db := r.dbFromContext(ctx)
+
+	// times are stored as RFC3339 text in UTC, so cutoff computed by postgres has to be formatted the same way
+	now := time.Now().UTC()
+	var dbEpisodes []dbEpisode
+	if err := sqlx.SelectContext(ctx, db, &dbEpisodes, `
+		SELECT e.* FROM episodes e
+		LEFT JOIN users u ON u.id = e.user_id
+		WHERE e.updated_at < CASE
+			WHEN COALESCE(u.retention_days, 0) > 0
+				THEN to_char(CAST(? AS TIMESTAMP) - u.retention_days * INTERVAL '1 day', 'YYYY-MM-DD"T"HH24:MI:SS"Z"')
+			ELSE ?
+		END
+		AND e.deleted_at = ''
+		AND NOT e.pinned
+		AND NOT EXISTS (
+			SELECT 1
+			FROM publications p
+			JOIN feeds f ON p.feed_id = f.id AND p.user_id = f.user_id
+			WHERE f.is_permanent = true
+			AND p.episode_id = e.id
+			AND p.user_id = e.user_id
+		)`,
+		timeToStr(now),
+		timeToStr(now.Add(-defaultMaxAge)),
+	); err != nil {
+		return nil, zaperr.Wrap(err, "failed to query episodes")
+	}
+
+	result := make([]*Episode, len(dbEpisodes))
+	for idx, dbEp := range dbEpisodes {
+		if ep, err := dbEp.ToBusinessModel(); err != nil {
+			return nil, zaperr.Wrap(err, "failed to convert to business model")
+		} else {
+			result[idx] = ep
+		}
+	}
+
+	return result, nil
+}
+
+// endregion
@@ -0,0 +1,57 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"net/url"
+	"testing"
+	"time"
+
+	_ "github.com/lib/pq"
+	"tg-podcastotron/db/migrations"
+	tests "tg-podcastotron/testutils"
+)
+
+func TestPostgresRepository__Conformance(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+	defer cancel()
+
+	postgresURL, teardown, err := tests.GetFakePostgresURL(ctx)
+	defer teardown()
+	if err != nil {
+		t.Fatalf("error getting postgres url: %v", err)
+	}
+
+	adminDB, err := sql.Open("postgres", postgresURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = adminDB.Close() }()
+
+	// every repository gets a database of its own, so that suite cases don't see each other's rows
+	var databasesCount int
+	RepositoryConformanceTest(t, func() Repository {
+		databasesCount++
+		dbName := fmt.Sprintf("conformance_%d", databasesCount)
+		if _, err := adminDB.ExecContext(ctx, "CREATE DATABASE "+dbName); err != nil {
+			t.Fatalf("failed to create database: %v", err)
+		}
+
+		dbURL, err := url.Parse(postgresURL)
+		if err != nil {
+			t.Fatal(err)
+		}
+		dbURL.Path = "/" + dbName
+		db, err := sql.Open("postgres", dbURL.String())
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { _ = db.Close() })
+
+		if _, err := migrations.Apply(db, migrations.DialectPostgres); err != nil {
+			t.Fatalf("failed to apply migrations: %v", err)
+		}
+		return NewPostgresRepository(db)
+	})
+}
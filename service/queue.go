@@ -1,15 +1,31 @@
 package service
 
 import (
+	"context"
 	"time"
 )
 
+// queueEvent is a job type bound to type of its payload, so that publishing a mismatching payload doesn't compile
+type queueEvent[T any] string
+
 const (
-	queueEventCreateEpisodes     = "create_episodes"
-	queueEventPollEpisodesStatus = "poll_episodes_status"
-	queueEventRegenerateFeed     = "regenerate_feed"
+	queueEventCreateEpisodes     queueEvent[CreateEpisodesQueuePayload]     = "create_episodes"
+	queueEventPollEpisodesStatus queueEvent[PollEpisodesStatusQueuePayload] = "poll_episodes_status"
+	queueEventRegenerateFeed     queueEvent[RegenerateFeedQueuePayload]     = "regenerate_feed"
+	queueEventPollReencodeJobs   queueEvent[PollReencodeJobsQueuePayload]   = "poll_reencode_jobs"
+	queueEventNotifyWebhook      queueEvent[NotifyWebhookQueuePayload]      = "notify_webhook"
 )
 
+// publish enqueues a job, payloads are always passed by pointer
+func publish[T any](ctx context.Context, jobsQueue JobsQueue, event queueEvent[T], payload *T) error {
+	return jobsQueue.Publish(ctx, string(event), payload)
+}
+
+// publishDelayed enqueues a job to be handled once delay passes
+func publishDelayed[T any](ctx context.Context, jobsQueue JobsQueue, event queueEvent[T], payload *T, delay time.Duration) error {
+	return jobsQueue.PublishDelayed(ctx, string(event), payload, delay)
+}
+
 type ProcessingType string
 
 const (
@@ -23,6 +39,25 @@ type CreateEpisodesQueuePayload struct {
 	VariantsPerEpisode [][]string
 	UserID             string
 	ProcessingType     ProcessingType
+	Tags               []string
+	Options            EpisodeOptions
+}
+
+// EpisodeOptions describe how mediary should encode episode
+type EpisodeOptions struct {
+	Codec       string // one of episode formats, mp3 by default
+	BitrateKbps int    // mediary picks bitrate itself when zero
+}
+
+// format returns episode format chosen by options, defaulting to mp3
+func (opts EpisodeOptions) format() (string, error) {
+	if opts.Codec == "" {
+		return "mp3", nil
+	}
+	if _, ok := episodeFormatMIMETypes[opts.Codec]; !ok {
+		return "", ErrUnsupportedFormat
+	}
+	return opts.Codec, nil
 }
 
 type PollEpisodesStatusQueuePayload struct {
@@ -32,9 +67,42 @@ type PollEpisodesStatusQueuePayload struct {
 	Delay            *time.Duration
 	PollAfter        *time.Time
 	RequeueCount     int
+	// ReportedProgress is progress in whole percents last reported for each episode,
+	// so that unchanged progress is not reported on every poll
+	ReportedProgress map[string]int `json:",omitempty"`
+}
+
+// PollReencodeJobsQueuePayload tracks files of complete episodes being made again in another format
+type PollReencodeJobsQueuePayload struct {
+	UserID       string
+	Jobs         []ReencodeJob
+	Delay        *time.Duration
+	PollAfter    *time.Time
+	RequeueCount int
+}
+
+// ReencodeJob is what episode is switched to once its mediary job is complete
+type ReencodeJob struct {
+	EpisodeID  string
+	MediaryID  string
+	StorageKey string
+	URL        string
+	Format     string
 }
 
 type RegenerateFeedQueuePayload struct {
 	FeedIDs []string
 	UserID  string
+	// IncludeIncomplete makes this single regeneration include episodes that are not complete yet, for debugging
+	IncludeIncomplete bool `json:",omitempty"`
+	// Force makes feeds to be uploaded even if they seem unchanged, for when files in S3 were edited or lost
+	Force bool `json:",omitempty"`
+	// Debounced is set on regeneration that has already waited for more changes, so it runs right away
+	Debounced bool `json:",omitempty"`
+}
+
+// NotifyWebhookQueuePayload carries complete episodes to user's webhook, so that slow webhooks don't hold up polling
+type NotifyWebhookQueuePayload struct {
+	UserID   string
+	Episodes []WebhookPayload
 }
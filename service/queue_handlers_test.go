@@ -0,0 +1,1727 @@
+package service
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+	"golang.org/x/exp/slices"
+	"tg-podcastotron/mediary"
+	"tg-podcastotron/mediary/mediarymocks"
+)
+
+func TestService__PollEpisodes__JobLost(t *testing.T) {
+	ctx := context.Background()
+	mediarySvc := &mediarymocks.ServiceMock{
+		FetchJobStatusBatchFunc: func(ctx context.Context, jobIDs []string) (map[string]*mediary.JobStatus, error) {
+			return map[string]*mediary.JobStatus{}, nil // mediary knows nothing about our job
+		},
+	}
+	svc, jobsQueue, _ := newTestService(t, mediarySvc)
+
+	ep := saveTestEpisode(t, svc, &Episode{
+		ID:        "1",
+		UserID:    "some-user",
+		Title:     "some episode",
+		MediaryID: "lost-job-id",
+		Status:    EpisodeStatusPending,
+	})
+
+	// region below the cap, episode is requeued
+	pollEpisodes(t, svc, &PollEpisodesStatusQueuePayload{
+		EpisodeIDs:   []string{ep.ID},
+		UserID:       ep.UserID,
+		RequeueCount: maxPollEpisodesRequeueCount - 1,
+	})
+	if len(publishedOf(t, jobsQueue, queueEventPollEpisodesStatus)) != 1 {
+		t.Fatalf("expected episode to be requeued for polling")
+	}
+	select {
+	case changes := <-svc.episodeStatusChangesChan:
+		t.Fatalf("expected no status changes, got %v", changes)
+	default:
+	}
+	// endregion
+
+	// region after the cap, episode is failed
+	pollEpisodes(t, svc, &PollEpisodesStatusQueuePayload{
+		EpisodeIDs:   []string{ep.ID},
+		UserID:       ep.UserID,
+		RequeueCount: maxPollEpisodesRequeueCount,
+	})
+	if len(publishedOf(t, jobsQueue, queueEventPollEpisodesStatus)) != 1 {
+		t.Fatalf("expected episode not to be requeued after reaching max requeue count")
+	}
+
+	select {
+	case changes := <-svc.episodeStatusChangesChan:
+		if len(changes) != 1 {
+			t.Fatalf("expected 1 status change, got %d", len(changes))
+		}
+		if changes[0].NewStatus != EpisodeStatusFailed {
+			t.Fatalf("expected episode to become %s, got %s", EpisodeStatusFailed, changes[0].NewStatus)
+		}
+		if !errors.Is(changes[0].Err, ErrJobLost) {
+			t.Fatalf("expected status change to carry ErrJobLost, got %v", changes[0].Err)
+		}
+	default:
+		t.Fatalf("expected user to be notified about failed episode")
+	}
+
+	epMap, err := svc.repository.GetEpisodesMap(ctx, ep.UserID, []string{ep.ID})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if epMap[ep.ID].Status != EpisodeStatusFailed {
+		t.Fatalf("expected saved episode to be %s, got %s", EpisodeStatusFailed, epMap[ep.ID].Status)
+	}
+	// endregion
+}
+
+func TestService__PollEpisodes__JobFailedThenRetried(t *testing.T) {
+	ctx := context.Background()
+	mediarySvc := &mediarymocks.ServiceMock{
+		FetchJobStatusBatchFunc: func(ctx context.Context, jobIDs []string) (map[string]*mediary.JobStatus, error) {
+			return map[string]*mediary.JobStatus{
+				"failed-job-id": {Id: "failed-job-id", Status: mediary.JobStatusFailed},
+				"retry-job-id":  {Id: "retry-job-id", Status: mediary.JobStatusComplete, ResultFileBytes: 1024},
+			}, nil
+		},
+		CreateUploadJobFunc: func(ctx context.Context, params *mediary.CreateUploadJobParams) (string, error) {
+			return "retry-job-id", nil
+		},
+	}
+	svc, jobsQueue, _ := newTestService(t, mediarySvc)
+
+	ep := saveTestEpisode(t, svc, &Episode{
+		ID:              "1",
+		UserID:          "some-user",
+		Title:           "some episode",
+		SourceURL:       "some-media-url",
+		SourceFilepaths: []string{"some/file.mp3"},
+		MediaryID:       "failed-job-id",
+		Status:          EpisodeStatusPending,
+		Format:          "mp3",
+	})
+
+	// region failed job fails episode without polling it further
+	pollEpisodes(t, svc, &PollEpisodesStatusQueuePayload{EpisodeIDs: []string{ep.ID}, UserID: ep.UserID})
+	if len(publishedOf(t, jobsQueue, queueEventPollEpisodesStatus)) != 0 {
+		t.Fatalf("expected failed episode not to be requeued for polling")
+	}
+	select {
+	case changes := <-svc.episodeStatusChangesChan:
+		if len(changes) != 1 || changes[0].NewStatus != EpisodeStatusFailed || !errors.Is(changes[0].Err, ErrJobFailed) {
+			t.Fatalf("expected episode to fail with ErrJobFailed, got %+v", changes)
+		}
+	default:
+		t.Fatalf("expected user to be notified about failed episode")
+	}
+	// endregion
+
+	// region retry creates a new job, which completes
+	retried, err := svc.RetryEpisodes(ctx, ep.UserID, []string{ep.ID})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if retried != 1 {
+		t.Fatalf("expected 1 episode to be retried, got %d", retried)
+	}
+	if len(mediarySvc.CreateUploadJobCalls()) != 1 || mediarySvc.CreateUploadJobCalls()[0].Params.URL != "some-media-url" {
+		t.Fatalf("expected mediary job to be re-created from original source")
+	}
+
+	pollEpisodes(t, svc, &PollEpisodesStatusQueuePayload{EpisodeIDs: []string{ep.ID}, UserID: ep.UserID})
+
+	epMap, err := svc.repository.GetEpisodesMap(ctx, ep.UserID, []string{ep.ID})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if epMap[ep.ID].Status != EpisodeStatusComplete || epMap[ep.ID].MediaryID != "retry-job-id" {
+		t.Fatalf("expected retried episode to complete, got %+v", epMap[ep.ID])
+	}
+	// endregion
+}
+
+func TestService__PollEpisodes__Progress(t *testing.T) {
+	var progress float64
+	mediarySvc := &mediarymocks.ServiceMock{
+		FetchJobStatusBatchFunc: func(ctx context.Context, jobIDs []string) (map[string]*mediary.JobStatus, error) {
+			return map[string]*mediary.JobStatus{
+				"some-job-id": {Id: "some-job-id", Status: mediary.JobStatusDownloading, Progress: progress},
+			}, nil
+		},
+	}
+	svc, jobsQueue, _ := newTestService(t, mediarySvc)
+
+	ep := saveTestEpisode(t, svc, &Episode{
+		ID:        "1",
+		UserID:    "some-user",
+		Title:     "some episode",
+		MediaryID: "some-job-id",
+		Status:    EpisodeStatusDownloading,
+	})
+
+	payload := &PollEpisodesStatusQueuePayload{EpisodeIDs: []string{ep.ID}, UserID: ep.UserID}
+	for _, step := range []struct {
+		progress       float64
+		expectReported bool
+	}{
+		{progress: 0.45, expectReported: true},
+		{progress: 0.45, expectReported: false},
+		{progress: 0.451, expectReported: false}, // rounds to the same percent
+		{progress: 0.6, expectReported: true},
+	} {
+		progress = step.progress
+		pollEpisodes(t, svc, payload)
+
+		select {
+		case changes := <-svc.episodeStatusChangesChan:
+			if !step.expectReported {
+				t.Fatalf("expected unchanged progress %v not to be reported, got %+v", step.progress, changes)
+			}
+			if len(changes) != 1 || changes[0].Progress != step.progress {
+				t.Fatalf("expected progress %v to be reported, got %+v", step.progress, changes)
+			}
+		default:
+			if step.expectReported {
+				t.Fatalf("expected progress %v to be reported", step.progress)
+			}
+		}
+
+		requeued := publishedOf(t, jobsQueue, queueEventPollEpisodesStatus)
+		payload = requeued[len(requeued)-1]
+		payload.PollAfter = nil
+	}
+}
+
+func TestService__PollEpisodes__CompletedEpisodeIsAddedToFeed(t *testing.T) {
+	ctx := context.Background()
+	mediarySvc := &mediarymocks.ServiceMock{
+		FetchJobStatusBatchFunc: func(ctx context.Context, jobIDs []string) (map[string]*mediary.JobStatus, error) {
+			return map[string]*mediary.JobStatus{
+				"pending-job-id": {Id: "pending-job-id", Status: mediary.JobStatusComplete, ResultFileBytes: 1024},
+			}, nil
+		},
+	}
+	svc, jobsQueue, s3Store := newTestService(t, mediarySvc)
+
+	userID := "some-user"
+	feed, err := svc.CreateFeed(ctx, userID, "some feed")
+	if err != nil {
+		t.Fatal(err)
+	}
+	saveTestEpisode(t, svc, &Episode{ID: "1", UserID: userID, Title: "finished episode", Status: EpisodeStatusComplete})
+	saveTestEpisode(t, svc, &Episode{ID: "2", UserID: userID, Title: "processed episode", MediaryID: "pending-job-id", Status: EpisodeStatusPending})
+	if err := svc.PublishEpisodes(ctx, userID, []string{"1", "2"}, []string{feed.ID}); err != nil {
+		t.Fatal(err)
+	}
+	feedKey := svc.constructS3FeedKey(userID, feed.ID, "")
+
+	// region pending episode is left out
+	if err := svc.regenerateFeedFile(ctx, feed); err != nil {
+		t.Fatal(err)
+	}
+	if xml := string(s3Store.objects[feedKey]); !strings.Contains(xml, "finished episode") || strings.Contains(xml, "processed episode") {
+		t.Fatalf("expected feed to include only complete episode, got:\n%s", xml)
+	}
+	// endregion
+
+	// region once complete, episode makes it to the feed
+	regenerationsCount := len(publishedOf(t, jobsQueue, queueEventRegenerateFeed))
+	pollEpisodes(t, svc, &PollEpisodesStatusQueuePayload{EpisodeIDs: []string{"2"}, UserID: userID})
+	regenerations := publishedOf(t, jobsQueue, queueEventRegenerateFeed)
+	if len(regenerations) != regenerationsCount+1 || !slices.Equal(regenerations[len(regenerations)-1].FeedIDs, []string{feed.ID}) {
+		t.Fatalf("expected feed %s to be regenerated once episode is complete, got %+v", feed.ID, regenerations)
+	}
+
+	if err := svc.regenerateFeedFile(ctx, feed); err != nil {
+		t.Fatal(err)
+	}
+	if xml := string(s3Store.objects[feedKey]); !strings.Contains(xml, "finished episode") || !strings.Contains(xml, "processed episode") {
+		t.Fatalf("expected feed to include completed episode, got:\n%s", xml)
+	}
+	// endregion
+}
+
+func TestService__CancelEpisodes(t *testing.T) {
+	ctx := context.Background()
+	mediarySvc := &mediarymocks.ServiceMock{
+		CancelJobFunc: func(ctx context.Context, jobID string) error {
+			return nil
+		},
+		FetchJobStatusBatchFunc: func(ctx context.Context, jobIDs []string) (map[string]*mediary.JobStatus, error) {
+			jobStatusMap := make(map[string]*mediary.JobStatus, len(jobIDs))
+			for _, jobID := range jobIDs {
+				jobStatusMap[jobID] = &mediary.JobStatus{Id: jobID, Status: mediary.JobStatusDownloading}
+			}
+			return jobStatusMap, nil
+		},
+	}
+	svc, jobsQueue, _ := newTestService(t, mediarySvc)
+
+	ep := saveTestEpisode(t, svc, &Episode{
+		ID:        "1",
+		UserID:    "some-user",
+		Title:     "some episode",
+		MediaryID: "some-job-id",
+		Status:    EpisodeStatusPending,
+	})
+
+	cancelled, err := svc.CancelEpisodes(ctx, ep.UserID, []string{ep.ID})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cancelled != 1 {
+		t.Fatalf("expected 1 episode to be cancelled, got %d", cancelled)
+	}
+	if calls := mediarySvc.CancelJobCalls(); len(calls) != 1 || calls[0].JobID != "some-job-id" {
+		t.Fatalf("expected mediary job to be cancelled, got %+v", calls)
+	}
+
+	// poll enqueued before cancellation arrives afterwards
+	pollEpisodes(t, svc, &PollEpisodesStatusQueuePayload{EpisodeIDs: []string{ep.ID}, UserID: ep.UserID})
+	if len(publishedOf(t, jobsQueue, queueEventPollEpisodesStatus)) != 0 {
+		t.Fatalf("expected cancelled episode not to be requeued for polling")
+	}
+
+	epMap, err := svc.repository.GetEpisodesMap(ctx, ep.UserID, []string{ep.ID})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if epMap[ep.ID].Status != EpisodeStatusCancelled {
+		t.Fatalf("expected episode to stay %s, got %s", EpisodeStatusCancelled, epMap[ep.ID].Status)
+	}
+}
+
+func TestService__CreateEpisodesQueueEvent__PartialFailure(t *testing.T) {
+	ctx := context.Background()
+	var mu sync.Mutex
+	jobsCreated := 0
+	failedOnce := false
+	mediarySvc := &mediarymocks.ServiceMock{
+		FetchMetadataLongPollingFunc: func(ctx context.Context, mediaURL string) (*mediary.Metadata, error) {
+			return &mediary.Metadata{URL: mediaURL, Name: "some torrent", DownloaderName: "torrent"}, nil
+		},
+		CreateUploadJobFunc: func(ctx context.Context, params *mediary.CreateUploadJobParams) (string, error) {
+			mu.Lock()
+			defer mu.Unlock()
+			// episodes are created concurrently, so failure is tied to a variant rather than to order of calls
+			if params.Params.(mediary.UploadOriginalJobParams).Variant == "3.mp3" && !failedOnce {
+				failedOnce = true
+				return "", fmt.Errorf("mediary is temporarily unavailable")
+			}
+			jobsCreated++
+			return fmt.Sprintf("job-%d", jobsCreated), nil
+		},
+	}
+	svc, jobsQueue, _ := newTestService(t, mediarySvc)
+
+	userID := "some-user"
+	payloadBytes, err := json.Marshal(&CreateEpisodesQueuePayload{
+		URL:                "magnet:?xt=urn:btih:some-hash",
+		VariantsPerEpisode: [][]string{{"1.mp3"}, {"2.mp3"}, {"3.mp3"}, {"4.mp3"}, {"5.mp3"}},
+		UserID:             userID,
+		ProcessingType:     ProcessingTypeUploadOriginal,
+		Options:            EpisodeOptions{BitrateKbps: 96},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := svc.onCreateEpisodesQueueEvent(ctx, payloadBytes); err != nil {
+		t.Fatalf("partial failure should not make the queue retry whole payload, got %v", err)
+	}
+	<-svc.episodeStatusChangesChan
+
+	// region only the failed variant is queued again
+	retries := publishedOf(t, jobsQueue, queueEventCreateEpisodes)
+	if len(retries) != 1 {
+		t.Fatalf("expected failed variants to be queued again, got %d payloads", len(retries))
+	}
+	retryPayload := retries[0]
+	if fmt.Sprint(retryPayload.VariantsPerEpisode) != "[[3.mp3]]" {
+		t.Fatalf("expected only failed variant to be retried, got %v", retryPayload.VariantsPerEpisode)
+	}
+	if retryPayload.Options.BitrateKbps != 96 {
+		t.Fatalf("expected retried episodes to keep requested options, got %+v", retryPayload.Options)
+	}
+	// endregion
+
+	retryPayloadBytes, err := json.Marshal(retryPayload)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := svc.onCreateEpisodesQueueEvent(ctx, retryPayloadBytes); err != nil {
+		t.Fatalf("failed to process retry: %v", err)
+	}
+	<-svc.episodeStatusChangesChan
+
+	episodes, err := svc.ListUserEpisodes(ctx, userID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	variantsCount := make(map[string]int)
+	for _, ep := range episodes {
+		variantsCount[ep.SourceFilepaths[0]]++
+	}
+	if len(episodes) != 5 || len(variantsCount) != 5 {
+		t.Fatalf("expected exactly one episode per variant, got %v", variantsCount)
+	}
+}
+
+func TestService__CreateEpisodesQueueEvent__Concurrent(t *testing.T) {
+	ctx := context.Background()
+	const episodesCount = 10
+	mediarySvc := &mediarymocks.ServiceMock{
+		FetchMetadataLongPollingFunc: func(ctx context.Context, mediaURL string) (*mediary.Metadata, error) {
+			return &mediary.Metadata{URL: mediaURL, Name: "some torrent", DownloaderName: "torrent"}, nil
+		},
+		CreateUploadJobFunc: func(ctx context.Context, params *mediary.CreateUploadJobParams) (string, error) {
+			// earlier variants take longer, so that episodes are created out of order
+			variant := params.Params.(mediary.UploadOriginalJobParams).Variant
+			idx, err := strconv.Atoi(strings.TrimSuffix(variant, ".mp3"))
+			if err != nil {
+				return "", err
+			}
+			time.Sleep(time.Duration(episodesCount-idx) * 5 * time.Millisecond)
+			return "job-" + variant, nil
+		},
+	}
+	svc, jobsQueue, _ := newTestService(t, mediarySvc)
+
+	userID := "some-user"
+	variantsPerEpisode := make([][]string, episodesCount)
+	for i := range variantsPerEpisode {
+		variantsPerEpisode[i] = []string{fmt.Sprintf("%d.mp3", i)}
+	}
+	payloadBytes, err := json.Marshal(&CreateEpisodesQueuePayload{
+		URL:                "magnet:?xt=urn:btih:some-hash",
+		VariantsPerEpisode: variantsPerEpisode,
+		UserID:             userID,
+		ProcessingType:     ProcessingTypeUploadOriginal,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := svc.onCreateEpisodesQueueEvent(ctx, payloadBytes); err != nil {
+		t.Fatal(err)
+	}
+	<-svc.episodeStatusChangesChan
+
+	if calls := len(mediarySvc.FetchMetadataLongPollingCalls()); calls != 1 {
+		t.Errorf("expected metadata to be fetched once for the whole batch, got %d fetches", calls)
+	}
+
+	polls := publishedOf(t, jobsQueue, queueEventPollEpisodesStatus)
+	if len(polls) != 1 || len(polls[0].EpisodeIDs) != episodesCount {
+		t.Fatalf("expected status polling of all %d episodes, got %+v", episodesCount, polls)
+	}
+	episodesMap, err := svc.GetEpisodesMap(ctx, userID, polls[0].EpisodeIDs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, epID := range polls[0].EpisodeIDs {
+		if ep := episodesMap[epID]; ep.SourceFilepaths[0] != variantsPerEpisode[i][0] {
+			t.Fatalf("expected episode IDs in order of variants, episode %s at position %d is made of %v", epID, i, ep.SourceFilepaths)
+		}
+	}
+
+	// IDs and creation times are given out in order of variants too, not in order jobs were started
+	for i := 1; i < len(polls[0].EpisodeIDs); i++ {
+		prev, cur := episodesMap[polls[0].EpisodeIDs[i-1]], episodesMap[polls[0].EpisodeIDs[i]]
+		prevID, err := strconv.Atoi(prev.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		curID, err := strconv.Atoi(cur.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if curID <= prevID {
+			t.Errorf("expected episode %s of variant %d to get a greater ID than episode %s", cur.ID, i, prev.ID)
+		}
+		if cur.CreatedAt.Before(prev.CreatedAt) {
+			t.Errorf("expected episode %s of variant %d to be created after episode %s", cur.ID, i, prev.ID)
+		}
+	}
+}
+
+func TestService__CreateEpisode__Metadata(t *testing.T) {
+	ctx := context.Background()
+	mediarySvc := &mediarymocks.ServiceMock{
+		FetchMetadataLongPollingFunc: func(ctx context.Context, mediaURL string) (*mediary.Metadata, error) {
+			return &mediary.Metadata{URL: mediaURL, Name: "some torrent", DownloaderName: "torrent"}, nil
+		},
+		CreateUploadJobFunc: func(ctx context.Context, params *mediary.CreateUploadJobParams) (string, error) {
+			return "some-job", nil
+		},
+	}
+	svc, _, _ := newTestService(t, mediarySvc)
+	userID := "some-user"
+	mediaURL := "magnet:?xt=urn:btih:some-hash"
+
+	t.Run("fetched when not supplied", func(t *testing.T) {
+		if _, err := svc.CreateEpisode(ctx, userID, mediaURL, []string{"1.mp3"}, ProcessingTypeUploadOriginal, nil, EpisodeOptions{}, nil); err != nil {
+			t.Fatal(err)
+		}
+		if calls := len(mediarySvc.FetchMetadataLongPollingCalls()); calls != 1 {
+			t.Fatalf("expected metadata to be fetched, got %d fetches", calls)
+		}
+	})
+
+	t.Run("fetched once when splitting 10 files", func(t *testing.T) {
+		fetchesBefore := len(mediarySvc.FetchMetadataLongPollingCalls())
+
+		filepaths := make([]string, 10)
+		for i := range filepaths {
+			filepaths[i] = fmt.Sprintf("dir/%02d.mp3", i+1)
+		}
+		original := saveTestEpisode(t, svc, &Episode{
+			ID: "100", UserID: userID, Title: "whole album", SourceURL: mediaURL,
+			SourceFilepaths: filepaths, Status: EpisodeStatusComplete,
+		})
+
+		split, err := svc.SplitEpisode(ctx, userID, original.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(split) != len(filepaths) {
+			t.Fatalf("expected %d episodes, got %d", len(filepaths), len(split))
+		}
+		if fetches := len(mediarySvc.FetchMetadataLongPollingCalls()) - fetchesBefore; fetches != 1 {
+			t.Fatalf("expected metadata to be fetched once for all episodes, got %d fetches", fetches)
+		}
+	})
+}
+
+func TestService__ListUserEpisodes(t *testing.T) {
+	ctx := context.Background()
+	svc, _, _ := newTestService(t, &mediarymocks.ServiceMock{})
+
+	for _, ep := range []*Episode{
+		{ID: "1", UserID: "some-user", Title: "first"},
+		{ID: "2", UserID: "some-user", Title: "second"},
+		{ID: "1", UserID: "other-user", Title: "someone else's"},
+	} {
+		saveTestEpisode(t, svc, ep)
+	}
+
+	episodes, err := svc.ListUserEpisodes(ctx, "some-user")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var titles []string
+	for _, ep := range episodes {
+		titles = append(titles, ep.Title)
+	}
+	slices.Sort(titles)
+	if fmt.Sprint(titles) != "[first second]" {
+		t.Fatalf("expected all of user's episodes and only them, got %v", titles)
+	}
+}
+
+func TestService__DefaultFeed__Concurrent(t *testing.T) {
+	ctx := context.Background()
+	svc, _, _ := newTestService(t, &mediarymocks.ServiceMock{})
+	userID := "some-user"
+
+	var wg sync.WaitGroup
+	feeds := make([]*Feed, 2)
+	errs := make([]error, 2)
+	for i := range feeds {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			feeds[i], errs[i] = svc.DefaultFeed(ctx, userID)
+		}(i)
+	}
+	wg.Wait()
+
+	for i := range feeds {
+		if errs[i] != nil {
+			t.Fatalf("failed to get default feed: %v", errs[i])
+		}
+		if feeds[i].ID != DefaultFeedID || feeds[i].URL != feeds[0].URL || feeds[i].Title != feeds[0].Title {
+			t.Fatalf("expected both callers to get the same default feed, got %+v and %+v", feeds[0], feeds[i])
+		}
+	}
+
+	userFeeds, err := svc.repository.ListUserFeeds(ctx, userID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(userFeeds) != 1 {
+		t.Fatalf("expected a single default feed, got %d feeds", len(userFeeds))
+	}
+}
+
+func TestService__GetFeedFile(t *testing.T) {
+	ctx := context.Background()
+	svc, _, s3Store := newTestService(t, &mediarymocks.ServiceMock{})
+
+	feed, err := svc.CreateFeed(ctx, "some-user", "some feed")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := svc.regenerateFeedFile(ctx, feed); err != nil {
+		t.Fatal(err)
+	}
+
+	stored, ok := s3Store.objects[svc.constructS3FeedKey(feed.UserID, feed.ID, "")]
+	if !ok {
+		t.Fatalf("expected feed file to be stored")
+	}
+
+	data, err := svc.GetFeedFile(ctx, feed.UserID, feed.ID)
+	if err != nil {
+		t.Fatalf("failed to get feed file: %v", err)
+	}
+	if !bytes.Equal(data, stored) {
+		t.Fatalf("expected feed file to be exactly as stored, got:\n%s\nwant:\n%s", data, stored)
+	}
+
+	if _, err := svc.GetFeedFile(ctx, feed.UserID, "non-existent"); !errors.Is(err, ErrFeedNotFound) {
+		t.Fatalf("expected ErrFeedNotFound, got %v", err)
+	}
+}
+
+func TestService__CreateEpisode__Normalize(t *testing.T) {
+	ctx := context.Background()
+	mediarySvc := &mediarymocks.ServiceMock{
+		FetchMetadataLongPollingFunc: func(ctx context.Context, mediaURL string) (*mediary.Metadata, error) {
+			return &mediary.Metadata{URL: mediaURL, Name: "some torrent", DownloaderName: "torrent"}, nil
+		},
+		CreateUploadJobFunc: func(ctx context.Context, params *mediary.CreateUploadJobParams) (string, error) {
+			return "some-job-id", nil
+		},
+	}
+	svc, _, _ := newTestService(t, mediarySvc)
+
+	userID := "some-user"
+	if _, err := svc.DefaultFeed(ctx, userID); err != nil {
+		t.Fatal(err)
+	}
+	if err := svc.SetFeedNormalize(ctx, userID, DefaultFeedID, true); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := svc.CreateEpisode(
+		ctx, userID, "magnet:?xt=urn:btih:some-hash", []string{"track 1.mp3", "track 2.mp3"}, ProcessingTypeConcatenate, nil, EpisodeOptions{}, nil,
+	); err != nil {
+		t.Fatalf("failed to create episode: %v", err)
+	}
+
+	calls := mediarySvc.CreateUploadJobCalls()
+	if len(calls) != 1 {
+		t.Fatalf("expected 1 mediary job, got %d", len(calls))
+	}
+	params, ok := calls[0].Params.Params.(mediary.ConcatenateJobParams)
+	if !ok {
+		t.Fatalf("expected concatenate job params, got %T", calls[0].Params.Params)
+	}
+	if !params.Normalize {
+		t.Fatalf("expected normalization flag to be forwarded to mediary")
+	}
+}
+
+func TestService__CreateEpisode__Codec(t *testing.T) {
+	ctx := context.Background()
+	mediarySvc := &mediarymocks.ServiceMock{
+		FetchMetadataLongPollingFunc: func(ctx context.Context, mediaURL string) (*mediary.Metadata, error) {
+			return &mediary.Metadata{URL: mediaURL, Name: "some torrent", DownloaderName: "torrent"}, nil
+		},
+		CreateUploadJobFunc: func(ctx context.Context, params *mediary.CreateUploadJobParams) (string, error) {
+			return "some-job-id", nil
+		},
+	}
+	svc, _, _ := newTestService(t, mediarySvc)
+	userID := "some-user"
+	variants := []string{"track 1.flac", "track 2.flac"}
+
+	for _, tc := range []struct {
+		name           string
+		opts           EpisodeOptions
+		expectedFormat string
+	}{
+		{name: "mp3 by default", opts: EpisodeOptions{}, expectedFormat: "mp3"},
+		{name: "chosen codec and bitrate", opts: EpisodeOptions{Codec: "opus", BitrateKbps: 96}, expectedFormat: "opus"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			ep, err := svc.CreateEpisode(ctx, userID, "magnet:?xt=urn:btih:some-hash", variants, ProcessingTypeConcatenate, nil, tc.opts, nil)
+			if err != nil {
+				t.Fatalf("failed to create episode: %v", err)
+			}
+
+			calls := mediarySvc.CreateUploadJobCalls()
+			params := calls[len(calls)-1].Params.Params.(mediary.ConcatenateJobParams)
+			if params.AudioCodec != tc.expectedFormat || params.BitrateKbps != tc.opts.BitrateKbps {
+				t.Fatalf("expected mediary to encode %s at %d kbps, got %+v", tc.expectedFormat, tc.opts.BitrateKbps, params)
+			}
+			if ep.Format != tc.expectedFormat || !strings.HasSuffix(ep.StorageKey, "."+tc.expectedFormat) {
+				t.Fatalf("expected episode to be stored as %s, got format %s and key %s", tc.expectedFormat, ep.Format, ep.StorageKey)
+			}
+		})
+	}
+
+	t.Run("unsupported codec", func(t *testing.T) {
+		_, err := svc.CreateEpisode(ctx, userID, "magnet:?xt=urn:btih:some-hash", variants, ProcessingTypeConcatenate, nil, EpisodeOptions{Codec: "wma"}, nil)
+		if !errors.Is(err, ErrUnsupportedFormat) {
+			t.Fatalf("expected ErrUnsupportedFormat, got %v", err)
+		}
+	})
+}
+
+func TestService__CreateEpisode__Dedupe(t *testing.T) {
+	ctx := context.Background()
+	mediarySvc := &mediarymocks.ServiceMock{
+		FetchMetadataLongPollingFunc: func(ctx context.Context, mediaURL string) (*mediary.Metadata, error) {
+			return &mediary.Metadata{URL: mediaURL, Name: "some torrent", DownloaderName: "torrent"}, nil
+		},
+		CreateUploadJobFunc: func(ctx context.Context, params *mediary.CreateUploadJobParams) (string, error) {
+			return "some-job-id", nil
+		},
+	}
+	svc, _, _ := newTestService(t, mediarySvc)
+	svc.dedupeEpisodes = true
+	userID := "some-user"
+	mediaURL := "magnet:?xt=urn:btih:some-hash"
+
+	first, err := svc.CreateEpisode(ctx, userID, mediaURL, []string{"track 1.mp3", "track 2.mp3"}, ProcessingTypeConcatenate, nil, EpisodeOptions{}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := svc.CreateEpisode(ctx, userID, mediaURL, []string{"track 2.mp3", "track 1.mp3"}, ProcessingTypeConcatenate, nil, EpisodeOptions{}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if second.ID != first.ID {
+		t.Fatalf("expected episode %s to be reused, got new episode %s", first.ID, second.ID)
+	}
+	if calls := mediarySvc.CreateUploadJobCalls(); len(calls) != 1 {
+		t.Fatalf("expected 1 mediary job, got %d", len(calls))
+	}
+
+	for _, tc := range []struct {
+		name     string
+		userID   string
+		variants []string
+		opts     EpisodeOptions
+	}{
+		{name: "other files", userID: userID, variants: []string{"track 1.mp3"}},
+		{name: "other codec", userID: userID, variants: []string{"track 1.mp3", "track 2.mp3"}, opts: EpisodeOptions{Codec: "opus"}},
+		{name: "other user", userID: "other-user", variants: []string{"track 1.mp3", "track 2.mp3"}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			ep, err := svc.CreateEpisode(ctx, tc.userID, mediaURL, tc.variants, ProcessingTypeConcatenate, nil, tc.opts, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if ep.UserID == first.UserID && ep.ID == first.ID {
+				t.Fatalf("expected new episode to be created")
+			}
+		})
+	}
+
+	t.Run("failed episode is not reused", func(t *testing.T) {
+		first.Status = EpisodeStatusFailed
+		saveTestEpisode(t, svc, first)
+		callsCount := len(mediarySvc.CreateUploadJobCalls())
+
+		ep, err := svc.CreateEpisode(ctx, userID, mediaURL, []string{"track 1.mp3", "track 2.mp3"}, ProcessingTypeConcatenate, nil, EpisodeOptions{}, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if ep.ID == first.ID {
+			t.Fatalf("expected failed episode to be created again")
+		}
+		if calls := mediarySvc.CreateUploadJobCalls(); len(calls) != callsCount+1 {
+			t.Fatalf("expected new mediary job, got %d jobs", len(calls)-callsCount)
+		}
+	})
+}
+
+func TestService__RegenerateFeed__Compressed(t *testing.T) {
+	ctx := context.Background()
+	svc, _, s3Store := newTestService(t, &mediarymocks.ServiceMock{})
+	svc.compressFeeds = true
+
+	feed, err := svc.CreateFeed(ctx, "some-user", "some feed")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := svc.regenerateFeedFile(ctx, feed); err != nil {
+		t.Fatal(err)
+	}
+
+	stored := s3Store.objects[svc.constructS3FeedKey(feed.UserID, feed.ID, "")]
+	gz, err := gzip.NewReader(bytes.NewReader(stored))
+	if err != nil {
+		t.Fatalf("expected stored feed to be gzipped: %v", err)
+	}
+	decompressed, err := io.ReadAll(gz)
+	if err != nil {
+		t.Fatalf("failed to decompress stored feed: %v", err)
+	}
+
+	var rss struct {
+		Title string `xml:"channel>title"`
+	}
+	if err := xml.Unmarshal(decompressed, &rss); err != nil {
+		t.Fatalf("expected decompressed feed to be valid XML: %v", err)
+	}
+	if rss.Title != feed.Title {
+		t.Fatalf("expected feed title to be %q, got %q", feed.Title, rss.Title)
+	}
+
+	if len(s3Store.putOptions) == 0 || s3Store.putOptions[len(s3Store.putOptions)-1].ContentEncoding != "gzip" {
+		t.Fatalf("expected feed to be stored with gzip content encoding")
+	}
+
+	// feed file is served decompressed
+	data, err := svc.GetFeedFile(ctx, feed.UserID, feed.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(data, decompressed) {
+		t.Fatalf("expected feed file to be decompressed")
+	}
+}
+
+func TestService__RegenerateFeed__Unchanged(t *testing.T) {
+	ctx := context.Background()
+	svc, _, s3Store := newTestService(t, &mediarymocks.ServiceMock{})
+
+	feed, err := svc.CreateFeed(ctx, "some-user", "some feed")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := svc.regenerateFeedFile(ctx, feed); err != nil {
+		t.Fatal(err)
+	}
+	putsCount := len(s3Store.putOptions)
+
+	if err := svc.regenerateFeedFile(ctx, feed); err != nil {
+		t.Fatal(err)
+	}
+	if len(s3Store.putOptions) != putsCount {
+		t.Fatalf("expected unchanged feed not to be uploaded again")
+	}
+
+	if err := svc.SetFeedCopyright(ctx, feed.UserID, feed.ID, "some copyright"); err != nil {
+		t.Fatal(err)
+	}
+	if feed, err = svc.GetFeed(ctx, feed.UserID, feed.ID); err != nil {
+		t.Fatal(err)
+	}
+	if err := svc.regenerateFeedFile(ctx, feed); err != nil {
+		t.Fatal(err)
+	}
+	if len(s3Store.putOptions) != putsCount+1 {
+		t.Fatalf("expected changed feed to be uploaded")
+	}
+}
+
+func TestService__SetEpisodePubDate(t *testing.T) {
+	ctx := context.Background()
+	svc, jobsQueue, s3Store := newTestService(t, &mediarymocks.ServiceMock{})
+
+	userID := "some-user"
+	feed, err := svc.CreateFeed(ctx, userID, "some feed")
+	if err != nil {
+		t.Fatal(err)
+	}
+	saveTestEpisode(t, svc, &Episode{ID: "1", UserID: userID, Title: "old recording", Status: EpisodeStatusComplete})
+	if err := svc.PublishEpisodes(ctx, userID, []string{"1"}, []string{feed.ID}); err != nil {
+		t.Fatal(err)
+	}
+	regenerationsCount := len(publishedOf(t, jobsQueue, queueEventRegenerateFeed))
+
+	pubDate := time.Date(1969, 7, 20, 20, 17, 0, 0, time.UTC)
+	if err := svc.SetEpisodePubDate(ctx, userID, "1", pubDate); err != nil {
+		t.Fatal(err)
+	}
+
+	episodes, err := svc.GetEpisodesMap(ctx, userID, []string{"1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !episodes["1"].PubDate.Equal(pubDate) {
+		t.Fatalf("expected publish date to be saved, got %v", episodes["1"].PubDate)
+	}
+
+	regenerations := publishedOf(t, jobsQueue, queueEventRegenerateFeed)
+	if len(regenerations) != regenerationsCount+1 {
+		t.Fatalf("expected feed regeneration to be queued")
+	}
+	if payload := regenerations[len(regenerations)-1]; !slices.Equal(payload.FeedIDs, []string{feed.ID}) {
+		t.Fatalf("expected feed %s to be regenerated, got %v", feed.ID, payload.FeedIDs)
+	}
+
+	if err := svc.regenerateFeedFile(ctx, feed); err != nil {
+		t.Fatal(err)
+	}
+	xml := string(s3Store.objects[svc.constructS3FeedKey(userID, feed.ID, "")])
+	if !strings.Contains(xml, "<pubDate>"+pubDate.Format(time.RFC1123Z)+"</pubDate>") {
+		t.Fatalf("expected feed to use overridden publish date, got:\n%s", xml)
+	}
+}
+
+func TestService__RegenerateFeed__GUIDSurvivesRename(t *testing.T) {
+	ctx := context.Background()
+	svc, _, s3Store := newTestService(t, &mediarymocks.ServiceMock{})
+
+	userID := "some-user"
+	feed, err := svc.CreateFeed(ctx, userID, "some feed")
+	if err != nil {
+		t.Fatal(err)
+	}
+	saveTestEpisode(t, svc, &Episode{ID: "1", UserID: userID, Title: "old title", Status: EpisodeStatusComplete})
+	if err := svc.PublishEpisodes(ctx, userID, []string{"1"}, []string{feed.ID}); err != nil {
+		t.Fatal(err)
+	}
+
+	feedKey := svc.constructS3FeedKey(userID, feed.ID, "")
+	guidRe := regexp.MustCompile(`<guid isPermaLink="false">([^<]+)</guid>`)
+
+	if err := svc.regenerateFeedFile(ctx, feed); err != nil {
+		t.Fatal(err)
+	}
+	xmlBefore := string(s3Store.objects[feedKey])
+
+	if err := svc.RenameEpisodes(ctx, userID, []string{"1"}, "new title"); err != nil {
+		t.Fatal(err)
+	}
+	if err := svc.regenerateFeedFile(ctx, feed); err != nil {
+		t.Fatal(err)
+	}
+	xmlAfter := string(s3Store.objects[feedKey])
+
+	if !strings.Contains(xmlBefore, "old title") || !strings.Contains(xmlAfter, "new title") {
+		t.Fatalf("expected title to change, got:\n%s\n%s", xmlBefore, xmlAfter)
+	}
+	guidBefore, guidAfter := guidRe.FindStringSubmatch(xmlBefore), guidRe.FindStringSubmatch(xmlAfter)
+	if guidBefore == nil || guidAfter == nil {
+		t.Fatalf("expected feed to contain guid, got:\n%s\n%s", xmlBefore, xmlAfter)
+	}
+	if guidBefore[1] != guidAfter[1] {
+		t.Fatalf("expected guid to survive rename, got %q and %q", guidBefore[1], guidAfter[1])
+	}
+	// episode IDs are only unique per user, so user ID makes GUID unique across all feeds
+	if guidAfter[1] != "some-user-1" {
+		t.Fatalf("expected guid to be made of user and episode ids, got %q", guidAfter[1])
+	}
+}
+
+func TestService__SetFeedSchedule(t *testing.T) {
+	ctx := context.Background()
+	svc, _, _ := newTestService(t, &mediarymocks.ServiceMock{})
+
+	userID := "some-user"
+	feed, err := svc.CreateFeed(ctx, userID, "some feed")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := svc.SetFeedSchedule(ctx, userID, feed.ID, 0, []int{24}, nil); !errors.Is(err, ErrInvalidSchedule) {
+		t.Fatalf("expected hour 24 to be rejected, got %v", err)
+	}
+	if err := svc.SetFeedSchedule(ctx, userID, feed.ID, -1, nil, nil); !errors.Is(err, ErrInvalidSchedule) {
+		t.Fatalf("expected negative ttl to be rejected, got %v", err)
+	}
+
+	if err := svc.SetFeedSchedule(ctx, userID, feed.ID, 60, []int{23, 0, 23}, []time.Weekday{time.Saturday, time.Sunday}); err != nil {
+		t.Fatal(err)
+	}
+	feed, err = svc.GetFeed(ctx, userID, feed.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if feed.TTLMinutes != 60 || !slices.Equal(feed.SkipHours, []int{0, 23}) || !slices.Equal(feed.SkipDays, []time.Weekday{time.Sunday, time.Saturday}) {
+		t.Fatalf("expected schedule to be saved sorted and deduplicated, got ttl %d, hours %v, days %v", feed.TTLMinutes, feed.SkipHours, feed.SkipDays)
+	}
+}
+
+func TestService__RegenerateFeed__IncludeIncomplete(t *testing.T) {
+	ctx := context.Background()
+	svc, jobsQueue, s3Store := newTestService(t, &mediarymocks.ServiceMock{})
+
+	userID := "some-user"
+	feed, err := svc.CreateFeed(ctx, userID, "some feed")
+	if err != nil {
+		t.Fatal(err)
+	}
+	saveTestEpisode(t, svc, &Episode{ID: "1", UserID: userID, Title: "complete episode", Status: EpisodeStatusComplete})
+	saveTestEpisode(t, svc, &Episode{ID: "2", UserID: userID, Title: "incomplete episode", Status: EpisodeStatusPending})
+	if err := svc.PublishEpisodes(ctx, userID, []string{"1", "2"}, []string{feed.ID}); err != nil {
+		t.Fatal(err)
+	}
+
+	feedKey := svc.constructS3FeedKey(userID, feed.ID, "")
+
+	if err := svc.regenerateFeedFile(ctx, feed); err != nil {
+		t.Fatal(err)
+	}
+	if xml := string(s3Store.objects[feedKey]); !strings.Contains(xml, "complete episode") || strings.Contains(xml, "incomplete episode") {
+		t.Fatalf("expected feed to exclude incomplete episode by default, got:\n%s", xml)
+	}
+
+	// override travels through the queue
+	if err := svc.RegenerateFeed(ctx, userID, feed.ID, WithIncompleteEpisodes()); err != nil {
+		t.Fatal(err)
+	}
+	published := publishedOf(t, jobsQueue, queueEventRegenerateFeed)
+	payloadBytes, err := json.Marshal(published[len(published)-1])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := svc.onRegenerateFeedQueueEvent(ctx, payloadBytes); err != nil {
+		t.Fatal(err)
+	}
+	if xml := string(s3Store.objects[feedKey]); !strings.Contains(xml, "incomplete episode") {
+		t.Fatalf("expected override to include incomplete episode, got:\n%s", xml)
+	}
+}
+
+func TestService__RegenerateAllFeeds(t *testing.T) {
+	ctx := context.Background()
+	svc, jobsQueue, s3Store := newTestService(t, &mediarymocks.ServiceMock{})
+
+	userID := "some-user"
+	for _, title := range []string{"feed 2", "feed 3"} {
+		if _, err := svc.CreateFeed(ctx, userID, title); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := svc.CreateFeed(ctx, "other-user", "other feed"); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := svc.RegenerateAllFeeds(ctx, userID); err != nil {
+		t.Fatal(err)
+	}
+
+	// region single regeneration carries every feed of the user, including recreated default one
+	regenerations := publishedOf(t, jobsQueue, queueEventRegenerateFeed)
+	if len(regenerations) != 1 {
+		t.Fatalf("expected single feed regeneration to be queued, got %d", len(regenerations))
+	}
+	payload := regenerations[0]
+	feedIDs := slices.Clone(payload.FeedIDs)
+	slices.Sort(feedIDs)
+	if payload.UserID != userID || !slices.Equal(feedIDs, []string{DefaultFeedID, "2", "3"}) || !payload.Force {
+		t.Fatalf("expected forced regeneration of feeds 1, 2 and 3, got %+v", payload)
+	}
+	// endregion
+
+	// region feeds are uploaded even if unchanged
+	payloadBytes, err := json.Marshal(payload)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 2; i++ {
+		putsCount := len(s3Store.putOptions)
+		if err := svc.onRegenerateFeedQueueEvent(ctx, payloadBytes); err != nil {
+			t.Fatal(err)
+		}
+		if len(s3Store.putOptions) != putsCount+3 {
+			t.Fatalf("expected every feed to be uploaded on forced regeneration, got %d uploads", len(s3Store.putOptions)-putsCount)
+		}
+	}
+	// endregion
+}
+
+func TestService__RegenerateFeedQueueEvent__EmptyUserID(t *testing.T) {
+	ctx := context.Background()
+	svc, _, s3Store := newTestService(t, &mediarymocks.ServiceMock{})
+
+	feed, err := svc.CreateFeed(ctx, "some-user", "some feed")
+	if err != nil {
+		t.Fatal(err)
+	}
+	putsCount := len(s3Store.putOptions)
+
+	payloadBytes, err := json.Marshal(&RegenerateFeedQueuePayload{FeedIDs: []string{feed.ID}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := svc.onRegenerateFeedQueueEvent(ctx, payloadBytes); !errors.Is(err, ErrInvalidPayload) {
+		t.Fatalf("expected payload without user id to be rejected with ErrInvalidPayload, got %v", err)
+	}
+	if len(s3Store.putOptions) != putsCount {
+		t.Fatalf("expected no feed to be uploaded")
+	}
+}
+
+func TestService__RegenerateFeedQueueEvent__Debounced(t *testing.T) {
+	ctx := context.Background()
+	svc, jobsQueue, s3Store := newTestService(t, &mediarymocks.ServiceMock{})
+	schedule := &fakeRegenerationSchedule{}
+	svc.regenerationSchedule = schedule
+	svc.regenerationDebounceWindow = 5 * time.Second
+
+	feed, err := svc.CreateFeed(ctx, "some-user", "some feed")
+	if err != nil {
+		t.Fatal(err)
+	}
+	putsCount := len(s3Store.putOptions)
+
+	payloadBytes, err := json.Marshal(&RegenerateFeedQueuePayload{UserID: feed.UserID, FeedIDs: []string{feed.ID}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// debounced regenerations are the ones enqueued with a delay
+	debounced := func() []fakePublishedJob {
+		jobsQueue.mu.Lock()
+		defer jobsQueue.mu.Unlock()
+		var result []fakePublishedJob
+		for _, j := range jobsQueue.published {
+			if j.JobType == string(queueEventRegenerateFeed) && j.Delay > 0 {
+				result = append(result, j)
+			}
+		}
+		return result
+	}
+	runDebounced := func() {
+		t.Helper()
+		jobs := debounced()
+		payloadBytes, err := json.Marshal(jobs[len(jobs)-1].Payload)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := svc.onRegenerateFeedQueueEvent(ctx, payloadBytes); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// region burst of changes is regenerated once, after the window
+	var wg sync.WaitGroup
+	errs := make(chan error, 10)
+	for i := 0; i < 10; i++ {
+		if err := svc.SetFeedCopyright(ctx, feed.UserID, feed.ID, fmt.Sprintf("copyright %d", i)); err != nil {
+			t.Fatal(err)
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs <- svc.onRegenerateFeedQueueEvent(ctx, payloadBytes)
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if puts := len(s3Store.putOptions) - putsCount; puts != 0 {
+		t.Fatalf("expected feed not to be uploaded before the window passes, got %d uploads", puts)
+	}
+	jobs := debounced()
+	if len(jobs) != 1 || jobs[0].Delay != svc.regenerationDebounceWindow {
+		t.Fatalf("expected a single regeneration delayed by the window, got %+v", jobs)
+	}
+
+	runDebounced()
+	if puts := len(s3Store.putOptions) - putsCount; puts != 1 {
+		t.Fatalf("expected burst of regenerations to upload feed once, got %d uploads", puts)
+	}
+	stored := s3Store.objects[svc.constructS3FeedKey(feed.UserID, feed.ID, "")]
+	if !bytes.Contains(stored, []byte("copyright 9")) {
+		t.Fatalf("expected feed to reflect the latest change, got %s", stored)
+	}
+	if len(schedule.scheduled) != 0 {
+		t.Fatalf("expected feed to be unscheduled once regenerated, got %v", schedule.scheduled)
+	}
+	// endregion
+
+	// region once regenerated, feed is regenerated again on the next change
+	if err := svc.SetFeedCopyright(ctx, feed.UserID, feed.ID, "copyright 10"); err != nil {
+		t.Fatal(err)
+	}
+	if err := svc.onRegenerateFeedQueueEvent(ctx, payloadBytes); err != nil {
+		t.Fatal(err)
+	}
+	if len(debounced()) != 2 {
+		t.Fatalf("expected another delayed regeneration after the burst")
+	}
+	runDebounced()
+	if puts := len(s3Store.putOptions) - putsCount; puts != 2 {
+		t.Fatalf("expected feed to be uploaded again after the burst, got %d uploads", puts)
+	}
+	// endregion
+}
+
+func TestService__PublishEpisodes__RegeneratesChangedFeeds(t *testing.T) {
+	ctx := context.Background()
+	svc, jobsQueue, _ := newTestService(t, &mediarymocks.ServiceMock{})
+
+	userID := "some-user"
+	feed1, err := svc.CreateFeed(ctx, userID, "first feed")
+	if err != nil {
+		t.Fatal(err)
+	}
+	feed2, err := svc.CreateFeed(ctx, userID, "second feed")
+	if err != nil {
+		t.Fatal(err)
+	}
+	saveTestEpisode(t, svc, &Episode{ID: "1", UserID: userID, Title: "some episode", Status: EpisodeStatusComplete})
+
+	if err := svc.PublishEpisodes(ctx, userID, []string{"1"}, []string{feed1.ID}); err != nil {
+		t.Fatal(err)
+	}
+	regenerations := publishedOf(t, jobsQueue, queueEventRegenerateFeed)
+	if len(regenerations) != 1 {
+		t.Fatalf("expected one feed regeneration to be queued, got %d", len(regenerations))
+	}
+	if payload := regenerations[0]; payload.UserID != userID || !slices.Equal(payload.FeedIDs, []string{feed1.ID}) {
+		t.Fatalf("expected feed %s of %s to be regenerated, got %v of %s", feed1.ID, userID, payload.FeedIDs, payload.UserID)
+	}
+
+	// the feed episode is moved out of changes as well as the one it is moved to
+	if err := svc.PublishEpisodes(ctx, userID, []string{"1"}, []string{feed2.ID}); err != nil {
+		t.Fatal(err)
+	}
+	regenerations = publishedOf(t, jobsQueue, queueEventRegenerateFeed)
+	expected := []string{feed1.ID, feed2.ID}
+	slices.Sort(expected)
+	if payload := regenerations[len(regenerations)-1]; !slices.Equal(payload.FeedIDs, expected) {
+		t.Fatalf("expected feeds %v to be regenerated, got %v", expected, payload.FeedIDs)
+	}
+}
+
+func TestService__PublishEpisodes__MaxTotalBytes(t *testing.T) {
+	ctx := context.Background()
+	svc, _, _ := newTestService(t, &mediarymocks.ServiceMock{})
+
+	userID := "some-user"
+	feed, err := svc.CreateFeed(ctx, userID, "some feed")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := svc.SetFeedMaxTotalBytes(ctx, userID, feed.ID, 250); err != nil {
+		t.Fatal(err)
+	}
+
+	// region publish two episodes of 100 bytes each, which fits the cap
+	now := time.Now().UTC()
+	for i, epID := range []string{"1", "2", "3"} {
+		saveTestEpisode(t, svc, &Episode{
+			ID:           epID,
+			UserID:       userID,
+			Title:        "episode " + epID,
+			Status:       EpisodeStatusComplete,
+			FileLenBytes: 100,
+			CreatedAt:    now.Add(time.Duration(i) * time.Hour),
+		})
+	}
+	if err := svc.PublishEpisodes(ctx, userID, []string{"1", "2"}, []string{feed.ID}); err != nil {
+		t.Fatal(err)
+	}
+	// endregion
+
+	// region publish the third one, going over the cap
+	if err := svc.PublishEpisodes(ctx, userID, []string{"3"}, []string{feed.ID}); err != nil {
+		t.Fatal(err)
+	}
+
+	episodes, err := svc.ListFeedEpisodes(ctx, userID, feed.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var epIDs []string
+	for _, ep := range episodes {
+		epIDs = append(epIDs, ep.ID)
+	}
+	if fmt.Sprint(epIDs) != "[2 3]" {
+		t.Fatalf("expected oldest episode to be removed from feed, leaving [2 3], got %v", epIDs)
+	}
+	// endregion
+}
+
+func TestService__RegenerateFeed__MaxEpisodes(t *testing.T) {
+	ctx := context.Background()
+	svc, _, s3Store := newTestService(t, &mediarymocks.ServiceMock{})
+
+	userID := "some-user"
+	feed, err := svc.CreateFeed(ctx, userID, "some feed")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := svc.MarkFeedAsPermanent(ctx, userID, feed.ID); err != nil {
+		t.Fatal(err)
+	}
+
+	now := time.Now().UTC()
+	for i, epID := range []string{"1", "2", "3"} {
+		storageKey := "episodes/some-user/" + epID + ".mp3"
+		s3Store.objects[storageKey] = []byte("some-audio")
+		saveTestEpisode(t, svc, &Episode{
+			ID:         epID,
+			UserID:     userID,
+			Title:      "episode " + epID,
+			Status:     EpisodeStatusComplete,
+			StorageKey: storageKey,
+			CreatedAt:  now.Add(time.Duration(i) * time.Hour),
+		})
+	}
+	if err := svc.PublishEpisodes(ctx, userID, []string{"1", "2", "3"}, []string{feed.ID}); err != nil {
+		t.Fatal(err)
+	}
+
+	// region limit applies to permanent feed on regeneration
+	if err := svc.SetFeedMaxEpisodes(ctx, userID, feed.ID, 2); err != nil {
+		t.Fatal(err)
+	}
+	if feed, err = svc.GetFeed(ctx, userID, feed.ID); err != nil {
+		t.Fatal(err)
+	}
+	if err := svc.regenerateFeedFile(ctx, feed); err != nil {
+		t.Fatal(err)
+	}
+
+	episodes, err := svc.ListFeedEpisodes(ctx, userID, feed.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var epIDs []string
+	for _, ep := range episodes {
+		epIDs = append(epIDs, ep.ID)
+	}
+	if fmt.Sprint(epIDs) != "[2 3]" {
+		t.Fatalf("expected oldest episode to be removed from feed, leaving [2 3], got %v", epIDs)
+	}
+	if xml := string(s3Store.objects[svc.constructS3FeedKey(userID, feed.ID, "")]); strings.Contains(xml, "episode 1") {
+		t.Fatalf("expected generated feed not to include removed episode, got:\n%s", xml)
+	}
+	// endregion
+
+	// region removed episode is only unpublished
+	if _, err := svc.GetEpisodesMap(ctx, userID, []string{"1"}); err != nil {
+		t.Fatalf("expected removed episode to stay in library, got %v", err)
+	}
+	if _, ok := s3Store.objects["episodes/some-user/1.mp3"]; !ok {
+		t.Fatalf("expected removed episode file to stay in storage")
+	}
+	// endregion
+}
+
+func TestService__ReencodeEpisodes(t *testing.T) {
+	ctx := context.Background()
+	jobStatus := mediary.JobStatusProcessing
+	mediarySvc := &mediarymocks.ServiceMock{
+		CreateUploadJobFunc: func(ctx context.Context, params *mediary.CreateUploadJobParams) (string, error) {
+			return "reencode-job-id", nil
+		},
+		FetchJobStatusBatchFunc: func(ctx context.Context, jobIDs []string) (map[string]*mediary.JobStatus, error) {
+			return map[string]*mediary.JobStatus{
+				"reencode-job-id": {Id: "reencode-job-id", Status: jobStatus, ResultFileBytes: 1000, ResultMediaDuration: time.Minute},
+			}, nil
+		},
+	}
+	svc, jobsQueue, s3Store := newTestService(t, mediarySvc)
+
+	userID := "some-user"
+	feed, err := svc.CreateFeed(ctx, userID, "some feed")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s3Store.objects["episodes/some-user/some-file.mp3"] = []byte("some-mp3-data")
+	saveTestEpisode(t, svc, &Episode{
+		ID:              "1",
+		UserID:          userID,
+		Title:           "some episode",
+		Status:          EpisodeStatusComplete,
+		Format:          "mp3",
+		SourceURL:       "magnet:?xt=urn:btih:some-hash",
+		SourceFilepaths: []string{"1.mp3", "2.mp3"},
+		MediaryID:       "create-job-id",
+		StorageKey:      "episodes/some-user/some-file.mp3",
+		URL:             "https://example.com/episodes/some-user/some-file.mp3",
+	})
+	saveTestEpisode(t, svc, &Episode{
+		ID:         "2",
+		UserID:     userID,
+		Title:      "uploaded episode",
+		Status:     EpisodeStatusComplete,
+		Format:     "mp3",
+		StorageKey: "episodes/some-user/uploaded-file.mp3",
+		URL:        "https://example.com/episodes/some-user/uploaded-file.mp3",
+	})
+	if err := svc.PublishEpisodes(ctx, userID, []string{"1"}, []string{feed.ID}); err != nil {
+		t.Fatal(err)
+	}
+
+	reencoded, err := svc.ReencodeEpisodes(ctx, userID, []string{"1", "2"}, "opus")
+	if err != nil {
+		t.Fatalf("failed to re-encode episodes: %v", err)
+	}
+	if reencoded != 1 {
+		t.Fatalf("expected only episode with sources to be re-encoded, got %d", reencoded)
+	}
+
+	// region episode is made again out of its sources
+	calls := mediarySvc.CreateUploadJobCalls()
+	if len(calls) != 1 || calls[0].Params.Type != mediary.JobTypeConcatenate || calls[0].Params.URL != "magnet:?xt=urn:btih:some-hash" {
+		t.Fatalf("expected a single concatenate job of episode sources, got %+v", calls)
+	}
+	params := calls[0].Params.Params.(mediary.ConcatenateJobParams)
+	if params.AudioCodec != "opus" || !slices.Equal(params.Variants, []string{"1.mp3", "2.mp3"}) {
+		t.Fatalf("expected sources to be encoded to opus, got %+v", params)
+	}
+	// endregion
+
+	getEpisode := func() *Episode {
+		t.Helper()
+		episodes, err := svc.GetEpisodesMap(ctx, userID, []string{"1"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return episodes["1"]
+	}
+	poll := func() {
+		t.Helper()
+		polls := publishedOf(t, jobsQueue, queueEventPollReencodeJobs)
+		payload := *polls[len(polls)-1]
+		payload.PollAfter = nil // not to wait for the delay between polls
+		payloadBytes, err := json.Marshal(&payload)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := svc.onPollReencodeJobsQueueEvent(ctx, payloadBytes); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// region old file is served while new one is being made
+	if ep := getEpisode(); ep.Status != EpisodeStatusComplete || ep.Format != "mp3" || ep.StorageKey != "episodes/some-user/some-file.mp3" {
+		t.Fatalf("expected episode to keep its file until re-encoding is complete, got %+v", ep)
+	}
+	pollsCount := len(publishedOf(t, jobsQueue, queueEventPollReencodeJobs))
+	poll()
+	if len(publishedOf(t, jobsQueue, queueEventPollReencodeJobs)) != pollsCount+1 {
+		t.Fatalf("expected incomplete re-encoding to be polled again")
+	}
+	if ep := getEpisode(); ep.Format != "mp3" {
+		t.Fatalf("expected episode to keep its file until re-encoding is complete, got %+v", ep)
+	}
+	// endregion
+
+	// region new file replaces old one once complete
+	jobStatus = mediary.JobStatusComplete
+	poll()
+	<-svc.episodeStatusChangesChan
+
+	ep := getEpisode()
+	if ep.Status != EpisodeStatusComplete || ep.Format != "opus" || ep.StorageKey != "episodes/some-user/some-file.opus" || ep.MediaryID != "reencode-job-id" {
+		t.Fatalf("expected episode to be switched to new file, got %+v", ep)
+	}
+	if ep.FileLenBytes != 1000 || ep.Duration != time.Minute {
+		t.Fatalf("expected episode size and duration of new file, got %d bytes and %s", ep.FileLenBytes, ep.Duration)
+	}
+	if _, ok := s3Store.objects["episodes/some-user/some-file.mp3"]; ok {
+		t.Fatalf("expected old episode file to be deleted")
+	}
+
+	regenerated := false
+	for _, p := range publishedOf(t, jobsQueue, queueEventRegenerateFeed) {
+		if slices.Contains(p.FeedIDs, feed.ID) {
+			regenerated = true
+		}
+	}
+	if !regenerated {
+		t.Fatalf("expected feed %s regeneration to be enqueued", feed.ID)
+	}
+	// endregion
+}
+
+func TestService__SetFeedImage(t *testing.T) {
+	ctx := context.Background()
+	svc, _, s3Store := newTestService(t, &mediarymocks.ServiceMock{})
+
+	userID := "some-user"
+	feed, err := svc.CreateFeed(ctx, userID, "some feed")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := svc.SetFeedImage(ctx, userID, feed.ID, bytes.NewReader([]byte("some-png-data")), "image/png"); err != nil {
+		t.Fatalf("failed to set feed image: %v", err)
+	}
+
+	imageKey := "feed-images/some-user/" + feed.ID
+	if data, err := s3Store.Get(ctx, imageKey); err != nil || string(data) != "some-png-data" {
+		t.Fatalf("expected image to be uploaded to %s, got %q (%v)", imageKey, data, err)
+	}
+	if contentType := s3Store.putOptions[len(s3Store.putOptions)-1].ContentType; contentType != "image/png" {
+		t.Fatalf("expected image to be uploaded with its content type, got %s", contentType)
+	}
+
+	feed, err = svc.GetFeed(ctx, userID, feed.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if feed.ImageURL != "https://example.com/"+imageKey {
+		t.Fatalf("expected feed to reference uploaded image, got %s", feed.ImageURL)
+	}
+}
+
+func TestService__VerifyObfuscationFingerprint(t *testing.T) {
+	ctx := context.Background()
+	repo := getRepo(t)
+	mkService := func(secret string) *Service {
+		obfuscateIDs := func(s string) string { return secret + s }
+		return New(&mediarymocks.ServiceMock{}, repo, &fakeS3Store{objects: map[string][]byte{}}, &fakeJobsQueue{}, "", "", false, obfuscateIDs, zap.NewNop())
+	}
+
+	if err := mkService("old-secret").VerifyObfuscationFingerprint(ctx, false); err != nil {
+		t.Fatalf("first start should store fingerprint, got %v", err)
+	}
+	if err := mkService("old-secret").VerifyObfuscationFingerprint(ctx, false); err != nil {
+		t.Fatalf("restart with the same secret should pass, got %v", err)
+	}
+
+	if err := mkService("new-secret").VerifyObfuscationFingerprint(ctx, false); !errors.Is(err, ErrObfuscationSecretChanged) {
+		t.Fatalf("expected ErrObfuscationSecretChanged, got %v", err)
+	}
+
+	if err := mkService("new-secret").VerifyObfuscationFingerprint(ctx, true); err != nil {
+		t.Fatalf("new secret should be accepted when explicitly allowed, got %v", err)
+	}
+	if err := mkService("new-secret").VerifyObfuscationFingerprint(ctx, false); err != nil {
+		t.Fatalf("accepted secret should be stored, got %v", err)
+	}
+}
+
+// region helpers
+
+func newTestService(t *testing.T, mediarySvc mediary.Service) (*Service, *fakeJobsQueue, *fakeS3Store) {
+	t.Helper()
+	jobsQueue := &fakeJobsQueue{}
+	s3Store := &fakeS3Store{objects: map[string][]byte{}}
+	obfuscateIDs := func(s string) string { return s }
+	svc := New(mediarySvc, getRepo(t), s3Store, jobsQueue, "default-feed-title", "", false, obfuscateIDs, zap.NewNop())
+	return svc, jobsQueue, s3Store
+}
+
+func saveTestEpisode(t *testing.T, svc *Service, ep *Episode) *Episode {
+	t.Helper()
+	if ep.CreatedAt.IsZero() {
+		ep.CreatedAt = time.Now().UTC()
+	}
+	if ep.UpdatedAt.IsZero() {
+		ep.UpdatedAt = ep.CreatedAt
+	}
+	ep, err := svc.repository.SaveEpisode(context.Background(), ep)
+	if err != nil {
+		t.Fatalf("failed to save episode: %v", err)
+	}
+	return ep
+}
+
+func pollEpisodes(t *testing.T, svc *Service, payload *PollEpisodesStatusQueuePayload) {
+	t.Helper()
+	payloadBytes, err := json.Marshal(payload)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := svc.onPollEpisodesQueueEvent(context.Background(), payloadBytes); err != nil {
+		t.Fatalf("failed to poll episodes: %v", err)
+	}
+}
+
+type fakePublishedJob struct {
+	JobType string
+	Payload any
+	Delay   time.Duration
+}
+
+type fakeJobsQueue struct {
+	mu        sync.Mutex
+	published []fakePublishedJob
+}
+
+func (q *fakeJobsQueue) Run() {}
+
+func (q *fakeJobsQueue) Subscribe(context.Context, string, func(payloadBytes []byte) error) {}
+
+func (q *fakeJobsQueue) Publish(_ context.Context, jobType string, payload any) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.published = append(q.published, fakePublishedJob{JobType: jobType, Payload: payload})
+	return nil
+}
+
+func (q *fakeJobsQueue) PublishDelayed(_ context.Context, jobType string, payload any, delay time.Duration) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.published = append(q.published, fakePublishedJob{JobType: jobType, Payload: payload, Delay: delay})
+	return nil
+}
+
+// publishedOf returns payloads published for an event, failing test on payloads of unexpected type
+func publishedOf[T any](t *testing.T, q *fakeJobsQueue, event queueEvent[T]) []*T {
+	t.Helper()
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	var result []*T
+	for _, j := range q.published {
+		if j.JobType != string(event) {
+			continue
+		}
+		payload, ok := j.Payload.(*T)
+		if !ok {
+			t.Fatalf("unexpected payload type %T published for %s", j.Payload, event)
+		}
+		result = append(result, payload)
+	}
+	return result
+}
+
+type fakeRegenerationSchedule struct {
+	mu        sync.Mutex
+	scheduled map[string]bool
+}
+
+func (s *fakeRegenerationSchedule) Schedule(_ context.Context, userID string, feedID string) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.scheduled == nil {
+		s.scheduled = make(map[string]bool)
+	}
+	if s.scheduled[userID+"/"+feedID] {
+		return false, nil
+	}
+	s.scheduled[userID+"/"+feedID] = true
+	return true, nil
+}
+
+func (s *fakeRegenerationSchedule) Unschedule(_ context.Context, userID string, feedID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.scheduled, userID+"/"+feedID)
+	return nil
+}
+
+type fakeS3Store struct {
+	mu         sync.Mutex
+	objects    map[string][]byte
+	putOptions []*PutOptions
+	metadata   map[string]map[string]string
+}
+
+func (s *fakeS3Store) PreSignedURL(key string) (string, error) {
+	return "https://example.com/" + key + "?signature=some-signature", nil
+}
+
+func (s *fakeS3Store) PreSignedURLWithTTL(key string, ttl time.Duration) (string, error) {
+	return fmt.Sprintf("https://example.com/%s?signature=some-signature&expires=%d", key, int(ttl.Seconds())), nil
+}
+
+func (s *fakeS3Store) URL(key string) (string, error) {
+	return "https://example.com/" + key, nil
+}
+
+func (s *fakeS3Store) Put(_ context.Context, key string, dataReader io.ReadSeeker, opts ...func(*PutOptions)) error {
+	b := &bytes.Buffer{}
+	if _, err := io.Copy(b, dataReader); err != nil {
+		return err
+	}
+	options := &PutOptions{}
+	for _, opt := range opts {
+		opt(options)
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.objects[key] = b.Bytes()
+	s.putOptions = append(s.putOptions, options)
+	if s.metadata == nil {
+		s.metadata = make(map[string]map[string]string)
+	}
+	s.metadata[key] = options.Metadata
+	return nil
+}
+
+func (s *fakeS3Store) Metadata(_ context.Context, key string) (map[string]string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.metadata[key], nil
+}
+
+func (s *fakeS3Store) Get(_ context.Context, key string) ([]byte, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	data, ok := s.objects[key]
+	if !ok {
+		return nil, fmt.Errorf("object %s not found", key)
+	}
+	return data, nil
+}
+
+func (s *fakeS3Store) Delete(_ context.Context, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.objects, key)
+	delete(s.metadata, key)
+	return nil
+}
+
+func (s *fakeS3Store) Copy(_ context.Context, srcKey string, dstKey string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	data, ok := s.objects[srcKey]
+	if !ok {
+		return fmt.Errorf("no such key: %s", srcKey)
+	}
+	s.objects[dstKey] = data
+	if metadata, ok := s.metadata[srcKey]; ok {
+		s.metadata[dstKey] = metadata
+	}
+	return nil
+}
+
+// endregion
+
+func TestService__ExcludeSmallFiles(t *testing.T) {
+	jobsQueue := &fakeJobsQueue{}
+	s3Store := &fakeS3Store{objects: map[string][]byte{}}
+	svc := New(&mediarymocks.ServiceMock{}, getRepo(t), s3Store, jobsQueue, "", "", false,
+		func(s string) string { return s }, zap.NewNop(), WithMinEpisodeFileBytes(1024*1024))
+
+	lenBytes := func(n int64) *int64 { return &n }
+	metadata := &Metadata{Variants: []mediary.Variant{
+		{ID: "album/01 - song.mp3", LenBytes: lenBytes(5 * 1024 * 1024)},
+		{ID: "album/sample.mp3", LenBytes: lenBytes(100 * 1024)},
+		{ID: "album/02 - song.mp3", LenBytes: lenBytes(4 * 1024 * 1024)},
+		{ID: "album/unknown.mp3"},
+	}}
+
+	kept, excluded := svc.ExcludeSmallFiles(metadata, [][]string{
+		{"album/01 - song.mp3"},
+		{"album/sample.mp3"},
+		{"album/02 - song.mp3"},
+		{"album/unknown.mp3"},
+	})
+
+	if !reflect.DeepEqual(kept, [][]string{{"album/01 - song.mp3"}, {"album/02 - song.mp3"}, {"album/unknown.mp3"}}) {
+		t.Errorf("expected files under threshold to be excluded, got %v", kept)
+	}
+	if !reflect.DeepEqual(excluded, []string{"album/sample.mp3"}) {
+		t.Errorf("expected sample to be reported as excluded, got %v", excluded)
+	}
+}
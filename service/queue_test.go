@@ -0,0 +1,72 @@
+package service
+
+import (
+	"go/ast"
+	"go/importer"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"strings"
+	"testing"
+)
+
+// TestPublish__PayloadType type-checks publish calls against queue.go,
+// making sure payload of a wrong type is rejected at compile time
+func TestPublish__PayloadType(t *testing.T) {
+	typeCheck := func(t *testing.T, call string) []error {
+		t.Helper()
+		fset := token.NewFileSet()
+		queueFile, err := parser.ParseFile(fset, "queue.go", nil, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		callFile, err := parser.ParseFile(fset, "call.go", `package service
+
+import "context"
+
+type JobsQueue interface {
+	Publish(ctx context.Context, jobType string, payload any) error
+}
+
+func _(ctx context.Context, jobsQueue JobsQueue) error {
+	return `+call+`
+}
+`, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		// the rest of the package is not loaded, so only errors in the call itself matter
+		var callErrs []error
+		conf := types.Config{
+			Importer: importer.ForCompiler(fset, "source", nil),
+			Error: func(err error) {
+				if typeErr, ok := err.(types.Error); ok && typeErr.Fset.Position(typeErr.Pos).Filename == "call.go" {
+					callErrs = append(callErrs, err)
+				}
+			},
+		}
+		_, _ = conf.Check("tg-podcastotron/service", fset, []*ast.File{queueFile, callFile}, nil)
+		return callErrs
+	}
+
+	t.Run("matching payload", func(t *testing.T) {
+		if errs := typeCheck(t, `publish(ctx, jobsQueue, queueEventRegenerateFeed, &RegenerateFeedQueuePayload{})`); len(errs) != 0 {
+			t.Fatalf("expected matching payload to compile, got %v", errs)
+		}
+	})
+
+	t.Run("wrong payload type", func(t *testing.T) {
+		errs := typeCheck(t, `publish(ctx, jobsQueue, queueEventRegenerateFeed, &PollEpisodesStatusQueuePayload{})`)
+		if len(errs) == 0 {
+			t.Fatalf("expected payload of another event not to compile")
+		}
+	})
+
+	t.Run("payload by value", func(t *testing.T) {
+		errs := typeCheck(t, `publish(ctx, jobsQueue, queueEventRegenerateFeed, RegenerateFeedQueuePayload{})`)
+		if len(errs) == 0 || !strings.Contains(errs[0].Error(), "RegenerateFeedQueuePayload") {
+			t.Fatalf("expected payload passed by value not to compile, got %v", errs)
+		}
+	})
+}
@@ -0,0 +1,108 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	"github.com/hori-ryota/zaperr"
+	"github.com/redis/go-redis/v9"
+	"go.uber.org/zap"
+)
+
+// DefaultRegenerationDebounceWindow is how long feed regeneration waits for more changes to the same feed
+const DefaultRegenerationDebounceWindow = 5 * time.Second
+
+// RegenerationSchedule remembers feeds whose regeneration is scheduled but not started yet,
+// it's shared between bot instances so that a burst of changes results in one regeneration per feed
+type RegenerationSchedule interface {
+	// Schedule returns false if regeneration of the feed is already scheduled
+	Schedule(ctx context.Context, userID string, feedID string) (bool, error)
+	Unschedule(ctx context.Context, userID string, feedID string) error
+}
+
+func NewRedisRegenerationSchedule(redisClient *redis.Client, namespace string, window time.Duration) RegenerationSchedule {
+	return &redisRegenerationSchedule{
+		redisClient: redisClient,
+		namespace:   namespace,
+		// feed is unscheduled once its delayed regeneration starts, expiration only matters if that never happened,
+		// e.g. the delayed job was lost, so that the feed isn't skipped forever
+		ttl: 2*window + time.Minute,
+	}
+}
+
+type redisRegenerationSchedule struct {
+	redisClient *redis.Client
+	namespace   string
+	ttl         time.Duration
+}
+
+func (s *redisRegenerationSchedule) Schedule(ctx context.Context, userID string, feedID string) (bool, error) {
+	ok, err := s.redisClient.SetNX(ctx, s.key(userID, feedID), 1, s.ttl).Result()
+	if err != nil {
+		return false, zaperr.Wrap(err, "failed to schedule feed regeneration", zap.String("user_id", userID), zap.String("feed_id", feedID))
+	}
+	return ok, nil
+}
+
+func (s *redisRegenerationSchedule) Unschedule(ctx context.Context, userID string, feedID string) error {
+	if err := s.redisClient.Del(ctx, s.key(userID, feedID)).Err(); err != nil {
+		return zaperr.Wrap(err, "failed to unschedule feed regeneration", zap.String("user_id", userID), zap.String("feed_id", feedID))
+	}
+	return nil
+}
+
+func (s *redisRegenerationSchedule) key(userID string, feedID string) string {
+	return s.namespace + ":" + userID + ":" + feedID
+}
+
+// debounceRegeneration schedules regeneration of feeds to run once the debounce window passes and returns feeds
+// this event should regenerate right away, which are all of them only when debouncing is off.
+// Feeds already scheduled by another event are left to it: that event regenerates them after the window,
+// so it reads whatever state changes led to this event as well
+func (svc *Service) debounceRegeneration(ctx context.Context, userID string, feedIDs []string) ([]string, error) {
+	if svc.regenerationSchedule == nil {
+		return feedIDs, nil
+	}
+
+	var scheduledFeedIDs []string
+	for _, feedID := range feedIDs {
+		ok, err := svc.regenerationSchedule.Schedule(ctx, userID, feedID)
+		if err != nil {
+			// regenerating twice is better than not regenerating at all
+			svc.logger.Warn("failed to schedule feed regeneration, regenerating anyway", zap.String("feed_id", feedID), zaperr.ToField(err))
+			ok = true
+		}
+		if ok {
+			scheduledFeedIDs = append(scheduledFeedIDs, feedID)
+		}
+	}
+	if len(scheduledFeedIDs) == 0 {
+		return nil, nil
+	}
+
+	// the wait is up to the queue, so that workers are not held up by sleeping events
+	if err := publishDelayed(ctx, svc.jobsQueue, queueEventRegenerateFeed, &RegenerateFeedQueuePayload{
+		UserID:    userID,
+		FeedIDs:   scheduledFeedIDs,
+		Debounced: true,
+	}, svc.regenerationDebounceWindow); err != nil {
+		// otherwise changes would be skipped as already scheduled until schedule expires
+		svc.unscheduleRegeneration(ctx, userID, scheduledFeedIDs)
+		return nil, zaperr.Wrap(err, "failed to enqueue debounced feed regeneration", zap.Strings("feed_ids", scheduledFeedIDs))
+	}
+	return nil, nil
+}
+
+// unscheduleRegeneration lets changes made from now on schedule another regeneration. It's done even if ctx is
+// cancelled, e.g. on shutdown, as feeds left scheduled would skip regenerations until the schedule expires
+func (svc *Service) unscheduleRegeneration(ctx context.Context, userID string, feedIDs []string) {
+	if svc.regenerationSchedule == nil {
+		return
+	}
+	ctx = context.WithoutCancel(ctx)
+	for _, feedID := range feedIDs {
+		if err := svc.regenerationSchedule.Unschedule(ctx, userID, feedID); err != nil {
+			svc.logger.Error("failed to unschedule feed regeneration", zap.String("feed_id", feedID), zaperr.ToField(err))
+		}
+	}
+}
@@ -0,0 +1,83 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"testing"
+
+	"tg-podcastotron/mediary/mediarymocks"
+)
+
+func TestService__RenameEpisodes__Batched(t *testing.T) {
+	ctx := context.Background()
+	svc, jobsQueue, _ := newTestService(t, &mediarymocks.ServiceMock{})
+
+	userID := "some-user"
+	feed, err := svc.CreateFeed(ctx, userID, "some feed")
+	if err != nil {
+		t.Fatal(err)
+	}
+	epIDs := make([]string, 300)
+	for i := range epIDs {
+		epIDs[i] = strconv.Itoa(i + 1)
+		saveTestEpisode(t, svc, &Episode{ID: epIDs[i], UserID: userID, Title: "old title", Status: EpisodeStatusComplete})
+	}
+	if err := svc.PublishEpisodes(ctx, userID, epIDs[:1], []string{feed.ID}); err != nil {
+		t.Fatal(err)
+	}
+	jobsQueue.published = nil
+
+	repo := &countingRepository{Repository: svc.repository}
+	svc.repository = repo
+	if err := svc.RenameEpisodes(ctx, userID, epIDs, "Chapter %id"); err != nil {
+		t.Fatal(err)
+	}
+
+	// region all episodes are saved at once, in a single transaction
+	if repo.transactions != 1 {
+		t.Errorf("expected a single transaction, got %d", repo.transactions)
+	}
+	if repo.saveEpisodeCalls != 0 {
+		t.Errorf("expected episodes not to be saved one by one, got %d SaveEpisode calls", repo.saveEpisodeCalls)
+	}
+	// endregion
+
+	// region every episode is renamed
+	episodesMap, err := svc.repository.GetEpisodesMap(ctx, userID, epIDs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(episodesMap) != len(epIDs) {
+		t.Fatalf("expected %d episodes, got %d", len(epIDs), len(episodesMap))
+	}
+	for _, epID := range epIDs {
+		id, _ := strconv.Atoi(epID)
+		if expected := fmt.Sprintf("Chapter %03d", id); episodesMap[epID].Title != expected {
+			t.Fatalf("expected episode %s to be renamed to %q, got %q", epID, expected, episodesMap[epID].Title)
+		}
+	}
+	// endregion
+
+	regenerated := publishedOf(t, jobsQueue, queueEventRegenerateFeed)
+	if len(regenerated) != 1 || len(regenerated[0].FeedIDs) != 1 || regenerated[0].FeedIDs[0] != feed.ID {
+		t.Errorf("expected feed %s to be regenerated, got %+v", feed.ID, regenerated)
+	}
+}
+
+// countingRepository counts calls that matter for batching, passing everything through
+type countingRepository struct {
+	Repository
+	transactions     int
+	saveEpisodeCalls int
+}
+
+func (r *countingRepository) Transaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	r.transactions++
+	return r.Repository.Transaction(ctx, fn)
+}
+
+func (r *countingRepository) SaveEpisode(ctx context.Context, episode *Episode) (*Episode, error) {
+	r.saveEpisodeCalls++
+	return r.Repository.SaveEpisode(ctx, episode)
+}
@@ -0,0 +1,778 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+)
+
+// RepositoryConformanceTest checks semantics Service relies on, so that every Repository backend behaves the same.
+// newRepo must return a fresh empty repository on every call
+func RepositoryConformanceTest(t *testing.T, newRepo func() Repository) {
+	t.Run("Local IDs are sequential per user", func(t *testing.T) {
+		repo := newRepo()
+		ctx := context.Background()
+
+		for _, expected := range []string{"1", "2", "3"} {
+			if feedID, err := repo.NextFeedID(ctx, "user-1"); err != nil || feedID != expected {
+				t.Fatalf("expected feed id %s, got %q, %v", expected, feedID, err)
+			}
+		}
+		for _, expected := range []string{"1", "2"} {
+			if epID, err := repo.NextEpisodeID(ctx, "user-1"); err != nil || epID != expected {
+				t.Fatalf("expected episode id %s, got %q, %v", expected, epID, err)
+			}
+		}
+		// other users start over, and episode IDs don't move feed IDs
+		if feedID, err := repo.NextFeedID(ctx, "user-2"); err != nil || feedID != "1" {
+			t.Fatalf("expected other user to start from 1, got %q, %v", feedID, err)
+		}
+		if feedID, err := repo.NextFeedID(ctx, "user-1"); err != nil || feedID != "4" {
+			t.Fatalf("expected feed id 4, got %q, %v", feedID, err)
+		}
+	})
+
+	t.Run("Concurrently taken feed IDs are unique and contiguous", func(t *testing.T) {
+		repo := newRepo()
+		ctx := context.Background()
+
+		const n = 20
+		feedIDs := make([]string, n)
+		errs := make([]error, n)
+		var wg sync.WaitGroup
+		for i := 0; i < n; i++ {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				feedIDs[i], errs[i] = repo.NextFeedID(ctx, "user-1")
+			}(i)
+		}
+		wg.Wait()
+
+		taken := make(map[string]bool, n)
+		for i, feedID := range feedIDs {
+			if errs[i] != nil {
+				t.Fatalf("failed to get next feed id: %v", errs[i])
+			}
+			taken[feedID] = true
+		}
+		for i := 1; i <= n; i++ {
+			if !taken[strconv.Itoa(i)] {
+				t.Fatalf("expected feed ids 1 to %d to be taken once each, got %v", n, feedIDs)
+			}
+		}
+	})
+
+	t.Run("Feeds", func(t *testing.T) {
+		repo := newRepo()
+		ctx := context.Background()
+		userID := "some-user"
+
+		// region save and get feed with every field set
+		feed := &Feed{
+			ID:             "1",
+			UserID:         userID,
+			Title:          "some title",
+			URL:            "https://example.com/feeds/1",
+			IsPermanent:    true,
+			Copyright:      "some copyright",
+			Normalize:      true,
+			MaxTotalBytes:  1024,
+			ImageURL:       "https://example.com/images/1",
+			MaxEpisodes:    10,
+			TTLMinutes:     60,
+			SkipHours:      []int{0, 23},
+			SkipDays:       []time.Weekday{time.Sunday, time.Saturday},
+			TokenProtected: true,
+			AccessToken:    "some-token",
+			Description:    "some description",
+			Author:         "some author",
+		}
+		if _, err := repo.SaveFeed(ctx, feed); err != nil {
+			t.Fatal(err)
+		}
+		loaded, err := repo.GetFeed(ctx, userID, "1")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(feed, loaded) {
+			t.Fatalf("expected feed\n%+v\nto be loaded as is, got\n%+v", feed, loaded)
+		}
+		// endregion
+
+		// region missing feeds are nil rather than errors
+		if missing, err := repo.GetFeed(ctx, userID, "missing"); err != nil || missing != nil {
+			t.Fatalf("expected missing feed to be nil, got %+v, %v", missing, err)
+		}
+		if missing, err := repo.GetFeed(ctx, "other-user", "1"); err != nil || missing != nil {
+			t.Fatalf("expected feed of other user to be nil, got %+v, %v", missing, err)
+		}
+		// endregion
+
+		// region saving existing feed updates it
+		feed.Title = "updated title"
+		feed.SkipHours = nil
+		feed.SkipDays = nil
+		if _, err := repo.SaveFeed(ctx, feed); err != nil {
+			t.Fatal(err)
+		}
+		if loaded, err = repo.GetFeed(ctx, userID, "1"); err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(feed, loaded) {
+			t.Fatalf("expected updated feed\n%+v\ngot\n%+v", feed, loaded)
+		}
+		// endregion
+
+		// region reordered feeds go first, the rest are ordered by numeric ID
+		for _, f := range []*Feed{
+			{ID: "10", UserID: userID, Title: "ten"},
+			{ID: "2", UserID: userID, Title: "two"},
+			{ID: "3", UserID: userID, Title: "three", SortOrder: 1},
+			{ID: "1", UserID: "other-user", Title: "other"},
+		} {
+			if _, err := repo.SaveFeed(ctx, f); err != nil {
+				t.Fatal(err)
+			}
+		}
+		feeds, err := repo.ListUserFeeds(ctx, userID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if ids := feedIDs(feeds); !reflect.DeepEqual(ids, []string{"3", "1", "2", "10"}) {
+			t.Fatalf("expected feeds to be listed as [3 1 2 10], got %v", ids)
+		}
+
+		feedsMap, err := repo.GetFeedsMap(ctx, userID, []string{"1", "10", "missing"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(feedsMap) != 2 || feedsMap["1"] == nil || feedsMap["10"] == nil {
+			t.Fatalf("expected found feeds only, got %v", feedsMap)
+		}
+		// endregion
+
+		// region delete feed
+		if err := repo.DeleteFeed(ctx, userID, "10"); err != nil {
+			t.Fatal(err)
+		}
+		if deleted, err := repo.GetFeed(ctx, userID, "10"); err != nil || deleted != nil {
+			t.Fatalf("expected feed to be deleted, got %+v, %v", deleted, err)
+		}
+		if other, err := repo.GetFeed(ctx, "other-user", "1"); err != nil || other == nil {
+			t.Fatalf("expected feed of other user to be left alone, got %+v, %v", other, err)
+		}
+		// endregion
+	})
+
+	t.Run("Episodes", func(t *testing.T) {
+		repo := newRepo()
+		ctx := context.Background()
+		userID := "some-user"
+
+		// region save and get episode with every field set
+		explicit := true
+		ep := conformanceEpisode(userID, "1", time.Now())
+		ep.Title = "Some Episode"
+		ep.SourceURL = "magnet:?xt=urn:btih:some-hash"
+		ep.SourceFilepaths = []string{"dir/01.mp3", "dir/02.mp3"}
+		ep.MediaryID = "some-mediary-id"
+		ep.URL = "https://example.com/episodes/1.mp3"
+		ep.Duration = 90 * time.Second
+		ep.FileLenBytes = 1000
+		ep.Format = "mp3"
+		ep.StorageKey = "episodes/some-user/1.mp3"
+		ep.Tags = []string{"live", "music"}
+		ep.Pinned = true
+		ep.PubDate = ep.CreatedAt.Add(-time.Hour)
+		ep.Explicit = &explicit
+		ep.SegmentDurations = []time.Duration{30 * time.Second, 60 * time.Second}
+		ep.ChaptersURL = "https://example.com/episodes/1.chapters.json"
+		ep.ContentHash = "some-content-hash"
+		if _, err := repo.SaveEpisode(ctx, ep); err != nil {
+			t.Fatal(err)
+		}
+		epMap, err := repo.GetEpisodesMap(ctx, userID, []string{"1", "missing"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(epMap) != 1 || !reflect.DeepEqual(ep, epMap["1"]) {
+			t.Fatalf("expected episode\n%+v\nto be loaded as is, got\n%+v", ep, epMap["1"])
+		}
+		// endregion
+
+		// region saving existing episode updates it
+		ep.Title = "Updated Episode"
+		ep.Explicit = nil
+		ep.Tags = nil
+		if _, err := repo.SaveEpisode(ctx, ep); err != nil {
+			t.Fatal(err)
+		}
+		if epMap, err = repo.GetEpisodesMap(ctx, userID, []string{"1"}); err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(ep, epMap["1"]) {
+			t.Fatalf("expected updated episode\n%+v\ngot\n%+v", ep, epMap["1"])
+		}
+		// endregion
+
+		// region clean episode is not confused with one following its feeds
+		clean := false
+		ep.Explicit = &clean
+		if _, err := repo.SaveEpisode(ctx, ep); err != nil {
+			t.Fatal(err)
+		}
+		if epMap, err = repo.GetEpisodesMap(ctx, userID, []string{"1"}); err != nil {
+			t.Fatal(err)
+		}
+		if loaded := epMap["1"].Explicit; loaded == nil || *loaded {
+			t.Fatalf("expected episode to stay marked clean, got %v", loaded)
+		}
+		// endregion
+
+		// region listing, paging and searching
+		for _, id := range []string{"2", "10", "3"} {
+			e := conformanceEpisode(userID, id, time.Now())
+			e.Title = "episode " + id
+			if _, err := repo.SaveEpisode(ctx, e); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if _, err := repo.SaveEpisode(ctx, conformanceEpisode("other-user", "5", time.Now())); err != nil {
+			t.Fatal(err)
+		}
+
+		episodes, err := repo.ListUserEpisodes(ctx, userID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if ids := sortedEpisodeIDs(episodes); !reflect.DeepEqual(ids, []string{"1", "10", "2", "3"}) {
+			t.Fatalf("expected user episodes only, got %v", ids)
+		}
+
+		page, total, err := repo.ListUserEpisodesPaged(ctx, userID, 1, 2)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if total != 4 || !reflect.DeepEqual(episodeIDs(page), []string{"2", "3"}) {
+			t.Fatalf("expected page [2 3] of 4 episodes, got %v of %d", episodeIDs(page), total)
+		}
+
+		found, err := repo.SearchEpisodes(ctx, userID, "EPISODE 1")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(episodeIDs(found), []string{"10"}) {
+			t.Fatalf("expected case-insensitive search to find [10], got %v", episodeIDs(found))
+		}
+		if found, err = repo.SearchEpisodes(ctx, userID, "some-hash"); err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(episodeIDs(found), []string{"1"}) {
+			t.Fatalf("expected search by source url to find [1], got %v", episodeIDs(found))
+		}
+		if found, err = repo.SearchEpisodes(ctx, userID, "%"); err != nil {
+			t.Fatal(err)
+		}
+		if len(found) != 0 {
+			t.Fatalf("expected wildcards to match literally, got %v", episodeIDs(found))
+		}
+		// endregion
+
+		// region listing by content hash
+		sameContent := conformanceEpisode(userID, "4", time.Now())
+		sameContent.ContentHash = "some-content-hash"
+		otherUserSameContent := conformanceEpisode("other-user", "6", time.Now())
+		otherUserSameContent.ContentHash = "some-content-hash"
+		for _, e := range []*Episode{sameContent, otherUserSameContent} {
+			if _, err := repo.SaveEpisode(ctx, e); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if found, err = repo.ListEpisodesByContentHash(ctx, userID, "some-content-hash"); err != nil {
+			t.Fatal(err)
+		}
+		if ids := sortedEpisodeIDs(found); !reflect.DeepEqual(ids, []string{"1", "4"}) {
+			t.Fatalf("expected user episodes with the same content hash only, got %v", ids)
+		}
+		if found, err = repo.ListEpisodesByContentHash(ctx, userID, "other-content-hash"); err != nil {
+			t.Fatal(err)
+		}
+		if len(found) != 0 {
+			t.Fatalf("expected no episodes with other content hash, got %v", episodeIDs(found))
+		}
+		// endregion
+
+		// region hard deletion
+		if err := repo.DeleteEpisodes(ctx, userID, []string{"2", "missing"}); err != nil {
+			t.Fatal(err)
+		}
+		if epMap, err = repo.GetEpisodesMap(ctx, userID, []string{"2"}); err != nil || len(epMap) != 0 {
+			t.Fatalf("expected episode to be deleted, got %v, %v", epMap, err)
+		}
+		// endregion
+	})
+
+	t.Run("Batched episodes saving", func(t *testing.T) {
+		repo := newRepo()
+		ctx := context.Background()
+		userID := "some-user"
+
+		existing := conformanceEpisode(userID, "1", time.Now())
+		if _, err := repo.SaveEpisode(ctx, existing); err != nil {
+			t.Fatal(err)
+		}
+
+		// more episodes than fit into a single query, one of them already saved
+		episodes := make([]*Episode, 120)
+		for i := range episodes {
+			ep := conformanceEpisode(userID, fmt.Sprint(i+1), time.Now())
+			ep.Title = fmt.Sprintf("renamed %d", i+1)
+			ep.Tags = []string{"batch"}
+			episodes[i] = ep
+		}
+		if err := repo.SaveEpisodes(ctx, episodes); err != nil {
+			t.Fatal(err)
+		}
+		if err := repo.SaveEpisodes(ctx, nil); err != nil {
+			t.Fatalf("expected saving no episodes to be a no-op, got %v", err)
+		}
+
+		saved, err := repo.ListUserEpisodes(ctx, userID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(saved) != len(episodes) {
+			t.Fatalf("expected %d episodes, got %d", len(episodes), len(saved))
+		}
+		savedMap := make(map[string]*Episode, len(saved))
+		for _, ep := range saved {
+			savedMap[ep.ID] = ep
+		}
+		for _, ep := range episodes {
+			if !reflect.DeepEqual(ep, savedMap[ep.ID]) {
+				t.Fatalf("expected episode\n%+v\nto be saved as is, got\n%+v", ep, savedMap[ep.ID])
+			}
+		}
+	})
+
+	t.Run("Soft deletion", func(t *testing.T) {
+		repo := newRepo()
+		ctx := context.Background()
+		userID := "some-user"
+
+		for _, id := range []string{"1", "2", "3"} {
+			if _, err := repo.SaveEpisode(ctx, conformanceEpisode(userID, id, time.Now())); err != nil {
+				t.Fatal(err)
+			}
+		}
+		longAgo := time.Now().UTC().Add(-30 * 24 * time.Hour).Truncate(time.Second)
+		if err := repo.SoftDeleteEpisodes(ctx, userID, []string{"1"}, longAgo); err != nil {
+			t.Fatal(err)
+		}
+		if err := repo.SoftDeleteEpisodes(ctx, userID, []string{"2"}, time.Now()); err != nil {
+			t.Fatal(err)
+		}
+
+		// region soft deleted episodes are hidden from everything but trash listings
+		episodes, err := repo.ListUserEpisodes(ctx, userID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if ids := episodeIDs(episodes); !reflect.DeepEqual(ids, []string{"3"}) {
+			t.Fatalf("expected only episode 3 to be listed, got %v", ids)
+		}
+		if epMap, err := repo.GetEpisodesMap(ctx, userID, []string{"1", "2"}); err != nil || len(epMap) != 0 {
+			t.Fatalf("expected soft deleted episodes to be hidden, got %v, %v", epMap, err)
+		}
+
+		trash, err := repo.ListUserDeletedEpisodes(ctx, userID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if ids := episodeIDs(trash); !reflect.DeepEqual(ids, []string{"2", "1"}) {
+			t.Fatalf("expected trash to be listed most recently deleted first, got %v", ids)
+		}
+		if !trash[1].DeletedAt.Equal(longAgo) {
+			t.Fatalf("expected deletion time %v, got %v", longAgo, trash[1].DeletedAt)
+		}
+
+		old, err := repo.ListDeletedEpisodes(ctx, 7*24*time.Hour)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if ids := episodeIDs(old); !reflect.DeepEqual(ids, []string{"1"}) {
+			t.Fatalf("expected only episode deleted long ago to be purgeable, got %v", ids)
+		}
+		if counts, err := repo.CountEpisodesByStatus(ctx); err != nil || !reflect.DeepEqual(counts, map[EpisodeStatus]int{EpisodeStatusComplete: 1}) {
+			t.Fatalf("expected only episode 3 to be counted, got %v, %v", counts, err)
+		}
+		// endregion
+
+		// region restore
+		if err := repo.RestoreEpisodes(ctx, userID, []string{"1", "2"}); err != nil {
+			t.Fatal(err)
+		}
+		if episodes, err = repo.ListUserEpisodes(ctx, userID); err != nil {
+			t.Fatal(err)
+		}
+		if len(episodes) != 3 {
+			t.Fatalf("expected restored episodes to be listed, got %v", episodeIDs(episodes))
+		}
+		// endregion
+	})
+
+	t.Run("Publications", func(t *testing.T) {
+		repo := newRepo()
+		ctx := context.Background()
+		userID := "some-user"
+
+		for _, f := range []string{"1", "2"} {
+			if _, err := repo.SaveFeed(ctx, &Feed{ID: f, UserID: userID, Title: "feed " + f}); err != nil {
+				t.Fatal(err)
+			}
+		}
+		now := time.Now()
+		for i, status := range []EpisodeStatus{EpisodeStatusComplete, EpisodeStatusComplete, EpisodeStatusFailed} {
+			ep := conformanceEpisode(userID, fmt.Sprint(i+1), now.Add(time.Duration(i)*time.Second))
+			ep.Status = status
+			if _, err := repo.SaveEpisode(ctx, ep); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		// episodes are published in reverse order to make sure feed lists them in publication order
+		if err := repo.BulkInsertPublications(ctx, []*Publication{
+			{UserID: userID, FeedID: "1", EpisodeID: "3", CreatedAt: now},
+			{UserID: userID, FeedID: "1", EpisodeID: "2", CreatedAt: now},
+			{UserID: userID, FeedID: "1", EpisodeID: "1", CreatedAt: now},
+			{UserID: userID, FeedID: "2", EpisodeID: "1", CreatedAt: now},
+		}); err != nil {
+			t.Fatal(err)
+		}
+
+		feedEpisodes, err := repo.ListFeedEpisodesJoined(ctx, userID, "1")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if ids := episodeIDs(feedEpisodes); !reflect.DeepEqual(ids, []string{"3", "2", "1"}) {
+			t.Fatalf("expected feed episodes in publication order, got %v", ids)
+		}
+		if feedEpisodes, err = repo.ListFeedEpisodes(ctx, userID, "2"); err != nil {
+			t.Fatal(err)
+		}
+		if ids := episodeIDs(feedEpisodes); !reflect.DeepEqual(ids, []string{"1"}) {
+			t.Fatalf("expected feed 2 to have [1], got %v", ids)
+		}
+
+		counts, err := repo.CountFeedsEpisodesByStatus(ctx, userID, []string{"1", "2"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		expectedCounts := map[string]map[EpisodeStatus]int{
+			"1": {EpisodeStatusComplete: 2, EpisodeStatusFailed: 1},
+			"2": {EpisodeStatusComplete: 1},
+		}
+		if !reflect.DeepEqual(counts, expectedCounts) {
+			t.Fatalf("expected counts %v, got %v", expectedCounts, counts)
+		}
+
+		publications, err := repo.ListPublicationsByEpisodeIDs(ctx, userID, []string{"1"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(publications) != 2 {
+			t.Fatalf("expected episode 1 to have 2 publications, got %d", len(publications))
+		}
+		for _, p := range publications {
+			if p.ID == "" || p.UserID != userID || p.EpisodeID != "1" {
+				t.Fatalf("unexpected publication %+v", p)
+			}
+		}
+
+		var feed2PublicationID string
+		for _, p := range publications {
+			if p.FeedID == "2" {
+				feed2PublicationID = p.ID
+			}
+		}
+		if err := repo.DeletePublications(ctx, userID, []string{feed2PublicationID}); err != nil {
+			t.Fatal(err)
+		}
+		if publications, err = repo.ListPublicationsByEpisodeIDs(ctx, userID, []string{"1"}); err != nil {
+			t.Fatal(err)
+		}
+		if len(publications) != 1 || publications[0].FeedID != "1" {
+			t.Fatalf("expected only publication in feed 1 to be left, got %+v", publications)
+		}
+	})
+
+	t.Run("Expired and stuck episodes", func(t *testing.T) {
+		repo := newRepo()
+		ctx := context.Background()
+		userID := "some-user"
+		longAgo := time.Now().Add(-60 * 24 * time.Hour)
+
+		stale := conformanceEpisode(userID, "stale", longAgo)
+		stale.Status = EpisodeStatusDownloading
+		pinned := conformanceEpisode(userID, "pinned", longAgo)
+		pinned.Pinned = true
+		inPermanentFeed := conformanceEpisode(userID, "in-permanent-feed", longAgo)
+		fresh := conformanceEpisode(userID, "fresh", time.Now())
+		fresh.Status = EpisodeStatusDownloading
+		for _, ep := range []*Episode{stale, pinned, inPermanentFeed, fresh} {
+			if _, err := repo.SaveEpisode(ctx, ep); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if _, err := repo.SaveFeed(ctx, &Feed{ID: "1", UserID: userID, Title: "permanent", IsPermanent: true}); err != nil {
+			t.Fatal(err)
+		}
+		if err := repo.BulkInsertPublications(ctx, []*Publication{
+			{UserID: userID, FeedID: "1", EpisodeID: inPermanentFeed.ID, CreatedAt: time.Now()},
+		}); err != nil {
+			t.Fatal(err)
+		}
+
+		expired, err := repo.ListExpiredEpisodes(ctx, 30*24*time.Hour)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if ids := episodeIDs(expired); !reflect.DeepEqual(ids, []string{"stale"}) {
+			t.Fatalf("expected only stale episode to expire, got %v", ids)
+		}
+
+		// user's own retention overrides default
+		if err := repo.SetUserRetentionDays(ctx, userID, 90); err != nil {
+			t.Fatal(err)
+		}
+		if expired, err = repo.ListExpiredEpisodes(ctx, 30*24*time.Hour); err != nil {
+			t.Fatal(err)
+		}
+		if len(expired) != 0 {
+			t.Fatalf("expected nothing to expire within user's retention, got %v", episodeIDs(expired))
+		}
+
+		stuck, err := repo.ListStuckEpisodes(ctx, 24*time.Hour, []EpisodeStatus{EpisodeStatusDownloading})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if ids := episodeIDs(stuck); !reflect.DeepEqual(ids, []string{"stale"}) {
+			t.Fatalf("expected only stale episode to be stuck, got %v", ids)
+		}
+	})
+
+	t.Run("User stats", func(t *testing.T) {
+		repo := newRepo()
+		ctx := context.Background()
+		userID := "some-user"
+
+		stats, err := repo.GetUserStats(ctx, userID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if stats.FeedsCount != 0 || len(stats.EpisodesByStatus) != 0 || !stats.OldestEpisodeAt.IsZero() {
+			t.Fatalf("expected empty stats for new user, got %+v", stats)
+		}
+
+		if _, err := repo.SaveFeed(ctx, &Feed{ID: "1", UserID: userID, Title: "feed"}); err != nil {
+			t.Fatal(err)
+		}
+		oldest := conformanceEpisode(userID, "1", time.Now().Add(-time.Hour))
+		oldest.FileLenBytes = 100
+		newest := conformanceEpisode(userID, "2", time.Now())
+		newest.FileLenBytes = 200
+		for _, ep := range []*Episode{oldest, newest} {
+			if _, err := repo.SaveEpisode(ctx, ep); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		if stats, err = repo.GetUserStats(ctx, userID); err != nil {
+			t.Fatal(err)
+		}
+		expected := &UserStats{
+			FeedsCount:       1,
+			EpisodesByStatus: map[EpisodeStatus]int{EpisodeStatusComplete: 2},
+			TotalBytes:       300,
+			OldestEpisodeAt:  oldest.CreatedAt,
+			NewestEpisodeAt:  newest.CreatedAt,
+		}
+		if !reflect.DeepEqual(expected, stats) {
+			t.Fatalf("expected stats %+v, got %+v", expected, stats)
+		}
+	})
+
+	t.Run("Settings, webhooks and retention", func(t *testing.T) {
+		repo := newRepo()
+		ctx := context.Background()
+
+		if value, err := repo.GetSetting(ctx, "some-key"); err != nil || value != "" {
+			t.Fatalf("expected missing setting to be empty, got %q, %v", value, err)
+		}
+		for _, value := range []string{"first", "second"} {
+			if err := repo.SetSetting(ctx, "some-key", value); err != nil {
+				t.Fatal(err)
+			}
+			if stored, err := repo.GetSetting(ctx, "some-key"); err != nil || stored != value {
+				t.Fatalf("expected setting %q, got %q, %v", value, stored, err)
+			}
+		}
+
+		if err := repo.SetWebhookURL(ctx, "some-user", "https://example.com/hook"); err != nil {
+			t.Fatal(err)
+		}
+		if url, err := repo.GetWebhookURL(ctx, "some-user"); err != nil || url != "https://example.com/hook" {
+			t.Fatalf("expected webhook url to be stored, got %q, %v", url, err)
+		}
+		if err := repo.SetWebhookURL(ctx, "some-user", ""); err != nil {
+			t.Fatal(err)
+		}
+		if url, err := repo.GetWebhookURL(ctx, "some-user"); err != nil || url != "" {
+			t.Fatalf("expected webhook url to be removed, got %q, %v", url, err)
+		}
+
+		if days, err := repo.GetUserRetentionDays(ctx, "some-user"); err != nil || days != 0 {
+			t.Fatalf("expected default retention, got %d, %v", days, err)
+		}
+		if err := repo.SetUserRetentionDays(ctx, "some-user", 14); err != nil {
+			t.Fatal(err)
+		}
+		if days, err := repo.GetUserRetentionDays(ctx, "some-user"); err != nil || days != 14 {
+			t.Fatalf("expected retention of 14 days, got %d, %v", days, err)
+		}
+	})
+
+	t.Run("Transaction commits", func(t *testing.T) {
+		repo := newRepo()
+		ctx := context.Background()
+		userID := "some-user"
+
+		if err := repo.Transaction(ctx, func(ctx context.Context) error {
+			if _, err := repo.SaveFeed(ctx, &Feed{ID: "1", UserID: userID, Title: "feed"}); err != nil {
+				return err
+			}
+			// writes made earlier in the transaction are visible to it
+			if feed, err := repo.GetFeed(ctx, userID, "1"); err != nil || feed == nil {
+				return fmt.Errorf("expected feed to be visible in transaction, got %+v, %v", feed, err)
+			}
+			_, err := repo.SaveEpisode(ctx, conformanceEpisode(userID, "1", time.Now()))
+			return err
+		}); err != nil {
+			t.Fatal(err)
+		}
+
+		if feed, err := repo.GetFeed(ctx, userID, "1"); err != nil || feed == nil {
+			t.Fatalf("expected feed to be committed, got %+v, %v", feed, err)
+		}
+		if epMap, err := repo.GetEpisodesMap(ctx, userID, []string{"1"}); err != nil || len(epMap) != 1 {
+			t.Fatalf("expected episode to be committed, got %v, %v", epMap, err)
+		}
+	})
+
+	t.Run("Transaction rollback", func(t *testing.T) {
+		repo := newRepo()
+		ctx := context.Background()
+		userID := "some-user"
+
+		if _, err := repo.SaveFeed(ctx, &Feed{ID: "1", UserID: userID, Title: "original title"}); err != nil {
+			t.Fatal(err)
+		}
+
+		errInTransaction := fmt.Errorf("something went wrong")
+		err := repo.Transaction(ctx, func(ctx context.Context) error {
+			if _, err := repo.SaveFeed(ctx, &Feed{ID: "1", UserID: userID, Title: "updated title"}); err != nil {
+				return err
+			}
+			if _, err := repo.SaveFeed(ctx, &Feed{ID: "2", UserID: userID, Title: "new feed"}); err != nil {
+				return err
+			}
+			if _, err := repo.SaveEpisode(ctx, conformanceEpisode(userID, "1", time.Now())); err != nil {
+				return err
+			}
+			if err := repo.BulkInsertPublications(ctx, []*Publication{
+				{UserID: userID, FeedID: "1", EpisodeID: "1", CreatedAt: time.Now()},
+			}); err != nil {
+				return err
+			}
+			return errInTransaction
+		})
+		if err == nil {
+			t.Fatal("expected error returned from transaction to be returned by Transaction")
+		}
+
+		// region no partial writes survive
+		feeds, err := repo.ListUserFeeds(ctx, userID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(feeds) != 1 || feeds[0].Title != "original title" {
+			t.Fatalf("expected feeds to be left as they were, got %+v", feeds)
+		}
+		episodes, err := repo.ListUserEpisodes(ctx, userID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(episodes) != 0 {
+			t.Fatalf("expected no episodes, got %v", episodeIDs(episodes))
+		}
+		publications, err := repo.ListPublicationsByEpisodeIDs(ctx, userID, []string{"1"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(publications) != 0 {
+			t.Fatalf("expected no publications, got %+v", publications)
+		}
+		// endregion
+
+		// region repository is still usable after rollback
+		if _, err := repo.SaveFeed(ctx, &Feed{ID: "2", UserID: userID, Title: "new feed"}); err != nil {
+			t.Fatal(err)
+		}
+		// endregion
+	})
+}
+
+func TestSqliteRepository__Conformance(t *testing.T) {
+	RepositoryConformanceTest(t, func() Repository { return getRepo(t) })
+}
+
+// conformanceEpisode is a complete episode, with times rounded the way they are stored
+func conformanceEpisode(userID, id string, at time.Time) *Episode {
+	at = at.UTC().Truncate(time.Second)
+	return &Episode{
+		ID:        id,
+		UserID:    userID,
+		Title:     "episode " + id,
+		Status:    EpisodeStatusComplete,
+		CreatedAt: at,
+		UpdatedAt: at,
+	}
+}
+
+func feedIDs(feeds []*Feed) []string {
+	ids := make([]string, 0, len(feeds))
+	for _, f := range feeds {
+		ids = append(ids, f.ID)
+	}
+	return ids
+}
+
+func episodeIDs(episodes []*Episode) []string {
+	ids := make([]string, 0, len(episodes))
+	for _, ep := range episodes {
+		ids = append(ids, ep.ID)
+	}
+	return ids
+}
+
+func sortedEpisodeIDs(episodes []*Episode) []string {
+	ids := episodeIDs(episodes)
+	sort.Strings(ids)
+	return ids
+}
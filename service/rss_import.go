@@ -0,0 +1,185 @@
+package service
+
+import (
+	"context"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/hori-ryota/zaperr"
+	"go.uber.org/zap"
+)
+
+const maxImportedRSSBytes = 10 * 1024 * 1024
+
+// ImportRSSFeed creates a new feed out of an existing podcast RSS feed.
+// Episodes are not re-downloaded: they keep pointing at the original enclosure URLs.
+func (svc *Service) ImportRSSFeed(ctx context.Context, userID string, rssURL string) (*Feed, error) {
+	zapFields := []zap.Field{
+		zap.String("user_id", userID),
+		zap.String("rss_url", rssURL),
+	}
+
+	doc, err := svc.fetchRSS(ctx, rssURL)
+	if err != nil {
+		return nil, zaperr.Wrap(err, "failed to fetch rss feed", zapFields...)
+	}
+
+	title := strings.TrimSpace(doc.Channel.Title)
+	if title == "" {
+		title = rssURL
+	}
+	title = truncateTitle(title) // feed is worth importing even if its title is too long for us
+
+	feed, err := svc.CreateFeed(ctx, userID, title)
+	if err != nil {
+		return nil, zaperr.Wrap(err, "failed to create feed", zapFields...)
+	}
+	zapFields = append(zapFields, zap.String("feed_id", feed.ID))
+
+	// feeds usually list newest items first, while we want older episodes to get lower IDs
+	items := doc.Channel.Items
+	epIDs := make([]string, 0, len(items))
+	for i := len(items) - 1; i >= 0; i-- {
+		item := items[i]
+		if item.Enclosure.URL == "" {
+			continue
+		}
+
+		epID, err := svc.repository.NextEpisodeID(ctx, userID)
+		if err != nil {
+			return nil, zaperr.Wrap(err, "failed to get next episode id", zapFields...)
+		}
+
+		now := time.Now().UTC()
+		createdAt := now
+		if pubDate, err := parseRSSPubDate(item.PubDate); err == nil {
+			createdAt = pubDate.UTC()
+		}
+
+		format := item.Enclosure.Type
+		if format == "" {
+			format = "mp3"
+		}
+
+		ep := &Episode{
+			ID:           epID,
+			UserID:       userID,
+			Title:        strings.TrimSpace(item.Title),
+			CreatedAt:    createdAt,
+			UpdatedAt:    now,
+			SourceURL:    rssURL,
+			URL:          item.Enclosure.URL,
+			Status:       EpisodeStatusComplete,
+			Duration:     parseRSSDuration(item.Duration),
+			FileLenBytes: item.Enclosure.lenBytes(),
+			Format:       format,
+		}
+		if _, err := svc.repository.SaveEpisode(ctx, ep); err != nil {
+			zapFields := append(zapFields, zap.String("episode_id", epID))
+			return nil, zaperr.Wrap(err, "failed to save episode", zapFields...)
+		}
+		epIDs = append(epIDs, epID)
+	}
+
+	if len(epIDs) > 0 {
+		if err := svc.PublishEpisodes(ctx, userID, epIDs, []string{feed.ID}); err != nil {
+			return nil, zaperr.Wrap(err, "failed to publish imported episodes", zapFields...)
+		}
+	}
+
+	if err := svc.RegenerateFeed(ctx, userID, feed.ID); err != nil {
+		return nil, zaperr.Wrap(err, "failed to regenerate imported feed", zapFields...)
+	}
+
+	feed.EpisodeIDs = epIDs
+	return feed, nil
+}
+
+type rssDocument struct {
+	Channel struct {
+		Title string    `xml:"title"`
+		Items []rssItem `xml:"item"`
+	} `xml:"channel"`
+}
+
+type rssItem struct {
+	Title     string       `xml:"title"`
+	PubDate   string       `xml:"pubDate"`
+	Duration  string       `xml:"http://www.itunes.com/dtds/podcast-1.0.dtd duration"`
+	Enclosure rssEnclosure `xml:"enclosure"`
+}
+
+type rssEnclosure struct {
+	URL    string `xml:"url,attr"`
+	Length string `xml:"length,attr"`
+	Type   string `xml:"type,attr"`
+}
+
+func (e rssEnclosure) lenBytes() int64 {
+	length, err := strconv.ParseInt(strings.TrimSpace(e.Length), 10, 64)
+	if err != nil {
+		return 0
+	}
+	return length
+}
+
+func (svc *Service) fetchRSS(ctx context.Context, rssURL string) (*rssDocument, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, rssURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	resp, err := svc.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get rss feed: %w", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status code %d", resp.StatusCode)
+	}
+
+	return parseRSS(io.LimitReader(resp.Body, maxImportedRSSBytes))
+}
+
+func parseRSS(r io.Reader) (*rssDocument, error) {
+	var doc rssDocument
+	if err := xml.NewDecoder(r).Decode(&doc); err != nil {
+		return nil, fmt.Errorf("failed to decode rss: %w", err)
+	}
+	return &doc, nil
+}
+
+func parseRSSPubDate(s string) (time.Time, error) {
+	s = strings.TrimSpace(s)
+	for _, layout := range []string{time.RFC1123Z, time.RFC1123, time.RFC822Z, time.RFC822, time.RFC3339} {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("unsupported pubDate format: %q", s)
+}
+
+// parseRSSDuration parses itunes:duration, which can be either
+// a number of seconds or one of HH:MM:SS / MM:SS forms
+func parseRSSDuration(s string) time.Duration {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return 0
+	}
+
+	var seconds int64
+	for _, part := range strings.Split(s, ":") {
+		n, err := strconv.ParseInt(part, 10, 64)
+		if err != nil {
+			return 0
+		}
+		seconds = seconds*60 + n
+	}
+	return time.Duration(seconds) * time.Second
+}
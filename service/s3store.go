@@ -2,6 +2,7 @@ package service
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"io"
 	"strings"
@@ -12,16 +13,50 @@ import (
 	"github.com/aws/aws-sdk-go-v2/service/s3/types"
 )
 
-func NewS3Store(s3Client *s3.Client, bucketName string) S3Store {
+// DefaultPresignTTL is how long presigned upload URLs stay valid unless configured otherwise
+const DefaultPresignTTL = 48 * time.Hour
+
+type S3StoreOptions struct {
+	PresignTTL        time.Duration
+	DefaultPutOptions []func(*PutOptions)
+}
+
+func WithPresignTTL(ttl time.Duration) func(*S3StoreOptions) {
+	return func(o *S3StoreOptions) {
+		o.PresignTTL = ttl
+	}
+}
+
+// WithDefaultPutOptions sets put options applied to every stored object before per-call ones,
+// e.g. storage class or encryption required by bucket policy
+func WithDefaultPutOptions(opts ...func(*PutOptions)) func(*S3StoreOptions) {
+	return func(o *S3StoreOptions) {
+		o.DefaultPutOptions = append(o.DefaultPutOptions, opts...)
+	}
+}
+
+func NewS3Store(s3Client *s3.Client, bucketName string, opts ...func(*S3StoreOptions)) S3Store {
+	options := &S3StoreOptions{PresignTTL: DefaultPresignTTL}
+	for _, opt := range opts {
+		opt(options)
+	}
+	if options.PresignTTL <= 0 {
+		options.PresignTTL = DefaultPresignTTL
+	}
+
 	return &s3Store{
-		s3Client:   s3Client,
-		bucketName: bucketName,
+		s3Client:          s3Client,
+		bucketName:        bucketName,
+		presignTTL:        options.PresignTTL,
+		defaultPutOptions: options.DefaultPutOptions,
 	}
 }
 
 type s3Store struct {
-	s3Client   *s3.Client
-	bucketName string
+	s3Client          *s3.Client
+	bucketName        string
+	presignTTL        time.Duration
+	defaultPutOptions []func(*PutOptions)
 }
 
 func (store *s3Store) URL(key string) (url string, err error) {
@@ -35,11 +70,16 @@ func (store *s3Store) URL(key string) (url string, err error) {
 }
 
 func (store *s3Store) PreSignedURL(key string) (string, error) {
+	return store.PreSignedURLWithTTL(key, store.presignTTL)
+}
+
+// PreSignedURLWithTTL is like PreSignedURL, but lets caller decide how long the URL stays valid
+func (store *s3Store) PreSignedURLWithTTL(key string, ttl time.Duration) (string, error) {
 	presignClient := s3.NewPresignClient(store.s3Client)
 	presignResult, err := presignClient.PresignPutObject(context.TODO(), &s3.PutObjectInput{
 		Bucket: aws.String(store.bucketName),
 		Key:    aws.String(key),
-	}, s3.WithPresignExpires(48*time.Hour))
+	}, s3.WithPresignExpires(ttl))
 	if err != nil {
 		return "", fmt.Errorf("failed to presign upload: %w", err)
 	}
@@ -48,7 +88,13 @@ func (store *s3Store) PreSignedURL(key string) (string, error) {
 }
 
 type PutOptions struct {
-	ContentType string
+	ContentType          string
+	ContentEncoding      string
+	Metadata             map[string]string
+	StorageClass         string
+	ServerSideEncryption string
+	// ACL is a canned ACL of stored object, empty means object is stored without ACL
+	ACL string
 }
 
 func WithContentType(contentType string) func(*PutOptions) {
@@ -57,8 +103,57 @@ func WithContentType(contentType string) func(*PutOptions) {
 	}
 }
 
+// WithContentEncoding sets Content-Encoding of stored object, e.g. "gzip" for objects that were compressed before upload
+func WithContentEncoding(contentEncoding string) func(*PutOptions) {
+	return func(opts *PutOptions) {
+		opts.ContentEncoding = contentEncoding
+	}
+}
+
+// WithMetadata adds user-defined metadata to stored object, S3 exposes it as x-amz-meta-<key>
+func WithMetadata(key, value string) func(*PutOptions) {
+	return func(opts *PutOptions) {
+		if opts.Metadata == nil {
+			opts.Metadata = make(map[string]string)
+		}
+		opts.Metadata[key] = value
+	}
+}
+
+// WithStorageClass sets storage class of stored object, e.g. "STANDARD_IA"
+func WithStorageClass(storageClass string) func(*PutOptions) {
+	return func(opts *PutOptions) {
+		opts.StorageClass = storageClass
+	}
+}
+
+// WithSSE sets server-side encryption of stored object, e.g. "AES256" or "aws:kms"
+func WithSSE(serverSideEncryption string) func(*PutOptions) {
+	return func(opts *PutOptions) {
+		opts.ServerSideEncryption = serverSideEncryption
+	}
+}
+
+// WithACL sets canned ACL of stored object, objects are "public-read" by default
+func WithACL(acl string) func(*PutOptions) {
+	return func(opts *PutOptions) {
+		opts.ACL = acl
+	}
+}
+
+// WithoutACL stores object without ACL, which is required by buckets with Object Ownership enforced.
+// Such buckets have to be made public with bucket policy instead
+func WithoutACL() func(*PutOptions) {
+	return func(opts *PutOptions) {
+		opts.ACL = ""
+	}
+}
+
 func (store *s3Store) Put(ctx context.Context, key string, dataReader io.ReadSeeker, opts ...func(*PutOptions)) error {
-	options := &PutOptions{}
+	options := &PutOptions{ACL: string(types.ObjectCannedACLPublicRead)}
+	for _, opt := range store.defaultPutOptions {
+		opt(options)
+	}
 	for _, opt := range opts {
 		opt(options)
 	}
@@ -67,11 +162,25 @@ func (store *s3Store) Put(ctx context.Context, key string, dataReader io.ReadSee
 		Bucket: aws.String(store.bucketName),
 		Key:    aws.String(key),
 		Body:   dataReader,
-		ACL:    types.ObjectCannedACLPublicRead,
+	}
+	if options.ACL != "" {
+		putObjectInput.ACL = types.ObjectCannedACL(options.ACL)
 	}
 	if options.ContentType != "" {
 		putObjectInput.ContentType = aws.String(options.ContentType)
 	}
+	if options.ContentEncoding != "" {
+		putObjectInput.ContentEncoding = aws.String(options.ContentEncoding)
+	}
+	if len(options.Metadata) > 0 {
+		putObjectInput.Metadata = options.Metadata
+	}
+	if options.StorageClass != "" {
+		putObjectInput.StorageClass = types.StorageClass(options.StorageClass)
+	}
+	if options.ServerSideEncryption != "" {
+		putObjectInput.ServerSideEncryption = types.ServerSideEncryption(options.ServerSideEncryption)
+	}
 	_, err := store.s3Client.PutObject(ctx, putObjectInput)
 	if err != nil {
 		return fmt.Errorf("failed to put object: %w", err)
@@ -79,6 +188,39 @@ func (store *s3Store) Put(ctx context.Context, key string, dataReader io.ReadSee
 	return nil
 }
 
+func (store *s3Store) Get(ctx context.Context, key string) ([]byte, error) {
+	out, err := store.s3Client.GetObject(ctx, &s3.GetObjectInput{
+		Bucket: aws.String(store.bucketName),
+		Key:    aws.String(key),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get object: %w", err)
+	}
+	defer func() { _ = out.Body.Close() }()
+
+	data, err := io.ReadAll(out.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read object: %w", err)
+	}
+	return data, nil
+}
+
+// Metadata returns user-defined metadata of stored object, or nil if there is no such object
+func (store *s3Store) Metadata(ctx context.Context, key string) (map[string]string, error) {
+	out, err := store.s3Client.HeadObject(ctx, &s3.HeadObjectInput{
+		Bucket: aws.String(store.bucketName),
+		Key:    aws.String(key),
+	})
+	if err != nil {
+		var notFound *types.NotFound
+		if errors.As(err, &notFound) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to head object: %w", err)
+	}
+	return out.Metadata, nil
+}
+
 func (store *s3Store) Delete(ctx context.Context, key string) error {
 	_, err := store.s3Client.DeleteObject(ctx, &s3.DeleteObjectInput{
 		Bucket: aws.String(store.bucketName),
@@ -90,6 +232,19 @@ func (store *s3Store) Delete(ctx context.Context, key string) error {
 	return nil
 }
 
+// Copy copies object within the bucket without downloading it, metadata is copied as well
+func (store *s3Store) Copy(ctx context.Context, srcKey string, dstKey string) error {
+	_, err := store.s3Client.CopyObject(ctx, &s3.CopyObjectInput{
+		Bucket:     aws.String(store.bucketName),
+		Key:        aws.String(dstKey),
+		CopySource: aws.String(store.bucketName + "/" + srcKey),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to copy object: %w", err)
+	}
+	return nil
+}
+
 func stripQuery(url string) string {
 	if i := strings.Index(url, "?"); i != -1 {
 		return url[:i]
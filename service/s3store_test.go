@@ -0,0 +1,114 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/credentials"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	tests "tg-podcastotron/testutils"
+)
+
+func TestS3Store__PreSignedURL__TTL(t *testing.T) {
+	s3Client := s3.New(s3.Options{
+		Region:      "us-east-1",
+		Credentials: credentials.NewStaticCredentialsProvider("some-key", "some-secret", ""),
+	})
+
+	for _, tc := range []struct {
+		name            string
+		opts            []func(*S3StoreOptions)
+		ttl             time.Duration
+		expectedExpires string
+	}{
+		{name: "default", expectedExpires: "X-Amz-Expires=172800"},
+		{name: "configured", opts: []func(*S3StoreOptions){WithPresignTTL(time.Hour)}, expectedExpires: "X-Amz-Expires=3600"},
+		{name: "per call override", opts: []func(*S3StoreOptions){WithPresignTTL(time.Hour)}, ttl: 10 * time.Minute, expectedExpires: "X-Amz-Expires=600"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			store := NewS3Store(s3Client, "some-bucket", tc.opts...)
+
+			var url string
+			var err error
+			if tc.ttl != 0 {
+				url, err = store.PreSignedURLWithTTL("some/key.mp3", tc.ttl)
+			} else {
+				url, err = store.PreSignedURL("some/key.mp3")
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !strings.Contains(url, tc.expectedExpires) {
+				t.Errorf("expected url to contain %s, got %s", tc.expectedExpires, url)
+			}
+		})
+	}
+}
+
+func TestS3Store__Put__ACL(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+	defer cancel()
+
+	bucketName := "some-bucket"
+	s3Client, teardown, err := tests.GetFakeS3Client(ctx, bucketName)
+	defer teardown()
+	if err != nil {
+		t.Fatalf("error getting s3 client: %v", err)
+	}
+
+	isPublic := func(t *testing.T, key string) bool {
+		out, err := s3Client.GetObjectAcl(ctx, &s3.GetObjectAclInput{Bucket: aws.String(bucketName), Key: aws.String(key)})
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, grant := range out.Grants {
+			if grant.Grantee != nil && aws.ToString(grant.Grantee.URI) == "http://acs.amazonaws.com/groups/global/AllUsers" {
+				return true
+			}
+		}
+		return false
+	}
+
+	t.Run("public-read by default", func(t *testing.T) {
+		store := NewS3Store(s3Client, bucketName)
+		if err := store.Put(ctx, "default.xml", bytes.NewReader([]byte("<rss/>"))); err != nil {
+			t.Fatal(err)
+		}
+		if !isPublic(t, "default.xml") {
+			t.Errorf("expected object to be public")
+		}
+	})
+
+	t.Run("without acl", func(t *testing.T) {
+		store := NewS3Store(s3Client, bucketName, WithDefaultPutOptions(WithoutACL()))
+		if err := store.Put(ctx, "no-acl.xml", bytes.NewReader([]byte("<rss/>"))); err != nil {
+			t.Fatal(err)
+		}
+		if isPublic(t, "no-acl.xml") {
+			t.Errorf("expected object to be stored without public-read acl")
+		}
+	})
+
+	t.Run("storage class and sse", func(t *testing.T) {
+		store := NewS3Store(s3Client, bucketName, WithDefaultPutOptions(WithStorageClass("STANDARD_IA")))
+		err := store.Put(ctx, "ia.xml", bytes.NewReader([]byte("<rss/>")), WithSSE("AES256"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		out, err := s3Client.HeadObject(ctx, &s3.HeadObjectInput{Bucket: aws.String(bucketName), Key: aws.String("ia.xml")})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if out.StorageClass != types.StorageClassStandardIa {
+			t.Errorf("expected storage class %s, got %s", types.StorageClassStandardIa, out.StorageClass)
+		}
+		if out.ServerSideEncryption != types.ServerSideEncryptionAes256 {
+			t.Errorf("expected sse %s, got %s", types.ServerSideEncryptionAes256, out.ServerSideEncryption)
+		}
+	})
+}
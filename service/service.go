@@ -2,11 +2,18 @@ package service
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
 	"io"
+	"math"
+	"net/http"
 	"path"
+	"strconv"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/google/uuid"
@@ -16,17 +23,28 @@ import (
 	"golang.org/x/exp/maps"
 	"golang.org/x/exp/slices"
 	"tg-podcastotron/mediary"
-	jobsqueue "tg-podcastotron/service/jobs_queue"
 )
 
 //go:generate moq -out servicemocks/s3.go -pkg servicemocks -rm . S3Store:MockS3Store
 type S3Store interface {
 	PreSignedURL(key string) (string, error)
+	PreSignedURLWithTTL(key string, ttl time.Duration) (string, error)
 	Put(ctx context.Context, key string, dataReader io.ReadSeeker, opts ...func(*PutOptions)) error
+	Get(ctx context.Context, key string) ([]byte, error)
+	Metadata(ctx context.Context, key string) (map[string]string, error)
 	Delete(ctx context.Context, key string) error
+	Copy(ctx context.Context, srcKey string, dstKey string) error
 	URL(key string) (url string, err error)
 }
 
+type JobsQueue interface {
+	Run()
+	Publish(ctx context.Context, jobType string, payload any) error
+	// PublishDelayed enqueues a job that isn't handled until delay passes
+	PublishDelayed(ctx context.Context, jobType string, payload any, delay time.Duration) error
+	Subscribe(ctx context.Context, jobType string, f func(payloadBytes []byte) error)
+}
+
 type Repository interface {
 	NextFeedID(ctx context.Context, userID string) (feedID string, err error)
 	SaveFeed(ctx context.Context, feed *Feed) (*Feed, error)
@@ -37,16 +55,37 @@ type Repository interface {
 
 	NextEpisodeID(ctx context.Context, userID string) (epID string, err error)
 	SaveEpisode(ctx context.Context, episode *Episode) (*Episode, error)
+	SaveEpisodes(ctx context.Context, episodes []*Episode) error
 	ListUserEpisodes(ctx context.Context, userID string) ([]*Episode, error)
+	ListUserEpisodesPaged(ctx context.Context, userID string, offset, limit int) ([]*Episode, int, error)
+	SearchEpisodes(ctx context.Context, userID, query string) ([]*Episode, error)
 	ListFeedEpisodes(ctx context.Context, userID, feedID string) ([]*Episode, error)
+	ListFeedEpisodesJoined(ctx context.Context, userID, feedID string) ([]*Episode, error)
 	GetEpisodesMap(ctx context.Context, userID string, episodeIDs []string) (map[string]*Episode, error)
+	ListEpisodesByContentHash(ctx context.Context, userID string, contentHash string) ([]*Episode, error)
 	DeleteEpisodes(ctx context.Context, userID string, episodeIDs []string) error
-	ListExpiredEpisodes(ctx context.Context, maxAge time.Duration) ([]*Episode, error)
+	SoftDeleteEpisodes(ctx context.Context, userID string, episodeIDs []string, deletedAt time.Time) error
+	RestoreEpisodes(ctx context.Context, userID string, episodeIDs []string) error
+	ListUserDeletedEpisodes(ctx context.Context, userID string) ([]*Episode, error)
+	ListDeletedEpisodes(ctx context.Context, olderThan time.Duration) ([]*Episode, error)
+	ListExpiredEpisodes(ctx context.Context, defaultMaxAge time.Duration) ([]*Episode, error)
+	ListStuckEpisodes(ctx context.Context, olderThan time.Duration, statuses []EpisodeStatus) ([]*Episode, error)
+	GetUserStats(ctx context.Context, userID string) (*UserStats, error)
+	CountEpisodesByStatus(ctx context.Context) (map[EpisodeStatus]int, error)
 
 	BulkInsertPublications(ctx context.Context, publications []*Publication) error
 	ListPublicationsByEpisodeIDs(ctx context.Context, userID string, episodeIDs []string) ([]*Publication, error)
+	CountFeedsEpisodesByStatus(ctx context.Context, userID string, feedIDs []string) (map[string]map[EpisodeStatus]int, error)
 	DeletePublications(ctx context.Context, userID string, publicationIDs []string) error
 
+	GetSetting(ctx context.Context, key string) (string, error)
+	SetSetting(ctx context.Context, key string, value string) error
+
+	GetWebhookURL(ctx context.Context, userID string) (string, error)
+	SetWebhookURL(ctx context.Context, userID string, webhookURL string) error
+	GetUserRetentionDays(ctx context.Context, userID string) (int, error)
+	SetUserRetentionDays(ctx context.Context, userID string, days int) error
+
 	Transaction(ctx context.Context, fn func(ctx context.Context) error) error
 }
 
@@ -55,11 +94,76 @@ type Service struct {
 	s3Store      S3Store
 	mediaSvc     mediary.Service
 	repository   Repository
-	jobsQueue    *jobsqueue.RJQ
+	jobsQueue    JobsQueue
 	obfuscateIDs func(string) string
 
 	episodeStatusChangesChan chan []EpisodeStatusChange
 	defaultFeedTitle         string
+	feedGenerator            string
+	compressFeeds            bool
+	minEpisodeFileBytes      int64
+	webhookSecret            string
+	httpClient               *http.Client
+	webhookRetryDelay        time.Duration
+	dedupeEpisodes           bool
+	// createEpisodesConcurrency is how many episodes of a queued batch are created at the same time
+	createEpisodesConcurrency int
+	// regenerationSchedule is nil unless feed regeneration is debounced
+	regenerationSchedule       RegenerationSchedule
+	regenerationDebounceWindow time.Duration
+	// feedRegenerations counts feed files uploaded since start, for monitoring
+	feedRegenerations atomic.Int64
+
+	defaultFeedMu sync.Mutex
+}
+
+type Options struct {
+	// MinEpisodeFileBytes is the size below which files are not turned into separate episodes,
+	// so that samples and jingles accompanying torrents don't end up as junk episodes
+	MinEpisodeFileBytes int64
+	// WebhookSecret signs webhook requests, so that receivers can tell they come from us
+	WebhookSecret string
+	// DedupeEpisodes makes CreateEpisode return existing episode made of the same files the same way,
+	// instead of having mediary process them again
+	DedupeEpisodes bool
+	// CreateEpisodesConcurrency limits how many episodes of a queued batch are created at the same time,
+	// DefaultCreateEpisodesConcurrency is used when not set
+	CreateEpisodesConcurrency int
+	// RegenerationSchedule collapses a burst of regenerations of the same feed into one,
+	// every feed is regenerated on each event when not set
+	RegenerationSchedule       RegenerationSchedule
+	RegenerationDebounceWindow time.Duration
+}
+
+func WithMinEpisodeFileBytes(minEpisodeFileBytes int64) func(*Options) {
+	return func(o *Options) {
+		o.MinEpisodeFileBytes = minEpisodeFileBytes
+	}
+}
+
+func WithWebhookSecret(webhookSecret string) func(*Options) {
+	return func(o *Options) {
+		o.WebhookSecret = webhookSecret
+	}
+}
+
+func WithEpisodeDedupe() func(*Options) {
+	return func(o *Options) {
+		o.DedupeEpisodes = true
+	}
+}
+
+func WithCreateEpisodesConcurrency(concurrency int) func(*Options) {
+	return func(o *Options) {
+		o.CreateEpisodesConcurrency = concurrency
+	}
+}
+
+func WithRegenerationDebounce(schedule RegenerationSchedule, window time.Duration) func(*Options) {
+	return func(o *Options) {
+		o.RegenerationSchedule = schedule
+		o.RegenerationDebounceWindow = window
+	}
 }
 
 type Metadata = mediary.Metadata
@@ -80,6 +184,16 @@ type Episode struct {
 	Format          string
 	FeedIDs         []string
 	StorageKey      string
+	Tags            []string
+	Pinned          bool      // pinned episodes are never auto-deleted, even from ephemeral feeds
+	PubDate         time.Time // overrides CreatedAt as publication date in feeds when set
+	Explicit        *bool     // nil means episode is as explicit as the feed it is in
+	DeletedAt       time.Time // zero unless episode is in trash, waiting to be either restored or purged
+	// SegmentDurations are durations of source files episode was concatenated from, they make its chapters
+	SegmentDurations []time.Duration
+	ChaptersURL      string // URL of Podcasting 2.0 chapters file, empty when episode has no chapters
+	// ContentHash identifies what episode was made of and how, episodes with the same hash have identical contents
+	ContentHash string
 }
 
 type EpisodeStatus string
@@ -92,10 +206,18 @@ const (
 	EpisodeStatusProcessing  EpisodeStatus = "processing"
 	EpisodeStatusUploading   EpisodeStatus = "uploading"
 	EpisodeStatusComplete    EpisodeStatus = "complete"
+	EpisodeStatusFailed      EpisodeStatus = "failed"
+	EpisodeStatusCancelled   EpisodeStatus = "cancelled"
 )
 
 const DefaultFeedID = "1"
 
+// DeletedEpisodesRetention is how long deleted episodes stay in trash before they are purged
+const DeletedEpisodesRetention = 7 * 24 * time.Hour
+
+// httpRequestTimeout bounds requests service makes on its own, like webhooks and enclosure checks
+const httpRequestTimeout = 10 * time.Second
+
 type Feed struct {
 	ID          string
 	UserID      string
@@ -103,6 +225,37 @@ type Feed struct {
 	URL         string
 	EpisodeIDs  []string
 	IsPermanent bool // whether episodes in this feed should be kept regardless or cleaned up after some time
+	Copyright   string
+	Normalize   bool // whether loudness of concatenated episodes created for this feed should be normalized
+	// MaxTotalBytes caps total size of episodes in an ephemeral feed, oldest episodes are removed from the feed when exceeded.
+	// Zero means no limit
+	MaxTotalBytes int64
+	ImageURL      string // cover image of the feed, empty if not set
+	SortOrder     int    // feeds are listed by sort order first, then by ID. Zero means feed was never reordered
+	// MaxEpisodes caps number of episodes in the feed, oldest episodes are removed from the feed when exceeded.
+	// Unlike MaxTotalBytes, it applies to permanent feeds as well. Zero means no limit
+	MaxEpisodes int
+	// TTLMinutes, SkipHours and SkipDays tell aggregators when polling the feed is pointless.
+	// Zero TTL and empty skip lists are not rendered
+	TTLMinutes int
+	SkipHours  []int          // hours of day in GMT, 0 to 23
+	SkipDays   []time.Weekday // days in GMT
+	// TokenProtected feeds are stored under a key with AccessToken in it, so that it can't be guessed
+	// from user and feed IDs, and rotating the token revokes access of everyone the feed URL was shared with
+	TokenProtected bool
+	AccessToken    string
+	// Description and Author are rendered in feed, podcast directories reject feeds without them,
+	// so title is rendered in their place when they are empty
+	Description string
+	Author      string
+}
+
+type UserStats struct {
+	FeedsCount       int
+	EpisodesByStatus map[EpisodeStatus]int
+	TotalBytes       int64     // size of all episode files
+	OldestEpisodeAt  time.Time // zero if user has no episodes
+	NewestEpisodeAt  time.Time // zero if user has no episodes
 }
 
 type Publication struct {
@@ -121,22 +274,61 @@ var (
 	ErrFeedNotFound    = fmt.Errorf("feed not found")
 	ErrEpisodeNotFound = fmt.Errorf("episode not found")
 	ErrNotImplemented  = fmt.Errorf("not implemented")
+	ErrJobLost         = fmt.Errorf("job lost: mediary never reported its status")
+	ErrJobFailed       = fmt.Errorf("job failed on mediary side")
+	ErrInvalidPayload  = fmt.Errorf("invalid queue payload")
+	ErrNothingToSplit  = fmt.Errorf("episode has less than two source files")
+	ErrNothingToMerge  = fmt.Errorf("less than two episodes to merge")
+	ErrSourcesDiffer   = fmt.Errorf("episodes come from different sources")
+	ErrInvalidSchedule = fmt.Errorf("invalid feed schedule")
+	ErrReencodeFailed  = fmt.Errorf("re-encoding failed, episode keeps its previous file")
+
+	ErrUnsupportedFormat        = fmt.Errorf("unsupported episode format")
+	ErrObfuscationSecretChanged = fmt.Errorf("user path secret changed: existing feed and episode URLs would break")
 )
 
+// EpisodesNotFoundError is returned when some of requested episodes do not exist
+type EpisodesNotFoundError struct {
+	IDs []string
+}
+
+func (e *EpisodesNotFoundError) Error() string {
+	return fmt.Sprintf("episodes not found: %s", strings.Join(e.IDs, ", "))
+}
+
+func (e *EpisodesNotFoundError) Unwrap() error {
+	return ErrEpisodeNotFound
+}
+
 const maxPollEpisodesRequeueCount = 100
 
+const DefaultCreateEpisodesConcurrency = 4
+
 func New(
 	mediaSvc mediary.Service,
 	repository Repository,
 	s3Store S3Store,
-	jobsQueue *jobsqueue.RJQ,
+	jobsQueue JobsQueue,
 	defaultFeedTitle string,
+	feedGenerator string,
+	compressFeeds bool,
 	obfuscateIDs func(string) string,
 	logger *zap.Logger,
+	opts ...func(*Options),
 ) *Service {
+	options := &Options{}
+	for _, opt := range opts {
+		opt(options)
+	}
 	if defaultFeedTitle == "" {
 		defaultFeedTitle = "Podcast-O-Tron"
 	}
+	if feedGenerator == "" {
+		feedGenerator = DefaultFeedGenerator
+	}
+	if options.CreateEpisodesConcurrency <= 0 {
+		options.CreateEpisodesConcurrency = DefaultCreateEpisodesConcurrency
+	}
 	return &Service{
 		logger:                   logger,
 		s3Store:                  s3Store,
@@ -146,25 +338,93 @@ func New(
 		episodeStatusChangesChan: make(chan []EpisodeStatusChange, 1),
 		obfuscateIDs:             obfuscateIDs,
 		defaultFeedTitle:         defaultFeedTitle,
+		feedGenerator:            feedGenerator,
+		compressFeeds:            compressFeeds,
+		minEpisodeFileBytes:      options.MinEpisodeFileBytes,
+		webhookSecret:            options.WebhookSecret,
+		httpClient:               &http.Client{Timeout: httpRequestTimeout},
+		webhookRetryDelay:        time.Second,
+		dedupeEpisodes:           options.DedupeEpisodes,
+
+		createEpisodesConcurrency: options.CreateEpisodesConcurrency,
+
+		regenerationSchedule:       options.RegenerationSchedule,
+		regenerationDebounceWindow: options.RegenerationDebounceWindow,
+	}
+}
+
+const (
+	obfuscationFingerprintSettingKey = "obfuscation_fingerprint"
+	obfuscationFingerprintProbe      = "obfuscation-fingerprint-probe"
+)
+
+// VerifyObfuscationFingerprint makes sure that obfuscateIDs function is deterministic
+// and produces the same output it did on previous runs.
+// Changing USER_PATH_SECRET silently breaks every existing feed URL, so unless acceptNew is set,
+// ErrObfuscationSecretChanged is returned when stored fingerprint does not match current one
+func (svc *Service) VerifyObfuscationFingerprint(ctx context.Context, acceptNew bool) error {
+	fingerprint := svc.obfuscateIDs(obfuscationFingerprintProbe)
+	if fingerprint != svc.obfuscateIDs(obfuscationFingerprintProbe) {
+		return fmt.Errorf("obfuscateIDs is not deterministic")
+	}
+	if fingerprint == svc.obfuscateIDs(obfuscationFingerprintProbe+"-other") {
+		return fmt.Errorf("obfuscateIDs maps different IDs to the same value")
+	}
+
+	stored, err := svc.repository.GetSetting(ctx, obfuscationFingerprintSettingKey)
+	if err != nil {
+		return zaperr.Wrap(err, "failed to get stored obfuscation fingerprint")
+	}
+
+	if stored != "" && stored != fingerprint {
+		if !acceptNew {
+			return ErrObfuscationSecretChanged
+		}
+		svc.logger.Warn(
+			"user path secret changed, existing feed and episode URLs will break",
+			zap.String("old_fingerprint", stored),
+			zap.String("new_fingerprint", fingerprint),
+		)
+	}
+
+	if stored != fingerprint {
+		if err := svc.repository.SetSetting(ctx, obfuscationFingerprintSettingKey, fingerprint); err != nil {
+			return zaperr.Wrap(err, "failed to store obfuscation fingerprint")
+		}
 	}
+
+	return nil
+}
+
+// progressPercent rounds progress the way it's shown to users
+func progressPercent(progress float64) int {
+	return int(math.Round(progress * 100))
 }
 
 type EpisodeStatusChange struct {
 	Episode   *Episode
 	OldStatus EpisodeStatus
 	NewStatus EpisodeStatus
+	Err       error   // reason of the failure, only set when NewStatus is EpisodeStatusFailed
+	Progress  float64 // from 0 to 1, progress of NewStatus when mediary reports it
 }
 
 func (svc *Service) Start(ctx context.Context) chan []EpisodeStatusChange {
-	svc.jobsQueue.Subscribe(ctx, queueEventCreateEpisodes, func(payload []byte) error {
+	svc.jobsQueue.Subscribe(ctx, string(queueEventCreateEpisodes), func(payload []byte) error {
 		return svc.onCreateEpisodesQueueEvent(ctx, payload)
 	})
-	svc.jobsQueue.Subscribe(ctx, queueEventPollEpisodesStatus, func(payload []byte) error {
+	svc.jobsQueue.Subscribe(ctx, string(queueEventPollEpisodesStatus), func(payload []byte) error {
 		return svc.onPollEpisodesQueueEvent(ctx, payload)
 	})
-	svc.jobsQueue.Subscribe(ctx, queueEventRegenerateFeed, func(payload []byte) error {
+	svc.jobsQueue.Subscribe(ctx, string(queueEventRegenerateFeed), func(payload []byte) error {
 		return svc.onRegenerateFeedQueueEvent(ctx, payload)
 	})
+	svc.jobsQueue.Subscribe(ctx, string(queueEventPollReencodeJobs), func(payload []byte) error {
+		return svc.onPollReencodeJobsQueueEvent(ctx, payload)
+	})
+	svc.jobsQueue.Subscribe(ctx, string(queueEventNotifyWebhook), func(payload []byte) error {
+		return svc.onNotifyWebhookQueueEvent(ctx, payload)
+	})
 	svc.jobsQueue.Run() // MUST be called after all subscriptions
 	return svc.episodeStatusChangesChan
 }
@@ -181,21 +441,31 @@ func (svc *Service) CreateEpisodesAsync(
 	url string,
 	variantsPerEpisode [][]string,
 	processingType ProcessingType,
+	tags []string,
+	opts EpisodeOptions,
 ) error {
 	zapFields := []zap.Field{
 		zap.String("url", url),
 		zap.Any("variants_per_episode", variantsPerEpisode),
 		zap.String("processing_type", string(processingType)),
 		zap.String("user_id", userID),
+		zap.Strings("tags", tags),
+		zap.Any("options", opts),
+	}
+
+	if _, err := opts.format(); err != nil {
+		return zaperr.Wrap(err, "failed to queue episodes creation", zapFields...)
 	}
 
 	svc.logger.Info("queueing episodes creation", zapFields...)
 
-	if err := svc.jobsQueue.Publish(ctx, queueEventCreateEpisodes, &CreateEpisodesQueuePayload{
+	if err := publish(ctx, svc.jobsQueue, queueEventCreateEpisodes, &CreateEpisodesQueuePayload{
 		URL:                url,
 		VariantsPerEpisode: variantsPerEpisode,
 		ProcessingType:     processingType,
 		UserID:             userID,
+		Tags:               tags,
+		Options:            opts,
 	}); err != nil {
 		return zaperr.Wrap(err, "failed to enqueue episodes creation", zapFields...)
 	}
@@ -203,8 +473,78 @@ func (svc *Service) CreateEpisodesAsync(
 	return nil
 }
 
-func (svc *Service) CreateEpisode(ctx context.Context, userID string, mediaURL string, variants []string, processingType ProcessingType) (*Episode, error) {
-	filename := uuid.New().String() + ".mp3" // TODO: implement more elaborate filename generation
+// ExcludeSmallFiles drops single-file episodes whose file is smaller than configured minimum,
+// returning the remaining episodes and paths of excluded files. Files of unknown size are kept
+func (svc *Service) ExcludeSmallFiles(metadata *Metadata, variantsPerEpisode [][]string) ([][]string, []string) {
+	if svc.minEpisodeFileBytes <= 0 {
+		return variantsPerEpisode, nil
+	}
+
+	lenBytesByVariant := make(map[string]int64, len(metadata.Variants))
+	for _, v := range metadata.Variants {
+		if v.LenBytes != nil {
+			lenBytesByVariant[v.ID] = *v.LenBytes
+		}
+	}
+
+	kept := make([][]string, 0, len(variantsPerEpisode))
+	var excluded []string
+	for _, variants := range variantsPerEpisode {
+		if len(variants) == 1 {
+			if lenBytes, ok := lenBytesByVariant[variants[0]]; ok && lenBytes < svc.minEpisodeFileBytes {
+				excluded = append(excluded, variants[0])
+				continue
+			}
+		}
+		kept = append(kept, variants)
+	}
+
+	return kept, excluded
+}
+
+// CreateEpisode creates an episode out of variants of mediaURL. Metadata of mediaURL is optional,
+// callers creating several episodes of the same URL pass it to avoid fetching it again for each of them
+func (svc *Service) CreateEpisode(
+	ctx context.Context,
+	userID string,
+	mediaURL string,
+	variants []string,
+	processingType ProcessingType,
+	tags []string,
+	opts EpisodeOptions,
+	metadata *Metadata,
+) (*Episode, error) {
+	ep, isNew, err := svc.prepareEpisode(ctx, userID, mediaURL, variants, processingType, tags, opts, metadata)
+	if err != nil {
+		return nil, err
+	}
+	if !isNew {
+		return ep, nil
+	}
+	return svc.saveNewEpisode(ctx, ep)
+}
+
+// prepareEpisode starts mediary job of an episode and returns the episode without ID, which is only given out
+// by saveNewEpisode. Existing episode with the same content is returned as is, with isNew set to false
+func (svc *Service) prepareEpisode(
+	ctx context.Context,
+	userID string,
+	mediaURL string,
+	variants []string,
+	processingType ProcessingType,
+	tags []string,
+	opts EpisodeOptions,
+	metadata *Metadata,
+) (ep *Episode, isNew bool, err error) {
+	format, err := opts.format()
+	if err != nil {
+		return nil, false, zaperr.Wrap(err, "failed to create episode", zap.Any("options", opts))
+	}
+	if processingType == ProcessingTypeUploadOriginal && opts.Codec != "" {
+		// original files are uploaded as is, so there's nothing to encode
+		return nil, false, zaperr.Wrap(ErrUnsupportedFormat, "codec can only be chosen for concatenated episodes", zap.Any("options", opts))
+	}
+	filename := uuid.New().String() + "." + format // TODO: implement more elaborate filename generation
 	episodeKey := svc.constructS3EpisodeKey(userID, filename)
 
 	zapFields := []zap.Field{
@@ -214,45 +554,46 @@ func (svc *Service) CreateEpisode(ctx context.Context, userID string, mediaURL s
 		zap.String("filename", filename),
 		zap.String("user_id", userID),
 		zap.String("episode_key", episodeKey),
+		zap.Strings("tags", tags),
+		zap.Any("options", opts),
+	}
+
+	contentHash := episodeContentHash(mediaURL, variants, processingType, format, opts.BitrateKbps)
+	if svc.dedupeEpisodes {
+		if existing, err := svc.findEpisodeByContentHash(ctx, userID, contentHash); err != nil {
+			return nil, false, zaperr.Wrap(err, "failed to find episode with the same content", zapFields...)
+		} else if existing != nil {
+			svc.logger.Info("episode with the same content already exists, not creating another one",
+				append(zapFields, zap.String("episode_id", existing.ID))...)
+			return existing, false, nil
+		}
 	}
 
 	presignURL, err := svc.s3Store.PreSignedURL(episodeKey)
 	if err != nil {
-		return nil, zaperr.Wrap(err, "failed to get presigned url", zapFields...)
+		return nil, false, zaperr.Wrap(err, "failed to get presigned url", zapFields...)
 	}
 
-	var mediaryParams *mediary.CreateUploadJobParams
-	switch processingType {
-	case ProcessingTypeConcatenate:
-		mediaryParams = &mediary.CreateUploadJobParams{
-			URL:  mediaURL,
-			Type: mediary.JobTypeConcatenate,
-			Params: mediary.ConcatenateJobParams{
-				Variants:  variants,
-				UploadURL: presignURL,
-			},
-		}
-	case ProcessingTypeUploadOriginal:
-		mediaryParams = &mediary.CreateUploadJobParams{
-			URL:  mediaURL,
-			Type: mediary.JobTypeUploadOriginal,
-			Params: mediary.UploadOriginalJobParams{
-				Variant:   variants[0],
-				UploadURL: presignURL,
-			},
-		}
-	default:
-		return nil, zaperr.Wrap(ErrNotImplemented, "unsupported processing type", zapFields...)
+	// new episodes always end up in the default feed, so it's the one defining processing settings
+	normalize, err := svc.shouldNormalize(ctx, userID, []string{DefaultFeedID})
+	if err != nil {
+		return nil, false, zaperr.Wrap(err, "failed to check if episode should be normalized", zapFields...)
 	}
 
-	metadata, err := svc.FetchMetadata(ctx, mediaURL)
+	mediaryParams, err := mediaryJobParams(mediaURL, variants, processingType, presignURL, normalize, opts)
 	if err != nil {
-		return nil, zaperr.Wrap(err, "failed to fetch metadata", zapFields...)
+		return nil, false, zaperr.Wrap(err, "failed to build mediary job params", zapFields...)
+	}
+
+	if metadata == nil {
+		if metadata, err = svc.FetchMetadata(ctx, mediaURL); err != nil {
+			return nil, false, zaperr.Wrap(err, "failed to fetch metadata", zapFields...)
+		}
 	}
 
 	mediaryID, err := svc.mediaSvc.CreateUploadJob(ctx, mediaryParams)
 	if err != nil {
-		return nil, zaperr.Wrap(err, "failed to create mediary job", zapFields...)
+		return nil, false, zaperr.Wrap(err, "failed to create mediary job", zapFields...)
 	}
 
 	var episodeTitle string
@@ -267,32 +608,123 @@ func (svc *Service) CreateEpisode(ctx context.Context, userID string, mediaURL s
 	case "ytdl":
 		episodeTitle = metadata.Name
 	default:
-		return nil, zaperr.Wrap(ErrNotImplemented, "unsupported downloader while generating episode title", zapFields...)
-	}
-
-	epID, err := svc.repository.NextEpisodeID(ctx, userID)
-	if err != nil {
-		return nil, zaperr.Wrap(err, "failed to get next episode id", zapFields...)
+		return nil, false, zaperr.Wrap(ErrNotImplemented, "unsupported downloader while generating episode title", zapFields...)
 	}
 
-	ep := &Episode{
-		ID:              epID,
+	return &Episode{
 		Title:           episodeTitle,
 		UserID:          userID,
 		SourceURL:       mediaURL,
-		CreatedAt:       time.Now().UTC(),
-		UpdatedAt:       time.Now().UTC(),
 		SourceFilepaths: variants,
 		StorageKey:      episodeKey,
 		URL:             stripQuery(presignURL),
 		MediaryID:       mediaryID,
-		Duration:        0,     // should be populated later when job is complete
-		FileLenBytes:    0,     // should be populated later when job is complete
-		Format:          "mp3", // FIXME: hardcoded
+		Duration:        0, // should be populated later when job is complete
+		FileLenBytes:    0, // should be populated later when job is complete
+		Format:          format,
+		Tags:            normalizeTags(tags),
+		ContentHash:     contentHash,
+	}, true, nil
+}
+
+// saveNewEpisode gives prepared episode its ID and creation time. Episodes created together are saved one by one
+// in the order they were requested, so that their IDs and creation times follow that order
+func (svc *Service) saveNewEpisode(ctx context.Context, ep *Episode) (*Episode, error) {
+	zapFields := []zap.Field{
+		zap.String("user_id", ep.UserID),
+		zap.String("media_url", ep.SourceURL),
+		zap.Strings("variants", ep.SourceFilepaths),
+		zap.String("mediary_id", ep.MediaryID),
+	}
+
+	epID, err := svc.repository.NextEpisodeID(ctx, ep.UserID)
+	if err != nil {
+		return nil, zaperr.Wrap(err, "failed to get next episode id", zapFields...)
 	}
+	ep.ID = epID
+	ep.CreatedAt = time.Now().UTC()
+	ep.UpdatedAt = ep.CreatedAt
 
 	ep, err = svc.repository.SaveEpisode(ctx, ep)
 	if err != nil {
+		return nil, zaperr.Wrap(err, "failed to save episode", append(zapFields, zap.String("episode_id", epID))...)
+	}
+	return ep, nil
+}
+
+// findEpisodeByContentHash returns episode that is either complete or still being created from the same content,
+// or nil if there is none. Failed and cancelled episodes are not reused, as user creates episode again to retry them
+func (svc *Service) findEpisodeByContentHash(ctx context.Context, userID string, contentHash string) (*Episode, error) {
+	episodes, err := svc.repository.ListEpisodesByContentHash(ctx, userID, contentHash)
+	if err != nil {
+		return nil, zaperr.Wrap(err, "failed to list episodes by content hash")
+	}
+	for _, ep := range episodes {
+		if ep.Status != EpisodeStatusFailed && ep.Status != EpisodeStatusCancelled {
+			return ep, nil
+		}
+	}
+	return nil, nil
+}
+
+// CreateEpisodeFromUpload stores a file user uploaded directly and creates a complete episode out of it.
+// Unlike other episodes, these never go through mediary
+func (svc *Service) CreateEpisodeFromUpload(
+	ctx context.Context,
+	userID string,
+	title string,
+	data io.ReadSeeker,
+	sizeBytes int64,
+	contentType string,
+) (*Episode, error) {
+	zapFields := []zap.Field{
+		zap.String("user_id", userID),
+		zap.String("title", title),
+		zap.Int64("size_bytes", sizeBytes),
+		zap.String("content_type", contentType),
+	}
+
+	format := formatFromMIMEType(contentType)
+	if format == "" {
+		return nil, zaperr.Wrap(ErrUnsupportedFormat, "unsupported uploaded file type", zapFields...)
+	}
+
+	filename := uuid.New().String() + "." + format
+	episodeKey := svc.constructS3EpisodeKey(userID, filename)
+	zapFields = append(zapFields, zap.String("episode_key", episodeKey))
+
+	if err := svc.s3Store.Put(ctx, episodeKey, data, WithContentType(enclosureType(format))); err != nil {
+		return nil, zaperr.Wrap(err, "failed to upload episode file", zapFields...)
+	}
+
+	url, err := svc.s3Store.URL(episodeKey)
+	if err != nil {
+		return nil, zaperr.Wrap(err, "failed to get episode url", zapFields...)
+	}
+
+	epID, err := svc.repository.NextEpisodeID(ctx, userID)
+	if err != nil {
+		return nil, zaperr.Wrap(err, "failed to get next episode id", zapFields...)
+	}
+
+	ep := &Episode{
+		ID:           epID,
+		Title:        title,
+		UserID:       userID,
+		CreatedAt:    time.Now().UTC(),
+		UpdatedAt:    time.Now().UTC(),
+		StorageKey:   episodeKey,
+		URL:          url,
+		Status:       EpisodeStatusComplete,
+		FileLenBytes: sizeBytes,
+		Format:       format,
+	}
+
+	if ep, err = svc.repository.SaveEpisode(ctx, ep); err != nil {
+		if err := svc.s3Store.Delete(ctx, episodeKey); err != nil {
+			zapFields := append(zapFields, zaperr.ToField(err))
+			svc.logger.Error("failed to delete uploaded file of unsaved episode", zapFields...)
+		}
 		return nil, zaperr.Wrap(err, "failed to save episode", zapFields...)
 	}
 
@@ -315,12 +747,53 @@ func (svc *Service) ListUserEpisodes(ctx context.Context, userID string) ([]*Epi
 	}
 }
 
+// ListUserEpisodesPaged returns at most limit user episodes starting from offset, and total number of user episodes
+func (svc *Service) ListUserEpisodesPaged(ctx context.Context, userID string, offset, limit int) ([]*Episode, int, error) {
+	episodes, total, err := svc.repository.ListUserEpisodesPaged(ctx, userID, offset, limit)
+	if err != nil {
+		return nil, 0, zaperr.Wrap(err, "failed to list user episodes page",
+			zap.String("user_id", userID), zap.Int("offset", offset), zap.Int("limit", limit))
+	}
+	return episodes, total, nil
+}
+
+// SearchEpisodes returns user episodes with query in their title or source URL
+func (svc *Service) SearchEpisodes(ctx context.Context, userID, query string) ([]*Episode, error) {
+	episodes, err := svc.repository.SearchEpisodes(ctx, userID, query)
+	if err != nil {
+		return nil, zaperr.Wrap(err, "failed to search episodes", zap.String("user_id", userID), zap.String("query", query))
+	}
+	return episodes, nil
+}
+
+// GetEpisodesMap returns a map of episode ID to episode.
+// If any of requested episodes does not exist, EpisodesNotFoundError listing missing IDs is returned
 func (svc *Service) GetEpisodesMap(ctx context.Context, userID string, ids []string) (map[string]*Episode, error) {
-	if episodes, err := svc.repository.GetEpisodesMap(ctx, userID, ids); err == nil {
-		return episodes, nil
-	} else {
+	episodes, err := svc.repository.GetEpisodesMap(ctx, userID, ids)
+	if err != nil {
 		return nil, zaperr.Wrap(ErrEpisodeNotFound, "failed to get episodes map", zap.Strings("ids", ids), zaperr.ToField(err))
 	}
+
+	var missingIDs []string
+	for _, id := range ids {
+		if _, ok := episodes[id]; !ok {
+			missingIDs = append(missingIDs, id)
+		}
+	}
+	if len(missingIDs) > 0 {
+		return nil, zaperr.Wrap(&EpisodesNotFoundError{IDs: missingIDs}, "some episodes do not exist", zap.Strings("ids", ids))
+	}
+
+	return episodes, nil
+}
+
+// ListUserFeeds lists feeds user already has. Unlike ListFeeds, it never creates default feed
+func (svc *Service) ListUserFeeds(ctx context.Context, userID string) ([]*Feed, error) {
+	if feeds, err := svc.repository.ListUserFeeds(ctx, userID); err == nil {
+		return feeds, nil
+	} else {
+		return nil, zaperr.Wrap(err, "failed to list user feeds", zap.String("user_id", userID))
+	}
 }
 
 func (svc *Service) ListFeeds(ctx context.Context, userID string) ([]*Feed, error) {
@@ -350,7 +823,6 @@ func (svc *Service) ListFeeds(ctx context.Context, userID string) ([]*Feed, erro
 }
 
 func (svc *Service) DefaultFeed(ctx context.Context, userID string) (*Feed, error) {
-
 	existing, err := svc.repository.GetFeed(ctx, userID, DefaultFeedID)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get default feed: %w", err)
@@ -359,6 +831,18 @@ func (svc *Service) DefaultFeed(ctx context.Context, userID string) (*Feed, erro
 		return existing, nil
 	}
 
+	// creation is serialized, so that concurrent callers don't overwrite each other's default feed
+	svc.defaultFeedMu.Lock()
+	defer svc.defaultFeedMu.Unlock()
+
+	existing, err = svc.repository.GetFeed(ctx, userID, DefaultFeedID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get default feed: %w", err)
+	}
+	if existing != nil {
+		return existing, nil
+	}
+
 	created, err := svc.createFeed(ctx, userID, svc.defaultFeedTitle, DefaultFeedID)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create default feed: %w", err)
@@ -371,6 +855,16 @@ func (svc *Service) CreateFeed(ctx context.Context, userID string, title string)
 	return svc.createFeed(ctx, userID, title, "")
 }
 
+// MoveEpisodes removes episodes from every feed they are currently published to
+// and publishes them to given feeds only
+func (svc *Service) MoveEpisodes(ctx context.Context, userID string, episodeIDs []string, feedIDs []string) error {
+	if len(feedIDs) == 0 {
+		return zaperr.New("at least one destination feed is required", zap.Strings("episode_ids", episodeIDs), zap.String("user_id", userID))
+	}
+	// PublishEpisodes already replaces existing publications with the given ones, which is exactly a move
+	return svc.PublishEpisodes(ctx, userID, episodeIDs, feedIDs)
+}
+
 func (svc *Service) PublishEpisodes(ctx context.Context, userID string, episodeIDs []string, feedIDs []string) error {
 	zapFields := []zap.Field{
 		zap.Strings("episode_ids", episodeIDs),
@@ -378,7 +872,7 @@ func (svc *Service) PublishEpisodes(ctx context.Context, userID string, episodeI
 		zap.String("user_id", userID),
 	}
 
-	changedFeedIDs := make([]string, 0, 10)
+	var changedFeedsMap map[string]struct{}
 
 	if err := svc.repository.Transaction(ctx, func(ctx context.Context) error {
 		existing, err := svc.repository.ListPublicationsByEpisodeIDs(ctx, userID, episodeIDs)
@@ -386,7 +880,7 @@ func (svc *Service) PublishEpisodes(ctx context.Context, userID string, episodeI
 			return zaperr.Wrap(err, "failed to list publicationsToCreate by episode ids")
 		}
 
-		changedFeedsMap := make(map[string]struct{}, len(feedIDs))
+		changedFeedsMap = make(map[string]struct{}, len(feedIDs))
 
 		publicationsToDelete := make([]string, 0, len(existing))
 
@@ -432,7 +926,13 @@ func (svc *Service) PublishEpisodes(ctx context.Context, userID string, episodeI
 		return zaperr.Wrap(err, "failed to publish episodes", zapFields...)
 	}
 
-	if err := svc.jobsQueue.Publish(ctx, queueEventRegenerateFeed, RegenerateFeedQueuePayload{
+	if err := svc.enforceFeedsMaxTotalBytes(ctx, userID, feedIDs); err != nil {
+		return zaperr.Wrap(err, "failed to enforce feeds max total size", zapFields...)
+	}
+
+	changedFeedIDs := maps.Keys(changedFeedsMap)
+	slices.Sort(changedFeedIDs)
+	if err := publish(ctx, svc.jobsQueue, queueEventRegenerateFeed, &RegenerateFeedQueuePayload{
 		UserID:  userID,
 		FeedIDs: changedFeedIDs,
 	}); err != nil {
@@ -463,15 +963,37 @@ func (svc *Service) RenameEpisodes(ctx context.Context, userID string, epIDs []s
 		epToFeedMap[p.EpisodeID] = append(epToFeedMap[p.EpisodeID], p.FeedID)
 	}
 
+	// episode published to several feeds gets title of the one user lists first
+	feedTitles := make(map[string]string, len(epToFeedMap))
+	if strings.Contains(newTitlePattern, "%feed") {
+		feeds, err := svc.repository.ListUserFeeds(ctx, userID)
+		if err != nil {
+			return zaperr.Wrap(err, "failed to list feeds", zapFields...)
+		}
+		for _, feed := range feeds {
+			for epID, feedIDs := range epToFeedMap {
+				if !slices.Contains(feedIDs, feed.ID) {
+					continue
+				}
+				if _, ok := feedTitles[epID]; !ok {
+					feedTitles[epID] = feed.Title
+				}
+			}
+		}
+	}
+
 	feedsToUpdate := map[string]bool{}
-	newTitleMap := getUpdatedEpisodeTitle(maps.Values(episodesMap), newTitlePattern)
+	newTitleMap := getUpdatedEpisodeTitle(maps.Values(episodesMap), newTitlePattern, feedTitles)
+	var renamed []*Episode
 	for _, ep := range episodesMap {
-		newTitle := newTitleMap[ep.ID]
+		newTitle, err := sanitizeTitle(newTitleMap[ep.ID])
+		if err != nil {
+			zapFields := append(zapFields, zap.String("episode_id", ep.ID), zap.String("new_title", newTitleMap[ep.ID]))
+			return zaperr.Wrap(err, "failed to rename episode", zapFields...)
+		}
 		if newTitle != ep.Title {
 			ep.Title = newTitle
-			if _, err := svc.repository.SaveEpisode(ctx, ep); err != nil { // TODO: batch save
-				return zaperr.Wrap(err, "failed to save episode", zapFields...)
-			}
+			renamed = append(renamed, ep)
 			if feedIDs, ok := epToFeedMap[ep.ID]; ok {
 				for _, feedID := range feedIDs {
 					feedsToUpdate[feedID] = true
@@ -480,8 +1002,16 @@ func (svc *Service) RenameEpisodes(ctx context.Context, userID string, epIDs []s
 		}
 	}
 
+	if len(renamed) > 0 {
+		if err := svc.repository.Transaction(ctx, func(ctx context.Context) error {
+			return svc.repository.SaveEpisodes(ctx, renamed)
+		}); err != nil {
+			return zaperr.Wrap(err, "failed to save episodes", zapFields...)
+		}
+	}
+
 	if len(feedsToUpdate) > 0 {
-		if err = svc.jobsQueue.Publish(ctx, queueEventRegenerateFeed, RegenerateFeedQueuePayload{
+		if err = publish(ctx, svc.jobsQueue, queueEventRegenerateFeed, &RegenerateFeedQueuePayload{
 			UserID:  userID,
 			FeedIDs: maps.Keys(feedsToUpdate),
 		}); err != nil {
@@ -492,127 +1022,802 @@ func (svc *Service) RenameEpisodes(ctx context.Context, userID string, epIDs []s
 	return nil
 }
 
-func (svc *Service) DeleteEpisodes(ctx context.Context, userID string, epIDs []string) error {
+// SetEpisodePubDate overrides date the episode is published with in feeds,
+// so that back-catalog episodes get sorted by their original dates in podcast clients
+func (svc *Service) SetEpisodePubDate(ctx context.Context, userID string, epID string, pubDate time.Time) error {
 	zapFields := []zap.Field{
-		zap.Strings("episode_ids", epIDs),
+		zap.String("episode_id", epID),
+		zap.Time("pub_date", pubDate),
 		zap.String("user_id", userID),
 	}
 
-	episodesMap, err := svc.GetEpisodesMap(ctx, userID, epIDs)
+	episodesMap, err := svc.GetEpisodesMap(ctx, userID, []string{epID})
 	if err != nil {
-		return err
+		return zaperr.Wrap(err, "failed to get episode", zapFields...)
 	}
 
-	publications, err := svc.repository.ListPublicationsByEpisodeIDs(ctx, userID, epIDs)
-	if err != nil {
-		return zaperr.Wrap(err, "failed to list publications", zapFields...)
+	ep := episodesMap[epID]
+	ep.PubDate = pubDate.UTC()
+	if _, err := svc.repository.SaveEpisode(ctx, ep); err != nil {
+		return zaperr.Wrap(err, "failed to save episode", zapFields...)
 	}
 
-	publicationIDs := make([]string, 0, len(publications))
-	for _, p := range publications {
-		publicationIDs = append(publicationIDs, p.ID)
+	publications, err := svc.repository.ListPublicationsByEpisodeIDs(ctx, userID, []string{epID})
+	if err != nil {
+		return zaperr.Wrap(err, "failed to list publications", zapFields...)
 	}
-
-	if err := svc.repository.DeletePublications(ctx, userID, publicationIDs); err != nil {
-		return zaperr.Wrap(err, "failed to delete publications", zapFields...)
+	if len(publications) == 0 {
+		return nil
 	}
 
-	for _, ep := range episodesMap {
-		if err := svc.s3Store.Delete(ctx, svc.extractEpisodeS3Key(ep)); err != nil {
-			svc.logger.Error("failed to delete episode file", zaperr.ToField(err))
-		}
+	feedIDs := make([]string, 0, len(publications))
+	for _, p := range publications {
+		feedIDs = append(feedIDs, p.FeedID)
 	}
-
-	if err := svc.repository.DeleteEpisodes(ctx, userID, epIDs); err != nil {
-		return zaperr.Wrap(err, "failed to delete episodes", zapFields...)
+	if err := publish(ctx, svc.jobsQueue, queueEventRegenerateFeed, &RegenerateFeedQueuePayload{
+		UserID:  userID,
+		FeedIDs: feedIDs,
+	}); err != nil {
+		return zaperr.Wrap(err, "failed to publish regenerate feed job", zapFields...)
 	}
 
 	return nil
 }
 
-func (svc *Service) GetFeed(ctx context.Context, userID string, feedID string) (*Feed, error) {
-	return svc.repository.GetFeed(ctx, userID, feedID)
-}
-
-func (svc *Service) RenameFeed(ctx context.Context, userID string, feedID string, newTitle string) error {
+// SetEpisodesExplicit marks episodes explicit or clean regardless of feeds they are in,
+// nil makes them follow their feeds again
+func (svc *Service) SetEpisodesExplicit(ctx context.Context, userID string, epIDs []string, explicit *bool) error {
 	zapFields := []zap.Field{
-		zap.String("feed_id", feedID),
+		zap.Strings("episode_ids", epIDs),
+		zap.Boolp("explicit", explicit),
 		zap.String("user_id", userID),
-		zap.String("new_title", newTitle),
 	}
 
-	feed, err := svc.repository.GetFeed(ctx, userID, feedID)
+	episodesMap, err := svc.GetEpisodesMap(ctx, userID, epIDs)
 	if err != nil {
-		zapFields := append(zapFields, zaperr.ToField(err))
-		return zaperr.Wrap(ErrFeedNotFound, "", zapFields...)
+		return zaperr.Wrap(err, "failed to get episodes", zapFields...)
 	}
 
-	feed.Title = newTitle
-	if _, err := svc.repository.SaveFeed(ctx, feed); err != nil {
-		return zaperr.Wrap(err, "failed to save feed", zapFields...)
+	if err := svc.repository.Transaction(ctx, func(ctx context.Context) error {
+		for _, ep := range episodesMap {
+			ep.Explicit = explicit
+			if _, err := svc.repository.SaveEpisode(ctx, ep); err != nil {
+				zapFields := append(zapFields, zap.String("episode_id", ep.ID))
+				return zaperr.Wrap(err, "failed to save episode", zapFields...)
+			}
+		}
+		return nil
+	}); err != nil {
+		return err
 	}
 
-	if err = svc.jobsQueue.Publish(ctx, queueEventRegenerateFeed, RegenerateFeedQueuePayload{
+	publications, err := svc.repository.ListPublicationsByEpisodeIDs(ctx, userID, epIDs)
+	if err != nil {
+		return zaperr.Wrap(err, "failed to list publications", zapFields...)
+	}
+	feedIDs := make(map[string]struct{}, len(publications))
+	for _, p := range publications {
+		feedIDs[p.FeedID] = struct{}{}
+	}
+	if len(feedIDs) == 0 {
+		return nil
+	}
+	if err := publish(ctx, svc.jobsQueue, queueEventRegenerateFeed, &RegenerateFeedQueuePayload{
+		UserID:  userID,
+		FeedIDs: maps.Keys(feedIDs),
+	}); err != nil {
+		return zaperr.Wrap(err, "failed to publish regenerate feed job", zapFields...)
+	}
+
+	return nil
+}
+
+// SetEpisodeTags adds and removes tags of given episodes, keeping the rest of their tags intact
+func (svc *Service) SetEpisodeTags(ctx context.Context, userID string, epIDs []string, addTags []string, removeTags []string) error {
+	zapFields := []zap.Field{
+		zap.Strings("episode_ids", epIDs),
+		zap.Strings("add_tags", addTags),
+		zap.Strings("remove_tags", removeTags),
+		zap.String("user_id", userID),
+	}
+
+	episodesMap, err := svc.GetEpisodesMap(ctx, userID, epIDs)
+	if err != nil {
+		return zaperr.Wrap(err, "failed to get episodes", zapFields...)
+	}
+
+	addTags, removeTags = normalizeTags(addTags), normalizeTags(removeTags)
+	return svc.repository.Transaction(ctx, func(ctx context.Context) error {
+		for _, ep := range episodesMap {
+			tags := make([]string, 0, len(ep.Tags)+len(addTags))
+			for _, t := range normalizeTags(append(ep.Tags, addTags...)) {
+				if !slices.Contains(removeTags, t) {
+					tags = append(tags, t)
+				}
+			}
+			if slices.Equal(tags, ep.Tags) {
+				continue
+			}
+
+			ep.Tags = tags
+			if _, err := svc.repository.SaveEpisode(ctx, ep); err != nil {
+				zapFields := append(zapFields, zap.String("episode_id", ep.ID))
+				return zaperr.Wrap(err, "failed to save episode", zapFields...)
+			}
+		}
+		return nil
+	})
+}
+
+// DeleteEpisodes moves episodes to trash: they disappear from the library and feeds,
+// but their files are kept for DeletedEpisodesRetention, so that they can be restored with RestoreEpisodes
+func (svc *Service) DeleteEpisodes(ctx context.Context, userID string, epIDs []string) error {
+	zapFields := []zap.Field{
+		zap.Strings("episode_ids", epIDs),
+		zap.String("user_id", userID),
+	}
+
+	if err := svc.deletePublicationsOfEpisodes(ctx, userID, epIDs); err != nil {
+		return zaperr.Wrap(err, "failed to delete publications", zapFields...)
+	}
+
+	if err := svc.repository.SoftDeleteEpisodes(ctx, userID, epIDs, time.Now().UTC()); err != nil {
+		return zaperr.Wrap(err, "failed to soft delete episodes", zapFields...)
+	}
+
+	return nil
+}
+
+// RestoreEpisodes brings episodes back from trash into the library. Feeds they were published to are not restored
+func (svc *Service) RestoreEpisodes(ctx context.Context, userID string, epIDs []string) error {
+	zapFields := []zap.Field{
+		zap.Strings("episode_ids", epIDs),
+		zap.String("user_id", userID),
+	}
+
+	if err := svc.repository.RestoreEpisodes(ctx, userID, epIDs); err != nil {
+		return zaperr.Wrap(err, "failed to restore episodes", zapFields...)
+	}
+
+	return nil
+}
+
+// ListDeletedEpisodes lists episodes in user's trash
+func (svc *Service) ListDeletedEpisodes(ctx context.Context, userID string) ([]*Episode, error) {
+	episodes, err := svc.repository.ListUserDeletedEpisodes(ctx, userID)
+	if err != nil {
+		return nil, zaperr.Wrap(err, "failed to list deleted episodes", zap.String("user_id", userID))
+	}
+	return episodes, nil
+}
+
+// PurgeDeletedEpisodes permanently deletes episodes that have been in trash for longer than olderThan
+// and returns how many of them were purged
+func (svc *Service) PurgeDeletedEpisodes(ctx context.Context, olderThan time.Duration) (int, error) {
+	episodes, err := svc.repository.ListDeletedEpisodes(ctx, olderThan)
+	if err != nil {
+		return 0, zaperr.Wrap(err, "failed to list deleted episodes", zap.Duration("older_than", olderThan))
+	}
+
+	epIDsByUser := make(map[string][]string)
+	for _, ep := range episodes {
+		epIDsByUser[ep.UserID] = append(epIDsByUser[ep.UserID], ep.ID)
+	}
+
+	purged := 0
+	for userID, epIDs := range epIDsByUser {
+		if err := svc.purgeEpisodes(ctx, userID, epIDs); err != nil {
+			return purged, zaperr.Wrap(err, "failed to purge episodes", zap.Duration("older_than", olderThan))
+		}
+		purged += len(epIDs)
+	}
+
+	return purged, nil
+}
+
+func (svc *Service) deletePublicationsOfEpisodes(ctx context.Context, userID string, epIDs []string) error {
+	publications, err := svc.repository.ListPublicationsByEpisodeIDs(ctx, userID, epIDs)
+	if err != nil {
+		return zaperr.Wrap(err, "failed to list publications")
+	}
+
+	publicationIDs := make([]string, 0, len(publications))
+	for _, p := range publications {
+		publicationIDs = append(publicationIDs, p.ID)
+	}
+
+	return svc.repository.DeletePublications(ctx, userID, publicationIDs)
+}
+
+// purgeEpisodes deletes episodes together with their files right away, bypassing trash
+func (svc *Service) purgeEpisodes(ctx context.Context, userID string, epIDs []string) error {
+	zapFields := []zap.Field{
+		zap.Strings("episode_ids", epIDs),
+		zap.String("user_id", userID),
+	}
+
+	// deleting episodes that do not exist is fine, so we don't insist on all of them being found.
+	// Episodes in trash are not returned by GetEpisodesMap, so they are looked up separately
+	episodesMap, err := svc.repository.GetEpisodesMap(ctx, userID, epIDs)
+	if err != nil {
+		return zaperr.Wrap(err, "failed to get episodes map", zapFields...)
+	}
+	deletedEpisodes, err := svc.repository.ListUserDeletedEpisodes(ctx, userID)
+	if err != nil {
+		return zaperr.Wrap(err, "failed to list deleted episodes", zapFields...)
+	}
+	for _, ep := range deletedEpisodes {
+		if slices.Contains(epIDs, ep.ID) {
+			episodesMap[ep.ID] = ep
+		}
+	}
+
+	if err := svc.deletePublicationsOfEpisodes(ctx, userID, epIDs); err != nil {
+		return zaperr.Wrap(err, "failed to delete publications", zapFields...)
+	}
+
+	svc.deleteEpisodeFiles(ctx, maps.Values(episodesMap))
+
+	if err := svc.repository.DeleteEpisodes(ctx, userID, epIDs); err != nil {
+		return zaperr.Wrap(err, "failed to delete episodes", zapFields...)
+	}
+
+	return nil
+}
+
+// deleteEpisodeFiles deletes files of episodes from S3, failures are only logged since there is nothing to retry with
+func (svc *Service) deleteEpisodeFiles(ctx context.Context, episodes []*Episode) {
+	for _, ep := range episodes {
+		key := svc.extractEpisodeS3Key(ep)
+		if key == "" {
+			continue // episode is hosted elsewhere, e.g. it was imported from an existing RSS feed
+		}
+		if err := svc.s3Store.Delete(ctx, key); err != nil {
+			svc.logger.Error("failed to delete episode file", zaperr.ToField(err))
+		}
+		if ep.ChaptersURL != "" {
+			if err := svc.s3Store.Delete(ctx, constructS3ChaptersKey(key)); err != nil {
+				svc.logger.Error("failed to delete episode chapters", zaperr.ToField(err))
+			}
+		}
+	}
+}
+
+// SplitEpisode is the inverse of concatenation: it replaces the original episode with one episode per its source file,
+// published to the same feeds the original was in
+func (svc *Service) SplitEpisode(ctx context.Context, userID string, epID string) ([]*Episode, error) {
+	zapFields := []zap.Field{
+		zap.String("episode_id", epID),
+		zap.String("user_id", userID),
+	}
+
+	episodesMap, err := svc.repository.GetEpisodesMap(ctx, userID, []string{epID})
+	if err != nil {
+		return nil, zaperr.Wrap(err, "failed to get episode", zapFields...)
+	}
+	original, ok := episodesMap[epID]
+	if !ok {
+		return nil, zaperr.Wrap(ErrEpisodeNotFound, "failed to get episode", zapFields...)
+	}
+	if len(original.SourceFilepaths) < 2 {
+		zapFields := append(zapFields, zap.Strings("source_filepaths", original.SourceFilepaths))
+		return nil, zaperr.Wrap(ErrNothingToSplit, "failed to split episode", zapFields...)
+	}
+
+	publications, err := svc.repository.ListPublicationsByEpisodeIDs(ctx, userID, []string{epID})
+	if err != nil {
+		return nil, zaperr.Wrap(err, "failed to list publications", zapFields...)
+	}
+
+	metadata, err := svc.FetchMetadata(ctx, original.SourceURL)
+	if err != nil {
+		return nil, zaperr.Wrap(err, "failed to fetch metadata", zapFields...)
+	}
+
+	created := make([]*Episode, 0, len(original.SourceFilepaths))
+	for _, filepath := range original.SourceFilepaths {
+		ep, err := svc.CreateEpisode(ctx, userID, original.SourceURL, []string{filepath}, ProcessingTypeUploadOriginal, original.Tags, EpisodeOptions{}, metadata)
+		if err != nil {
+			zapFields := append(zapFields, zap.String("filepath", filepath))
+			return nil, zaperr.Wrap(err, "failed to create episode", zapFields...)
+		}
+		created = append(created, ep)
+	}
+
+	episodeIDs := make([]string, len(created))
+	for i, ep := range created {
+		episodeIDs[i] = ep.ID
+	}
+	zapFields = append(zapFields, zap.Strings("created_episode_ids", episodeIDs))
+
+	if len(publications) > 0 {
+		feedIDs := make([]string, 0, len(publications))
+		for _, p := range publications {
+			feedIDs = append(feedIDs, p.FeedID)
+		}
+		if err := svc.PublishEpisodes(ctx, userID, episodeIDs, feedIDs); err != nil {
+			return nil, zaperr.Wrap(err, "failed to publish split episodes", zapFields...)
+		}
+	}
+
+	if err := publish(ctx, svc.jobsQueue, queueEventPollEpisodesStatus, &PollEpisodesStatusQueuePayload{
+		EpisodeIDs: episodeIDs,
+		UserID:     userID,
+	}); err != nil {
+		return nil, zaperr.Wrap(err, "failed to enqueue episode status polling", zapFields...)
+	}
+
+	if err := svc.purgeEpisodes(ctx, userID, []string{epID}); err != nil {
+		return nil, zaperr.Wrap(err, "failed to delete original episode", zapFields...)
+	}
+
+	return created, nil
+}
+
+// MergeEpisodes is the inverse of splitting: it replaces episodes of the same source with a single one
+// concatenated from their source files in given order. Empty title keeps generated one
+func (svc *Service) MergeEpisodes(ctx context.Context, userID string, epIDs []string, title string) (*Episode, error) {
+	zapFields := []zap.Field{
+		zap.Strings("episode_ids", epIDs),
+		zap.String("user_id", userID),
+		zap.String("title", title),
+	}
+
+	if len(epIDs) < 2 {
+		return nil, zaperr.Wrap(ErrNothingToMerge, "failed to merge episodes", zapFields...)
+	}
+
+	episodesMap, err := svc.GetEpisodesMap(ctx, userID, epIDs)
+	if err != nil {
+		return nil, zaperr.Wrap(err, "failed to get episodes", zapFields...)
+	}
+
+	sourceURL := episodesMap[epIDs[0]].SourceURL
+	var filepaths, tags []string
+	for _, epID := range epIDs {
+		ep := episodesMap[epID]
+		if ep.SourceURL != sourceURL {
+			zapFields := append(zapFields, zap.String("source_url", sourceURL), zap.String("other_source_url", ep.SourceURL))
+			return nil, zaperr.Wrap(ErrSourcesDiffer, "failed to merge episodes", zapFields...)
+		}
+		filepaths = append(filepaths, ep.SourceFilepaths...)
+		tags = append(tags, ep.Tags...)
+	}
+	zapFields = append(zapFields, zap.String("source_url", sourceURL), zap.Strings("filepaths", filepaths))
+
+	publications, err := svc.repository.ListPublicationsByEpisodeIDs(ctx, userID, epIDs)
+	if err != nil {
+		return nil, zaperr.Wrap(err, "failed to list publications", zapFields...)
+	}
+
+	merged, err := svc.CreateEpisode(ctx, userID, sourceURL, filepaths, ProcessingTypeConcatenate, tags, EpisodeOptions{}, nil)
+	if err != nil {
+		return nil, zaperr.Wrap(err, "failed to create episode", zapFields...)
+	}
+	zapFields = append(zapFields, zap.String("merged_episode_id", merged.ID))
+
+	if title != "" {
+		merged.Title = title
+		if merged, err = svc.repository.SaveEpisode(ctx, merged); err != nil {
+			return nil, zaperr.Wrap(err, "failed to save episode", zapFields...)
+		}
+	}
+
+	if len(publications) > 0 {
+		feedIDs := make(map[string]struct{}, len(publications))
+		for _, p := range publications {
+			feedIDs[p.FeedID] = struct{}{}
+		}
+		if err := svc.PublishEpisodes(ctx, userID, []string{merged.ID}, maps.Keys(feedIDs)); err != nil {
+			return nil, zaperr.Wrap(err, "failed to publish merged episode", zapFields...)
+		}
+	}
+
+	if err := publish(ctx, svc.jobsQueue, queueEventPollEpisodesStatus, &PollEpisodesStatusQueuePayload{
+		EpisodeIDs: []string{merged.ID},
+		UserID:     userID,
+	}); err != nil {
+		return nil, zaperr.Wrap(err, "failed to enqueue episode status polling", zapFields...)
+	}
+
+	if err := svc.purgeEpisodes(ctx, userID, epIDs); err != nil {
+		return nil, zaperr.Wrap(err, "failed to delete original episodes", zapFields...)
+	}
+
+	return merged, nil
+}
+
+func (svc *Service) GetFeed(ctx context.Context, userID string, feedID string) (*Feed, error) {
+	return svc.repository.GetFeed(ctx, userID, feedID)
+}
+
+// GetFeedFile returns contents of the feed file as it is stored in S3
+func (svc *Service) GetFeedFile(ctx context.Context, userID string, feedID string) ([]byte, error) {
+	zapFields := []zap.Field{
+		zap.String("feed_id", feedID),
+		zap.String("user_id", userID),
+	}
+
+	feed, err := svc.repository.GetFeed(ctx, userID, feedID)
+	if err != nil || feed == nil {
+		zapFields := append(zapFields, zaperr.ToField(err))
+		return nil, zaperr.Wrap(ErrFeedNotFound, "", zapFields...)
+	}
+
+	data, err := svc.s3Store.Get(ctx, svc.constructS3FeedKey(userID, feedID, feed.AccessToken))
+	if err != nil {
+		return nil, zaperr.Wrap(err, "failed to get feed file", zapFields...)
+	}
+
+	// feed might have been stored compressed, while whoever asks for a file wants plain XML
+	if data, err = gunzipFeed(data); err != nil {
+		return nil, zaperr.Wrap(err, "failed to decompress feed file", zapFields...)
+	}
+
+	return data, nil
+}
+
+func (svc *Service) RenameFeed(ctx context.Context, userID string, feedID string, newTitle string) error {
+	zapFields := []zap.Field{
+		zap.String("feed_id", feedID),
+		zap.String("user_id", userID),
+		zap.String("new_title", newTitle),
+	}
+
+	newTitle, err := sanitizeTitle(newTitle)
+	if err != nil {
+		return zaperr.Wrap(err, "failed to rename feed", zapFields...)
+	}
+
+	feed, err := svc.repository.GetFeed(ctx, userID, feedID)
+	if err != nil {
+		zapFields := append(zapFields, zaperr.ToField(err))
+		return zaperr.Wrap(ErrFeedNotFound, "", zapFields...)
+	}
+
+	feed.Title = newTitle
+	if _, err := svc.repository.SaveFeed(ctx, feed); err != nil {
+		return zaperr.Wrap(err, "failed to save feed", zapFields...)
+	}
+
+	if err = publish(ctx, svc.jobsQueue, queueEventRegenerateFeed, &RegenerateFeedQueuePayload{
+		UserID:  userID,
+		FeedIDs: []string{feedID},
+	}); err != nil {
+		return zaperr.Wrap(err, "failed to publish regenerate feed job", zapFields...)
+	}
+
+	return nil
+}
+
+// UpdateFeedMetadata sets description and author of the feed, empty values fall back to feed title in the feed file
+func (svc *Service) UpdateFeedMetadata(ctx context.Context, userID string, feedID string, description string, author string) error {
+	zapFields := []zap.Field{
+		zap.String("feed_id", feedID),
+		zap.String("user_id", userID),
+		zap.String("description", description),
+		zap.String("author", author),
+	}
+
+	feed, err := svc.repository.GetFeed(ctx, userID, feedID)
+	if err != nil {
+		return zaperr.Wrap(err, "failed to get feed", zapFields...)
+	}
+	if feed == nil {
+		return zaperr.Wrap(ErrFeedNotFound, "", zapFields...)
+	}
+
+	feed.Description = strings.TrimSpace(description)
+	feed.Author = strings.TrimSpace(author)
+	if _, err := svc.repository.SaveFeed(ctx, feed); err != nil {
+		return zaperr.Wrap(err, "failed to save feed", zapFields...)
+	}
+
+	if err = publish(ctx, svc.jobsQueue, queueEventRegenerateFeed, &RegenerateFeedQueuePayload{
+		UserID:  userID,
+		FeedIDs: []string{feedID},
+	}); err != nil {
+		return zaperr.Wrap(err, "failed to publish regenerate feed job", zapFields...)
+	}
+
+	return nil
+}
+
+func (svc *Service) SetFeedCopyright(ctx context.Context, userID string, feedID string, copyright string) error {
+	zapFields := []zap.Field{
+		zap.String("feed_id", feedID),
+		zap.String("user_id", userID),
+		zap.String("copyright", copyright),
+	}
+
+	feed, err := svc.repository.GetFeed(ctx, userID, feedID)
+	if err != nil || feed == nil {
+		zapFields := append(zapFields, zaperr.ToField(err))
+		return zaperr.Wrap(ErrFeedNotFound, "", zapFields...)
+	}
+
+	feed.Copyright = copyright
+	if _, err := svc.repository.SaveFeed(ctx, feed); err != nil {
+		return zaperr.Wrap(err, "failed to save feed", zapFields...)
+	}
+
+	if err = publish(ctx, svc.jobsQueue, queueEventRegenerateFeed, &RegenerateFeedQueuePayload{
+		UserID:  userID,
+		FeedIDs: []string{feedID},
+	}); err != nil {
+		return zaperr.Wrap(err, "failed to publish regenerate feed job", zapFields...)
+	}
+
+	return nil
+}
+
+// SetFeedImage uploads feed cover image to S3 and makes feed point to it
+func (svc *Service) SetFeedImage(ctx context.Context, userID string, feedID string, data io.ReadSeeker, contentType string) error {
+	zapFields := []zap.Field{
+		zap.String("feed_id", feedID),
+		zap.String("user_id", userID),
+		zap.String("content_type", contentType),
+	}
+
+	feed, err := svc.repository.GetFeed(ctx, userID, feedID)
+	if err != nil || feed == nil {
+		zapFields := append(zapFields, zaperr.ToField(err))
+		return zaperr.Wrap(ErrFeedNotFound, "", zapFields...)
+	}
+
+	imageKey := svc.constructS3FeedImageKey(userID, feedID)
+	if err := svc.s3Store.Put(ctx, imageKey, data, WithContentType(contentType)); err != nil {
+		return zaperr.Wrap(err, "failed to upload feed image", zapFields...)
+	}
+
+	imageURL, err := svc.s3Store.URL(imageKey)
+	if err != nil {
+		return zaperr.Wrap(err, "failed to get feed image url", zapFields...)
+	}
+
+	feed.ImageURL = imageURL
+	if _, err := svc.repository.SaveFeed(ctx, feed); err != nil {
+		return zaperr.Wrap(err, "failed to save feed", zapFields...)
+	}
+
+	if err = publish(ctx, svc.jobsQueue, queueEventRegenerateFeed, &RegenerateFeedQueuePayload{
 		UserID:  userID,
 		FeedIDs: []string{feedID},
 	}); err != nil {
 		return zaperr.Wrap(err, "failed to publish regenerate feed job", zapFields...)
 	}
 
-	return nil
+	return nil
+}
+
+func (svc *Service) DeleteFeed(ctx context.Context, userID string, feedID string, deleteEpisodes bool) error {
+	zapFields := []zap.Field{
+		zap.String("feed_id", feedID),
+		zap.String("user_id", userID),
+		zap.Bool("delete_episodes", deleteEpisodes),
+	}
+
+	feed, err := svc.repository.GetFeed(ctx, userID, feedID)
+	if err != nil || feed == nil {
+		return zaperr.Wrap(err, "failed to find feed", zapFields...)
+	}
+
+	episodes, err := svc.repository.ListFeedEpisodesJoined(ctx, feed.UserID, feed.ID)
+	if err != nil {
+		return zaperr.Wrap(err, "failed to list feed episodes", zapFields...)
+	}
+
+	var epIDs []string
+	for _, ep := range episodes {
+		epIDs = append(epIDs, ep.ID)
+	}
+
+	publications, err := svc.repository.ListPublicationsByEpisodeIDs(ctx, userID, epIDs)
+	if err != nil {
+		return zaperr.Wrap(err, "failed to list publications", zapFields...)
+	}
+	var publicationToDeleteIDs []string
+	for _, p := range publications {
+		if p.FeedID == feedID {
+			publicationToDeleteIDs = append(publicationToDeleteIDs, p.ID)
+		}
+	}
+	if err := svc.repository.DeletePublications(ctx, userID, publicationToDeleteIDs); err != nil {
+		return zaperr.Wrap(err, "failed to delete publications", zapFields...)
+	}
+
+	// episodes go to trash like any other deleted ones, only purge removes them for good
+	if deleteEpisodes {
+		if err := svc.DeleteEpisodes(ctx, userID, epIDs); err != nil {
+			return zaperr.Wrap(err, "failed to delete episodes", zapFields...)
+		}
+	}
+
+	if err := svc.s3Store.Delete(ctx, svc.constructS3FeedKey(userID, feedID, feed.AccessToken)); err != nil {
+		return zaperr.Wrap(err, "failed to delete feed from s3", zapFields...)
+	}
+
+	if err := svc.repository.DeleteFeed(ctx, userID, feedID); err != nil {
+		return zaperr.Wrap(err, "failed to delete feed", zapFields...)
+	}
+
+	return nil
+}
+
+// SetFeedNormalize sets whether concatenated episodes created for the feed should have their loudness normalized
+func (svc *Service) SetFeedNormalize(ctx context.Context, userID string, feedID string, normalize bool) error {
+	feed, err := svc.repository.GetFeed(ctx, userID, feedID)
+	if err != nil {
+		return zaperr.Wrap(err, "failed to get feed")
+	}
+	if feed == nil {
+		return zaperr.Wrap(ErrFeedNotFound, "", zap.String("feed_id", feedID), zap.String("user_id", userID))
+	}
+
+	feed.Normalize = normalize
+
+	if _, err := svc.repository.SaveFeed(ctx, feed); err != nil {
+		return zaperr.Wrap(err, "failed to save feed")
+	}
+
+	return nil
+}
+
+// SetFeedMaxTotalBytes sets the cap of total size of feed episodes, zero removes the cap
+func (svc *Service) SetFeedMaxTotalBytes(ctx context.Context, userID string, feedID string, maxTotalBytes int64) error {
+	zapFields := []zap.Field{
+		zap.String("feed_id", feedID),
+		zap.String("user_id", userID),
+		zap.Int64("max_total_bytes", maxTotalBytes),
+	}
+
+	if maxTotalBytes < 0 {
+		return zaperr.New("max total bytes can not be negative", zapFields...)
+	}
+
+	feed, err := svc.repository.GetFeed(ctx, userID, feedID)
+	if err != nil {
+		return zaperr.Wrap(err, "failed to get feed", zapFields...)
+	}
+	if feed == nil {
+		return zaperr.Wrap(ErrFeedNotFound, "", zapFields...)
+	}
+
+	feed.MaxTotalBytes = maxTotalBytes
+
+	if _, err := svc.repository.SaveFeed(ctx, feed); err != nil {
+		return zaperr.Wrap(err, "failed to save feed", zapFields...)
+	}
+
+	if err := svc.enforceFeedsMaxTotalBytes(ctx, userID, []string{feedID}); err != nil {
+		return zaperr.Wrap(err, "failed to enforce feed max total size", zapFields...)
+	}
+
+	return svc.RegenerateFeed(ctx, userID, feedID)
 }
 
-func (svc *Service) DeleteFeed(ctx context.Context, userID string, feedID string, deleteEpisodes bool) error {
+// SetFeedMaxEpisodes sets the cap of number of feed episodes, zero removes the cap
+// SetFeedSchedule sets feed's TTL and hours and days aggregators should skip polling it at
+func (svc *Service) SetFeedSchedule(ctx context.Context, userID string, feedID string, ttlMinutes int, skipHours []int, skipDays []time.Weekday) error {
 	zapFields := []zap.Field{
 		zap.String("feed_id", feedID),
 		zap.String("user_id", userID),
-		zap.Bool("delete_episodes", deleteEpisodes),
+		zap.Int("ttl_minutes", ttlMinutes),
+		zap.Ints("skip_hours", skipHours),
+		zap.Any("skip_days", skipDays),
 	}
 
-	feed, err := svc.repository.GetFeed(ctx, userID, feedID)
-	if err != nil || feed == nil {
-		return zaperr.Wrap(err, "failed to find feed", zapFields...)
+	if ttlMinutes < 0 {
+		return zaperr.Wrap(ErrInvalidSchedule, "ttl can not be negative", zapFields...)
+	}
+	for _, h := range skipHours {
+		if h < 0 || h > 23 {
+			return zaperr.Wrap(ErrInvalidSchedule, "skip hour should be from 0 to 23", zapFields...)
+		}
+	}
+	for _, d := range skipDays {
+		if d < time.Sunday || d > time.Saturday {
+			return zaperr.Wrap(ErrInvalidSchedule, "unknown skip day", zapFields...)
+		}
 	}
 
-	episodes, err := svc.repository.ListFeedEpisodes(ctx, feed.UserID, feed.ID)
+	feed, err := svc.repository.GetFeed(ctx, userID, feedID)
 	if err != nil {
-		return zaperr.Wrap(err, "failed to list feed episodes", zapFields...)
+		return zaperr.Wrap(err, "failed to get feed", zapFields...)
+	}
+	if feed == nil {
+		return zaperr.Wrap(ErrFeedNotFound, "", zapFields...)
 	}
 
-	var epIDs []string
-	for _, ep := range episodes {
-		epIDs = append(epIDs, ep.ID)
+	// RSS forbids repeating skip hours and days
+	feed.TTLMinutes = ttlMinutes
+	feed.SkipHours = slices.Clone(skipHours)
+	slices.Sort(feed.SkipHours)
+	feed.SkipHours = slices.Compact(feed.SkipHours)
+	feed.SkipDays = slices.Clone(skipDays)
+	slices.Sort(feed.SkipDays)
+	feed.SkipDays = slices.Compact(feed.SkipDays)
+
+	if _, err := svc.repository.SaveFeed(ctx, feed); err != nil {
+		return zaperr.Wrap(err, "failed to save feed", zapFields...)
 	}
 
-	publications, err := svc.repository.ListPublicationsByEpisodeIDs(ctx, userID, epIDs)
+	return svc.RegenerateFeed(ctx, userID, feedID)
+}
+
+func (svc *Service) SetFeedMaxEpisodes(ctx context.Context, userID string, feedID string, maxEpisodes int) error {
+	zapFields := []zap.Field{
+		zap.String("feed_id", feedID),
+		zap.String("user_id", userID),
+		zap.Int("max_episodes", maxEpisodes),
+	}
+
+	if maxEpisodes < 0 {
+		return zaperr.New("max episodes can not be negative", zapFields...)
+	}
+
+	feed, err := svc.repository.GetFeed(ctx, userID, feedID)
 	if err != nil {
-		return zaperr.Wrap(err, "failed to list publications", zapFields...)
+		return zaperr.Wrap(err, "failed to get feed", zapFields...)
 	}
-	var publicationToDeleteIDs []string
-	for _, p := range publications {
-		if p.FeedID == feedID {
-			publicationToDeleteIDs = append(publicationToDeleteIDs, p.ID)
-		}
+	if feed == nil {
+		return zaperr.Wrap(ErrFeedNotFound, "", zapFields...)
 	}
-	if err := svc.repository.DeletePublications(ctx, userID, publicationToDeleteIDs); err != nil {
-		return zaperr.Wrap(err, "failed to delete publications", zapFields...)
+
+	feed.MaxEpisodes = maxEpisodes
+
+	if _, err := svc.repository.SaveFeed(ctx, feed); err != nil {
+		return zaperr.Wrap(err, "failed to save feed", zapFields...)
 	}
 
-	if deleteEpisodes {
-		if err := svc.DeleteEpisodes(ctx, userID, epIDs); err != nil {
-			return zaperr.Wrap(err, "failed to delete episodes", zapFields...)
-		}
+	// excess episodes are removed from the feed on regeneration
+	return svc.RegenerateFeed(ctx, userID, feedID)
+}
+
+// ReorderFeeds puts given feeds first in the listing, in given order.
+// Rest of the feeds keep their relative order after them
+func (svc *Service) ReorderFeeds(ctx context.Context, userID string, feedIDs []string) error {
+	zapFields := []zap.Field{
+		zap.String("user_id", userID),
+		zap.Strings("feed_ids", feedIDs),
 	}
 
-	if err := svc.s3Store.Delete(ctx, svc.constructS3FeedKey(userID, feedID)); err != nil {
-		return zaperr.Wrap(err, "failed to delete feed from s3", zapFields...)
+	feeds, err := svc.ListFeeds(ctx, userID)
+	if err != nil {
+		return zaperr.Wrap(err, "failed to list feeds", zapFields...)
 	}
 
-	if err := svc.repository.DeleteFeed(ctx, userID, feedID); err != nil {
-		return zaperr.Wrap(err, "failed to delete feed", zapFields...)
+	feedsMap := make(map[string]*Feed, len(feeds))
+	for _, f := range feeds {
+		feedsMap[f.ID] = f
+	}
+	ordered := make([]*Feed, 0, len(feeds))
+	for _, feedID := range feedIDs {
+		f, ok := feedsMap[feedID]
+		if !ok {
+			zapFields := append(zapFields, zap.String("feed_id", feedID))
+			return zaperr.Wrap(ErrFeedNotFound, "failed to reorder feeds", zapFields...)
+		}
+		if !slices.Contains(ordered, f) {
+			ordered = append(ordered, f)
+		}
+	}
+	for _, f := range feeds {
+		if !slices.Contains(ordered, f) {
+			ordered = append(ordered, f)
+		}
 	}
 
-	return nil
+	return svc.repository.Transaction(ctx, func(ctx context.Context) error {
+		for i, f := range ordered {
+			f.SortOrder = i + 1
+			if _, err := svc.repository.SaveFeed(ctx, f); err != nil {
+				zapFields := append(zapFields, zap.String("feed_id", f.ID))
+				return zaperr.Wrap(err, "failed to save feed", zapFields...)
+			}
+		}
+		return nil
+	})
 }
 
 func (svc *Service) MarkFeedAsPermanent(ctx context.Context, userID string, feedID string) error {
@@ -630,23 +1835,51 @@ func (svc *Service) MarkFeedAsPermanent(ctx context.Context, userID string, feed
 	return nil
 }
 
-func (svc *Service) MarkFeedAsEphemeral(ctx context.Context, userID string, feedID string) error {
-	feed, err := svc.repository.GetFeed(ctx, userID, feedID)
-	if err != nil {
-		return zaperr.Wrap(err, "failed to get feed")
+// MarkFeedAsEphemeral makes feed episodes subject to auto-deletion.
+// With pinExisting, episodes feed already has are pinned, so that only future ones expire
+func (svc *Service) MarkFeedAsEphemeral(ctx context.Context, userID string, feedID string, pinExisting bool) error {
+	zapFields := []zap.Field{
+		zap.String("user_id", userID),
+		zap.String("feed_id", feedID),
 	}
 
-	feed.IsPermanent = false
+	return svc.repository.Transaction(ctx, func(ctx context.Context) error {
+		feed, err := svc.repository.GetFeed(ctx, userID, feedID)
+		if err != nil {
+			return zaperr.Wrap(err, "failed to get feed", zapFields...)
+		}
 
-	if _, err := svc.repository.SaveFeed(ctx, feed); err != nil {
-		return zaperr.Wrap(err, "failed to save feed")
-	}
+		feed.IsPermanent = false
 
-	return nil
+		if _, err := svc.repository.SaveFeed(ctx, feed); err != nil {
+			return zaperr.Wrap(err, "failed to save feed", zapFields...)
+		}
+
+		if !pinExisting {
+			return nil
+		}
+
+		episodes, err := svc.repository.ListFeedEpisodes(ctx, userID, feedID)
+		if err != nil {
+			return zaperr.Wrap(err, "failed to list feed episodes", zapFields...)
+		}
+		for _, ep := range episodes {
+			if ep.Pinned {
+				continue
+			}
+			ep.Pinned = true
+			if _, err := svc.repository.SaveEpisode(ctx, ep); err != nil {
+				zapFields := append(zapFields, zap.String("episode_id", ep.ID))
+				return zaperr.Wrap(err, "failed to pin episode", zapFields...)
+			}
+		}
+
+		return nil
+	})
 }
 
 func (svc *Service) ListFeedEpisodes(ctx context.Context, userID string, feedID string) ([]*Episode, error) {
-	return svc.repository.ListFeedEpisodes(ctx, userID, feedID)
+	return svc.repository.ListFeedEpisodesJoined(ctx, userID, feedID)
 }
 
 func (svc *Service) ListEpisodeFeeds(ctx context.Context, userID string, epID string) ([]*Feed, error) {
@@ -689,23 +1922,360 @@ func (svc *Service) GetPublishedFeedsMap(ctx context.Context, userID string, epI
 	return epToFeedMap, nil
 }
 
-func (svc *Service) ListExpiredEpisodes(ctx context.Context, maxAge time.Duration) ([]*Episode, error) {
-	return svc.repository.ListExpiredEpisodes(ctx, maxAge)
-}
+// CountFeedsEpisodesByStatus returns a map of feed ID to number of its episodes in each status
+func (svc *Service) CountFeedsEpisodesByStatus(ctx context.Context, userID string, feedIDs []string) (map[string]map[EpisodeStatus]int, error) {
+	if counts, err := svc.repository.CountFeedsEpisodesByStatus(ctx, userID, feedIDs); err == nil {
+		return counts, nil
+	} else {
+		return nil, zaperr.Wrap(err, "failed to count feeds episodes by status", zap.String("user_id", userID), zap.Strings("feed_ids", feedIDs))
+	}
+}
+
+// UserStats returns summary of user's library: how many feeds and episodes they have and how much space episodes take
+func (svc *Service) UserStats(ctx context.Context, userID string) (*UserStats, error) {
+	if stats, err := svc.repository.GetUserStats(ctx, userID); err == nil {
+		return stats, nil
+	} else {
+		return nil, zaperr.Wrap(err, "failed to get user stats", zap.String("user_id", userID))
+	}
+}
+
+// CountEpisodesByStatus counts episodes of all users, grouped by status
+func (svc *Service) CountEpisodesByStatus(ctx context.Context) (map[EpisodeStatus]int, error) {
+	if counts, err := svc.repository.CountEpisodesByStatus(ctx); err == nil {
+		return counts, nil
+	} else {
+		return nil, zaperr.Wrap(err, "failed to count episodes by status")
+	}
+}
+
+// FeedRegenerationsCount returns how many times feed files were uploaded since start.
+// Regenerations that found the feed unchanged are not counted
+func (svc *Service) FeedRegenerationsCount() int64 {
+	return svc.feedRegenerations.Load()
+}
+
+// ListExpiredEpisodes lists episodes that outlived their user's retention period, or defaultMaxAge if user has not set one
+func (svc *Service) ListExpiredEpisodes(ctx context.Context, defaultMaxAge time.Duration) ([]*Episode, error) {
+	return svc.repository.ListExpiredEpisodes(ctx, defaultMaxAge)
+}
+
+// Retention returns how many days user's episodes are kept before they expire, zero means default
+func (svc *Service) Retention(ctx context.Context, userID string) (int, error) {
+	if days, err := svc.repository.GetUserRetentionDays(ctx, userID); err == nil {
+		return days, nil
+	} else {
+		return 0, zaperr.Wrap(err, "failed to get retention", zap.String("user_id", userID))
+	}
+}
+
+// SetRetention sets how many days user's episodes are kept before they expire, zero brings back default.
+// Episodes in permanent feeds and pinned ones never expire regardless
+func (svc *Service) SetRetention(ctx context.Context, userID string, days int) error {
+	zapFields := []zap.Field{
+		zap.String("user_id", userID),
+		zap.Int("days", days),
+	}
+
+	if days < 0 {
+		return zaperr.New("retention can not be negative", zapFields...)
+	}
+
+	if err := svc.repository.SetUserRetentionDays(ctx, userID, days); err != nil {
+		return zaperr.Wrap(err, "failed to set retention", zapFields...)
+	}
+	return nil
+}
+
+// ListStuckEpisodes lists episodes of all users that have been sitting in one of given statuses for longer than olderThan
+func (svc *Service) ListStuckEpisodes(ctx context.Context, olderThan time.Duration, statuses []EpisodeStatus) ([]*Episode, error) {
+	if episodes, err := svc.repository.ListStuckEpisodes(ctx, olderThan, statuses); err == nil {
+		return episodes, nil
+	} else {
+		return nil, zaperr.Wrap(err, "failed to list stuck episodes", zap.Duration("older_than", olderThan), zap.Any("statuses", statuses))
+	}
+}
+
+// RetryEpisodes re-creates mediary jobs for given episodes and starts polling them again.
+// It returns the number of episodes that were retried.
+func (svc *Service) RetryEpisodes(ctx context.Context, userID string, epIDs []string) (int, error) {
+	return svc.recreateEpisodes(ctx, userID, epIDs, false)
+}
+
+// RedownloadEpisodes is RetryEpisodes for complete episodes, e.g. the ones whose files went missing from storage
+func (svc *Service) RedownloadEpisodes(ctx context.Context, userID string, epIDs []string) (int, error) {
+	return svc.recreateEpisodes(ctx, userID, epIDs, true)
+}
+
+func (svc *Service) recreateEpisodes(ctx context.Context, userID string, epIDs []string, includeComplete bool) (int, error) {
+	zapFields := []zap.Field{
+		zap.Strings("episode_ids", epIDs),
+		zap.String("user_id", userID),
+	}
+
+	episodesMap, err := svc.repository.GetEpisodesMap(ctx, userID, epIDs)
+	if err != nil {
+		return 0, zaperr.Wrap(err, "failed to get episodes", zapFields...)
+	}
+
+	epFeedsMap, err := svc.GetPublishedFeedsMap(ctx, userID, epIDs)
+	if err != nil {
+		return 0, zaperr.Wrap(err, "failed to get episodes feeds", zapFields...)
+	}
+
+	retriedIDs := make([]string, 0, len(episodesMap))
+	for _, epID := range epIDs {
+		ep, ok := episodesMap[epID]
+		if !ok || (ep.Status == EpisodeStatusComplete && !includeComplete) {
+			continue
+		}
+		zapFields := append(zapFields, zap.String("episode_id", ep.ID))
+
+		presignURL, err := svc.s3Store.PreSignedURL(svc.extractEpisodeS3Key(ep))
+		if err != nil {
+			return len(retriedIDs), zaperr.Wrap(err, "failed to get presigned url", zapFields...)
+		}
+
+		// we don't store processing type, but glued episodes are the only ones having several source files
+		processingType := ProcessingTypeUploadOriginal
+		if len(ep.SourceFilepaths) > 1 {
+			processingType = ProcessingTypeConcatenate
+		}
+
+		normalize, err := svc.shouldNormalize(ctx, userID, epFeedsMap[ep.ID])
+		if err != nil {
+			return len(retriedIDs), zaperr.Wrap(err, "failed to check if episode should be normalized", zapFields...)
+		}
+
+		// bitrate is not stored, so retried episodes only keep their codec
+		mediaryParams, err := mediaryJobParams(ep.SourceURL, ep.SourceFilepaths, processingType, presignURL, normalize, EpisodeOptions{Codec: ep.Format})
+		if err != nil {
+			return len(retriedIDs), zaperr.Wrap(err, "failed to build mediary job params", zapFields...)
+		}
+
+		mediaryID, err := svc.mediaSvc.CreateUploadJob(ctx, mediaryParams)
+		if err != nil {
+			return len(retriedIDs), zaperr.Wrap(err, "failed to create mediary job", zapFields...)
+		}
+
+		ep.MediaryID = mediaryID
+		ep.Status = EpisodeStatusCreated
+		ep.UpdatedAt = time.Now().UTC()
+		if _, err := svc.repository.SaveEpisode(ctx, ep); err != nil {
+			return len(retriedIDs), zaperr.Wrap(err, "failed to save episode", zapFields...)
+		}
+		retriedIDs = append(retriedIDs, ep.ID)
+	}
+
+	if len(retriedIDs) == 0 {
+		return 0, nil
+	}
+
+	if err := publish(ctx, svc.jobsQueue, queueEventPollEpisodesStatus, &PollEpisodesStatusQueuePayload{
+		EpisodeIDs: retriedIDs,
+		UserID:     userID,
+	}); err != nil {
+		return len(retriedIDs), zaperr.Wrap(err, "failed to enqueue episode status polling", zapFields...)
+	}
+
+	return len(retriedIDs), nil
+}
+
+// CancelEpisodes stops mediary jobs of episodes which are still being created and marks them as cancelled,
+// so that they are not polled anymore. It returns the number of episodes that were cancelled.
+func (svc *Service) CancelEpisodes(ctx context.Context, userID string, epIDs []string) (int, error) {
+	zapFields := []zap.Field{
+		zap.Strings("episode_ids", epIDs),
+		zap.String("user_id", userID),
+	}
+
+	episodesMap, err := svc.repository.GetEpisodesMap(ctx, userID, epIDs)
+	if err != nil {
+		return 0, zaperr.Wrap(err, "failed to get episodes", zapFields...)
+	}
+
+	cancelled := 0
+	for _, epID := range epIDs {
+		ep, ok := episodesMap[epID]
+		if !ok {
+			continue
+		}
+		switch ep.Status {
+		case EpisodeStatusComplete, EpisodeStatusFailed, EpisodeStatusCancelled:
+			continue
+		}
+		zapFields := append(zapFields, zap.String("episode_id", ep.ID), zap.String("mediary_id", ep.MediaryID))
+
+		if ep.MediaryID != "" {
+			if err := svc.mediaSvc.CancelJob(ctx, ep.MediaryID); err != nil {
+				return cancelled, zaperr.Wrap(err, "failed to cancel mediary job", zapFields...)
+			}
+		}
+
+		ep.Status = EpisodeStatusCancelled
+		ep.UpdatedAt = time.Now().UTC()
+		if _, err := svc.repository.SaveEpisode(ctx, ep); err != nil {
+			return cancelled, zaperr.Wrap(err, "failed to save episode", zapFields...)
+		}
+		cancelled++
+	}
+
+	return cancelled, nil
+}
+
+// ReencodeEpisodes submits mediary jobs making complete episodes again out of their sources, in a given format.
+// Episodes keep serving their current files until new ones are ready, then old files are deleted.
+// Episodes without source files, like uploaded ones, can't be made again and are skipped.
+// Returns number of episodes that were submitted for re-encoding
+func (svc *Service) ReencodeEpisodes(ctx context.Context, userID string, epIDs []string, format string) (int, error) {
+	zapFields := []zap.Field{
+		zap.Strings("episode_ids", epIDs),
+		zap.String("user_id", userID),
+		zap.String("format", format),
+	}
+
+	if _, ok := episodeFormatMIMETypes[format]; !ok {
+		return 0, zaperr.Wrap(ErrUnsupportedFormat, "failed to re-encode episodes", zapFields...)
+	}
+
+	episodesMap, err := svc.GetEpisodesMap(ctx, userID, epIDs)
+	if err != nil {
+		return 0, zaperr.Wrap(err, "failed to get episodes", zapFields...)
+	}
+	epFeedsMap, err := svc.GetPublishedFeedsMap(ctx, userID, epIDs)
+	if err != nil {
+		return 0, zaperr.Wrap(err, "failed to get episodes feeds", zapFields...)
+	}
+
+	jobs := make([]ReencodeJob, 0, len(episodesMap))
+	for _, epID := range epIDs {
+		ep := episodesMap[epID]
+		if ep.Status != EpisodeStatusComplete || ep.Format == format || len(ep.SourceFilepaths) == 0 {
+			continue
+		}
+		zapFields := append(zapFields, zap.String("episode_id", ep.ID))
+
+		oldKey := svc.extractEpisodeS3Key(ep)
+		if oldKey == "" {
+			return len(jobs), zaperr.New("failed to extract episode storage key", zapFields...)
+		}
+		newKey := strings.TrimSuffix(oldKey, path.Ext(oldKey)) + "." + format
+
+		presignURL, err := svc.s3Store.PreSignedURL(newKey)
+		if err != nil {
+			return len(jobs), zaperr.Wrap(err, "failed to get presigned url", zapFields...)
+		}
+
+		normalize, err := svc.shouldNormalize(ctx, userID, epFeedsMap[ep.ID])
+		if err != nil {
+			return len(jobs), zaperr.Wrap(err, "failed to check if episode should be normalized", zapFields...)
+		}
+		mediaryParams, err := mediaryJobParams(
+			ep.SourceURL, ep.SourceFilepaths, ProcessingTypeConcatenate, presignURL, normalize, EpisodeOptions{Codec: format},
+		)
+		if err != nil {
+			return len(jobs), zaperr.Wrap(err, "failed to build mediary job params", zapFields...)
+		}
+
+		mediaryID, err := svc.mediaSvc.CreateUploadJob(ctx, mediaryParams)
+		if err != nil {
+			return len(jobs), zaperr.Wrap(err, "failed to create mediary job", zapFields...)
+		}
+		jobs = append(jobs, ReencodeJob{
+			EpisodeID:  ep.ID,
+			MediaryID:  mediaryID,
+			StorageKey: newKey,
+			URL:        stripQuery(presignURL),
+			Format:     format,
+		})
+	}
+
+	if len(jobs) == 0 {
+		return 0, nil
+	}
+
+	if err := publish(ctx, svc.jobsQueue, queueEventPollReencodeJobs, &PollReencodeJobsQueuePayload{
+		UserID: userID,
+		Jobs:   jobs,
+	}); err != nil {
+		return len(jobs), zaperr.Wrap(err, "failed to enqueue re-encoding polling", zapFields...)
+	}
+
+	return len(jobs), nil
+}
+
+type RegenerateOptions struct {
+	IncludeIncomplete bool
+	Force             bool
+}
+
+// WithIncompleteEpisodes makes regeneration include episodes which are still processing or have failed.
+// It only affects a single regeneration, next one will exclude them again
+func WithIncompleteEpisodes() func(*RegenerateOptions) {
+	return func(opts *RegenerateOptions) {
+		opts.IncludeIncomplete = true
+	}
+}
+
+// WithForce makes regeneration upload feed even if it is unchanged since the last upload
+func WithForce() func(*RegenerateOptions) {
+	return func(opts *RegenerateOptions) {
+		opts.Force = true
+	}
+}
+
+func (svc *Service) RegenerateFeed(ctx context.Context, userID string, feedID string, opts ...func(*RegenerateOptions)) error {
+	options := &RegenerateOptions{}
+	for _, opt := range opts {
+		opt(options)
+	}
+
+	if err := publish(ctx, svc.jobsQueue, queueEventRegenerateFeed, &RegenerateFeedQueuePayload{
+		UserID:            userID,
+		FeedIDs:           []string{feedID},
+		IncludeIncomplete: options.IncludeIncomplete,
+		Force:             options.Force,
+	}); err != nil {
+		return zaperr.Wrap(err, "failed to publish regenerate feed job", zap.String("feed_id", feedID), zap.String("user_id", userID))
+	}
+
+	return nil
+}
+
+// RegenerateAllFeeds rebuilds every feed of the user, e.g. after their files in S3 were edited or lost.
+// Default feed is recreated if it is missing
+func (svc *Service) RegenerateAllFeeds(ctx context.Context, userID string) error {
+	zapFields := []zap.Field{
+		zap.String("user_id", userID),
+	}
+
+	feeds, err := svc.ListFeeds(ctx, userID)
+	if err != nil {
+		return zaperr.Wrap(err, "failed to list feeds", zapFields...)
+	}
+	feedIDs := make([]string, 0, len(feeds))
+	for _, f := range feeds {
+		feedIDs = append(feedIDs, f.ID)
+	}
+	zapFields = append(zapFields, zap.Strings("feed_ids", feedIDs))
 
-func (svc *Service) RegenerateFeed(ctx context.Context, userID string, feedID string) error {
-	if err := svc.jobsQueue.Publish(ctx, queueEventRegenerateFeed, RegenerateFeedQueuePayload{
+	if err := publish(ctx, svc.jobsQueue, queueEventRegenerateFeed, &RegenerateFeedQueuePayload{
 		UserID:  userID,
-		FeedIDs: []string{feedID},
+		FeedIDs: feedIDs,
+		Force:   true,
 	}); err != nil {
-		return zaperr.Wrap(err, "failed to publish regenerate feed job", zap.String("feed_id", feedID), zap.String("user_id", userID))
+		return zaperr.Wrap(err, "failed to publish regenerate feed job", zapFields...)
 	}
 
 	return nil
 }
 
 func (svc *Service) createFeed(ctx context.Context, userID string, title string, feedID string) (*Feed, error) {
-	var err error
+	sanitizedTitle, err := sanitizeTitle(title)
+	if err != nil {
+		return nil, zaperr.Wrap(err, "failed to create feed", zap.String("title", title))
+	}
+	title = sanitizedTitle
 	if feedID == "" {
 		for feedID == "" || feedID == DefaultFeedID {
 			feedID, err = svc.repository.NextFeedID(ctx, userID)
@@ -715,7 +2285,7 @@ func (svc *Service) createFeed(ctx context.Context, userID string, title string,
 		}
 	}
 
-	feedKey := svc.constructS3FeedKey(userID, feedID)
+	feedKey := svc.constructS3FeedKey(userID, feedID, "")
 
 	url, err := svc.s3Store.URL(feedKey)
 	if err != nil {
@@ -734,6 +2304,52 @@ func (svc *Service) createFeed(ctx context.Context, userID string, title string,
 	return feed, nil
 }
 
+// createEpisodesConcurrently creates an episode for each of payload variants, at most createEpisodesConcurrency at a time.
+// Only mediary jobs are started concurrently, episodes are saved in the order of variants afterwards,
+// so that their IDs and creation times don't depend on which job happened to start first.
+// Episodes and errors are returned in the order of variants
+func (svc *Service) createEpisodesConcurrently(ctx context.Context, payload *CreateEpisodesQueuePayload, metadata *Metadata) ([]*Episode, []error) {
+	episodes := make([]*Episode, len(payload.VariantsPerEpisode))
+	isNew := make([]bool, len(payload.VariantsPerEpisode))
+	errs := make([]error, len(payload.VariantsPerEpisode))
+
+	idxChan := make(chan int, len(payload.VariantsPerEpisode))
+	for i := range payload.VariantsPerEpisode {
+		idxChan <- i
+	}
+	close(idxChan)
+
+	workersCount := svc.createEpisodesConcurrency
+	if len(payload.VariantsPerEpisode) < workersCount {
+		workersCount = len(payload.VariantsPerEpisode)
+	}
+
+	var wg sync.WaitGroup
+	for w := 0; w < workersCount; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range idxChan {
+				// every worker writes its own indexes only, so no locking is needed
+				episodes[i], isNew[i], errs[i] = svc.prepareEpisode(
+					ctx, payload.UserID, payload.URL, payload.VariantsPerEpisode[i],
+					payload.ProcessingType, payload.Tags, payload.Options, metadata,
+				)
+			}
+		}()
+	}
+	wg.Wait()
+
+	for i := range episodes {
+		if errs[i] != nil || !isNew[i] {
+			continue
+		}
+		episodes[i], errs[i] = svc.saveNewEpisode(ctx, episodes[i])
+	}
+
+	return episodes, errs
+}
+
 func (svc *Service) onCreateEpisodesQueueEvent(ctx context.Context, payloadBytes []byte) error {
 	var payload CreateEpisodesQueuePayload
 	if err := json.Unmarshal(payloadBytes, &payload); err != nil {
@@ -748,13 +2364,49 @@ func (svc *Service) onCreateEpisodesQueueEvent(ctx context.Context, payloadBytes
 
 	svc.logger.Info("creating queued episodes", zapFields...)
 
+	// all episodes of the batch come from the same URL, so its metadata is fetched once for all of them
+	metadata, err := svc.FetchMetadata(ctx, payload.URL)
+	if err != nil {
+		return zaperr.Wrap(err, "failed to fetch metadata", zapFields...)
+	}
+
+	episodes, errs := svc.createEpisodesConcurrently(ctx, &payload, metadata)
+
 	var createdEpisodes []*Episode
-	for _, variants := range payload.VariantsPerEpisode {
-		episode, err := svc.CreateEpisode(ctx, payload.UserID, payload.URL, variants, payload.ProcessingType)
-		if err != nil {
-			return zaperr.Wrap(err, "failed to create single file episode", zapFields...)
+	var failedVariants [][]string
+	var createErr error
+	for i, variants := range payload.VariantsPerEpisode {
+		if errs[i] != nil {
+			zapFields := append(zapFields, zap.Strings("variants", variants))
+			createErr = multierr.Append(createErr, zaperr.Wrap(errs[i], "failed to create single file episode", zapFields...))
+			failedVariants = append(failedVariants, variants)
+			continue
+		}
+		createdEpisodes = append(createdEpisodes, episodes[i])
+	}
+
+	if len(failedVariants) > 0 {
+		// nothing was created, so it's safe to let the queue retry the whole payload
+		if len(createdEpisodes) == 0 {
+			return createErr
+		}
+
+		// otherwise, retrying the whole payload would duplicate episodes that were already created,
+		// so only the failed subset is queued again
+		zapFields := append(zapFields, zap.Any("failed_variants", failedVariants), zaperr.ToField(createErr))
+		svc.logger.Error("failed to create some of queued episodes, retrying them", zapFields...)
+		if err := publish(ctx, svc.jobsQueue, queueEventCreateEpisodes, &CreateEpisodesQueuePayload{
+			URL:                payload.URL,
+			VariantsPerEpisode: failedVariants,
+			UserID:             payload.UserID,
+			ProcessingType:     payload.ProcessingType,
+			Tags:               payload.Tags,
+			Options:            payload.Options,
+		}); err != nil {
+			// returning an error here would make the queue retry episodes that were already created
+			zapFields := append(zapFields, zap.NamedError("enqueue_error", err))
+			svc.logger.Error("failed to enqueue failed episodes creation", zapFields...)
 		}
-		createdEpisodes = append(createdEpisodes, episode)
 	}
 
 	episodeIDs := make([]string, len(createdEpisodes))
@@ -762,7 +2414,7 @@ func (svc *Service) onCreateEpisodesQueueEvent(ctx context.Context, payloadBytes
 		episodeIDs[i] = e.ID
 	}
 
-	if err := svc.jobsQueue.Publish(ctx, queueEventPollEpisodesStatus, &PollEpisodesStatusQueuePayload{
+	if err := publish(ctx, svc.jobsQueue, queueEventPollEpisodesStatus, &PollEpisodesStatusQueuePayload{
 		EpisodeIDs: episodeIDs,
 		UserID:     payload.UserID,
 	}); err != nil {
@@ -815,6 +2467,13 @@ func (svc *Service) onPollEpisodesQueueEvent(ctx context.Context, payloadBytes [
 		return zaperr.Wrap(err, "failed to get episodes", zapFields...)
 	}
 
+	// cancelled episodes could still be in a payload enqueued before they were cancelled
+	for epID, e := range episodesMap {
+		if e.Status == EpisodeStatusCancelled {
+			delete(episodesMap, epID)
+		}
+	}
+
 	mediaryIDs := make([]string, 0, len(episodesMap))
 	for _, e := range episodesMap {
 		if e.Status == EpisodeStatusComplete {
@@ -826,7 +2485,7 @@ func (svc *Service) onPollEpisodesQueueEvent(ctx context.Context, payloadBytes [
 		mediaryIDs = append(mediaryIDs, e.MediaryID)
 	}
 
-	jobStatusMap, err := svc.mediaSvc.FetchJobStatusMap(ctx, mediaryIDs)
+	jobStatusMap, err := svc.mediaSvc.FetchJobStatusBatch(ctx, mediaryIDs)
 	if err != nil {
 		zapFields := append(zapFields, zap.Strings("mediary_ids", mediaryIDs))
 		return zaperr.Wrap(err, "failed to fetch job status", zapFields...)
@@ -835,44 +2494,86 @@ func (svc *Service) onPollEpisodesQueueEvent(ctx context.Context, payloadBytes [
 	episodesStateChanges := make([]EpisodeStatusChange, 0, len(episodesMap))
 	episodesToSave := make([]*Episode, 0, len(episodesMap))
 	episodeIDsToRequeue := make([]string, 0, len(episodesMap))
+	reportedProgress := make(map[string]int, len(episodesMap))
 	for _, ep := range episodesMap {
 		zapFields := append(zapFields, zap.String("episode_id", ep.ID), zap.String("mediary_id", ep.MediaryID))
+		if progress, ok := payload.ReportedProgress[ep.ID]; ok {
+			reportedProgress[ep.ID] = progress
+		}
 		jstat, exists := jobStatusMap[ep.MediaryID]
 		if !exists {
 			if payload.RequeueCount < maxPollEpisodesRequeueCount {
 				svc.logger.Warn("mediary job status not found", zapFields...)
 				episodeIDsToRequeue = append(episodeIDsToRequeue, ep.ID)
-			} else {
-				svc.logger.Warn("mediary job status not found, max requeue count reached", zapFields...)
+				continue
 			}
+
+			svc.logger.Warn("mediary job status not found, max requeue count reached", zapFields...)
+			episodesStateChanges = append(episodesStateChanges, EpisodeStatusChange{
+				Episode:   ep,
+				OldStatus: ep.Status,
+				NewStatus: EpisodeStatusFailed,
+				Err:       ErrJobLost,
+			})
+			ep.Status = EpisodeStatusFailed
+			episodesToSave = append(episodesToSave, ep)
 			continue
 		}
 
 		newStatus, err := jobStatusToEpisodeStatus(jstat.Status)
 		if err != nil {
-			zapFields := append(zapFields, zap.String("job_status", string(jstat.Status)))
-			return zaperr.Wrap(err, "failed to convert job status to episode status", zapFields...)
+			// one episode with a status we don't understand should not stop the rest from being updated
+			zapFields := append(zapFields, zap.String("job_status", string(jstat.Status)), zaperr.ToField(err))
+			svc.logger.Error("failed to convert job status to episode status", zapFields...)
+			episodeIDsToRequeue = append(episodeIDsToRequeue, ep.ID)
+			continue
 		}
 
-		if newStatus != EpisodeStatusComplete {
+		// failed episodes are not polled anymore, they stay failed until retried
+		if newStatus != EpisodeStatusComplete && newStatus != EpisodeStatusFailed {
 			episodeIDsToRequeue = append(episodeIDsToRequeue, ep.ID)
 		}
 
+		progress := progressPercent(jstat.Progress)
 		if newStatus == ep.Status {
+			// status is the same, but changed progress is still news to someone waiting for the episode
+			progressChanged := progress > 0 && progress != payload.ReportedProgress[ep.ID]
+			if progressChanged && newStatus != EpisodeStatusComplete && newStatus != EpisodeStatusFailed {
+				episodesStateChanges = append(episodesStateChanges, EpisodeStatusChange{
+					Episode:   ep,
+					OldStatus: ep.Status,
+					NewStatus: newStatus,
+					Progress:  jstat.Progress,
+				})
+				reportedProgress[ep.ID] = progress
+			}
 			continue
 		}
+		reportedProgress[ep.ID] = progress
 
-		episodesStateChanges = append(episodesStateChanges, EpisodeStatusChange{
+		statusChange := EpisodeStatusChange{
 			Episode:   ep,
 			OldStatus: ep.Status,
 			NewStatus: newStatus,
-		})
+			Progress:  jstat.Progress,
+		}
+		if newStatus == EpisodeStatusFailed {
+			statusChange.Err = ErrJobFailed
+		}
+		episodesStateChanges = append(episodesStateChanges, statusChange)
 
 		ep.Status = newStatus
 		switch newStatus {
 		case EpisodeStatusUploading, EpisodeStatusComplete:
 			ep.FileLenBytes = jstat.ResultFileBytes
 			ep.Duration = jstat.ResultMediaDuration
+			ep.SegmentDurations = jstat.SegmentDurations
+		}
+		// chapters are nice to have, so episode is completed without them if they fail
+		if newStatus == EpisodeStatusComplete && hasChapters(ep) {
+			if err := svc.uploadChapters(ctx, ep); err != nil {
+				svc.logger.Error("failed to upload chapters", append(zapFields, zaperr.ToField(err))...)
+			}
 		}
 		episodesToSave = append(episodesToSave, ep)
 	}
@@ -888,9 +2589,13 @@ func (svc *Service) onPollEpisodesQueueEvent(ctx context.Context, payloadBytes [
 
 	var episodesSaveError error
 	feedsToPublish := make(map[string]bool)
+	completedEpisodes := make([]*Episode, 0, len(episodesToSave))
 	for _, e := range episodesToSave {
 		zapFields := append(zapFields, zap.String("episode_id", e.ID))
 		if _, err := svc.repository.SaveEpisode(ctx, e); err == nil {
+			if e.Status == EpisodeStatusComplete {
+				completedEpisodes = append(completedEpisodes, e)
+			}
 			if _, exists := epFeedsMap[e.ID]; exists {
 				for _, f := range epFeedsMap[e.ID] {
 					feedsToPublish[f] = true
@@ -905,8 +2610,13 @@ func (svc *Service) onPollEpisodesQueueEvent(ctx context.Context, payloadBytes [
 	for f := range feedsToPublish {
 		feedIDs = append(feedIDs, f)
 	}
+	// completed episodes now have their size known, so feeds might have grown over their limits
+	if err := svc.enforceFeedsMaxTotalBytes(ctx, payload.UserID, feedIDs); err != nil {
+		zapFields := append(zapFields, zap.Strings("feed_ids", feedIDs), zaperr.ToField(err))
+		svc.logger.Error("failed to enforce feeds max total size", zapFields...)
+	}
 	if len(feedIDs) > 0 {
-		if err := svc.jobsQueue.Publish(ctx, queueEventRegenerateFeed, &RegenerateFeedQueuePayload{
+		if err := publish(ctx, svc.jobsQueue, queueEventRegenerateFeed, &RegenerateFeedQueuePayload{
 			FeedIDs: feedIDs,
 			UserID:  payload.UserID,
 		}); err != nil {
@@ -920,6 +2630,8 @@ func (svc *Service) onPollEpisodesQueueEvent(ctx context.Context, payloadBytes [
 		svc.episodeStatusChangesChan <- episodesStateChanges
 	}
 
+	svc.enqueueWebhook(ctx, payload.UserID, completedEpisodes, epFeedsMap)
+
 	if len(episodeIDsToRequeue) > 0 {
 		newPayload := &PollEpisodesStatusQueuePayload{
 			EpisodeIDs:       episodeIDsToRequeue,
@@ -929,6 +2641,14 @@ func (svc *Service) onPollEpisodesQueueEvent(ctx context.Context, payloadBytes [
 			PollAfter:        payload.PollAfter,
 			RequeueCount:     payload.RequeueCount + 1,
 		}
+		for _, epID := range episodeIDsToRequeue {
+			if progress, ok := reportedProgress[epID]; ok {
+				if newPayload.ReportedProgress == nil {
+					newPayload.ReportedProgress = make(map[string]int, len(episodeIDsToRequeue))
+				}
+				newPayload.ReportedProgress[epID] = progress
+			}
+		}
 
 		now := time.Now()
 		if newPayload.PollingStartedAt == nil {
@@ -947,7 +2667,7 @@ func (svc *Service) onPollEpisodesQueueEvent(ctx context.Context, payloadBytes [
 		pollAfter := now.Add(*newPayload.Delay)
 		newPayload.PollAfter = &pollAfter
 
-		if err := svc.jobsQueue.Publish(ctx, queueEventPollEpisodesStatus, newPayload); err != nil {
+		if err := publish(ctx, svc.jobsQueue, queueEventPollEpisodesStatus, newPayload); err != nil {
 			zapFields := append(zapFields, zap.Strings("episode_ids", episodeIDsToRequeue))
 			return zaperr.Wrap(err, "failed to enqueue episode status polling", zapFields...)
 		}
@@ -956,6 +2676,172 @@ func (svc *Service) onPollEpisodesQueueEvent(ctx context.Context, payloadBytes [
 	return nil
 }
 
+// onPollReencodeJobsQueueEvent swaps files of re-encoded episodes once their jobs are complete.
+// Until then episodes stay complete and keep their old files, so they never drop out of feeds
+func (svc *Service) onPollReencodeJobsQueueEvent(ctx context.Context, payloadBytes []byte) error {
+	var payload PollReencodeJobsQueuePayload
+	if err := json.Unmarshal(payloadBytes, &payload); err != nil {
+		return zaperr.Wrap(err, "failed to unmarshal payload", zap.String("payload", string(payloadBytes)))
+	}
+
+	epIDs := make([]string, len(payload.Jobs))
+	mediaryIDs := make([]string, len(payload.Jobs))
+	for i, job := range payload.Jobs {
+		epIDs[i] = job.EpisodeID
+		mediaryIDs[i] = job.MediaryID
+	}
+	zapFields := []zap.Field{
+		zap.Strings("episode_ids", epIDs),
+		zap.String("user_id", payload.UserID),
+		zap.Timep("poll_after", payload.PollAfter),
+	}
+
+	if payload.PollAfter != nil {
+		sleepDuration := time.Until(*payload.PollAfter)
+		if sleepDuration > 0 {
+			select {
+			case <-time.After(sleepDuration):
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		}
+	}
+
+	svc.logger.Info("polling re-encoding jobs", zapFields...)
+
+	episodesMap, err := svc.repository.GetEpisodesMap(ctx, payload.UserID, epIDs)
+	if err != nil {
+		return zaperr.Wrap(err, "failed to get episodes", zapFields...)
+	}
+	jobStatusMap, err := svc.mediaSvc.FetchJobStatusBatch(ctx, mediaryIDs)
+	if err != nil {
+		zapFields := append(zapFields, zap.Strings("mediary_ids", mediaryIDs))
+		return zaperr.Wrap(err, "failed to fetch job status", zapFields...)
+	}
+
+	var jobsToRequeue []ReencodeJob
+	var reencodedIDs []string
+	var statusChanges []EpisodeStatusChange
+	for _, job := range payload.Jobs {
+		zapFields := append(zapFields, zap.String("episode_id", job.EpisodeID), zap.String("mediary_id", job.MediaryID))
+
+		jstat, exists := jobStatusMap[job.MediaryID]
+		var newStatus EpisodeStatus
+		if exists {
+			if newStatus, err = jobStatusToEpisodeStatus(jstat.Status); err != nil {
+				zapFields := append(zapFields, zap.String("job_status", string(jstat.Status)), zaperr.ToField(err))
+				svc.logger.Error("failed to convert job status to episode status", zapFields...)
+			}
+		}
+		if newStatus != EpisodeStatusComplete && newStatus != EpisodeStatusFailed {
+			if payload.RequeueCount < maxPollEpisodesRequeueCount {
+				jobsToRequeue = append(jobsToRequeue, job)
+				continue
+			}
+			svc.logger.Warn("re-encoding job is not complete, max requeue count reached", zapFields...)
+			newStatus = EpisodeStatusFailed
+		}
+
+		ep, exists := episodesMap[job.EpisodeID]
+		if !exists {
+			// episode was purged while being re-encoded, so nothing is going to point at its new file
+			if newStatus == EpisodeStatusComplete {
+				if err := svc.s3Store.Delete(ctx, job.StorageKey); err != nil {
+					svc.logger.Error("failed to delete re-encoded file of purged episode", append(zapFields, zaperr.ToField(err))...)
+				}
+			}
+			continue
+		}
+
+		if newStatus == EpisodeStatusFailed {
+			statusChanges = append(statusChanges, EpisodeStatusChange{
+				Episode:   ep,
+				OldStatus: ep.Status,
+				NewStatus: ep.Status,
+				Err:       ErrReencodeFailed,
+			})
+			continue
+		}
+
+		oldKey := svc.extractEpisodeS3Key(ep)
+		ep.MediaryID = job.MediaryID
+		ep.StorageKey = job.StorageKey
+		ep.URL = job.URL
+		ep.Format = job.Format
+		ep.FileLenBytes = jstat.ResultFileBytes
+		ep.Duration = jstat.ResultMediaDuration
+		ep.SegmentDurations = jstat.SegmentDurations
+		// episode isn't made the way its hash says anymore, so it must not be reused for requests of the old format
+		ep.ContentHash = ""
+		ep.UpdatedAt = time.Now().UTC()
+		if _, err := svc.repository.SaveEpisode(ctx, ep); err != nil {
+			return zaperr.Wrap(err, "failed to save episode", zapFields...)
+		}
+		// nothing points at the old file anymore, failing to delete it only wastes some space
+		if oldKey != "" && oldKey != job.StorageKey {
+			if err := svc.s3Store.Delete(ctx, oldKey); err != nil {
+				svc.logger.Error("failed to delete old episode file", append(zapFields, zap.String("key", oldKey), zaperr.ToField(err))...)
+			}
+		}
+		reencodedIDs = append(reencodedIDs, ep.ID)
+		statusChanges = append(statusChanges, EpisodeStatusChange{
+			Episode:   ep,
+			OldStatus: EpisodeStatusComplete,
+			NewStatus: EpisodeStatusComplete,
+		})
+	}
+
+	if len(reencodedIDs) > 0 {
+		epFeedsMap, err := svc.GetPublishedFeedsMap(ctx, payload.UserID, reencodedIDs)
+		if err != nil {
+			return zaperr.Wrap(err, "failed to get episodes feeds", zapFields...)
+		}
+		var feedIDs []string
+		for _, epFeedIDs := range epFeedsMap {
+			for _, feedID := range epFeedIDs {
+				if !slices.Contains(feedIDs, feedID) {
+					feedIDs = append(feedIDs, feedID)
+				}
+			}
+		}
+		if len(feedIDs) > 0 {
+			if err := publish(ctx, svc.jobsQueue, queueEventRegenerateFeed, &RegenerateFeedQueuePayload{
+				UserID:  payload.UserID,
+				FeedIDs: feedIDs,
+			}); err != nil {
+				zapFields := append(zapFields, zap.Strings("feed_ids", feedIDs))
+				return zaperr.Wrap(err, "failed to enqueue feed regeneration", zapFields...)
+			}
+		}
+	}
+
+	if len(statusChanges) > 0 {
+		svc.episodeStatusChangesChan <- statusChanges
+	}
+
+	if len(jobsToRequeue) > 0 {
+		delay := 10 * time.Second
+		if payload.Delay != nil {
+			delay = time.Duration(float64(*payload.Delay) * 1.1)
+			if delay > 60*time.Minute {
+				delay = 60 * time.Minute
+			}
+		}
+		pollAfter := time.Now().Add(delay)
+		if err := publish(ctx, svc.jobsQueue, queueEventPollReencodeJobs, &PollReencodeJobsQueuePayload{
+			UserID:       payload.UserID,
+			Jobs:         jobsToRequeue,
+			Delay:        &delay,
+			PollAfter:    &pollAfter,
+			RequeueCount: payload.RequeueCount + 1,
+		}); err != nil {
+			return zaperr.Wrap(err, "failed to enqueue re-encoding polling", zapFields...)
+		}
+	}
+
+	return nil
+}
+
 func (svc *Service) onRegenerateFeedQueueEvent(ctx context.Context, payloadBytes []byte) error {
 	var payload RegenerateFeedQueuePayload
 	if err := json.Unmarshal(payloadBytes, &payload); err != nil {
@@ -964,6 +2850,7 @@ func (svc *Service) onRegenerateFeedQueueEvent(ctx context.Context, payloadBytes
 
 	zapFields := []zap.Field{
 		zap.Strings("feed_ids", payload.FeedIDs),
+		zap.String("user_id", payload.UserID),
 	}
 
 	if len(payload.FeedIDs) == 0 {
@@ -971,15 +2858,45 @@ func (svc *Service) onRegenerateFeedQueueEvent(ctx context.Context, payloadBytes
 		return nil
 	}
 
+	// feeds are looked up by user, so without one nothing would be found and regeneration silently skipped
+	if payload.UserID == "" {
+		zapFields := append(zapFields, zap.String("payload", string(payloadBytes)))
+		svc.logger.Error("refusing to regenerate feeds of unknown user", zapFields...)
+		return zaperr.Wrap(ErrInvalidPayload, "regenerate feed payload has no user id", zapFields...)
+	}
+
+	// one-off override or forced rebuild must not be swallowed by a regular regeneration scheduled earlier
+	feedIDs := payload.FeedIDs
+	var opts []func(*RegenerateOptions)
+	if payload.IncludeIncomplete {
+		opts = append(opts, WithIncompleteEpisodes())
+	}
+	if payload.Force {
+		opts = append(opts, WithForce())
+	}
+	if payload.Debounced {
+		// changes made while feeds are being regenerated might be missed by this regeneration
+		svc.unscheduleRegeneration(ctx, payload.UserID, payload.FeedIDs)
+	} else if !payload.IncludeIncomplete && !payload.Force {
+		var err error
+		if feedIDs, err = svc.debounceRegeneration(ctx, payload.UserID, payload.FeedIDs); err != nil {
+			return zaperr.Wrap(err, "failed to debounce feed regeneration", zapFields...)
+		}
+		if len(feedIDs) == 0 {
+			svc.logger.Debug("feeds regeneration is scheduled", zapFields...)
+			return nil
+		}
+	}
+
 	svc.logger.Info("regenerating feeds", zapFields...)
 
-	feedsMap, err := svc.repository.GetFeedsMap(ctx, payload.UserID, payload.FeedIDs)
+	feedsMap, err := svc.repository.GetFeedsMap(ctx, payload.UserID, feedIDs)
 	if err != nil {
 		return zaperr.Wrap(err, "failed to get feeds map to regenerate feed queue", zapFields...)
 	}
 
 	for _, f := range feedsMap {
-		if err := svc.regenerateFeedFile(ctx, f); err != nil {
+		if err := svc.regenerateFeedFile(ctx, f, opts...); err != nil {
 			zapFields := append(zapFields, zap.String("feed_id", f.ID))
 			return zaperr.Wrap(err, "failed to regenerate feed", zapFields...)
 		}
@@ -988,35 +2905,225 @@ func (svc *Service) onRegenerateFeedQueueEvent(ctx context.Context, payloadBytes
 	return nil
 }
 
-func (svc *Service) regenerateFeedFile(ctx context.Context, feed *Feed) error {
+func (svc *Service) regenerateFeedFile(ctx context.Context, feed *Feed, opts ...func(*RegenerateOptions)) error {
+	options := &RegenerateOptions{}
+	for _, opt := range opts {
+		opt(options)
+	}
+
 	zapFields := []zap.Field{
 		zap.String("feed_id", feed.ID),
 		zap.String("user_id", feed.UserID),
 	}
 
-	episodes, err := svc.repository.ListFeedEpisodes(ctx, feed.UserID, feed.ID)
+	episodes, err := svc.repository.ListFeedEpisodesJoined(ctx, feed.UserID, feed.ID)
 	if err != nil {
 		return zaperr.Wrap(err, "failed to list feed episodes", zapFields...)
 	}
 
-	objectKey := svc.constructS3FeedKey(feed.UserID, feed.ID)
-	feedReader, err := generateFeed(feed, episodes)
+	if episodes, err = svc.enforceFeedMaxEpisodes(ctx, feed, episodes); err != nil {
+		return zaperr.Wrap(err, "failed to enforce feed max episodes", zapFields...)
+	}
+
+	// incomplete episodes have nothing behind their URLs yet, so podcast clients would fail to download them
+	if options.IncludeIncomplete {
+		svc.logger.Warn("regenerating feed with incomplete episodes included, this is a one-off override", zapFields...)
+	} else {
+		episodes = slices.DeleteFunc(episodes, func(ep *Episode) bool {
+			return ep.Status != EpisodeStatusComplete
+		})
+	}
+
+	objectKey := svc.constructS3FeedKey(feed.UserID, feed.ID, feed.AccessToken)
+
+	// uploading identical feed would only churn Last-Modified and invalidate CDN caches for nothing.
+	// Build date differs every time, so the hash is taken of the feed without it
+	contentsReader, err := generateFeed(feed, episodes, svc.feedGenerator, time.Time{})
+	if err != nil {
+		return zaperr.Wrap(err, "failed to generate feed", zapFields...)
+	}
+	hash, err := hashFeed(contentsReader)
+	if err != nil {
+		return zaperr.Wrap(err, "failed to hash feed", zapFields...)
+	}
+	zapFields = append(zapFields, zap.String("hash", hash))
+	metadata, err := svc.s3Store.Metadata(ctx, objectKey)
+	if err != nil {
+		return zaperr.Wrap(err, "failed to get feed metadata", zapFields...)
+	}
+	if metadata[feedHashMetadataKey] == hash && !options.Force {
+		svc.logger.Debug("feed unchanged, skipping upload", zapFields...)
+		return nil
+	}
+
+	feedReader, err := generateFeed(feed, episodes, svc.feedGenerator, time.Now().UTC())
 	if err != nil {
 		return zaperr.Wrap(err, "failed to generate feed", zapFields...)
 	}
 
-	if err := svc.s3Store.Put(ctx, objectKey, feedReader, WithContentType("text/xml; charset=utf-8")); err != nil {
+	putOpts := []func(*PutOptions){WithContentType("text/xml; charset=utf-8")}
+	if svc.compressFeeds {
+		if feedReader, err = gzipFeed(feedReader); err != nil {
+			return zaperr.Wrap(err, "failed to compress feed", zapFields...)
+		}
+		putOpts = append(putOpts, WithContentEncoding("gzip"))
+	}
+	putOpts = append(putOpts, WithMetadata(feedHashMetadataKey, hash))
+
+	if err := svc.s3Store.Put(ctx, objectKey, feedReader, putOpts...); err != nil {
 		return zaperr.Wrap(err, "failed to upload feed", zapFields...)
 	}
+	svc.feedRegenerations.Add(1)
+
+	return nil
+}
+
+// enforceFeedsMaxTotalBytes removes oldest episodes from ephemeral feeds whose episodes exceed feed's MaxTotalBytes.
+// The newest episode is always kept, even if it alone is over the limit
+func (svc *Service) enforceFeedsMaxTotalBytes(ctx context.Context, userID string, feedIDs []string) error {
+	feedsMap, err := svc.repository.GetFeedsMap(ctx, userID, feedIDs)
+	if err != nil {
+		return zaperr.Wrap(err, "failed to get feeds", zap.Strings("feed_ids", feedIDs))
+	}
+
+	for _, feed := range feedsMap {
+		if feed.MaxTotalBytes <= 0 || feed.IsPermanent {
+			continue
+		}
+		zapFields := []zap.Field{
+			zap.String("feed_id", feed.ID),
+			zap.String("user_id", userID),
+			zap.Int64("max_total_bytes", feed.MaxTotalBytes),
+		}
+
+		episodes, err := svc.repository.ListFeedEpisodesJoined(ctx, userID, feed.ID)
+		if err != nil {
+			return zaperr.Wrap(err, "failed to list feed episodes", zapFields...)
+		}
+		slices.SortStableFunc(episodes, func(a, b *Episode) int {
+			return a.CreatedAt.Compare(b.CreatedAt)
+		})
+
+		var totalBytes int64
+		for _, ep := range episodes {
+			totalBytes += ep.FileLenBytes
+		}
+
+		var epIDsToRemove []string
+		for i := 0; i < len(episodes)-1 && totalBytes > feed.MaxTotalBytes; i++ {
+			if episodes[i].Pinned {
+				continue
+			}
+			epIDsToRemove = append(epIDsToRemove, episodes[i].ID)
+			totalBytes -= episodes[i].FileLenBytes
+		}
+		if len(epIDsToRemove) == 0 {
+			continue
+		}
+
+		publications, err := svc.repository.ListPublicationsByEpisodeIDs(ctx, userID, epIDsToRemove)
+		if err != nil {
+			return zaperr.Wrap(err, "failed to list publications", zapFields...)
+		}
+		publicationIDs := make([]string, 0, len(epIDsToRemove))
+		for _, p := range publications {
+			if p.FeedID == feed.ID {
+				publicationIDs = append(publicationIDs, p.ID)
+			}
+		}
+		if err := svc.repository.DeletePublications(ctx, userID, publicationIDs); err != nil {
+			return zaperr.Wrap(err, "failed to delete publications", zapFields...)
+		}
+
+		zapFields = append(zapFields, zap.Strings("episode_ids", epIDsToRemove))
+		svc.logger.Info("removed oldest episodes from feed exceeding its max total size", zapFields...)
+	}
 
 	return nil
 }
 
-func (svc *Service) constructS3FeedKey(userID string, feedID string) string {
+// enforceFeedMaxEpisodes removes oldest episodes from the feed when it has more than feed's MaxEpisodes of them,
+// returning the episodes left. Removed episodes are only unpublished, their files are kept
+func (svc *Service) enforceFeedMaxEpisodes(ctx context.Context, feed *Feed, episodes []*Episode) ([]*Episode, error) {
+	if feed.MaxEpisodes <= 0 || len(episodes) <= feed.MaxEpisodes {
+		return episodes, nil
+	}
+	zapFields := []zap.Field{
+		zap.String("feed_id", feed.ID),
+		zap.String("user_id", feed.UserID),
+		zap.Int("max_episodes", feed.MaxEpisodes),
+	}
+
+	oldestFirst := slices.Clone(episodes)
+	slices.SortStableFunc(oldestFirst, func(a, b *Episode) int {
+		return a.CreatedAt.Compare(b.CreatedAt)
+	})
+
+	var epIDsToRemove []string
+	for i := 0; i < len(oldestFirst) && len(epIDsToRemove) < len(episodes)-feed.MaxEpisodes; i++ {
+		if oldestFirst[i].Pinned {
+			continue
+		}
+		epIDsToRemove = append(epIDsToRemove, oldestFirst[i].ID)
+	}
+	if len(epIDsToRemove) == 0 {
+		return episodes, nil
+	}
+
+	publications, err := svc.repository.ListPublicationsByEpisodeIDs(ctx, feed.UserID, epIDsToRemove)
+	if err != nil {
+		return nil, zaperr.Wrap(err, "failed to list publications", zapFields...)
+	}
+	publicationIDs := make([]string, 0, len(epIDsToRemove))
+	for _, p := range publications {
+		if p.FeedID == feed.ID {
+			publicationIDs = append(publicationIDs, p.ID)
+		}
+	}
+	if err := svc.repository.DeletePublications(ctx, feed.UserID, publicationIDs); err != nil {
+		return nil, zaperr.Wrap(err, "failed to delete publications", zapFields...)
+	}
+
+	zapFields = append(zapFields, zap.Strings("episode_ids", epIDsToRemove))
+	svc.logger.Info("removed oldest episodes from feed exceeding its max episodes", zapFields...)
+
+	return slices.DeleteFunc(slices.Clone(episodes), func(ep *Episode) bool {
+		return slices.Contains(epIDsToRemove, ep.ID)
+	}), nil
+}
+
+// shouldNormalize reports whether any of given feeds has loudness normalization enabled
+func (svc *Service) shouldNormalize(ctx context.Context, userID string, feedIDs []string) (bool, error) {
+	if len(feedIDs) == 0 {
+		return false, nil
+	}
+
+	feedsMap, err := svc.repository.GetFeedsMap(ctx, userID, feedIDs)
+	if err != nil {
+		return false, zaperr.Wrap(err, "failed to get feeds", zap.Strings("feed_ids", feedIDs))
+	}
+
+	for _, feed := range feedsMap {
+		if feed.Normalize {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// constructS3FeedKey returns key feed file is stored under. Access token is empty for feeds that are not token protected
+func (svc *Service) constructS3FeedKey(userID string, feedID string, accessToken string) string {
+	if accessToken != "" {
+		feedID += "-" + accessToken
+	}
 	// we want `feeds` to go first to make it easier to assign prefix-based policies
 	return path.Join("feeds", svc.getUserKeyPrefix(userID), feedID)
 }
 
+func (svc *Service) constructS3FeedImageKey(userID string, feedID string) string {
+	return path.Join("feed-images", svc.getUserKeyPrefix(userID), feedID)
+}
+
 func (svc *Service) constructS3EpisodeKey(userID string, filename string) string {
 	// we want `episodes` to go first to make it easier to assign prefix-based policies
 	return path.Join("episodes", svc.getUserKeyPrefix(userID), filename)
@@ -1034,7 +3141,80 @@ func (svc *Service) extractEpisodeS3Key(ep *Episode) string {
 	// that were created before we started saving storage key
 	// TODO: remove this fallback after some time
 	userPrefix := svc.getUserKeyPrefix(ep.UserID)
-	return ep.URL[strings.Index(ep.URL, userPrefix):]
+	idx := strings.Index(ep.URL, userPrefix)
+	if idx == -1 {
+		return ""
+	}
+	return ep.URL[idx:]
+}
+
+// normalizeTags trims and lowercases tags, dropping empty ones and duplicates
+func normalizeTags(tags []string) []string {
+	var result []string
+	for _, t := range tags {
+		t = strings.ToLower(strings.TrimSpace(t))
+		if t == "" || slices.Contains(result, t) {
+			continue
+		}
+		result = append(result, t)
+	}
+	return result
+}
+
+// episodeContentHash identifies episode contents by what they are made of and how.
+// Filepaths are sorted, so that selecting the same files in different order is the same episode
+func episodeContentHash(mediaURL string, variants []string, processingType ProcessingType, format string, bitrateKbps int) string {
+	sortedVariants := slices.Clone(variants)
+	slices.Sort(sortedVariants)
+	h := sha256.New()
+	// every part is terminated with a zero byte, so that parts can't be shifted into one another
+	for _, part := range append([]string{mediaURL, string(processingType), format, strconv.Itoa(bitrateKbps)}, sortedVariants...) {
+		h.Write([]byte(part))
+		h.Write([]byte{0})
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+func mediaryJobParams(
+	mediaURL string,
+	variants []string,
+	processingType ProcessingType,
+	uploadURL string,
+	normalize bool,
+	opts EpisodeOptions,
+) (*mediary.CreateUploadJobParams, error) {
+	switch processingType {
+	case ProcessingTypeConcatenate:
+		format, err := opts.format()
+		if err != nil {
+			return nil, err
+		}
+		return &mediary.CreateUploadJobParams{
+			URL:  mediaURL,
+			Type: mediary.JobTypeConcatenate,
+			Params: mediary.ConcatenateJobParams{
+				Variants:    variants,
+				AudioCodec:  format,
+				BitrateKbps: opts.BitrateKbps,
+				UploadURL:   uploadURL,
+				Normalize:   normalize,
+			},
+		}, nil
+	case ProcessingTypeUploadOriginal:
+		if len(variants) == 0 {
+			return nil, zaperr.New("upload original requires a variant")
+		}
+		return &mediary.CreateUploadJobParams{
+			URL:  mediaURL,
+			Type: mediary.JobTypeUploadOriginal,
+			Params: mediary.UploadOriginalJobParams{
+				Variant:   variants[0],
+				UploadURL: uploadURL,
+			},
+		}, nil
+	default:
+		return nil, zaperr.Wrap(ErrNotImplemented, "unsupported processing type", zap.String("processing_type", string(processingType)))
+	}
 }
 
 func jobStatusToEpisodeStatus(status mediary.JobStatusName) (EpisodeStatus, error) {
@@ -1049,6 +3229,8 @@ func jobStatusToEpisodeStatus(status mediary.JobStatusName) (EpisodeStatus, erro
 		return EpisodeStatusUploading, nil
 	case mediary.JobStatusComplete:
 		return EpisodeStatusComplete, nil
+	case mediary.JobStatusFailed:
+		return EpisodeStatusFailed, nil
 	}
 	return "", zaperr.New("unknown job status", zap.String("status", string(status)))
 }
@@ -3,7 +3,10 @@ package service_test
 import (
 	"context"
 	"database/sql"
+	"errors"
 	migrate "github.com/rubenv/sql-migrate"
+	"net/http"
+	"net/http/httptest"
 	"reflect"
 	"strings"
 	"testing"
@@ -12,6 +15,7 @@ import (
 	"github.com/google/uuid"
 	"github.com/redis/go-redis/v9"
 	"go.uber.org/zap"
+	"golang.org/x/exp/slices"
 	"tg-podcastotron/mediary"
 	"tg-podcastotron/mediary/mediarymocks"
 	"tg-podcastotron/service"
@@ -78,7 +82,7 @@ func TestService(t *testing.T) {
 	obfuscateIDs := func(s string) string {
 		return s
 	}
-	svc := service.New(mockedMediary, repo, mockedS3Store, jobsQueue, "default-feed-title", obfuscateIDs, logger)
+	svc := service.New(mockedMediary, repo, mockedS3Store, jobsQueue, "default-feed-title", "", false, obfuscateIDs, logger)
 
 	mkUserID := func() string {
 		return uuid.Must(uuid.NewRandom()).String()
@@ -140,7 +144,7 @@ func TestService(t *testing.T) {
 		userID := mkUserID()
 
 		// region Create and publish
-		ep := must(svc.CreateEpisode(ctx, userID, "some-media-url", []string{}, "concatenate"))(t)
+		ep := must(svc.CreateEpisode(ctx, userID, "some-media-url", []string{}, "concatenate", nil, service.EpisodeOptions{}, nil))(t)
 
 		defaultFeed := must(svc.DefaultFeed(ctx, userID))(t)
 
@@ -161,11 +165,41 @@ func TestService(t *testing.T) {
 		// endregion
 	})
 
+	t.Run("Move episodes clears prior memberships", func(t *testing.T) {
+		userID := mkUserID()
+
+		feed1 := must(svc.CreateFeed(ctx, userID, "first feed"))(t)
+		feed2 := must(svc.CreateFeed(ctx, userID, "second feed"))(t)
+		feed3 := must(svc.CreateFeed(ctx, userID, "third feed"))(t)
+
+		ep := must(svc.CreateEpisode(ctx, userID, "some-media-url", []string{}, "concatenate", nil, service.EpisodeOptions{}, nil))(t)
+		if err = svc.PublishEpisodes(ctx, userID, []string{ep.ID}, []string{feed1.ID, feed2.ID}); err != nil {
+			t.Fatalf("error publishing episode: %v", err)
+		}
+
+		if err = svc.MoveEpisodes(ctx, userID, []string{ep.ID}, []string{feed2.ID, feed3.ID}); err != nil {
+			t.Fatalf("error moving episode: %v", err)
+		}
+
+		epFeedsMap := must(svc.GetPublishedFeedsMap(ctx, userID, []string{ep.ID}))(t)
+		epFeedIDs := epFeedsMap[ep.ID]
+		slices.Sort(epFeedIDs)
+		expected := []string{feed2.ID, feed3.ID}
+		slices.Sort(expected)
+		if !reflect.DeepEqual(epFeedIDs, expected) {
+			t.Fatalf("expected episode to be published to %v only, got %v", expected, epFeedIDs)
+		}
+
+		if err = svc.MoveEpisodes(ctx, userID, []string{ep.ID}, nil); err == nil {
+			t.Fatalf("expected moving episode to no feeds to fail")
+		}
+	})
+
 	t.Run("Double publish is not allowed", func(t *testing.T) {
 		userID := mkUserID()
 
 		// region Create and publish twice
-		ep := must(svc.CreateEpisode(ctx, userID, "some-media-url", []string{}, "concatenate"))(t)
+		ep := must(svc.CreateEpisode(ctx, userID, "some-media-url", []string{}, "concatenate", nil, service.EpisodeOptions{}, nil))(t)
 
 		defaultFeed := must(svc.DefaultFeed(ctx, userID))(t)
 
@@ -194,7 +228,7 @@ func TestService(t *testing.T) {
 		// region Create and publish 10 episodes feed1 and feed2
 		episodeIDs := make([]string, 10)
 		for i := 0; i < 10; i++ {
-			ep := must(svc.CreateEpisode(ctx, userID, "some-media-url", []string{}, "concatenate"))(t)
+			ep := must(svc.CreateEpisode(ctx, userID, "some-media-url", []string{}, "concatenate", nil, service.EpisodeOptions{}, nil))(t)
 
 			var f *service.Feed
 			if i%2 == 0 {
@@ -213,7 +247,7 @@ func TestService(t *testing.T) {
 
 		// region Prepare feed3 with one existing episode
 		feed3 := must(svc.CreateFeed(ctx, userID, "third feed of user-1"))(t)
-		feed3ep := must(svc.CreateEpisode(ctx, userID, "some-media-url", []string{}, "concatenate"))(t)
+		feed3ep := must(svc.CreateEpisode(ctx, userID, "some-media-url", []string{}, "concatenate", nil, service.EpisodeOptions{}, nil))(t)
 		if err = svc.PublishEpisodes(ctx, userID, []string{feed3ep.ID}, []string{feed3.ID}); err != nil {
 			t.Fatalf("error publishing episode: %v", err)
 		}
@@ -311,7 +345,7 @@ func TestService(t *testing.T) {
 		userID := mkUserID()
 
 		feed := must(svc.CreateFeed(ctx, userID, "feed to be deleted"))(t)
-		ep1 := must(svc.CreateEpisode(ctx, userID, "some-media-url", []string{}, "concatenate"))(t)
+		ep1 := must(svc.CreateEpisode(ctx, userID, "some-media-url", []string{}, "concatenate", nil, service.EpisodeOptions{}, nil))(t)
 		if err = svc.PublishEpisodes(ctx, userID, []string{ep1.ID}, []string{feed.ID}); err != nil {
 			t.Fatalf("error publishing episode1: %v", err)
 		}
@@ -331,12 +365,12 @@ func TestService(t *testing.T) {
 
 		feed := must(svc.CreateFeed(ctx, userID, "feed to be deleted"))(t)
 
-		ep1 := must(svc.CreateEpisode(ctx, userID, "some-media-url", []string{}, "concatenate"))(t)
+		ep1 := must(svc.CreateEpisode(ctx, userID, "some-media-url", []string{}, "concatenate", nil, service.EpisodeOptions{}, nil))(t)
 		if err = svc.PublishEpisodes(ctx, userID, []string{ep1.ID}, []string{feed.ID}); err != nil {
 			t.Fatalf("error publishing episode1: %v", err)
 		}
 
-		ep2 := must(svc.CreateEpisode(ctx, userID, "some-media-url", []string{}, "concatenate"))(t)
+		ep2 := must(svc.CreateEpisode(ctx, userID, "some-media-url", []string{}, "concatenate", nil, service.EpisodeOptions{}, nil))(t)
 		if err = svc.PublishEpisodes(ctx, userID, []string{ep2.ID}, []string{feed.ID}); err != nil {
 			t.Fatalf("error publishing episode2: %v", err)
 		}
@@ -354,30 +388,25 @@ func TestService(t *testing.T) {
 		if len(episodes) != 0 {
 			t.Fatalf("expected 0 episodes, got %d", len(episodes))
 		}
+		// episodes go to trash, so that they can be restored until purged
+		deletedEpisodes := must(svc.ListDeletedEpisodes(ctx, userID))(t)
+		if len(deletedEpisodes) != 2 {
+			t.Fatalf("expected 2 episodes in trash, got %d", len(deletedEpisodes))
+		}
 
 		feedWasDeleted := false
-		ep1WasDeleted := false
-		ep2WasDeleted := false
 		for _, call := range mockedS3Store.DeleteCalls() {
 			switch {
 			case call.Key == "feeds/"+userID+"/2":
 				feedWasDeleted = true
-			case strings.Contains(ep1.URL, call.Key):
-				ep1WasDeleted = true
-			case strings.Contains(ep2.URL, call.Key):
-				ep2WasDeleted = true
+			case strings.Contains(ep1.URL, call.Key), strings.Contains(ep2.URL, call.Key):
+				t.Fatalf("expected episode files to be kept until purge, but %s was deleted", call.Key)
 			default:
 			}
 		}
 		if !feedWasDeleted {
 			t.Fatalf("expected feed to be deleted from s3 store, but it wasn't")
 		}
-		if !ep1WasDeleted {
-			t.Fatalf("expected episode1 to be deleted from s3 store, but it wasn't")
-		}
-		if !ep2WasDeleted {
-			t.Fatalf("expected episode2 to be deleted from s3 store, but it wasn't")
-		}
 	})
 
 	t.Run("Default feed can not be deleted", func(t *testing.T) {
@@ -393,7 +422,7 @@ func TestService(t *testing.T) {
 	t.Run("Delete episodes with missing IDs is allowed", func(t *testing.T) {
 		userID := mkUserID()
 
-		ep := must(svc.CreateEpisode(ctx, userID, "some-media-url", []string{}, "concatenate"))(t)
+		ep := must(svc.CreateEpisode(ctx, userID, "some-media-url", []string{}, "concatenate", nil, service.EpisodeOptions{}, nil))(t)
 
 		epMap := must(svc.GetEpisodesMap(ctx, userID, []string{ep.ID}))(t)
 		if len(epMap) != 1 || epMap[ep.ID] == nil {
@@ -404,11 +433,243 @@ func TestService(t *testing.T) {
 			t.Fatalf("expected no error deleting episodes, got %v", err)
 		}
 
-		epMap = must(svc.GetEpisodesMap(ctx, userID, []string{ep.ID}))(t)
-		if len(epMap) != 0 {
+		if _, err = svc.GetEpisodesMap(ctx, userID, []string{ep.ID}); !errors.Is(err, service.ErrEpisodeNotFound) {
 			t.Fatalf("expected episode to be deleted, but it wasn't")
 		}
 	})
+
+	t.Run("Tags provided at creation are persisted", func(t *testing.T) {
+		userID := mkUserID()
+
+		ep := must(svc.CreateEpisode(ctx, userID, "some-media-url", []string{}, "concatenate", []string{" Music ", "live", "music"}, service.EpisodeOptions{}, nil))(t)
+
+		epMap := must(svc.GetEpisodesMap(ctx, userID, []string{ep.ID}))(t)
+		if !reflect.DeepEqual(epMap[ep.ID].Tags, []string{"music", "live"}) {
+			t.Fatalf("expected episode tags to be [music live], got %v", epMap[ep.ID].Tags)
+		}
+	})
+
+	t.Run("Tag added to several episodes keeps their existing tags", func(t *testing.T) {
+		userID := mkUserID()
+
+		ep1 := must(svc.CreateEpisode(ctx, userID, "some-media-url", []string{}, "concatenate", []string{"music"}, service.EpisodeOptions{}, nil))(t)
+		ep2 := must(svc.CreateEpisode(ctx, userID, "some-media-url", []string{}, "concatenate", nil, service.EpisodeOptions{}, nil))(t)
+		ep3 := must(svc.CreateEpisode(ctx, userID, "some-media-url", []string{}, "concatenate", []string{"live", "favorite"}, service.EpisodeOptions{}, nil))(t)
+		epIDs := []string{ep1.ID, ep2.ID, ep3.ID}
+
+		if err := svc.SetEpisodeTags(ctx, userID, epIDs, []string{"Favorite"}, nil); err != nil {
+			t.Fatalf("failed to add tag: %v", err)
+		}
+
+		epMap := must(svc.GetEpisodesMap(ctx, userID, epIDs))(t)
+		expectedTags := map[string][]string{
+			ep1.ID: {"music", "favorite"},
+			ep2.ID: {"favorite"},
+			ep3.ID: {"live", "favorite"},
+		}
+		for epID, expected := range expectedTags {
+			if !reflect.DeepEqual(epMap[epID].Tags, expected) {
+				t.Fatalf("expected episode %s tags to be %v, got %v", epID, expected, epMap[epID].Tags)
+			}
+		}
+	})
+
+	t.Run("Mark feed as ephemeral keeping current episodes pins them", func(t *testing.T) {
+		userID := mkUserID()
+
+		feed := must(svc.CreateFeed(ctx, userID, "some feed"))(t)
+		if err := svc.MarkFeedAsPermanent(ctx, userID, feed.ID); err != nil {
+			t.Fatal(err)
+		}
+		existingEp := must(svc.CreateEpisode(ctx, userID, "some-media-url", []string{}, "concatenate", nil, service.EpisodeOptions{}, nil))(t)
+		if err := svc.PublishEpisodes(ctx, userID, []string{existingEp.ID}, []string{feed.ID}); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := svc.MarkFeedAsEphemeral(ctx, userID, feed.ID, true); err != nil {
+			t.Fatal(err)
+		}
+
+		futureEp := must(svc.CreateEpisode(ctx, userID, "some-media-url", []string{}, "concatenate", nil, service.EpisodeOptions{}, nil))(t)
+		if err := svc.PublishEpisodes(ctx, userID, []string{futureEp.ID}, []string{feed.ID}); err != nil {
+			t.Fatal(err)
+		}
+
+		feed = must(svc.GetFeed(ctx, userID, feed.ID))(t)
+		if feed.IsPermanent {
+			t.Fatalf("expected feed to become ephemeral")
+		}
+		epMap := must(svc.GetEpisodesMap(ctx, userID, []string{existingEp.ID, futureEp.ID}))(t)
+		if !epMap[existingEp.ID].Pinned {
+			t.Fatalf("expected existing episode to be pinned")
+		}
+		if epMap[futureEp.ID].Pinned {
+			t.Fatalf("expected episode added afterwards not to be pinned")
+		}
+	})
+
+	t.Run("Get episodes map with missing IDs", func(t *testing.T) {
+		userID := mkUserID()
+
+		ep := must(svc.CreateEpisode(ctx, userID, "some-media-url", []string{}, "concatenate", nil, service.EpisodeOptions{}, nil))(t)
+
+		_, err := svc.GetEpisodesMap(ctx, userID, []string{"missing-id-1", ep.ID, "missing-id-2"})
+		if !errors.Is(err, service.ErrEpisodeNotFound) {
+			t.Fatalf("expected ErrEpisodeNotFound, got %v", err)
+		}
+		var notFoundErr *service.EpisodesNotFoundError
+		if !errors.As(err, &notFoundErr) {
+			t.Fatalf("expected EpisodesNotFoundError, got %T", err)
+		}
+		if !reflect.DeepEqual(notFoundErr.IDs, []string{"missing-id-1", "missing-id-2"}) {
+			t.Fatalf("expected missing ids to be [missing-id-1 missing-id-2], got %v", notFoundErr.IDs)
+		}
+	})
+
+	t.Run("Split concatenated episode", func(t *testing.T) {
+		userID := mkUserID()
+
+		ep := must(svc.CreateEpisode(ctx, userID, "some-media-url", []string{"dir/01.mp3", "dir/02.mp3"}, "concatenate", nil, service.EpisodeOptions{}, nil))(t)
+		feed := must(svc.CreateFeed(ctx, userID, "some feed"))(t)
+		if err := svc.PublishEpisodes(ctx, userID, []string{ep.ID}, []string{feed.ID}); err != nil {
+			t.Fatal(err)
+		}
+
+		splitEpisodes := must(svc.SplitEpisode(ctx, userID, ep.ID))(t)
+		if len(splitEpisodes) != 2 {
+			t.Fatalf("expected split to produce 2 episodes, got %d", len(splitEpisodes))
+		}
+		for i, expectedFilepath := range []string{"dir/01.mp3", "dir/02.mp3"} {
+			if !reflect.DeepEqual(splitEpisodes[i].SourceFilepaths, []string{expectedFilepath}) {
+				t.Fatalf("expected episode %d to have source file %s, got %v", i, expectedFilepath, splitEpisodes[i].SourceFilepaths)
+			}
+		}
+
+		episodes := must(svc.ListUserEpisodes(ctx, userID))(t)
+		if len(episodes) != 2 {
+			t.Fatalf("expected original episode to be removed leaving 2 episodes, got %d", len(episodes))
+		}
+		for _, e := range episodes {
+			if e.ID == ep.ID {
+				t.Fatalf("expected original episode %s to be deleted", ep.ID)
+			}
+		}
+
+		feedEpisodes := must(svc.ListFeedEpisodes(ctx, userID, feed.ID))(t)
+		if len(feedEpisodes) != 2 {
+			t.Fatalf("expected split episodes to take original's place in its feed, got %d episodes", len(feedEpisodes))
+		}
+
+		singleFileEp := splitEpisodes[0]
+		if _, err := svc.SplitEpisode(ctx, userID, singleFileEp.ID); !errors.Is(err, service.ErrNothingToSplit) {
+			t.Fatalf("expected ErrNothingToSplit for single file episode, got %v", err)
+		}
+	})
+
+	t.Run("Merge episodes of the same torrent", func(t *testing.T) {
+		userID := mkUserID()
+
+		first := must(svc.CreateEpisode(ctx, userID, "some-torrent-url", []string{"dir/01.mp3"}, service.ProcessingTypeUploadOriginal, nil, service.EpisodeOptions{}, nil))(t)
+		second := must(svc.CreateEpisode(ctx, userID, "some-torrent-url", []string{"dir/02.mp3"}, service.ProcessingTypeUploadOriginal, nil, service.EpisodeOptions{}, nil))(t)
+		other := must(svc.CreateEpisode(ctx, userID, "other-torrent-url", []string{"dir/03.mp3"}, service.ProcessingTypeUploadOriginal, nil, service.EpisodeOptions{}, nil))(t)
+		feed := must(svc.CreateFeed(ctx, userID, "some feed"))(t)
+		if err := svc.PublishEpisodes(ctx, userID, []string{second.ID}, []string{feed.ID}); err != nil {
+			t.Fatal(err)
+		}
+
+		if _, err := svc.MergeEpisodes(ctx, userID, []string{first.ID, other.ID}, ""); !errors.Is(err, service.ErrSourcesDiffer) {
+			t.Fatalf("expected episodes of different torrents not to be merged, got %v", err)
+		}
+
+		merged := must(svc.MergeEpisodes(ctx, userID, []string{first.ID, second.ID}, "Whole album"))(t)
+		if merged.Title != "Whole album" {
+			t.Fatalf("expected merged episode to get given title, got %s", merged.Title)
+		}
+		if !reflect.DeepEqual(merged.SourceFilepaths, []string{"dir/01.mp3", "dir/02.mp3"}) {
+			t.Fatalf("expected merged episode to consist of files of both episodes in order, got %v", merged.SourceFilepaths)
+		}
+
+		calls := mockedMediary.CreateUploadJobCalls()
+		lastJob := calls[len(calls)-1].Params
+		if lastJob.Type != mediary.JobTypeConcatenate {
+			t.Fatalf("expected concatenate job, got %s", lastJob.Type)
+		}
+		if params := lastJob.Params.(mediary.ConcatenateJobParams); !reflect.DeepEqual(params.Variants, []string{"dir/01.mp3", "dir/02.mp3"}) {
+			t.Fatalf("expected both files to be concatenated, got %v", params.Variants)
+		}
+
+		episodes := must(svc.ListUserEpisodes(ctx, userID))(t)
+		if len(episodes) != 2 {
+			t.Fatalf("expected originals to be replaced by merged episode, got %d episodes", len(episodes))
+		}
+		feedEpisodes := must(svc.ListFeedEpisodes(ctx, userID, feed.ID))(t)
+		if len(feedEpisodes) != 1 || feedEpisodes[0].ID != merged.ID {
+			t.Fatalf("expected merged episode to take originals' place in their feed, got %v", feedEpisodes)
+		}
+	})
+
+	t.Run("Import RSS feed", func(t *testing.T) {
+		userID := mkUserID()
+
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "application/rss+xml")
+			_, _ = w.Write([]byte(`<?xml version="1.0" encoding="UTF-8"?>
+<rss version="2.0" xmlns:itunes="http://www.itunes.com/dtds/podcast-1.0.dtd">
+	<channel>
+		<title>Imported podcast</title>
+		<item>
+			<title>Second episode</title>
+			<pubDate>Tue, 02 Jan 2024 10:00:00 +0000</pubDate>
+			<itunes:duration>01:02:03</itunes:duration>
+			<enclosure url="https://old-host.example.com/2.mp3" length="2000" type="audio/mpeg"/>
+		</item>
+		<item>
+			<title>First episode</title>
+			<pubDate>Mon, 01 Jan 2024 10:00:00 +0000</pubDate>
+			<itunes:duration>90</itunes:duration>
+			<enclosure url="https://old-host.example.com/1.mp3" length="1000" type="audio/mpeg"/>
+		</item>
+		<item>
+			<title>Item without enclosure is skipped</title>
+		</item>
+	</channel>
+</rss>`))
+		}))
+		defer srv.Close()
+
+		feed := must(svc.ImportRSSFeed(ctx, userID, srv.URL))(t)
+		if feed.Title != "Imported podcast" {
+			t.Fatalf("expected imported feed title to be 'Imported podcast', got %s", feed.Title)
+		}
+
+		episodes := must(svc.ListFeedEpisodes(ctx, userID, feed.ID))(t)
+		if len(episodes) != 2 {
+			t.Fatalf("expected imported feed to have 2 episodes, got %d", len(episodes))
+		}
+
+		first, second := episodes[0], episodes[1]
+		if first.Title != "First episode" || second.Title != "Second episode" {
+			t.Fatalf("expected episodes to be imported oldest first, got %s, %s", first.Title, second.Title)
+		}
+		if first.URL != "https://old-host.example.com/1.mp3" {
+			t.Fatalf("expected episode to point at original enclosure, got %s", first.URL)
+		}
+		if first.Status != service.EpisodeStatusComplete {
+			t.Fatalf("expected imported episode to be complete, got %s", first.Status)
+		}
+		if first.FileLenBytes != 1000 {
+			t.Fatalf("expected imported episode to be 1000 bytes long, got %d", first.FileLenBytes)
+		}
+		if first.Duration != 90*time.Second {
+			t.Fatalf("expected imported episode duration to be 90s, got %s", first.Duration)
+		}
+		if second.Duration != time.Hour+2*time.Minute+3*time.Second {
+			t.Fatalf("expected imported episode duration to be 1h2m3s, got %s", second.Duration)
+		}
+		if !first.CreatedAt.Equal(time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC)) {
+			t.Fatalf("expected imported episode to keep its pubDate, got %s", first.CreatedAt)
+		}
+	})
 }
 
 func must[R any](result R, err error) func(t *testing.T) R {
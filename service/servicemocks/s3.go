@@ -8,6 +8,7 @@ import (
 	"io"
 	"sync"
 	"tg-podcastotron/service"
+	"time"
 )
 
 // Ensure, that MockS3Store does implement service.S3Store.
@@ -20,12 +21,24 @@ var _ service.S3Store = &MockS3Store{}
 //
 //		// make and configure a mocked service.S3Store
 //		mockedS3Store := &MockS3Store{
+//			CopyFunc: func(ctx context.Context, srcKey string, dstKey string) error {
+//				panic("mock out the Copy method")
+//			},
 //			DeleteFunc: func(ctx context.Context, key string) error {
 //				panic("mock out the Delete method")
 //			},
+//			GetFunc: func(ctx context.Context, key string) ([]byte, error) {
+//				panic("mock out the Get method")
+//			},
+//			MetadataFunc: func(ctx context.Context, key string) (map[string]string, error) {
+//				panic("mock out the Metadata method")
+//			},
 //			PreSignedURLFunc: func(key string) (string, error) {
 //				panic("mock out the PreSignedURL method")
 //			},
+//			PreSignedURLWithTTLFunc: func(key string, ttl time.Duration) (string, error) {
+//				panic("mock out the PreSignedURLWithTTL method")
+//			},
 //			PutFunc: func(ctx context.Context, key string, dataReader io.ReadSeeker, opts ...func(*service.PutOptions)) error {
 //				panic("mock out the Put method")
 //			},
@@ -39,12 +52,24 @@ var _ service.S3Store = &MockS3Store{}
 //
 //	}
 type MockS3Store struct {
+	// CopyFunc mocks the Copy method.
+	CopyFunc func(ctx context.Context, srcKey string, dstKey string) error
+
 	// DeleteFunc mocks the Delete method.
 	DeleteFunc func(ctx context.Context, key string) error
 
+	// GetFunc mocks the Get method.
+	GetFunc func(ctx context.Context, key string) ([]byte, error)
+
+	// MetadataFunc mocks the Metadata method.
+	MetadataFunc func(ctx context.Context, key string) (map[string]string, error)
+
 	// PreSignedURLFunc mocks the PreSignedURL method.
 	PreSignedURLFunc func(key string) (string, error)
 
+	// PreSignedURLWithTTLFunc mocks the PreSignedURLWithTTL method.
+	PreSignedURLWithTTLFunc func(key string, ttl time.Duration) (string, error)
+
 	// PutFunc mocks the Put method.
 	PutFunc func(ctx context.Context, key string, dataReader io.ReadSeeker, opts ...func(*service.PutOptions)) error
 
@@ -53,6 +78,15 @@ type MockS3Store struct {
 
 	// calls tracks calls to the methods.
 	calls struct {
+		// Copy holds details about calls to the Copy method.
+		Copy []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// SrcKey is the srcKey argument value.
+			SrcKey string
+			// DstKey is the dstKey argument value.
+			DstKey string
+		}
 		// Delete holds details about calls to the Delete method.
 		Delete []struct {
 			// Ctx is the ctx argument value.
@@ -60,11 +94,32 @@ type MockS3Store struct {
 			// Key is the key argument value.
 			Key string
 		}
+		// Get holds details about calls to the Get method.
+		Get []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// Key is the key argument value.
+			Key string
+		}
+		// Metadata holds details about calls to the Metadata method.
+		Metadata []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// Key is the key argument value.
+			Key string
+		}
 		// PreSignedURL holds details about calls to the PreSignedURL method.
 		PreSignedURL []struct {
 			// Key is the key argument value.
 			Key string
 		}
+		// PreSignedURLWithTTL holds details about calls to the PreSignedURLWithTTL method.
+		PreSignedURLWithTTL []struct {
+			// Key is the key argument value.
+			Key string
+			// Ttl is the ttl argument value.
+			Ttl time.Duration
+		}
 		// Put holds details about calls to the Put method.
 		Put []struct {
 			// Ctx is the ctx argument value.
@@ -82,10 +137,54 @@ type MockS3Store struct {
 			Key string
 		}
 	}
-	lockDelete       sync.RWMutex
-	lockPreSignedURL sync.RWMutex
-	lockPut          sync.RWMutex
-	lockURL          sync.RWMutex
+	lockCopy                sync.RWMutex
+	lockDelete              sync.RWMutex
+	lockGet                 sync.RWMutex
+	lockMetadata            sync.RWMutex
+	lockPreSignedURL        sync.RWMutex
+	lockPreSignedURLWithTTL sync.RWMutex
+	lockPut                 sync.RWMutex
+	lockURL                 sync.RWMutex
+}
+
+// Copy calls CopyFunc.
+func (mock *MockS3Store) Copy(ctx context.Context, srcKey string, dstKey string) error {
+	if mock.CopyFunc == nil {
+		panic("MockS3Store.CopyFunc: method is nil but S3Store.Copy was just called")
+	}
+	callInfo := struct {
+		Ctx    context.Context
+		SrcKey string
+		DstKey string
+	}{
+		Ctx:    ctx,
+		SrcKey: srcKey,
+		DstKey: dstKey,
+	}
+	mock.lockCopy.Lock()
+	mock.calls.Copy = append(mock.calls.Copy, callInfo)
+	mock.lockCopy.Unlock()
+	return mock.CopyFunc(ctx, srcKey, dstKey)
+}
+
+// CopyCalls gets all the calls that were made to Copy.
+// Check the length with:
+//
+//	len(mockedS3Store.CopyCalls())
+func (mock *MockS3Store) CopyCalls() []struct {
+	Ctx    context.Context
+	SrcKey string
+	DstKey string
+} {
+	var calls []struct {
+		Ctx    context.Context
+		SrcKey string
+		DstKey string
+	}
+	mock.lockCopy.RLock()
+	calls = mock.calls.Copy
+	mock.lockCopy.RUnlock()
+	return calls
 }
 
 // Delete calls DeleteFunc.
@@ -124,6 +223,78 @@ func (mock *MockS3Store) DeleteCalls() []struct {
 	return calls
 }
 
+// Get calls GetFunc.
+func (mock *MockS3Store) Get(ctx context.Context, key string) ([]byte, error) {
+	if mock.GetFunc == nil {
+		panic("MockS3Store.GetFunc: method is nil but S3Store.Get was just called")
+	}
+	callInfo := struct {
+		Ctx context.Context
+		Key string
+	}{
+		Ctx: ctx,
+		Key: key,
+	}
+	mock.lockGet.Lock()
+	mock.calls.Get = append(mock.calls.Get, callInfo)
+	mock.lockGet.Unlock()
+	return mock.GetFunc(ctx, key)
+}
+
+// GetCalls gets all the calls that were made to Get.
+// Check the length with:
+//
+//	len(mockedS3Store.GetCalls())
+func (mock *MockS3Store) GetCalls() []struct {
+	Ctx context.Context
+	Key string
+} {
+	var calls []struct {
+		Ctx context.Context
+		Key string
+	}
+	mock.lockGet.RLock()
+	calls = mock.calls.Get
+	mock.lockGet.RUnlock()
+	return calls
+}
+
+// Metadata calls MetadataFunc.
+func (mock *MockS3Store) Metadata(ctx context.Context, key string) (map[string]string, error) {
+	if mock.MetadataFunc == nil {
+		panic("MockS3Store.MetadataFunc: method is nil but S3Store.Metadata was just called")
+	}
+	callInfo := struct {
+		Ctx context.Context
+		Key string
+	}{
+		Ctx: ctx,
+		Key: key,
+	}
+	mock.lockMetadata.Lock()
+	mock.calls.Metadata = append(mock.calls.Metadata, callInfo)
+	mock.lockMetadata.Unlock()
+	return mock.MetadataFunc(ctx, key)
+}
+
+// MetadataCalls gets all the calls that were made to Metadata.
+// Check the length with:
+//
+//	len(mockedS3Store.MetadataCalls())
+func (mock *MockS3Store) MetadataCalls() []struct {
+	Ctx context.Context
+	Key string
+} {
+	var calls []struct {
+		Ctx context.Context
+		Key string
+	}
+	mock.lockMetadata.RLock()
+	calls = mock.calls.Metadata
+	mock.lockMetadata.RUnlock()
+	return calls
+}
+
 // PreSignedURL calls PreSignedURLFunc.
 func (mock *MockS3Store) PreSignedURL(key string) (string, error) {
 	if mock.PreSignedURLFunc == nil {
@@ -156,6 +327,42 @@ func (mock *MockS3Store) PreSignedURLCalls() []struct {
 	return calls
 }
 
+// PreSignedURLWithTTL calls PreSignedURLWithTTLFunc.
+func (mock *MockS3Store) PreSignedURLWithTTL(key string, ttl time.Duration) (string, error) {
+	if mock.PreSignedURLWithTTLFunc == nil {
+		panic("MockS3Store.PreSignedURLWithTTLFunc: method is nil but S3Store.PreSignedURLWithTTL was just called")
+	}
+	callInfo := struct {
+		Key string
+		Ttl time.Duration
+	}{
+		Key: key,
+		Ttl: ttl,
+	}
+	mock.lockPreSignedURLWithTTL.Lock()
+	mock.calls.PreSignedURLWithTTL = append(mock.calls.PreSignedURLWithTTL, callInfo)
+	mock.lockPreSignedURLWithTTL.Unlock()
+	return mock.PreSignedURLWithTTLFunc(key, ttl)
+}
+
+// PreSignedURLWithTTLCalls gets all the calls that were made to PreSignedURLWithTTL.
+// Check the length with:
+//
+//	len(mockedS3Store.PreSignedURLWithTTLCalls())
+func (mock *MockS3Store) PreSignedURLWithTTLCalls() []struct {
+	Key string
+	Ttl time.Duration
+} {
+	var calls []struct {
+		Key string
+		Ttl time.Duration
+	}
+	mock.lockPreSignedURLWithTTL.RLock()
+	calls = mock.calls.PreSignedURLWithTTL
+	mock.lockPreSignedURLWithTTL.RUnlock()
+	return calls
+}
+
 // Put calls PutFunc.
 func (mock *MockS3Store) Put(ctx context.Context, key string, dataReader io.ReadSeeker, opts ...func(*service.PutOptions)) error {
 	if mock.PutFunc == nil {
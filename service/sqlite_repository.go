@@ -45,12 +45,33 @@ func (r *sqliteRepository) Transaction(ctx context.Context, fn func(ctx context.
 
 func (r *sqliteRepository) dbFromContext(ctx context.Context) sqlx.ExtContext {
 	if tx, ok := ctx.Value("tx").(*sqlx.Tx); ok {
-		return tx
+		return rebindingExt{tx}
 	} else {
-		return r.db
+		return rebindingExt{r.db}
 	}
 }
 
+// rebindingExt rebinds ? placeholders to the ones driver expects, so that queries are written once for every database
+type rebindingExt struct {
+	sqlx.ExtContext
+}
+
+func (e rebindingExt) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	return e.ExtContext.QueryContext(ctx, e.Rebind(query), args...)
+}
+
+func (e rebindingExt) QueryxContext(ctx context.Context, query string, args ...interface{}) (*sqlx.Rows, error) {
+	return e.ExtContext.QueryxContext(ctx, e.Rebind(query), args...)
+}
+
+func (e rebindingExt) QueryRowxContext(ctx context.Context, query string, args ...interface{}) *sqlx.Row {
+	return e.ExtContext.QueryRowxContext(ctx, e.Rebind(query), args...)
+}
+
+func (e rebindingExt) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	return e.ExtContext.ExecContext(ctx, e.Rebind(query), args...)
+}
+
 // endregion
 
 // region local ids
@@ -61,7 +82,7 @@ func (r *sqliteRepository) NextEpisodeID(ctx context.Context, userID string) (ep
 	var episodeID int64
 	err = db.QueryRowxContext(ctx, `
 		INSERT INTO local_ids (user_id, episode_id, feed_id) VALUES (?, 1, 0)
-		ON CONFLICT (user_id) DO UPDATE SET episode_id=episode_id+1
+		ON CONFLICT (user_id) DO UPDATE SET episode_id=local_ids.episode_id+1
 		RETURNING episode_id
 	`, userID).Scan(&episodeID)
 	if err != nil {
@@ -75,23 +96,15 @@ func (r *sqliteRepository) NextFeedID(ctx context.Context, userID string) (feedI
 	db := r.dbFromContext(ctx)
 
 	var feedIDInt int64
-	rows, err := db.QueryxContext(ctx, `
+	err = db.QueryRowxContext(ctx, `
 		INSERT INTO local_ids (user_id, feed_id, episode_id) VALUES (?, 1, 0)
-		ON CONFLICT (user_id) DO UPDATE SET feed_id=feed_id+1
+		ON CONFLICT (user_id) DO UPDATE SET feed_id=local_ids.feed_id+1
 		RETURNING feed_id
-	`, userID, &feedIDInt)
+	`, userID).Scan(&feedIDInt)
 	if err != nil {
 		return "", zaperr.Wrap(err, "failed to insert")
 	}
 
-	defer func() { _ = rows.Close() }()
-	for rows.Next() {
-		if err := rows.Scan(&feedIDInt); err != nil {
-			return "", zaperr.Wrap(err, "failed to scan")
-		}
-		break
-	}
-
 	return strconv.FormatInt(feedIDInt, 10), nil
 }
 
@@ -104,13 +117,26 @@ func (r *sqliteRepository) SaveFeed(ctx context.Context, feed *Feed) (*Feed, err
 	dbFeed := dbFeed{}.FromBusinessModel(feed)
 
 	if _, err := sqlx.NamedExecContext(ctx, db, `
-			INSERT INTO feeds (id, user_id, title, url, is_permanent) 
-			VALUES (:id, :user_id, :title, :url, :is_permanent)
+			INSERT INTO feeds (id, user_id, title, url, is_permanent, copyright, normalize, max_total_bytes, image_url, sort_order, max_episodes, ttl_minutes, skip_hours, skip_days, token_protected, access_token, description, author) 
+			VALUES (:id, :user_id, :title, :url, :is_permanent, :copyright, :normalize, :max_total_bytes, :image_url, :sort_order, :max_episodes, :ttl_minutes, :skip_hours, :skip_days, :token_protected, :access_token, :description, :author)
 			ON CONFLICT (user_id, id) DO UPDATE SET 
 				user_id=:user_id,
 				title=:title,
 				url=:url,
-				is_permanent=:is_permanent
+				is_permanent=:is_permanent,
+				copyright=:copyright,
+				normalize=:normalize,
+				max_total_bytes=:max_total_bytes,
+				image_url=:image_url,
+				sort_order=:sort_order,
+				max_episodes=:max_episodes,
+				ttl_minutes=:ttl_minutes,
+				skip_hours=:skip_hours,
+				skip_days=:skip_days,
+				token_protected=:token_protected,
+				access_token=:access_token,
+				description=:description,
+				author=:author
 	`, dbFeed); err != nil {
 		return nil, zaperr.Wrap(err, "failed to insert feed")
 	}
@@ -186,8 +212,9 @@ func (r *sqliteRepository) GetFeedsMap(ctx context.Context, userID string, feedI
 
 func (r *sqliteRepository) ListUserFeeds(ctx context.Context, userID string) ([]*Feed, error) {
 	var dbFeeds []dbFeed
+	// feeds that were never reordered have zero sort order and go last
 	if err := sqlx.SelectContext(ctx, r.dbFromContext(ctx), &dbFeeds, `
-		SELECT * FROM feeds WHERE user_id = ? ORDER BY id`, userID,
+		SELECT * FROM feeds WHERE user_id = ? ORDER BY sort_order = 0, sort_order, CAST(id AS INTEGER)`, userID,
 	); err != nil {
 		return nil, zaperr.Wrap(err, "failed to list user feeds")
 	}
@@ -232,7 +259,14 @@ func (r *sqliteRepository) SaveEpisode(ctx context.Context, ep *Episode) (*Episo
 				duration, 
 				file_len_bytes, 
 				format, 
-				storage_key
+				storage_key,
+				tags,
+				pinned,
+				pub_date,
+				explicit,
+				segment_durations,
+				chapters_url,
+				content_hash
 		) VALUES (
 				:id,
 				:user_id,
@@ -247,7 +281,14 @@ func (r *sqliteRepository) SaveEpisode(ctx context.Context, ep *Episode) (*Episo
 				:duration,
 				:file_len_bytes,
 				:format,
-				:storage_key
+				:storage_key,
+				:tags,
+				:pinned,
+				:pub_date,
+				:explicit,
+				:segment_durations,
+				:chapters_url,
+				:content_hash
 	  	) ON CONFLICT (user_id, id) DO UPDATE SET
 				title = :title,
 				updated_at = :updated_at,
@@ -259,7 +300,14 @@ func (r *sqliteRepository) SaveEpisode(ctx context.Context, ep *Episode) (*Episo
 				duration = :duration,
 				file_len_bytes = :file_len_bytes,
 				format = :format,
-				storage_key = :storage_key`, dbEp,
+				storage_key = :storage_key,
+				tags = :tags,
+				pinned = :pinned,
+				pub_date = :pub_date,
+				explicit = :explicit,
+				segment_durations = :segment_durations,
+				chapters_url = :chapters_url,
+				content_hash = :content_hash`, dbEp,
 	); err != nil {
 		return nil, zaperr.Wrap(err, "failed to insert ep")
 	}
@@ -272,11 +320,63 @@ func (r *sqliteRepository) SaveEpisode(ctx context.Context, ep *Episode) (*Episo
 	return ep, nil
 }
 
+// maxQueryVariables is SQLITE_MAX_VARIABLE_NUMBER of sqlite builds older than 3.32, the lowest limit among supported databases
+const maxQueryVariables = 999
+
+// SaveEpisodes upserts episodes with as few queries as variables limit allows.
+// It takes several queries for many episodes, so call it in Transaction to save all or nothing
+func (r *sqliteRepository) SaveEpisodes(ctx context.Context, episodes []*Episode) error {
+	db := r.dbFromContext(ctx)
+
+	columns := []string{
+		"id", "user_id", "title", "created_at", "updated_at", "source_url", "source_filepaths", "mediary_id", "url",
+		"status", "duration", "file_len_bytes", "format", "storage_key", "tags", "pinned", "pub_date", "explicit",
+		"segment_durations", "chapters_url", "content_hash",
+	}
+	var updates []string
+	for _, c := range columns {
+		if c != "id" && c != "user_id" && c != "created_at" {
+			updates = append(updates, fmt.Sprintf("%s = excluded.%s", c, c))
+		}
+	}
+	placeholders := "(" + strings.TrimSuffix(strings.Repeat("?, ", len(columns)), ", ") + ")"
+
+	batchSize := maxQueryVariables / len(columns)
+	for start := 0; start < len(episodes); start += batchSize {
+		batch := episodes[start:min(start+batchSize, len(episodes))]
+
+		values := make([]string, 0, len(batch))
+		args := make([]interface{}, 0, len(batch)*len(columns))
+		for _, ep := range batch {
+			dbEp, err := dbEpisode{}.FromBusinessModel(ep)
+			if err != nil {
+				return zaperr.Wrap(err, "failed to serialize episode")
+			}
+			values = append(values, placeholders)
+			args = append(args,
+				dbEp.ID, dbEp.UserID, dbEp.Title, dbEp.CreatedAt, dbEp.UpdatedAt, dbEp.SourceURL, dbEp.SourceFilepaths, dbEp.MediaryID, dbEp.URL,
+				dbEp.Status, dbEp.Duration, dbEp.FileLenBytes, dbEp.Format, dbEp.StorageKey, dbEp.Tags, dbEp.Pinned, dbEp.PubDate, dbEp.Explicit,
+				dbEp.SegmentDurations, dbEp.ChaptersURL, dbEp.ContentHash,
+			)
+		}
+
+		query := fmt.Sprintf(
+			"INSERT INTO episodes (%s) VALUES %s ON CONFLICT (user_id, id) DO UPDATE SET %s",
+			strings.Join(columns, ", "), strings.Join(values, ", "), strings.Join(updates, ", "),
+		)
+		if _, err := db.ExecContext(ctx, query, args...); err != nil {
+			return zaperr.Wrap(err, "failed to upsert episodes")
+		}
+	}
+
+	return nil
+}
+
 func (r *sqliteRepository) ListUserEpisodes(ctx context.Context, userID string) ([]*Episode, error) {
 	var dbEpisodes []dbEpisode
 	var epIDs []string
 	if res, err := r.dbFromContext(ctx).QueryxContext(ctx, `
-		SELECT * FROM episodes WHERE user_id = ?`, userID,
+		SELECT * FROM episodes WHERE user_id = ? AND deleted_at = ''`, userID,
 	); err != nil {
 		return nil, zaperr.Wrap(err, "failed to query episodes")
 	} else {
@@ -315,6 +415,73 @@ func (r *sqliteRepository) ListUserEpisodes(ctx context.Context, userID string)
 	return result, nil
 }
 
+// ListUserEpisodesPaged returns a page of user episodes ordered by ID, along with total number of user episodes
+func (r *sqliteRepository) ListUserEpisodesPaged(ctx context.Context, userID string, offset, limit int) ([]*Episode, int, error) {
+	db := r.dbFromContext(ctx)
+
+	var total int
+	if err := sqlx.GetContext(ctx, db, &total, `SELECT COUNT(*) FROM episodes WHERE user_id = ? AND deleted_at = ''`, userID); err != nil {
+		return nil, 0, zaperr.Wrap(err, "failed to count episodes")
+	}
+
+	var dbEpisodes []dbEpisode
+	if err := sqlx.SelectContext(ctx, db, &dbEpisodes, `
+		SELECT * FROM episodes
+			WHERE user_id = ?
+			AND deleted_at = ''
+			ORDER BY CAST(id AS INTEGER)
+			LIMIT ? OFFSET ?`,
+		userID, limit, offset,
+	); err != nil {
+		return nil, 0, zaperr.Wrap(err, "failed to query episodes")
+	}
+
+	result := make([]*Episode, 0, len(dbEpisodes))
+	for _, dbEp := range dbEpisodes {
+		if ep, err := dbEp.ToBusinessModel(); err != nil {
+			return nil, 0, zaperr.Wrap(err, "failed to convert episode to business model")
+		} else {
+			result = append(result, ep)
+		}
+	}
+
+	return result, total, nil
+}
+
+// SearchEpisodes returns user episodes whose title or source URL contain query, case-insensitively for ASCII
+func (r *sqliteRepository) SearchEpisodes(ctx context.Context, userID, query string) ([]*Episode, error) {
+	db := r.dbFromContext(ctx)
+
+	pattern := "%" + escapeLikePattern(query) + "%"
+	var dbEpisodes []dbEpisode
+	if err := sqlx.SelectContext(ctx, db, &dbEpisodes, `
+		SELECT * FROM episodes
+			WHERE user_id = ?
+			AND deleted_at = ''
+			AND (LOWER(title) LIKE LOWER(?) ESCAPE '\' OR LOWER(source_url) LIKE LOWER(?) ESCAPE '\')
+			ORDER BY CAST(id AS INTEGER)`,
+		userID, pattern, pattern,
+	); err != nil {
+		return nil, zaperr.Wrap(err, "failed to query episodes")
+	}
+
+	result := make([]*Episode, 0, len(dbEpisodes))
+	for _, dbEp := range dbEpisodes {
+		if ep, err := dbEp.ToBusinessModel(); err != nil {
+			return nil, zaperr.Wrap(err, "failed to convert episode to business model")
+		} else {
+			result = append(result, ep)
+		}
+	}
+
+	return result, nil
+}
+
+// escapeLikePattern makes LIKE wildcards in s match literally, given backslash is used as ESCAPE character
+func escapeLikePattern(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s)
+}
+
 func (r *sqliteRepository) ListFeedEpisodes(ctx context.Context, userID, feedID string) ([]*Episode, error) {
 	publications, err := r.ListPublicationsByFeedIDs(ctx, []string{feedID}, userID)
 	if err != nil {
@@ -343,6 +510,35 @@ func (r *sqliteRepository) ListFeedEpisodes(ctx context.Context, userID, feedID
 	return result, nil
 }
 
+// ListFeedEpisodesJoined returns feed episodes in the order they were published, using a single query
+func (r *sqliteRepository) ListFeedEpisodesJoined(ctx context.Context, userID, feedID string) ([]*Episode, error) {
+	db := r.dbFromContext(ctx)
+
+	var dbEpisodes []dbEpisode
+	if err := sqlx.SelectContext(ctx, db, &dbEpisodes, `
+		SELECT e.* FROM publications p
+			JOIN episodes e ON e.id = p.episode_id AND e.user_id = p.user_id
+			WHERE p.user_id = ?
+			AND p.feed_id = ?
+			AND e.deleted_at = ''
+			ORDER BY p.id`,
+		userID, feedID,
+	); err != nil {
+		return nil, zaperr.Wrap(err, "failed to query feed episodes")
+	}
+
+	result := make([]*Episode, len(dbEpisodes))
+	for idx, dbEp := range dbEpisodes {
+		if ep, err := dbEp.ToBusinessModel(); err != nil {
+			return nil, zaperr.Wrap(err, "failed to convert to business model")
+		} else {
+			result[idx] = ep
+		}
+	}
+
+	return result, nil
+}
+
 func (r *sqliteRepository) GetEpisodesMap(ctx context.Context, userID string, episodeIDs []string) (map[string]*Episode, error) {
 	if len(episodeIDs) == 0 {
 		return map[string]*Episode{}, nil
@@ -353,7 +549,8 @@ func (r *sqliteRepository) GetEpisodesMap(ctx context.Context, userID string, ep
 	query, args, err := sqlx.Named(`
 		SELECT * FROM episodes 
 			WHERE user_id=:user_id
-			AND id IN (:ids)`,
+			AND id IN (:ids)
+			AND deleted_at = ''`,
 		map[string]interface{}{
 			"user_id": userID,
 			"ids":     episodeIDs,
@@ -399,6 +596,32 @@ func (r *sqliteRepository) GetEpisodesMap(ctx context.Context, userID string, ep
 	return result, nil
 }
 
+// ListEpisodesByContentHash returns user episodes with given content hash, newest first. Deleted ones are not returned
+func (r *sqliteRepository) ListEpisodesByContentHash(ctx context.Context, userID string, contentHash string) ([]*Episode, error) {
+	var dbEpisodes []dbEpisode
+	if err := sqlx.SelectContext(ctx, r.dbFromContext(ctx), &dbEpisodes, `
+		SELECT * FROM episodes
+			WHERE user_id = ?
+			AND content_hash = ?
+			AND deleted_at = ''
+			ORDER BY created_at DESC`,
+		userID, contentHash,
+	); err != nil {
+		return nil, zaperr.Wrap(err, "failed to query episodes by content hash")
+	}
+
+	result := make([]*Episode, 0, len(dbEpisodes))
+	for _, dbEp := range dbEpisodes {
+		ep, err := dbEp.ToBusinessModel()
+		if err != nil {
+			return nil, zaperr.Wrap(err, "failed to convert to business model")
+		}
+		result = append(result, ep)
+	}
+
+	return result, nil
+}
+
 func (r *sqliteRepository) DeleteEpisodes(ctx context.Context, userID string, episodeIDs []string) error {
 	db := r.dbFromContext(ctx)
 	query, args, err := sqlx.Named(`
@@ -426,13 +649,117 @@ func (r *sqliteRepository) DeleteEpisodes(ctx context.Context, userID string, ep
 	return nil
 }
 
-func (r *sqliteRepository) ListExpiredEpisodes(ctx context.Context, maxAge time.Duration) ([]*Episode, error) {
-	db := r.dbFromContext(ctx)
+// SoftDeleteEpisodes moves episodes to trash, where they are hidden from everything but trash listings
+func (r *sqliteRepository) SoftDeleteEpisodes(ctx context.Context, userID string, episodeIDs []string, deletedAt time.Time) error {
+	return r.setEpisodesDeletedAt(ctx, userID, episodeIDs, timeToStr(deletedAt))
+}
+
+// RestoreEpisodes brings episodes back from trash
+func (r *sqliteRepository) RestoreEpisodes(ctx context.Context, userID string, episodeIDs []string) error {
+	return r.setEpisodesDeletedAt(ctx, userID, episodeIDs, "")
+}
 
-	minUpdatedAt := timeToStr(time.Now().UTC().Add(-maxAge))
+func (r *sqliteRepository) setEpisodesDeletedAt(ctx context.Context, userID string, episodeIDs []string, deletedAt string) error {
+	if len(episodeIDs) == 0 {
+		return nil
+	}
+
+	db := r.dbFromContext(ctx)
 	query, args, err := sqlx.Named(`
+		UPDATE episodes
+			SET deleted_at = :deleted_at
+			WHERE id IN (:ids)
+			AND user_id = :user_id`,
+		map[string]interface{}{
+			"deleted_at": deletedAt,
+			"ids":        episodeIDs,
+			"user_id":    userID,
+		},
+	)
+	if err != nil {
+		return zaperr.Wrap(err, "failed to create query")
+	}
+	query, args, err = sqlx.In(query, args...)
+	if err != nil {
+		return zaperr.Wrap(err, "failed to create IN query")
+	}
+	query = db.Rebind(query)
+
+	if _, err := db.ExecContext(ctx, query, args...); err != nil {
+		return zaperr.Wrap(err, "failed to update episodes deleted_at")
+	}
+
+	return nil
+}
+
+// ListUserDeletedEpisodes returns episodes in user's trash, most recently deleted first
+func (r *sqliteRepository) ListUserDeletedEpisodes(ctx context.Context, userID string) ([]*Episode, error) {
+	var dbEpisodes []dbEpisode
+	if err := sqlx.SelectContext(ctx, r.dbFromContext(ctx), &dbEpisodes, `
+		SELECT * FROM episodes
+			WHERE user_id = ?
+			AND deleted_at != ''
+			ORDER BY deleted_at DESC, CAST(id AS INTEGER)`,
+		userID,
+	); err != nil {
+		return nil, zaperr.Wrap(err, "failed to query deleted episodes")
+	}
+
+	result := make([]*Episode, len(dbEpisodes))
+	for idx, dbEp := range dbEpisodes {
+		if ep, err := dbEp.ToBusinessModel(); err != nil {
+			return nil, zaperr.Wrap(err, "failed to convert to business model")
+		} else {
+			result[idx] = ep
+		}
+	}
+
+	return result, nil
+}
+
+// ListDeletedEpisodes returns episodes of all users that have been in trash for longer than olderThan
+func (r *sqliteRepository) ListDeletedEpisodes(ctx context.Context, olderThan time.Duration) ([]*Episode, error) {
+	var dbEpisodes []dbEpisode
+	if err := sqlx.SelectContext(ctx, r.dbFromContext(ctx), &dbEpisodes, `
+		SELECT * FROM episodes
+			WHERE deleted_at != ''
+			AND deleted_at < ?
+			ORDER BY deleted_at`,
+		timeToStr(time.Now().UTC().Add(-olderThan)),
+	); err != nil {
+		return nil, zaperr.Wrap(err, "failed to query deleted episodes")
+	}
+
+	result := make([]*Episode, len(dbEpisodes))
+	for idx, dbEp := range dbEpisodes {
+		if ep, err := dbEp.ToBusinessModel(); err != nil {
+			return nil, zaperr.Wrap(err, "failed to convert to business model")
+		} else {
+			result[idx] = ep
+		}
+	}
+
+	return result, nil
+}
+
+// ListExpiredEpisodes lists episodes that were not updated for longer than their user's retention period,
+// defaultMaxAge applies to users who did not set one
+func (r *sqliteRepository) ListExpiredEpisodes(ctx context.Context, defaultMaxAge time.Duration) ([]*Episode, error) {
+	db := r.dbFromContext(ctx)
+
+	// times are stored as RFC3339 text in UTC, so cutoff computed by sqlite has to be formatted the same way
+	now := time.Now().UTC()
+	var dbEpisodes []dbEpisode
+	if err := sqlx.SelectContext(ctx, db, &dbEpisodes, `
 		SELECT e.* FROM episodes e
-		WHERE e.updated_at < :min_updated_at
+		LEFT JOIN users u ON u.id = e.user_id
+		WHERE e.updated_at < CASE
+			WHEN COALESCE(u.retention_days, 0) > 0
+				THEN strftime('%Y-%m-%dT%H:%M:%SZ', ?, '-' || u.retention_days || ' days')
+			ELSE ?
+		END
+		AND e.deleted_at = ''
+		AND NOT e.pinned
 		AND NOT EXISTS (
 			SELECT 1
 			FROM publications p
@@ -440,17 +767,61 @@ func (r *sqliteRepository) ListExpiredEpisodes(ctx context.Context, maxAge time.
 			WHERE f.is_permanent = true
 			AND p.episode_id = e.id
 			AND p.user_id = e.user_id
-		)
-	`, map[string]interface{}{
-		"min_updated_at": minUpdatedAt,
-	})
+		)`,
+		timeToStr(now),
+		timeToStr(now.Add(-defaultMaxAge)),
+	); err != nil {
+		return nil, zaperr.Wrap(err, "failed to query episodes")
+	}
+
+	result := make([]*Episode, len(dbEpisodes))
+	for idx, dbEp := range dbEpisodes {
+		if ep, err := dbEp.ToBusinessModel(); err != nil {
+			return nil, zaperr.Wrap(err, "failed to convert to business model")
+		} else {
+			result[idx] = ep
+		}
+	}
+
+	return result, nil
+}
+
+func (r *sqliteRepository) ListStuckEpisodes(ctx context.Context, olderThan time.Duration, statuses []EpisodeStatus) ([]*Episode, error) {
+	if len(statuses) == 0 {
+		return []*Episode{}, nil
+	}
+
+	db := r.dbFromContext(ctx)
+
+	statusStrings := make([]string, len(statuses))
+	for i, s := range statuses {
+		statusStrings[i] = string(s)
+	}
+
+	query, args, err := sqlx.Named(`
+		SELECT * FROM episodes
+			WHERE updated_at < :max_updated_at
+			AND deleted_at = ''
+			AND status IN (:statuses)
+			ORDER BY updated_at`,
+		map[string]interface{}{
+			"max_updated_at": timeToStr(time.Now().UTC().Add(-olderThan)),
+			"statuses":       statusStrings,
+		})
 	if err != nil {
 		return nil, zaperr.Wrap(err, "failed to create query")
 	}
 
+	query, args, err = sqlx.In(query, args...)
+	if err != nil {
+		return nil, zaperr.Wrap(err, "failed to create IN query")
+	}
+
+	query = db.Rebind(query)
+
 	var dbEpisodes []dbEpisode
 	if err := sqlx.SelectContext(ctx, db, &dbEpisodes, query, args...); err != nil {
-		return nil, zaperr.Wrap(err, "failed to query episodes")
+		return nil, zaperr.Wrap(err, "failed to query stuck episodes")
 	}
 
 	result := make([]*Episode, len(dbEpisodes))
@@ -570,6 +941,130 @@ func (r *sqliteRepository) ListPublicationsByFeedIDs(ctx context.Context, feedID
 	return result, nil
 }
 
+// CountFeedsEpisodesByStatus returns number of episodes published to each of given feeds, grouped by episode status
+func (r *sqliteRepository) CountFeedsEpisodesByStatus(ctx context.Context, userID string, feedIDs []string) (map[string]map[EpisodeStatus]int, error) {
+	result := make(map[string]map[EpisodeStatus]int, len(feedIDs))
+	if len(feedIDs) == 0 {
+		return result, nil
+	}
+
+	db := r.dbFromContext(ctx)
+
+	query, args, err := sqlx.Named(`
+		SELECT p.feed_id, e.status, COUNT(*) AS count FROM publications p
+			JOIN episodes e ON e.id = p.episode_id AND e.user_id = p.user_id
+			WHERE p.user_id=:user_id
+			AND p.feed_id IN (:feed_ids)
+			AND e.deleted_at = ''
+			GROUP BY p.feed_id, e.status`,
+		map[string]interface{}{
+			"user_id":  userID,
+			"feed_ids": feedIDs,
+		})
+	if err != nil {
+		return nil, zaperr.Wrap(err, "failed to create query")
+	}
+
+	query, args, err = sqlx.In(query, args...)
+	if err != nil {
+		return nil, zaperr.Wrap(err, "failed to create IN query")
+	}
+
+	query = db.Rebind(query)
+
+	var rows []struct {
+		FeedID string         `db:"feed_id"`
+		Status sql.NullString `db:"status"`
+		Count  int            `db:"count"`
+	}
+	if err := sqlx.SelectContext(ctx, db, &rows, query, args...); err != nil {
+		return nil, zaperr.Wrap(err, "failed to count feeds episodes by status")
+	}
+
+	for _, row := range rows {
+		if _, ok := result[row.FeedID]; !ok {
+			result[row.FeedID] = make(map[EpisodeStatus]int)
+		}
+		result[row.FeedID][EpisodeStatus(row.Status.String)] += row.Count
+	}
+
+	return result, nil
+}
+
+// GetUserStats aggregates user's feeds and episodes. Oldest and newest episode times are zero if user has no episodes
+func (r *sqliteRepository) GetUserStats(ctx context.Context, userID string) (*UserStats, error) {
+	db := r.dbFromContext(ctx)
+
+	stats := &UserStats{EpisodesByStatus: make(map[EpisodeStatus]int)}
+
+	if err := sqlx.GetContext(ctx, db, &stats.FeedsCount, `
+		SELECT COUNT(*) FROM feeds WHERE user_id = ?`, userID,
+	); err != nil {
+		return nil, zaperr.Wrap(err, "failed to count feeds")
+	}
+
+	var statusRows []struct {
+		Status string `db:"status"`
+		Count  int    `db:"count"`
+	}
+	if err := sqlx.SelectContext(ctx, db, &statusRows, `
+		SELECT status, COUNT(*) AS count FROM episodes WHERE user_id = ? AND deleted_at = '' GROUP BY status`, userID,
+	); err != nil {
+		return nil, zaperr.Wrap(err, "failed to count episodes by status")
+	}
+	for _, row := range statusRows {
+		stats.EpisodesByStatus[EpisodeStatus(row.Status)] = row.Count
+	}
+
+	// created_at is stored as RFC3339 in UTC, so it sorts as text
+	var totals struct {
+		TotalBytes sql.NullInt64  `db:"total_bytes"`
+		OldestAt   sql.NullString `db:"oldest_at"`
+		NewestAt   sql.NullString `db:"newest_at"`
+	}
+	if err := sqlx.GetContext(ctx, db, &totals, `
+		SELECT SUM(file_len_bytes) AS total_bytes, MIN(created_at) AS oldest_at, MAX(created_at) AS newest_at
+			FROM episodes WHERE user_id = ? AND deleted_at = ''`, userID,
+	); err != nil {
+		return nil, zaperr.Wrap(err, "failed to aggregate episodes")
+	}
+	stats.TotalBytes = totals.TotalBytes.Int64
+	if totals.OldestAt.Valid {
+		oldestAt, err := strToTime(totals.OldestAt.String)
+		if err != nil {
+			return nil, zaperr.Wrap(err, "failed to parse oldest episode time")
+		}
+		newestAt, err := strToTime(totals.NewestAt.String)
+		if err != nil {
+			return nil, zaperr.Wrap(err, "failed to parse newest episode time")
+		}
+		stats.OldestEpisodeAt, stats.NewestEpisodeAt = oldestAt, newestAt
+	}
+
+	return stats, nil
+}
+
+// CountEpisodesByStatus counts episodes of all users, grouped by status. Soft deleted episodes are not counted
+func (r *sqliteRepository) CountEpisodesByStatus(ctx context.Context) (map[EpisodeStatus]int, error) {
+	db := r.dbFromContext(ctx)
+
+	var rows []struct {
+		Status string `db:"status"`
+		Count  int    `db:"count"`
+	}
+	if err := sqlx.SelectContext(ctx, db, &rows, `
+		SELECT status, COUNT(*) AS count FROM episodes WHERE deleted_at = '' GROUP BY status`,
+	); err != nil {
+		return nil, zaperr.Wrap(err, "failed to count episodes by status")
+	}
+
+	counts := make(map[EpisodeStatus]int, len(rows))
+	for _, row := range rows {
+		counts[EpisodeStatus(row.Status)] = row.Count
+	}
+	return counts, nil
+}
+
 func (r *sqliteRepository) DeletePublications(ctx context.Context, userID string, publicationIDs []string) error {
 	if len(publicationIDs) == 0 {
 		return nil
@@ -605,6 +1100,109 @@ func (r *sqliteRepository) DeletePublications(ctx context.Context, userID string
 
 // endregion
 
+// region settings
+
+// GetSetting returns value of a setting or empty string if it was never set
+func (r *sqliteRepository) GetSetting(ctx context.Context, key string) (string, error) {
+	db := r.dbFromContext(ctx)
+
+	var value string
+	if err := sqlx.GetContext(ctx, db, &value, "SELECT value FROM settings WHERE key = ?", key); err != nil {
+		if err == sql.ErrNoRows {
+			return "", nil
+		}
+		return "", zaperr.Wrap(err, "failed to get setting")
+	}
+	return value, nil
+}
+
+func (r *sqliteRepository) SetSetting(ctx context.Context, key string, value string) error {
+	db := r.dbFromContext(ctx)
+
+	if _, err := db.ExecContext(ctx, `
+		INSERT INTO settings (key, value) VALUES (?, ?)
+		ON CONFLICT (key) DO UPDATE SET value = ?`,
+		key, value, value,
+	); err != nil {
+		return zaperr.Wrap(err, "failed to set setting")
+	}
+	return nil
+}
+
+// endregion
+
+// region webhooks
+
+// GetWebhookURL returns URL user wants to be notified at or empty string if it was never set
+func (r *sqliteRepository) GetWebhookURL(ctx context.Context, userID string) (string, error) {
+	db := r.dbFromContext(ctx)
+
+	var webhookURL string
+	if err := sqlx.GetContext(ctx, db, &webhookURL, "SELECT url FROM webhooks WHERE user_id = ?", userID); err != nil {
+		if err == sql.ErrNoRows {
+			return "", nil
+		}
+		return "", zaperr.Wrap(err, "failed to get webhook url")
+	}
+	return webhookURL, nil
+}
+
+// SetWebhookURL sets URL user wants to be notified at, empty URL removes it
+func (r *sqliteRepository) SetWebhookURL(ctx context.Context, userID string, webhookURL string) error {
+	db := r.dbFromContext(ctx)
+
+	if webhookURL == "" {
+		if _, err := db.ExecContext(ctx, "DELETE FROM webhooks WHERE user_id = ?", userID); err != nil {
+			return zaperr.Wrap(err, "failed to delete webhook url")
+		}
+		return nil
+	}
+
+	if _, err := db.ExecContext(ctx, `
+		INSERT INTO webhooks (user_id, url) VALUES (?, ?)
+		ON CONFLICT (user_id) DO UPDATE SET url = ?`,
+		userID, webhookURL, webhookURL,
+	); err != nil {
+		return zaperr.Wrap(err, "failed to set webhook url")
+	}
+	return nil
+}
+
+// endregion
+
+// region users
+
+// GetUserRetentionDays returns how many days user's episodes are kept, zero means default
+func (r *sqliteRepository) GetUserRetentionDays(ctx context.Context, userID string) (int, error) {
+	db := r.dbFromContext(ctx)
+
+	var days int
+	if err := sqlx.GetContext(ctx, db, &days, "SELECT retention_days FROM users WHERE id = ?", userID); err != nil {
+		if err == sql.ErrNoRows {
+			return 0, nil
+		}
+		return 0, zaperr.Wrap(err, "failed to get user retention days")
+	}
+	return days, nil
+}
+
+// SetUserRetentionDays sets how many days user's episodes are kept, zero brings back default.
+// Admin does not have to be added as a user, so the user row is created when missing
+func (r *sqliteRepository) SetUserRetentionDays(ctx context.Context, userID string, days int) error {
+	db := r.dbFromContext(ctx)
+
+	if _, err := db.ExecContext(ctx, `
+		INSERT INTO users (id, retention_days) VALUES (?, ?)
+		ON CONFLICT (id) DO UPDATE SET retention_days = ?`,
+		userID, days, days,
+	); err != nil {
+		return zaperr.Wrap(err, "failed to set user retention days")
+	}
+	return nil
+}
+
+// endregion
+
 // region private
 
 func (r *sqliteRepository) toBusinessFeeds(dbFeeds []dbFeed) ([]*Feed, error) {
@@ -639,6 +1237,15 @@ type dbEpisode struct {
 	FileLenBytes    int64         `db:"file_len_bytes"`
 	Format          string        `db:"format"`
 	StorageKey      string        `db:"storage_key"`
+	Tags            string        `db:"tags"`
+	Pinned          bool          `db:"pinned"`
+	PubDate         string        `db:"pub_date"`
+	Explicit        sql.NullBool  `db:"explicit"`
+	DeletedAt       string        `db:"deleted_at"`
+	// SegmentDurations are comma separated nanoseconds, the same unit duration is stored in
+	SegmentDurations string `db:"segment_durations"`
+	ChaptersURL      string `db:"chapters_url"`
+	ContentHash      string `db:"content_hash"`
 }
 
 func (dbEpisode) FromBusinessModel(ep *Episode) (*dbEpisode, error) {
@@ -651,21 +1258,45 @@ func (dbEpisode) FromBusinessModel(ep *Episode) (*dbEpisode, error) {
 	if ep.UpdatedAt.IsZero() {
 		return nil, fmt.Errorf(".UpdatedAt is zero")
 	}
+	var pubDate string
+	if !ep.PubDate.IsZero() {
+		pubDate = timeToStr(ep.PubDate)
+	}
+	var explicit sql.NullBool
+	if ep.Explicit != nil {
+		explicit = sql.NullBool{Bool: *ep.Explicit, Valid: true}
+	}
+	var deletedAt string
+	if !ep.DeletedAt.IsZero() {
+		deletedAt = timeToStr(ep.DeletedAt)
+	}
+	segmentDurations := make([]string, len(ep.SegmentDurations))
+	for i, d := range ep.SegmentDurations {
+		segmentDurations[i] = strconv.FormatInt(int64(d), 10)
+	}
 	return &dbEpisode{
-		ID:              ep.ID,
-		UserID:          ep.UserID,
-		Title:           ep.Title,
-		CreatedAt:       timeToStr(ep.CreatedAt),
-		UpdatedAt:       timeToStr(ep.UpdatedAt),
-		SourceURL:       ep.SourceURL,
-		SourceFilepaths: strings.Join(ep.SourceFilepaths, ","),
-		MediaryID:       ep.MediaryID,
-		URL:             ep.URL,
-		Status:          string(ep.Status),
-		Duration:        ep.Duration,
-		FileLenBytes:    ep.FileLenBytes,
-		Format:          ep.Format,
-		StorageKey:      ep.StorageKey,
+		ID:               ep.ID,
+		UserID:           ep.UserID,
+		Title:            ep.Title,
+		CreatedAt:        timeToStr(ep.CreatedAt),
+		UpdatedAt:        timeToStr(ep.UpdatedAt),
+		SourceURL:        ep.SourceURL,
+		SourceFilepaths:  strings.Join(ep.SourceFilepaths, ","),
+		MediaryID:        ep.MediaryID,
+		URL:              ep.URL,
+		Status:           string(ep.Status),
+		Duration:         ep.Duration,
+		FileLenBytes:     ep.FileLenBytes,
+		Format:           ep.Format,
+		StorageKey:       ep.StorageKey,
+		Tags:             strings.Join(ep.Tags, ","),
+		Pinned:           ep.Pinned,
+		PubDate:          pubDate,
+		Explicit:         explicit,
+		DeletedAt:        deletedAt,
+		SegmentDurations: strings.Join(segmentDurations, ","),
+		ChaptersURL:      ep.ChaptersURL,
+		ContentHash:      ep.ContentHash,
 	}, nil
 }
 
@@ -685,21 +1316,64 @@ func (d dbEpisode) ToBusinessModel() (*Episode, error) {
 		sourceFilePaths = strings.Split(d.SourceFilepaths, ",")
 	}
 
+	var tags []string
+	if d.Tags != "" {
+		tags = strings.Split(d.Tags, ",")
+	}
+
+	var explicit *bool
+	if d.Explicit.Valid {
+		explicit = &d.Explicit.Bool
+	}
+
+	var pubDate time.Time
+	if d.PubDate != "" {
+		if pubDate, err = strToTime(d.PubDate); err != nil {
+			return nil, zaperr.Wrap(err, "failed to parse pub_date")
+		}
+	}
+
+	var deletedAt time.Time
+	if d.DeletedAt != "" {
+		if deletedAt, err = strToTime(d.DeletedAt); err != nil {
+			return nil, zaperr.Wrap(err, "failed to parse deleted_at")
+		}
+	}
+
+	var segmentDurations []time.Duration
+	if d.SegmentDurations != "" {
+		for _, s := range strings.Split(d.SegmentDurations, ",") {
+			ns, err := strconv.ParseInt(s, 10, 64)
+			if err != nil {
+				return nil, zaperr.Wrap(err, "failed to parse segment_durations")
+			}
+			segmentDurations = append(segmentDurations, time.Duration(ns))
+		}
+	}
+
 	return &Episode{
-		ID:              d.ID,
-		UserID:          d.UserID,
-		Title:           d.Title,
-		CreatedAt:       createdAt,
-		UpdatedAt:       updatedAt,
-		SourceURL:       d.SourceURL,
-		SourceFilepaths: sourceFilePaths,
-		MediaryID:       d.MediaryID,
-		URL:             d.URL,
-		Status:          EpisodeStatus(d.Status),
-		Duration:        d.Duration,
-		FileLenBytes:    d.FileLenBytes,
-		Format:          d.Format,
-		StorageKey:      d.StorageKey,
+		ID:               d.ID,
+		UserID:           d.UserID,
+		Title:            d.Title,
+		CreatedAt:        createdAt,
+		UpdatedAt:        updatedAt,
+		SourceURL:        d.SourceURL,
+		SourceFilepaths:  sourceFilePaths,
+		MediaryID:        d.MediaryID,
+		URL:              d.URL,
+		Status:           EpisodeStatus(d.Status),
+		Duration:         d.Duration,
+		FileLenBytes:     d.FileLenBytes,
+		Format:           d.Format,
+		StorageKey:       d.StorageKey,
+		Tags:             tags,
+		Pinned:           d.Pinned,
+		PubDate:          pubDate,
+		Explicit:         explicit,
+		DeletedAt:        deletedAt,
+		SegmentDurations: segmentDurations,
+		ChaptersURL:      d.ChaptersURL,
+		ContentHash:      d.ContentHash,
 	}, nil
 }
 
@@ -708,33 +1382,105 @@ func (d dbEpisode) ToBusinessModel() (*Episode, error) {
 // region dbFeed
 
 type dbFeed struct {
-	ID          string `db:"id"`
-	UserID      string `db:"user_id"`
-	Title       string `db:"title"`
-	URL         string `db:"url"`
-	IsPermanent bool   `db:"is_permanent"`
+	ID             string `db:"id"`
+	UserID         string `db:"user_id"`
+	Title          string `db:"title"`
+	URL            string `db:"url"`
+	IsPermanent    bool   `db:"is_permanent"`
+	Copyright      string `db:"copyright"`
+	Normalize      bool   `db:"normalize"`
+	MaxTotalBytes  int64  `db:"max_total_bytes"`
+	ImageURL       string `db:"image_url"`
+	SortOrder      int    `db:"sort_order"`
+	MaxEpisodes    int    `db:"max_episodes"`
+	TTLMinutes     int    `db:"ttl_minutes"`
+	SkipHours      string `db:"skip_hours"`
+	SkipDays       string `db:"skip_days"`
+	TokenProtected bool   `db:"token_protected"`
+	AccessToken    string `db:"access_token"`
+	Description    string `db:"description"`
+	Author         string `db:"author"`
 }
 
 func (f dbFeed) FromBusinessModel(feed *Feed) interface{} {
 	return dbFeed{
-		ID:          feed.ID,
-		UserID:      feed.UserID,
-		Title:       feed.Title,
-		URL:         feed.URL,
-		IsPermanent: feed.IsPermanent,
+		ID:             feed.ID,
+		UserID:         feed.UserID,
+		Title:          feed.Title,
+		URL:            feed.URL,
+		IsPermanent:    feed.IsPermanent,
+		Copyright:      feed.Copyright,
+		Normalize:      feed.Normalize,
+		MaxTotalBytes:  feed.MaxTotalBytes,
+		ImageURL:       feed.ImageURL,
+		SortOrder:      feed.SortOrder,
+		MaxEpisodes:    feed.MaxEpisodes,
+		TTLMinutes:     feed.TTLMinutes,
+		SkipHours:      joinInts(feed.SkipHours),
+		SkipDays:       joinInts(feed.SkipDays),
+		TokenProtected: feed.TokenProtected,
+		AccessToken:    feed.AccessToken,
+		Description:    feed.Description,
+		Author:         feed.Author,
 	}
 }
 
 func (f dbFeed) ToBusinessModel() (*Feed, error) {
+	skipHours, err := splitInts[int](f.SkipHours)
+	if err != nil {
+		return nil, zaperr.Wrap(err, "failed to parse skip hours")
+	}
+	skipDays, err := splitInts[time.Weekday](f.SkipDays)
+	if err != nil {
+		return nil, zaperr.Wrap(err, "failed to parse skip days")
+	}
+
 	return &Feed{
-		ID:          f.ID,
-		UserID:      f.UserID,
-		Title:       f.Title,
-		URL:         f.URL,
-		IsPermanent: f.IsPermanent,
+		ID:             f.ID,
+		UserID:         f.UserID,
+		Title:          f.Title,
+		URL:            f.URL,
+		IsPermanent:    f.IsPermanent,
+		Copyright:      f.Copyright,
+		Normalize:      f.Normalize,
+		MaxTotalBytes:  f.MaxTotalBytes,
+		ImageURL:       f.ImageURL,
+		SortOrder:      f.SortOrder,
+		MaxEpisodes:    f.MaxEpisodes,
+		TTLMinutes:     f.TTLMinutes,
+		SkipHours:      skipHours,
+		SkipDays:       skipDays,
+		TokenProtected: f.TokenProtected,
+		AccessToken:    f.AccessToken,
+		Description:    f.Description,
+		Author:         f.Author,
 	}, nil
 }
 
+func joinInts[T ~int](values []T) string {
+	parts := make([]string, 0, len(values))
+	for _, v := range values {
+		parts = append(parts, strconv.Itoa(int(v)))
+	}
+	return strings.Join(parts, ",")
+}
+
+func splitInts[T ~int](s string) ([]T, error) {
+	if s == "" {
+		return nil, nil
+	}
+	parts := strings.Split(s, ",")
+	values := make([]T, 0, len(parts))
+	for _, p := range parts {
+		v, err := strconv.Atoi(p)
+		if err != nil {
+			return nil, err
+		}
+		values = append(values, T(v))
+	}
+	return values, nil
+}
+
 // endregion
 
 // region dbPublication
@@ -3,7 +3,10 @@ package service
 import (
 	"context"
 	"database/sql"
+	"fmt"
 	"reflect"
+	"sort"
+	"strconv"
 	"testing"
 	"time"
 
@@ -87,6 +90,8 @@ func TestSqliteRepository__Feeds(t *testing.T) {
 	// region update feed1
 	feed1.Title = "some-updated-title"
 	feed1.URL = "some-updated-url"
+	feed1.Description = "some-updated-description"
+	feed1.Author = "some-updated-author"
 	_, err = repo.SaveFeed(context.TODO(), feed1)
 	if err != nil {
 		t.Fatal(err)
@@ -357,6 +362,19 @@ func TestSqliteRepository__ListExpiredEpisodes(t *testing.T) {
 	}
 	// endregion
 
+	// region pinned episodes do not expire
+	staleEpisode.Pinned = true
+	if staleEpisode, err = repo.SaveEpisode(context.Background(), staleEpisode); err != nil {
+		t.Fatal(err)
+	}
+	if episodes, err = repo.ListExpiredEpisodes(context.Background(), expirationPeriod); err != nil {
+		t.Fatal(err)
+	}
+	if len(episodes) != 0 {
+		t.Fatalf("expected pinned episode not to expire, got %d expired episodes", len(episodes))
+	}
+	// endregion
+
 	// region publish episodes to permanent feed
 	permanentFeed := &Feed{
 		ID:          "permanent-feed-id",
@@ -387,11 +405,155 @@ func TestSqliteRepository__ListExpiredEpisodes(t *testing.T) {
 
 }
 
+func TestSqliteRepository__ListExpiredEpisodes_PerUserRetention(t *testing.T) {
+	repo := getRepo(t)
+	ctx := context.Background()
+
+	defaultMaxAge := 30 * 24 * time.Hour
+	shortUserID, longUserID, defaultUserID := "short-retention-user", "long-retention-user", "default-retention-user"
+	if err := repo.SetUserRetentionDays(ctx, shortUserID, 7); err != nil {
+		t.Fatal(err)
+	}
+	if err := repo.SetUserRetentionDays(ctx, longUserID, 90); err != nil {
+		t.Fatal(err)
+	}
+	if days, err := repo.GetUserRetentionDays(ctx, shortUserID); err != nil || days != 7 {
+		t.Fatalf("expected retention of 7 days, got %d, %v", days, err)
+	}
+	if days, err := repo.GetUserRetentionDays(ctx, defaultUserID); err != nil || days != 0 {
+		t.Fatalf("expected no retention for unknown user, got %d, %v", days, err)
+	}
+
+	// region every user has an episode last updated 10 days ago and another one 60 days ago
+	for _, userID := range []string{shortUserID, longUserID, defaultUserID} {
+		for _, ageDays := range []int{10, 60} {
+			updatedAt := time.Now().UTC().Add(-time.Duration(ageDays) * 24 * time.Hour)
+			if _, err := repo.SaveEpisode(ctx, &Episode{
+				ID:        fmt.Sprintf("%d-days-old", ageDays),
+				UserID:    userID,
+				CreatedAt: updatedAt,
+				UpdatedAt: updatedAt,
+			}); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	// endregion
+
+	episodes, err := repo.ListExpiredEpisodes(ctx, defaultMaxAge)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var expired []string
+	for _, ep := range episodes {
+		expired = append(expired, ep.UserID+"/"+ep.ID)
+	}
+	sort.Strings(expired)
+	expected := []string{
+		defaultUserID + "/60-days-old",
+		shortUserID + "/10-days-old",
+		shortUserID + "/60-days-old",
+	}
+	if !reflect.DeepEqual(expected, expired) {
+		t.Fatalf("expected expired episodes to be %v, got %v", expected, expired)
+	}
+
+	// region permanent feed protects episodes regardless of retention
+	if _, err := repo.SaveFeed(ctx, &Feed{ID: "permanent-feed-id", UserID: shortUserID, Title: "feed-title", IsPermanent: true}); err != nil {
+		t.Fatal(err)
+	}
+	if err := repo.BulkInsertPublications(ctx, []*Publication{
+		{UserID: shortUserID, FeedID: "permanent-feed-id", EpisodeID: "10-days-old"},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if episodes, err = repo.ListExpiredEpisodes(ctx, defaultMaxAge); err != nil {
+		t.Fatal(err)
+	}
+	if len(episodes) != 2 {
+		t.Fatalf("expected 2 expired episodes, got %d", len(episodes))
+	}
+	// endregion
+
+	// region zero retention brings back default
+	if err := repo.SetUserRetentionDays(ctx, shortUserID, 0); err != nil {
+		t.Fatal(err)
+	}
+	if episodes, err = repo.ListExpiredEpisodes(ctx, defaultMaxAge); err != nil {
+		t.Fatal(err)
+	}
+	if len(episodes) != 2 {
+		t.Fatalf("expected 2 expired episodes, got %d", len(episodes))
+	}
+	// endregion
+}
+
+func TestSqliteRepository__ListStuckEpisodes(t *testing.T) {
+	repo := getRepo(t)
+
+	userID := "some-user-id"
+
+	// region save a 3 days old downloading episode, a fresh downloading one and an old complete one
+	var err error
+	stuckEpisode := &Episode{
+		ID:        "stuck-episode-id",
+		UserID:    userID,
+		Status:    EpisodeStatusDownloading,
+		CreatedAt: time.Now().UTC().Add(-3 * 24 * time.Hour),
+		UpdatedAt: time.Now().UTC().Add(-3 * 24 * time.Hour),
+	}
+	if stuckEpisode, err = repo.SaveEpisode(context.Background(), stuckEpisode); err != nil {
+		t.Fatal(err)
+	}
+
+	freshEpisode := &Episode{
+		ID:        "fresh-episode-id",
+		UserID:    userID,
+		Status:    EpisodeStatusDownloading,
+		CreatedAt: time.Now().UTC(),
+		UpdatedAt: time.Now().UTC(),
+	}
+	if _, err = repo.SaveEpisode(context.Background(), freshEpisode); err != nil {
+		t.Fatal(err)
+	}
+
+	completeEpisode := &Episode{
+		ID:        "complete-episode-id",
+		UserID:    userID,
+		Status:    EpisodeStatusComplete,
+		CreatedAt: time.Now().UTC().Add(-3 * 24 * time.Hour),
+		UpdatedAt: time.Now().UTC().Add(-3 * 24 * time.Hour),
+	}
+	if _, err = repo.SaveEpisode(context.Background(), completeEpisode); err != nil {
+		t.Fatal(err)
+	}
+	// endregion
+
+	// region list stuck episodes
+	episodes, err := repo.ListStuckEpisodes(
+		context.Background(),
+		24*time.Hour,
+		[]EpisodeStatus{EpisodeStatusPending, EpisodeStatusDownloading},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(episodes) != 1 {
+		t.Fatalf("expected 1 stuck episode, got %d", len(episodes))
+	}
+	if !reflect.DeepEqual(stuckEpisode, episodes[0]) {
+		t.Errorf("expected stuck episode to be\n%v\n, got\n%v", stuckEpisode, episodes[0])
+	}
+	// endregion
+}
+
 func getRepo(t *testing.T) Repository {
 	db, err := sql.Open("sqlite3", ":memory:")
 	if err != nil {
 		t.Fatal(err)
 	}
+	// every connection to :memory: gets its own database, so concurrent queries must share a single one
+	db.SetMaxOpenConns(1)
 
 	repo := NewSqliteRepository(db)
 
@@ -405,3 +567,286 @@ func getRepo(t *testing.T) Repository {
 
 	return repo
 }
+
+func TestSqliteRepository__CountFeedsEpisodesByStatus(t *testing.T) {
+	repo := getRepo(t)
+	ctx := context.Background()
+
+	userID := "some-user-id"
+
+	// region save episodes in mixed statuses and publish them to two feeds
+	statuses := map[string]EpisodeStatus{
+		"1": EpisodeStatusComplete,
+		"2": EpisodeStatusComplete,
+		"3": EpisodeStatusComplete,
+		"4": EpisodeStatusProcessing,
+		"5": EpisodeStatusDownloading,
+	}
+	for epID, status := range statuses {
+		if _, err := repo.SaveEpisode(ctx, &Episode{
+			ID:        epID,
+			UserID:    userID,
+			Title:     "some episode",
+			Status:    status,
+			CreatedAt: time.Now().UTC(),
+			UpdatedAt: time.Now().UTC(),
+		}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var publications []*Publication
+	for _, epID := range []string{"1", "2", "3", "4"} {
+		publications = append(publications, &Publication{UserID: userID, FeedID: "feed-1", EpisodeID: epID})
+	}
+	publications = append(publications, &Publication{UserID: userID, FeedID: "feed-2", EpisodeID: "5"})
+	if err := repo.BulkInsertPublications(ctx, publications); err != nil {
+		t.Fatal(err)
+	}
+	// endregion
+
+	counts, err := repo.CountFeedsEpisodesByStatus(ctx, userID, []string{"feed-1", "feed-2", "feed-3"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := map[string]map[EpisodeStatus]int{
+		"feed-1": {EpisodeStatusComplete: 3, EpisodeStatusProcessing: 1},
+		"feed-2": {EpisodeStatusDownloading: 1},
+	}
+	if !reflect.DeepEqual(expected, counts) {
+		t.Errorf("expected counts to be\n%v\n, got\n%v", expected, counts)
+	}
+}
+
+func TestSqliteRepository__GetUserStats(t *testing.T) {
+	repo := getRepo(t)
+	ctx := context.Background()
+
+	userID := "some-user-id"
+
+	// region user without anything gets zero stats
+	stats, err := repo.GetUserStats(ctx, userID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stats.FeedsCount != 0 || len(stats.EpisodesByStatus) != 0 || stats.TotalBytes != 0 || !stats.OldestEpisodeAt.IsZero() || !stats.NewestEpisodeAt.IsZero() {
+		t.Fatalf("expected empty stats, got %+v", stats)
+	}
+	// endregion
+
+	// region save feeds and episodes of the user and someone else
+	for _, feed := range []*Feed{
+		{ID: "1", UserID: userID, Title: "feed 1"},
+		{ID: "2", UserID: userID, Title: "feed 2"},
+		{ID: "1", UserID: "other-user-id", Title: "other feed"},
+	} {
+		if _, err := repo.SaveFeed(ctx, feed); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	oldest := time.Date(2023, 1, 2, 3, 4, 5, 0, time.UTC)
+	newest := time.Date(2024, 6, 7, 8, 9, 10, 0, time.UTC)
+	for _, ep := range []*Episode{
+		{ID: "1", UserID: userID, Status: EpisodeStatusComplete, FileLenBytes: 100, CreatedAt: oldest},
+		{ID: "2", UserID: userID, Status: EpisodeStatusComplete, FileLenBytes: 200, CreatedAt: newest},
+		{ID: "3", UserID: userID, Status: EpisodeStatusFailed, CreatedAt: oldest.Add(time.Hour)},
+		{ID: "1", UserID: "other-user-id", Status: EpisodeStatusComplete, FileLenBytes: 1000, CreatedAt: oldest.Add(-time.Hour)},
+	} {
+		ep.Title = "some episode"
+		ep.UpdatedAt = ep.CreatedAt
+		if _, err := repo.SaveEpisode(ctx, ep); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// endregion
+
+	stats, err = repo.GetUserStats(ctx, userID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := &UserStats{
+		FeedsCount:       2,
+		EpisodesByStatus: map[EpisodeStatus]int{EpisodeStatusComplete: 2, EpisodeStatusFailed: 1},
+		TotalBytes:       300,
+		OldestEpisodeAt:  oldest,
+		NewestEpisodeAt:  newest,
+	}
+	if !reflect.DeepEqual(expected, stats) {
+		t.Errorf("expected stats to be\n%+v\n, got\n%+v", expected, stats)
+	}
+}
+
+func TestSqliteRepository__ListFeedEpisodesJoined(t *testing.T) {
+	repo := getRepo(t)
+	ctx := context.Background()
+
+	userID := "some-user-id"
+	feedID := "some-feed-id"
+
+	// region save episodes and publish them in an order different from their IDs
+	for _, epID := range []string{"1", "2", "3", "4"} {
+		if _, err := repo.SaveEpisode(ctx, &Episode{
+			ID:        epID,
+			UserID:    userID,
+			Title:     "episode " + epID,
+			Status:    EpisodeStatusComplete,
+			Tags:      []string{"some-tag"},
+			CreatedAt: time.Now().UTC(),
+			UpdatedAt: time.Now().UTC(),
+		}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var publications []*Publication
+	for _, epID := range []string{"3", "1", "4"} {
+		publications = append(publications, &Publication{UserID: userID, FeedID: feedID, EpisodeID: epID})
+	}
+	publications = append(publications, &Publication{UserID: userID, FeedID: "other-feed-id", EpisodeID: "2"})
+	if err := repo.BulkInsertPublications(ctx, publications); err != nil {
+		t.Fatal(err)
+	}
+	// endregion
+
+	twoStep, err := repo.ListFeedEpisodes(ctx, userID, feedID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	joined, err := repo.ListFeedEpisodesJoined(ctx, userID, feedID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(twoStep, joined) {
+		t.Errorf("expected joined episodes to be\n%v\n, got\n%v", twoStep, joined)
+	}
+
+	var joinedIDs []string
+	for _, ep := range joined {
+		joinedIDs = append(joinedIDs, ep.ID)
+	}
+	if !reflect.DeepEqual(joinedIDs, []string{"3", "1", "4"}) {
+		t.Errorf("expected episodes to be in publication order [3 1 4], got %v", joinedIDs)
+	}
+}
+
+func TestSqliteRepository__ListUserFeeds__SortOrder(t *testing.T) {
+	repo := getRepo(t)
+	ctx := context.Background()
+	userID := "some-user"
+
+	for _, f := range []*Feed{
+		{ID: "1", UserID: userID, Title: "default", SortOrder: 3},
+		{ID: "2", UserID: userID, Title: "never reordered"},
+		{ID: "10", UserID: userID, Title: "favorite", SortOrder: 1},
+		{ID: "3", UserID: userID, Title: "second favorite", SortOrder: 2},
+		{ID: "4", UserID: userID, Title: "also never reordered"},
+	} {
+		if _, err := repo.SaveFeed(ctx, f); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	feeds, err := repo.ListUserFeeds(ctx, userID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var feedIDs []string
+	for _, f := range feeds {
+		feedIDs = append(feedIDs, f.ID)
+	}
+	if !reflect.DeepEqual(feedIDs, []string{"10", "3", "1", "2", "4"}) {
+		t.Fatalf("expected feeds to be ordered by sort order, then by id, got %v", feedIDs)
+	}
+}
+
+func TestSqliteRepository__ListUserEpisodesPaged(t *testing.T) {
+	repo := getRepo(t)
+	ctx := context.Background()
+	userID := "some-user"
+
+	for i := 1; i <= 12; i++ {
+		if _, err := repo.SaveEpisode(ctx, &Episode{
+			ID:        strconv.Itoa(i),
+			UserID:    userID,
+			CreatedAt: time.Now().UTC(),
+			UpdatedAt: time.Now().UTC(),
+		}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := repo.SaveEpisode(ctx, &Episode{
+		ID:        "1",
+		UserID:    "other-user",
+		CreatedAt: time.Now().UTC(),
+		UpdatedAt: time.Now().UTC(),
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	episodes, total, err := repo.ListUserEpisodesPaged(ctx, userID, 10, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if total != 12 {
+		t.Fatalf("expected total of 12 episodes, got %d", total)
+	}
+	var epIDs []string
+	for _, ep := range episodes {
+		epIDs = append(epIDs, ep.ID)
+	}
+	if !reflect.DeepEqual(epIDs, []string{"11", "12"}) {
+		t.Fatalf("expected second page to hold episodes 11 and 12, got %v", epIDs)
+	}
+}
+
+func TestSqliteRepository__SearchEpisodes(t *testing.T) {
+	repo := getRepo(t)
+	ctx := context.Background()
+	userID := "some-user"
+
+	for _, ep := range []*Episode{
+		{ID: "1", UserID: userID, Title: "Lecture 1: Intro"},
+		{ID: "2", UserID: userID, Title: "Lecture 2: Graphs", SourceURL: "magnet:?dn=algorithms"},
+		{ID: "3", UserID: userID, Title: "100% Pure Jazz"},
+		{ID: "4", UserID: userID, Title: "1000 Pure Jazz"},
+		{ID: "5", UserID: userID, Title: "some_file"},
+		{ID: "6", UserID: userID, Title: "some file"},
+		{ID: "1", UserID: "other-user", Title: "Lecture 1: Intro"},
+	} {
+		ep.CreatedAt = time.Now().UTC()
+		ep.UpdatedAt = time.Now().UTC()
+		if _, err := repo.SaveEpisode(ctx, ep); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	search := func(query string) []string {
+		episodes, err := repo.SearchEpisodes(ctx, userID, query)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var epIDs []string
+		for _, ep := range episodes {
+			epIDs = append(epIDs, ep.ID)
+		}
+		return epIDs
+	}
+
+	if epIDs := search("lecture"); !reflect.DeepEqual(epIDs, []string{"1", "2"}) {
+		t.Errorf("expected title search to find episodes 1 and 2 of the user only, got %v", epIDs)
+	}
+	if epIDs := search("algorithms"); !reflect.DeepEqual(epIDs, []string{"2"}) {
+		t.Errorf("expected source url search to find episode 2, got %v", epIDs)
+	}
+	if epIDs := search("100%"); !reflect.DeepEqual(epIDs, []string{"3"}) {
+		t.Errorf("expected %% to match literally, got %v", epIDs)
+	}
+	if epIDs := search("some_file"); !reflect.DeepEqual(epIDs, []string{"5"}) {
+		t.Errorf("expected _ to match literally, got %v", epIDs)
+	}
+}
@@ -0,0 +1,38 @@
+package service
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// MaxTitleLength is the max number of characters in feed and episode titles
+const MaxTitleLength = 255
+
+var ErrInvalidTitle = fmt.Errorf("title must not be empty or longer than %d characters", MaxTitleLength)
+
+// sanitizeTitle replaces control characters, newlines included, with spaces and trims the title,
+// since they break both <title> of the feed and HTML messages title is rendered in.
+// ErrInvalidTitle is returned if nothing is left or the title is too long
+func sanitizeTitle(title string) (string, error) {
+	title = strings.TrimSpace(strings.Map(func(r rune) rune {
+		if unicode.IsControl(r) {
+			return ' '
+		}
+		return r
+	}, title))
+
+	if title == "" || utf8.RuneCountInString(title) > MaxTitleLength {
+		return "", ErrInvalidTitle
+	}
+	return title, nil
+}
+
+// truncateTitle shortens title coming from elsewhere to MaxTitleLength, instead of rejecting it
+func truncateTitle(title string) string {
+	if utf8.RuneCountInString(title) <= MaxTitleLength {
+		return title
+	}
+	return string([]rune(title)[:MaxTitleLength])
+}
@@ -0,0 +1,75 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"tg-podcastotron/mediary/mediarymocks"
+)
+
+func TestSanitizeTitle(t *testing.T) {
+	for _, tc := range []struct {
+		name          string
+		title         string
+		expectedTitle string
+		expectedErr   error
+	}{
+		{name: "valid", title: "Some Podcast", expectedTitle: "Some Podcast"},
+		{name: "surrounding whitespace is trimmed", title: "  Some Podcast \n", expectedTitle: "Some Podcast"},
+		{name: "control characters are replaced", title: "Some\nPodcast\x00\x07Title", expectedTitle: "Some Podcast  Title"},
+		{name: "non-ascii is kept", title: "Подкаст 🎧", expectedTitle: "Подкаст 🎧"},
+		{name: "empty", title: "", expectedErr: ErrInvalidTitle},
+		{name: "only whitespace and control characters", title: " \t\n\x1b ", expectedErr: ErrInvalidTitle},
+		{name: "max length", title: strings.Repeat("я", MaxTitleLength), expectedTitle: strings.Repeat("я", MaxTitleLength)},
+		{name: "overlong", title: strings.Repeat("a", MaxTitleLength+1), expectedErr: ErrInvalidTitle},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			title, err := sanitizeTitle(tc.title)
+			if !errors.Is(err, tc.expectedErr) {
+				t.Fatalf("expected error %v, got %v", tc.expectedErr, err)
+			}
+			if title != tc.expectedTitle {
+				t.Fatalf("expected title %q, got %q", tc.expectedTitle, title)
+			}
+		})
+	}
+}
+
+func TestService__InvalidTitles(t *testing.T) {
+	ctx := context.Background()
+	svc, _, _ := newTestService(t, &mediarymocks.ServiceMock{})
+	userID := "some-user"
+
+	if _, err := svc.CreateFeed(ctx, userID, " \n "); !errors.Is(err, ErrInvalidTitle) {
+		t.Errorf("expected feed with empty title not to be created, got %v", err)
+	}
+
+	feed, err := svc.CreateFeed(ctx, userID, "Some\nFeed")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if feed.Title != "Some Feed" {
+		t.Errorf("expected newline to be replaced in feed title, got %q", feed.Title)
+	}
+
+	if err := svc.RenameFeed(ctx, userID, feed.ID, strings.Repeat("a", MaxTitleLength+1)); !errors.Is(err, ErrInvalidTitle) {
+		t.Errorf("expected feed not to be renamed to overlong title, got %v", err)
+	}
+
+	saveTestEpisode(t, svc, &Episode{ID: "1", UserID: userID, Title: "old title", Status: EpisodeStatusComplete})
+	if err := svc.RenameEpisodes(ctx, userID, []string{"1"}, "\t"); !errors.Is(err, ErrInvalidTitle) {
+		t.Errorf("expected episode not to be renamed to empty title, got %v", err)
+	}
+	if err := svc.RenameEpisodes(ctx, userID, []string{"1"}, "new\x00title"); err != nil {
+		t.Fatal(err)
+	}
+	episodesMap, err := svc.GetEpisodesMap(ctx, userID, []string{"1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if title := episodesMap["1"].Title; title != "new title" {
+		t.Errorf("expected control character to be replaced in episode title, got %q", title)
+	}
+}
@@ -0,0 +1,198 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"path"
+
+	"github.com/hori-ryota/zaperr"
+	"go.uber.org/zap"
+	"golang.org/x/exp/maps"
+	"golang.org/x/exp/slices"
+)
+
+var ErrFeedBusy = fmt.Errorf("feed has episodes that are still being created")
+
+// TransferFeed moves a feed with all its episodes from one user to another, e.g. when someone changes Telegram accounts.
+// Feed and episodes get new IDs in destination user's sequence and their files are moved under destination user's prefix,
+// so feed URL changes. Episodes are removed from the rest of source user's feeds, since they don't belong to the user anymore.
+// Admin rights are up to the caller to check, adminUserID is only recorded in logs.
+func (svc *Service) TransferFeed(ctx context.Context, adminUserID string, feedID string, fromUserID string, toUserID string) (*Feed, error) {
+	zapFields := []zap.Field{
+		zap.String("admin_user_id", adminUserID),
+		zap.String("feed_id", feedID),
+		zap.String("from_user_id", fromUserID),
+		zap.String("to_user_id", toUserID),
+	}
+
+	if fromUserID == toUserID {
+		return nil, zaperr.New("can not transfer feed to its owner", zapFields...)
+	}
+
+	feed, err := svc.repository.GetFeed(ctx, fromUserID, feedID)
+	if err != nil {
+		return nil, zaperr.Wrap(err, "failed to get feed", zapFields...)
+	}
+	if feed == nil {
+		return nil, zaperr.Wrap(ErrFeedNotFound, "", zapFields...)
+	}
+
+	episodes, err := svc.repository.ListFeedEpisodesJoined(ctx, fromUserID, feedID)
+	if err != nil {
+		return nil, zaperr.Wrap(err, "failed to list feed episodes", zapFields...)
+	}
+	// mediary keeps uploading to the old location and polling keeps looking for the old episode,
+	// so episodes being created can't be moved
+	for _, ep := range episodes {
+		if ep.Status != EpisodeStatusComplete && ep.Status != EpisodeStatusFailed && ep.Status != EpisodeStatusCancelled {
+			return nil, zaperr.Wrap(ErrFeedBusy, "", append(zapFields, zap.String("episode_id", ep.ID))...)
+		}
+	}
+
+	// region copy files and create destination feed and episodes
+
+	var copiedKeys []string
+	cleanupCopies := func() {
+		for _, key := range copiedKeys {
+			if err := svc.s3Store.Delete(ctx, key); err != nil {
+				svc.logger.Error("failed to delete copied file", append(zapFields, zap.String("key", key), zaperr.ToField(err))...)
+			}
+		}
+	}
+
+	newFeed := *feed
+	newFeed.UserID = toUserID
+	newFeed.EpisodeIDs = nil
+	newFeed.SortOrder = 0
+	newFeed.ID = ""
+	for newFeed.ID == "" || newFeed.ID == DefaultFeedID {
+		if newFeed.ID, err = svc.repository.NextFeedID(ctx, toUserID); err != nil {
+			return nil, zaperr.Wrap(err, "failed to get next feed id", zapFields...)
+		}
+	}
+	if newFeed.URL, err = svc.feedURL(&newFeed); err != nil {
+		return nil, zaperr.Wrap(err, "failed to get feed url", zapFields...)
+	}
+	if feed.ImageURL != "" {
+		oldImageKey, newImageKey := svc.constructS3FeedImageKey(fromUserID, feedID), svc.constructS3FeedImageKey(toUserID, newFeed.ID)
+		if err := svc.s3Store.Copy(ctx, oldImageKey, newImageKey); err != nil {
+			return nil, zaperr.Wrap(err, "failed to copy feed image", zapFields...)
+		}
+		copiedKeys = append(copiedKeys, newImageKey)
+		if newFeed.ImageURL, err = svc.s3Store.URL(newImageKey); err != nil {
+			cleanupCopies()
+			return nil, zaperr.Wrap(err, "failed to get feed image url", zapFields...)
+		}
+	}
+
+	newEpisodes := make([]*Episode, 0, len(episodes))
+	for _, ep := range episodes {
+		zapFields := append(zapFields, zap.String("episode_id", ep.ID))
+
+		newEp := *ep
+		newEp.UserID = toUserID
+		newEp.FeedIDs = nil
+		if newEp.ID, err = svc.repository.NextEpisodeID(ctx, toUserID); err != nil {
+			cleanupCopies()
+			return nil, zaperr.Wrap(err, "failed to get next episode id", zapFields...)
+		}
+
+		// episodes imported from other podcasts are hosted elsewhere and have nothing to copy
+		if oldKey := svc.extractEpisodeS3Key(ep); oldKey != "" && ep.Status == EpisodeStatusComplete {
+			newKey := svc.constructS3EpisodeKey(toUserID, path.Base(oldKey))
+			if err := svc.s3Store.Copy(ctx, oldKey, newKey); err != nil {
+				cleanupCopies()
+				return nil, zaperr.Wrap(err, "failed to copy episode file", zapFields...)
+			}
+			copiedKeys = append(copiedKeys, newKey)
+			newEp.StorageKey = newKey
+			if newEp.URL, err = svc.s3Store.URL(newKey); err != nil {
+				cleanupCopies()
+				return nil, zaperr.Wrap(err, "failed to get episode url", zapFields...)
+			}
+			if ep.ChaptersURL != "" {
+				newChaptersKey := constructS3ChaptersKey(newKey)
+				if err := svc.s3Store.Copy(ctx, constructS3ChaptersKey(oldKey), newChaptersKey); err != nil {
+					cleanupCopies()
+					return nil, zaperr.Wrap(err, "failed to copy episode chapters", zapFields...)
+				}
+				copiedKeys = append(copiedKeys, newChaptersKey)
+				if newEp.ChaptersURL, err = svc.s3Store.URL(newChaptersKey); err != nil {
+					cleanupCopies()
+					return nil, zaperr.Wrap(err, "failed to get episode chapters url", zapFields...)
+				}
+			}
+		}
+		newEpisodes = append(newEpisodes, &newEp)
+	}
+
+	if err := svc.repository.Transaction(ctx, func(ctx context.Context) error {
+		if _, err := svc.repository.SaveFeed(ctx, &newFeed); err != nil {
+			return zaperr.Wrap(err, "failed to save feed")
+		}
+		publications := make([]*Publication, 0, len(newEpisodes))
+		for _, ep := range newEpisodes {
+			if _, err := svc.repository.SaveEpisode(ctx, ep); err != nil {
+				return zaperr.Wrap(err, "failed to save episode", zap.String("episode_id", ep.ID))
+			}
+			publications = append(publications, &Publication{
+				UserID:    toUserID,
+				FeedID:    newFeed.ID,
+				EpisodeID: ep.ID,
+				CreatedAt: ep.CreatedAt,
+			})
+		}
+		return svc.repository.BulkInsertPublications(ctx, publications)
+	}); err != nil {
+		cleanupCopies()
+		return nil, zaperr.Wrap(err, "failed to create transferred feed", zapFields...)
+	}
+
+	// endregion
+
+	// region remove source feed and episodes
+
+	epIDs := make([]string, 0, len(episodes))
+	for _, ep := range episodes {
+		epIDs = append(epIDs, ep.ID)
+	}
+	otherFeedIDs := make(map[string]struct{})
+	publications, err := svc.repository.ListPublicationsByEpisodeIDs(ctx, fromUserID, epIDs)
+	if err != nil {
+		return nil, zaperr.Wrap(err, "failed to list source publications", zapFields...)
+	}
+	for _, p := range publications {
+		if p.FeedID != feedID {
+			otherFeedIDs[p.FeedID] = struct{}{}
+		}
+	}
+
+	if err := svc.DeleteFeed(ctx, fromUserID, feedID, true); err != nil {
+		return nil, zaperr.Wrap(err, "failed to delete source feed", zapFields...)
+	}
+	if feed.ImageURL != "" {
+		if err := svc.s3Store.Delete(ctx, svc.constructS3FeedImageKey(fromUserID, feedID)); err != nil {
+			svc.logger.Error("failed to delete source feed image", append(zapFields, zaperr.ToField(err))...)
+		}
+	}
+
+	if len(otherFeedIDs) > 0 {
+		feedIDs := maps.Keys(otherFeedIDs)
+		slices.Sort(feedIDs)
+		if err := publish(ctx, svc.jobsQueue, queueEventRegenerateFeed, &RegenerateFeedQueuePayload{
+			UserID:  fromUserID,
+			FeedIDs: feedIDs,
+		}); err != nil {
+			return nil, zaperr.Wrap(err, "failed to enqueue source feeds regeneration", zapFields...)
+		}
+	}
+
+	// endregion
+
+	if err := svc.RegenerateFeed(ctx, toUserID, newFeed.ID); err != nil {
+		return nil, zaperr.Wrap(err, "failed to regenerate transferred feed", zapFields...)
+	}
+
+	svc.logger.Info("feed transferred", append(zapFields, zap.String("new_feed_id", newFeed.ID))...)
+	return &newFeed, nil
+}
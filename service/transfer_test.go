@@ -0,0 +1,137 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"golang.org/x/exp/slices"
+	"tg-podcastotron/mediary/mediarymocks"
+)
+
+func TestService__TransferFeed(t *testing.T) {
+	ctx := context.Background()
+	svc, jobsQueue, s3Store := newTestService(t, &mediarymocks.ServiceMock{})
+
+	fromUserID, toUserID := "old-user", "new-user"
+	feed, err := svc.CreateFeed(ctx, fromUserID, "some feed")
+	if err != nil {
+		t.Fatal(err)
+	}
+	otherFeed, err := svc.CreateFeed(ctx, fromUserID, "other feed")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	oldKeys := make([]string, 0, 2)
+	for _, epID := range []string{"1", "2"} {
+		key := svc.constructS3EpisodeKey(fromUserID, "file-"+epID+".mp3")
+		s3Store.objects[key] = []byte("episode " + epID)
+		oldKeys = append(oldKeys, key)
+		saveTestEpisode(t, svc, &Episode{
+			ID:         epID,
+			UserID:     fromUserID,
+			Title:      "episode " + epID,
+			URL:        "https://example.com/" + key,
+			StorageKey: key,
+			Status:     EpisodeStatusComplete,
+		})
+	}
+	if err := svc.PublishEpisodes(ctx, fromUserID, []string{"1", "2"}, []string{feed.ID}); err != nil {
+		t.Fatal(err)
+	}
+	if err := svc.PublishEpisodes(ctx, fromUserID, []string{"2"}, []string{feed.ID, otherFeed.ID}); err != nil {
+		t.Fatal(err)
+	}
+	jobsQueue.published = nil
+
+	transferred, err := svc.TransferFeed(ctx, "admin", feed.ID, fromUserID, toUserID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// region destination user owns the feed and its episodes
+	if transferred.UserID != toUserID || transferred.Title != "some feed" {
+		t.Fatalf("unexpected transferred feed: %+v", transferred)
+	}
+	toFeeds, err := svc.repository.ListUserFeeds(ctx, toUserID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(toFeeds) != 1 || toFeeds[0].ID != transferred.ID {
+		t.Fatalf("expected destination user to have transferred feed only, got %+v", toFeeds)
+	}
+	toEpisodes, err := svc.repository.ListFeedEpisodesJoined(ctx, toUserID, transferred.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(toEpisodes) != 2 {
+		t.Fatalf("expected 2 transferred episodes, got %d", len(toEpisodes))
+	}
+	for _, ep := range toEpisodes {
+		if ep.UserID != toUserID {
+			t.Errorf("expected episode %s to belong to %s, got %s", ep.ID, toUserID, ep.UserID)
+		}
+		if ep.StorageKey == "" || slices.Contains(oldKeys, ep.StorageKey) {
+			t.Errorf("expected episode %s to be stored under new key, got %q", ep.ID, ep.StorageKey)
+		}
+		if _, ok := s3Store.objects[ep.StorageKey]; !ok {
+			t.Errorf("expected episode %s file to be copied to %s", ep.ID, ep.StorageKey)
+		}
+		if ep.URL != "https://example.com/"+ep.StorageKey {
+			t.Errorf("expected episode %s url to point to new key, got %s", ep.ID, ep.URL)
+		}
+	}
+	// endregion
+
+	// region source user has nothing left but the other feed
+	fromFeed, err := svc.repository.GetFeed(ctx, fromUserID, feed.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fromFeed != nil {
+		t.Errorf("expected source feed to be deleted")
+	}
+	fromEpisodes, err := svc.repository.GetEpisodesMap(ctx, fromUserID, []string{"1", "2"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(fromEpisodes) != 0 {
+		t.Errorf("expected source episodes to be deleted, got %d", len(fromEpisodes))
+	}
+	for _, key := range oldKeys {
+		if _, ok := s3Store.objects[key]; ok {
+			t.Errorf("expected old key %s to be removed", key)
+		}
+	}
+	// endregion
+
+	regeneratedFeeds := map[string][]string{}
+	for _, payload := range publishedOf(t, jobsQueue, queueEventRegenerateFeed) {
+		regeneratedFeeds[payload.UserID] = append(regeneratedFeeds[payload.UserID], payload.FeedIDs...)
+	}
+	if !slices.Equal(regeneratedFeeds[toUserID], []string{transferred.ID}) {
+		t.Errorf("expected transferred feed to be regenerated, got %v", regeneratedFeeds[toUserID])
+	}
+	if !slices.Contains(regeneratedFeeds[fromUserID], otherFeed.ID) {
+		t.Errorf("expected other source feed to be regenerated, got %v", regeneratedFeeds[fromUserID])
+	}
+}
+
+func TestService__TransferFeed__Busy(t *testing.T) {
+	ctx := context.Background()
+	svc, _, _ := newTestService(t, &mediarymocks.ServiceMock{})
+
+	feed, err := svc.CreateFeed(ctx, "old-user", "some feed")
+	if err != nil {
+		t.Fatal(err)
+	}
+	saveTestEpisode(t, svc, &Episode{ID: "1", UserID: "old-user", Status: EpisodeStatusProcessing})
+	if err := svc.PublishEpisodes(ctx, "old-user", []string{"1"}, []string{feed.ID}); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := svc.TransferFeed(ctx, "admin", feed.ID, "old-user", "new-user"); !errors.Is(err, ErrFeedBusy) {
+		t.Fatalf("expected ErrFeedBusy, got %v", err)
+	}
+}
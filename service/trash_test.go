@@ -0,0 +1,123 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"tg-podcastotron/mediary/mediarymocks"
+)
+
+func TestService__Trash(t *testing.T) {
+	ctx := context.Background()
+	svc, _, s3Store := newTestService(t, &mediarymocks.ServiceMock{})
+
+	userID := "some-user"
+	feed, err := svc.CreateFeed(ctx, userID, "some feed")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	keys := make(map[string]string)
+	for _, epID := range []string{"1", "2"} {
+		key := svc.constructS3EpisodeKey(userID, "file-"+epID+".mp3")
+		s3Store.objects[key] = []byte("episode " + epID)
+		keys[epID] = key
+		saveTestEpisode(t, svc, &Episode{
+			ID:         epID,
+			UserID:     userID,
+			Title:      "episode " + epID,
+			URL:        "https://example.com/" + key,
+			StorageKey: key,
+			Status:     EpisodeStatusComplete,
+		})
+	}
+	if err := svc.PublishEpisodes(ctx, userID, []string{"1", "2"}, []string{feed.ID}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := svc.DeleteEpisodes(ctx, userID, []string{"1", "2"}); err != nil {
+		t.Fatal(err)
+	}
+
+	// region deleted episodes are hidden but their files are kept
+	if _, err := svc.GetEpisodesMap(ctx, userID, []string{"1"}); !errors.Is(err, ErrEpisodeNotFound) {
+		t.Fatalf("expected deleted episode to be hidden, got %v", err)
+	}
+	episodes, err := svc.ListUserEpisodes(ctx, userID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(episodes) != 0 {
+		t.Fatalf("expected no episodes in library, got %d", len(episodes))
+	}
+	feedEpisodes, err := svc.repository.ListFeedEpisodesJoined(ctx, userID, feed.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(feedEpisodes) != 0 {
+		t.Fatalf("expected no episodes in feed, got %d", len(feedEpisodes))
+	}
+	for epID, key := range keys {
+		if _, ok := s3Store.objects[key]; !ok {
+			t.Fatalf("expected episode %s file to be kept", epID)
+		}
+	}
+	trash, err := svc.ListDeletedEpisodes(ctx, userID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(trash) != 2 || trash[0].DeletedAt.IsZero() {
+		t.Fatalf("expected 2 episodes in trash, got %+v", trash)
+	}
+	// endregion
+
+	// region delete -> restore
+	if err := svc.RestoreEpisodes(ctx, userID, []string{"1"}); err != nil {
+		t.Fatal(err)
+	}
+	restored, err := svc.GetEpisodesMap(ctx, userID, []string{"1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !restored["1"].DeletedAt.IsZero() {
+		t.Fatalf("expected restored episode to have no deleted_at, got %v", restored["1"].DeletedAt)
+	}
+	// endregion
+
+	// region delete -> purge
+	// episodes deleted within retention period are not purged
+	purged, err := svc.PurgeDeletedEpisodes(ctx, DeletedEpisodesRetention)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if purged != 0 {
+		t.Fatalf("expected nothing to be purged, got %d", purged)
+	}
+
+	if err := svc.repository.SoftDeleteEpisodes(ctx, userID, []string{"2"}, time.Now().UTC().Add(-DeletedEpisodesRetention-time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+	purged, err = svc.PurgeDeletedEpisodes(ctx, DeletedEpisodesRetention)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if purged != 1 {
+		t.Fatalf("expected 1 episode to be purged, got %d", purged)
+	}
+	if _, ok := s3Store.objects[keys["2"]]; ok {
+		t.Fatalf("expected purged episode file to be deleted")
+	}
+	if _, ok := s3Store.objects[keys["1"]]; !ok {
+		t.Fatalf("expected restored episode file to be kept")
+	}
+	trash, err = svc.ListDeletedEpisodes(ctx, userID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(trash) != 0 {
+		t.Fatalf("expected trash to be empty, got %+v", trash)
+	}
+	// endregion
+}
@@ -0,0 +1,186 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/hori-ryota/zaperr"
+	"go.uber.org/zap"
+)
+
+// WebhookSignatureHeader carries hex encoded HMAC-SHA256 of request body, keyed by user's WebhookSigningKey
+const WebhookSignatureHeader = "X-Podcastotron-Signature"
+
+const webhookAttempts = 3
+
+var ErrInvalidWebhookURL = fmt.Errorf("invalid webhook url")
+
+// WebhookPayload is sent to user's webhook when an episode is complete
+type WebhookPayload struct {
+	EpisodeID       string   `json:"episode_id"`
+	Title           string   `json:"title"`
+	URL             string   `json:"url"`
+	DurationSeconds int64    `json:"duration_seconds"`
+	FeedIDs         []string `json:"feed_ids"`
+}
+
+func (svc *Service) GetWebhookURL(ctx context.Context, userID string) (string, error) {
+	webhookURL, err := svc.repository.GetWebhookURL(ctx, userID)
+	if err != nil {
+		return "", zaperr.Wrap(err, "failed to get webhook url", zap.String("user_id", userID))
+	}
+	return webhookURL, nil
+}
+
+// SetWebhookURL sets URL to notify about complete episodes at, empty URL disables notifications
+func (svc *Service) SetWebhookURL(ctx context.Context, userID string, webhookURL string) error {
+	zapFields := []zap.Field{
+		zap.String("user_id", userID),
+		zap.String("webhook_url", webhookURL),
+	}
+
+	if webhookURL != "" {
+		u, err := url.Parse(webhookURL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return ErrInvalidWebhookURL
+		}
+	}
+
+	if err := svc.repository.SetWebhookURL(ctx, userID, webhookURL); err != nil {
+		return zaperr.Wrap(err, "failed to set webhook url", zapFields...)
+	}
+	return nil
+}
+
+// WebhookSigningKey is the key user's webhook requests are signed with. It's derived from webhook secret,
+// so that every user can verify signatures without being able to forge requests to others
+func (svc *Service) WebhookSigningKey(userID string) string {
+	mac := hmac.New(sha256.New, []byte(svc.webhookSecret))
+	mac.Write([]byte(userID))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// enqueueWebhook schedules notification of user's webhook about complete episodes. Webhooks are best effort,
+// so failures are logged and never fail the caller
+func (svc *Service) enqueueWebhook(ctx context.Context, userID string, episodes []*Episode, epFeedsMap map[string][]string) {
+	if len(episodes) == 0 {
+		return
+	}
+	zapFields := []zap.Field{
+		zap.String("user_id", userID),
+	}
+
+	webhookURL, err := svc.repository.GetWebhookURL(ctx, userID)
+	if err != nil {
+		svc.logger.Error("failed to get webhook url", append(zapFields, zaperr.ToField(err))...)
+		return
+	}
+	if webhookURL == "" {
+		return
+	}
+
+	payload := &NotifyWebhookQueuePayload{
+		UserID:   userID,
+		Episodes: make([]WebhookPayload, 0, len(episodes)),
+	}
+	for _, ep := range episodes {
+		feedIDs := epFeedsMap[ep.ID]
+		if feedIDs == nil {
+			feedIDs = []string{}
+		}
+		payload.Episodes = append(payload.Episodes, WebhookPayload{
+			EpisodeID:       ep.ID,
+			Title:           ep.Title,
+			URL:             ep.URL,
+			DurationSeconds: int64(ep.Duration.Seconds()),
+			FeedIDs:         feedIDs,
+		})
+	}
+	if err := publish(ctx, svc.jobsQueue, queueEventNotifyWebhook, payload); err != nil {
+		svc.logger.Error("failed to enqueue webhook notification", append(zapFields, zaperr.ToField(err))...)
+	}
+}
+
+// onNotifyWebhookQueueEvent posts every episode to user's webhook. Failed webhooks are only logged,
+// as postWebhook already retries and requeuing would resend episodes that were delivered
+func (svc *Service) onNotifyWebhookQueueEvent(ctx context.Context, payloadBytes []byte) error {
+	var payload NotifyWebhookQueuePayload
+	if err := json.Unmarshal(payloadBytes, &payload); err != nil {
+		return zaperr.Wrap(err, "failed to unmarshal payload", zap.String("payload", string(payloadBytes)))
+	}
+
+	zapFields := []zap.Field{
+		zap.String("user_id", payload.UserID),
+	}
+
+	// webhook might have been changed or turned off since episodes were complete
+	webhookURL, err := svc.repository.GetWebhookURL(ctx, payload.UserID)
+	if err != nil {
+		return zaperr.Wrap(err, "failed to get webhook url", zapFields...)
+	}
+	if webhookURL == "" {
+		return nil
+	}
+
+	signingKey := svc.WebhookSigningKey(payload.UserID)
+	for _, ep := range payload.Episodes {
+		zapFields := append(zapFields, zap.String("episode_id", ep.EpisodeID), zap.String("webhook_url", webhookURL))
+		body, err := json.Marshal(&ep)
+		if err != nil {
+			svc.logger.Error("failed to marshal webhook payload", append(zapFields, zaperr.ToField(err))...)
+			continue
+		}
+		if err := svc.postWebhook(ctx, webhookURL, signingKey, body); err != nil {
+			svc.logger.Error("failed to notify webhook", append(zapFields, zaperr.ToField(err))...)
+		}
+	}
+	return nil
+}
+
+// postWebhook makes a few attempts, waiting longer after each failure
+func (svc *Service) postWebhook(ctx context.Context, webhookURL string, signingKey string, body []byte) error {
+	mac := hmac.New(sha256.New, []byte(signingKey))
+	mac.Write(body)
+	signature := hex.EncodeToString(mac.Sum(nil))
+
+	var err error
+	delay := svc.webhookRetryDelay
+	for attempt := 1; attempt <= webhookAttempts; attempt++ {
+		if attempt > 1 {
+			select {
+			case <-time.After(delay):
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+			delay *= 2
+		}
+
+		var req *http.Request
+		req, err = http.NewRequestWithContext(ctx, http.MethodPost, webhookURL, bytes.NewReader(body))
+		if err != nil {
+			return zaperr.Wrap(err, "failed to create webhook request")
+		}
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set(WebhookSignatureHeader, signature)
+
+		var resp *http.Response
+		resp, err = svc.httpClient.Do(req)
+		if err != nil {
+			continue
+		}
+		resp.Body.Close()
+		if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+			return nil
+		}
+		err = fmt.Errorf("webhook returned status code %d", resp.StatusCode)
+	}
+	return zaperr.Wrap(err, "webhook attempts exhausted", zap.Int("attempts", webhookAttempts))
+}
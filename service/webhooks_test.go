@@ -0,0 +1,134 @@
+package service
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"golang.org/x/exp/slices"
+	"tg-podcastotron/mediary"
+	"tg-podcastotron/mediary/mediarymocks"
+)
+
+func TestService__PollEpisodes__Webhook(t *testing.T) {
+	ctx := context.Background()
+	const secret = "some-secret"
+
+	// region webhook receiver failing first request to make sure it's retried
+	var mu sync.Mutex
+	var bodies [][]byte
+	var signatures []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		mu.Lock()
+		defer mu.Unlock()
+		bodies = append(bodies, body)
+		signatures = append(signatures, r.Header.Get(WebhookSignatureHeader))
+		if len(bodies) == 1 {
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	}))
+	defer srv.Close()
+	// endregion
+
+	svc, jobsQueue, _ := newTestService(t, &mediarymocks.ServiceMock{
+		FetchJobStatusBatchFunc: func(ctx context.Context, jobIDs []string) (map[string]*mediary.JobStatus, error) {
+			return map[string]*mediary.JobStatus{
+				"mediary-1": {Id: "mediary-1", Status: mediary.JobStatusComplete, ResultMediaDuration: 90 * time.Second},
+			}, nil
+		},
+	})
+	svc.webhookSecret = secret
+	svc.webhookRetryDelay = time.Millisecond
+
+	userID := "some-user"
+	if err := svc.SetWebhookURL(ctx, userID, srv.URL); err != nil {
+		t.Fatal(err)
+	}
+	feed, err := svc.CreateFeed(ctx, userID, "some feed")
+	if err != nil {
+		t.Fatal(err)
+	}
+	saveTestEpisode(t, svc, &Episode{
+		ID:        "1",
+		UserID:    userID,
+		Title:     "some episode",
+		MediaryID: "mediary-1",
+		URL:       "https://example.com/episodes/1.mp3",
+		Status:    EpisodeStatusProcessing,
+	})
+	if err := svc.PublishEpisodes(ctx, userID, []string{"1"}, []string{feed.ID}); err != nil {
+		t.Fatal(err)
+	}
+
+	pollEpisodes(t, svc, &PollEpisodesStatusQueuePayload{EpisodeIDs: []string{"1"}, UserID: userID})
+	<-svc.episodeStatusChangesChan
+
+	// region webhook is sent by its own job, not by polling
+	mu.Lock()
+	if len(bodies) != 0 {
+		t.Fatalf("expected polling not to wait for webhook, got %d requests", len(bodies))
+	}
+	mu.Unlock()
+	notifications := publishedOf(t, jobsQueue, queueEventNotifyWebhook)
+	if len(notifications) != 1 {
+		t.Fatalf("expected one webhook notification to be enqueued, got %d", len(notifications))
+	}
+	payloadBytes, err := json.Marshal(notifications[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := svc.onNotifyWebhookQueueEvent(ctx, payloadBytes); err != nil {
+		t.Fatal(err)
+	}
+	// endregion
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(bodies) != 2 {
+		t.Fatalf("expected failed webhook request to be retried once, got %d requests", len(bodies))
+	}
+
+	var payload WebhookPayload
+	if err := json.Unmarshal(bodies[1], &payload); err != nil {
+		t.Fatal(err)
+	}
+	if payload.EpisodeID != "1" || payload.Title != "some episode" || payload.URL != "https://example.com/episodes/1.mp3" {
+		t.Errorf("unexpected payload: %+v", payload)
+	}
+	if payload.DurationSeconds != 90 {
+		t.Errorf("expected duration of 90 seconds, got %d", payload.DurationSeconds)
+	}
+	if !slices.Equal(payload.FeedIDs, []string{feed.ID}) {
+		t.Errorf("expected feed ids %v, got %v", []string{feed.ID}, payload.FeedIDs)
+	}
+
+	// signing key is per user, so that users can't forge requests to each other's webhooks
+	signingKey := svc.WebhookSigningKey(userID)
+	if signingKey == secret || signingKey == svc.WebhookSigningKey("other-user") {
+		t.Errorf("expected signing key to be derived per user, got %s", signingKey)
+	}
+	mac := hmac.New(sha256.New, []byte(signingKey))
+	mac.Write(bodies[1])
+	if expected := hex.EncodeToString(mac.Sum(nil)); signatures[1] != expected {
+		t.Errorf("expected signature %s, got %s", expected, signatures[1])
+	}
+}
+
+func TestService__SetWebhookURL__Invalid(t *testing.T) {
+	svc, _, _ := newTestService(t, &mediarymocks.ServiceMock{})
+
+	for _, webhookURL := range []string{"not a url", "ftp://example.com/hook", "https://"} {
+		if err := svc.SetWebhookURL(context.Background(), "some-user", webhookURL); err != ErrInvalidWebhookURL {
+			t.Errorf("expected %q to be rejected, got %v", webhookURL, err)
+		}
+	}
+}
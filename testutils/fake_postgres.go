@@ -0,0 +1,50 @@
+package testsutils
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/docker/go-connections/nat"
+	"github.com/testcontainers/testcontainers-go"
+	"github.com/testcontainers/testcontainers-go/wait"
+)
+
+func GetFakePostgresURL(ctx context.Context) (postgresURL string, teardown func(), err error) {
+	var targetPort nat.Port = "5432"
+
+	req := testcontainers.ContainerRequest{
+		Image:        "postgres:16-alpine",
+		ExposedPorts: []string{fmt.Sprintf("%s/tcp", targetPort)},
+		Env: map[string]string{
+			"POSTGRES_USER":     "postgres",
+			"POSTGRES_PASSWORD": "postgres",
+		},
+		// postgres restarts once after initializing, so it's only ready the second time it says so
+		WaitingFor: wait.ForLog("database system is ready to accept connections").
+			WithOccurrence(2).
+			WithStartupTimeout(5 * time.Minute),
+	}
+
+	container, err := testcontainers.GenericContainer(ctx, testcontainers.GenericContainerRequest{
+		ContainerRequest: req,
+		Started:          true,
+	})
+	if err != nil {
+		return "", func() {}, err
+	}
+	teardown = func() { _ = container.Terminate(ctx) }
+
+	ip, err := container.Host(ctx)
+	if err != nil {
+		return "", teardown, err
+	}
+
+	port, err := container.MappedPort(ctx, targetPort)
+	if err != nil {
+		return "", teardown, err
+	}
+
+	url := fmt.Sprintf("postgres://postgres:postgres@%s:%s/postgres?sslmode=disable", ip, port.Port())
+	return url, teardown, nil
+}
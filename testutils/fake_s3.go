@@ -13,7 +13,7 @@ import (
 	"github.com/testcontainers/testcontainers-go/wait"
 )
 
-func getS3Client(ctx context.Context, bucketName string) (client *s3.Client, teardown func(), err error) {
+func GetFakeS3Client(ctx context.Context, bucketName string) (client *s3.Client, teardown func(), err error) {
 	req := testcontainers.ContainerRequest{
 		Image:        "localstack/localstack:latest",
 		ExposedPorts: []string{"4566/tcp"},